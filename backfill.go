@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"Backend/internal/config"
+	"Backend/internal/database"
+	"Backend/internal/logger"
+	"Backend/internal/models"
+	"Backend/internal/repository"
+	"Backend/internal/services"
+)
+
+// runBackfill implements "backfill": it re-ingests the upstream history between two
+// dates, or resumes a job, in the foreground. Ctrl-C pauses the job.
+func runBackfill(args []string) int {
+	fs := flag.NewFlagSet("backfill", flag.ContinueOnError)
+	from := fs.String("from", "", "Start date (YYYY-MM-DD or RFC 3339)")
+	to := fs.String("to", "", "End date; a date-only value includes that whole day")
+	resume := fs.Int64("resume", 0, "ID of a paused or failed job to resume")
+	configFile := fs.String("config", "", "Path to a YAML or TOML config file")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: stock-analyzer backfill --from DATE --to DATE | --resume ID [--config FILE]")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 2
+	}
+	resuming := *resume != 0 && *from == "" && *to == ""
+	starting := *resume == 0 && *from != "" && *to != ""
+	if !resuming && !starting || fs.NArg() > 0 {
+		fs.Usage()
+		return 2
+	}
+
+	var configArgs []string
+	if *configFile != "" {
+		configArgs = []string{"--config", *configFile}
+	}
+	cfg, err := config.Parse(configArgs)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Configuration error:\n%v\n", err)
+		return 1
+	}
+
+	log := logger.Setup(cfg.Log.Level, cfg.Log.Format).With("component", "main")
+	logger.AddSecrets(cfg.APIKey, string(cfg.JwtSecretKey), cfg.DatabaseURL, cfg.DB.ReplicaURL)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	db, err := database.ConnectWithRetry(ctx, cfg.DatabaseURL, cfg.DB)
+	if err != nil {
+		log.Error("error connecting to database", "error", err)
+		return 1
+	}
+	defer db.Close()
+
+	if err := database.Migrate(db); err != nil {
+		log.Error("error executing migrations", "error", err)
+		return 1
+	}
+
+	repo := repository.NewPostgresRepository(db, nil)
+	stockService := services.NewStockService(repo, repo, repo, cfg.Scoring)
+	client := services.NewAPIClient(cfg.APIKey, cfg.APIBaseURL, cfg.Backfill.PageDelay, 0)
+	backfillService := services.NewBackfillService(repo, stockService, client, cfg.Backfill)
+
+	var job *models.BackfillJob
+	if *resume != 0 {
+		job, err = backfillService.Resume(ctx, *resume)
+	} else {
+		start, end, rangeErr := services.ParseBackfillRange(*from, *to)
+		if rangeErr != nil {
+			fmt.Fprintln(os.Stderr, rangeErr)
+			return 2
+		}
+		job, err = backfillService.Run(ctx, start, end)
+	}
+	if err != nil {
+		log.Error("backfill failed", "error", err)
+		return 1
+	}
+
+	out, _ := json.MarshalIndent(job, "", "  ")
+	fmt.Println(string(out))
+
+	if job.Status == models.BackfillStatusPaused {
+		fmt.Fprintf(os.Stderr, "Backfill paused; resume it with: stock-analyzer backfill --resume %d\n", job.ID)
+	}
+	if job.Status == models.BackfillStatusFailed {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,216 @@
+package api
+
+import (
+	"Backend/internal/models"
+	"Backend/internal/services"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// @Summary Get alert history
+// @Description Retrieve the alerts triggered by the authenticated user's rules, newest first.
+// @Description Pass the ID of the last alert received as before to page back.
+// @Tags Alerts
+// @Produce json
+// @Param rule_id query int false "Only alerts of this rule"
+// @Param before  query int false "Only alerts with a lower ID"
+// @Param limit   query int false "Maximum alerts returned (default 50, max 500)"
+// @Success 200 {object} map[string][]models.Alert
+// @Failure 400 {object} map[string]string "error"
+// @Failure 401 {object} map[string]string "error"
+// @Failure 500 {object} map[string]string "error"
+// @Security BearerAuth
+// @Router /api/v1/alerts [get]
+func getAlerts(alertService *services.AlertService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var filters models.AlertFilters
+		if err := c.ShouldBindQuery(&filters); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid query parameters"})
+			return
+		}
+		filters.Username = currentUsername(c)
+
+		alerts, err := alertService.Alerts(c.Request.Context(), filters)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"alerts": alerts})
+	}
+}
+
+// @Summary List alert rules
+// @Description Retrieve the authenticated user's alert rules
+// @Tags Alerts
+// @Produce json
+// @Success 200 {object} map[string][]models.AlertRule
+// @Failure 401 {object} map[string]string "error"
+// @Failure 500 {object} map[string]string "error"
+// @Security BearerAuth
+// @Router /api/v1/alerts/rules [get]
+func getAlertRules(alertService *services.AlertService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		rules, err := alertService.ListRules(c.Request.Context(), currentUsername(c))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"rules": rules})
+	}
+}
+
+// @Summary Create an alert rule
+// @Description Create an alert rule for the authenticated user. Every condition set must hold for a
+// @Description change to trigger it: ticker, brokerage, change_type, rating (given by the change),
+// @Description score_above (the score rises past it) and target_change_pct (-20 is a target cut of 20% or more).
+// @Description Rules are evaluated after each sync.
+// @Tags Alerts
+// @Accept json
+// @Produce json
+// @Param rule body models.AlertRuleRequest true "Name and conditions"
+// @Success 201 {object} models.AlertRule
+// @Failure 400 {object} map[string]string "error"
+// @Failure 401 {object} map[string]string "error"
+// @Failure 409 {object} map[string]string "error"
+// @Failure 500 {object} map[string]string "error"
+// @Security BearerAuth
+// @Router /api/v1/alerts/rules [post]
+func createAlertRule(alertService *services.AlertService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var request models.AlertRuleRequest
+		if err := c.ShouldBindJSON(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Missing input parameters"})
+			return
+		}
+
+		rule, err := alertService.CreateRule(c.Request.Context(), currentUsername(c), request)
+		if err != nil {
+			c.JSON(alertErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusCreated, rule)
+	}
+}
+
+// @Summary Get an alert rule
+// @Description Retrieve one of the authenticated user's alert rules
+// @Tags Alerts
+// @Produce json
+// @Param id path int true "Alert rule ID"
+// @Success 200 {object} models.AlertRule
+// @Failure 400 {object} map[string]string "error"
+// @Failure 401 {object} map[string]string "error"
+// @Failure 404 {object} map[string]string "error"
+// @Failure 500 {object} map[string]string "error"
+// @Security BearerAuth
+// @Router /api/v1/alerts/rules/{id} [get]
+func getAlertRule(alertService *services.AlertService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, ok := alertRuleID(c)
+		if !ok {
+			return
+		}
+
+		rule, err := alertService.GetRule(c.Request.Context(), currentUsername(c), id)
+		if err != nil {
+			c.JSON(alertErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, rule)
+	}
+}
+
+// @Summary Replace an alert rule
+// @Description Replace the name and conditions of one of the authenticated user's alert rules
+// @Tags Alerts
+// @Accept json
+// @Produce json
+// @Param id   path int                     true "Alert rule ID"
+// @Param rule body models.AlertRuleRequest true "Name and conditions"
+// @Success 200 {object} models.AlertRule
+// @Failure 400 {object} map[string]string "error"
+// @Failure 401 {object} map[string]string "error"
+// @Failure 404 {object} map[string]string "error"
+// @Failure 500 {object} map[string]string "error"
+// @Security BearerAuth
+// @Router /api/v1/alerts/rules/{id} [put]
+func updateAlertRule(alertService *services.AlertService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, ok := alertRuleID(c)
+		if !ok {
+			return
+		}
+
+		var request models.AlertRuleRequest
+		if err := c.ShouldBindJSON(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Missing input parameters"})
+			return
+		}
+
+		rule, err := alertService.UpdateRule(c.Request.Context(), currentUsername(c), id, request)
+		if err != nil {
+			c.JSON(alertErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, rule)
+	}
+}
+
+// @Summary Delete an alert rule
+// @Description Delete one of the authenticated user's alert rules together with its alerts
+// @Tags Alerts
+// @Param id path int true "Alert rule ID"
+// @Success 204
+// @Failure 400 {object} map[string]string "error"
+// @Failure 401 {object} map[string]string "error"
+// @Failure 404 {object} map[string]string "error"
+// @Failure 500 {object} map[string]string "error"
+// @Security BearerAuth
+// @Router /api/v1/alerts/rules/{id} [delete]
+func deleteAlertRule(alertService *services.AlertService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, ok := alertRuleID(c)
+		if !ok {
+			return
+		}
+
+		if err := alertService.DeleteRule(c.Request.Context(), currentUsername(c), id); err != nil {
+			c.JSON(alertErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+
+		c.Status(http.StatusNoContent)
+	}
+}
+
+// alertRuleID parses the :id parameter, replying 400 when it is invalid
+func alertRuleID(c *gin.Context) (int64, bool) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid alert rule ID"})
+		return 0, false
+	}
+	return id, true
+}
+
+// alertErrorStatus maps alert rule errors to HTTP status codes
+func alertErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrInvalidAlertRule):
+		return http.StatusBadRequest
+	case errors.Is(err, services.ErrAlertRuleNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, services.ErrAlertRuleLimit):
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
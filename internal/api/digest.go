@@ -0,0 +1,136 @@
+package api
+
+import (
+	"Backend/internal/models"
+	"Backend/internal/services"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// @Summary Get the digest subscription
+// @Description Retrieve the authenticated user's email digest subscription
+// @Tags Digest
+// @Produce json
+// @Success 200 {object} models.DigestSubscription
+// @Failure 401 {object} map[string]string "error"
+// @Failure 404 {object} map[string]string "error"
+// @Failure 500 {object} map[string]string "error"
+// @Security BearerAuth
+// @Router /api/v1/digest/subscription [get]
+func getDigestSubscription(digestService *services.DigestService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		sub, err := digestService.Subscription(c.Request.Context(), currentUsername(c))
+		if err != nil {
+			c.JSON(digestErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, sub)
+	}
+}
+
+// @Summary Subscribe to the digest
+// @Description Opt the authenticated user in to the daily email digest, or change its address.
+// @Description The digest lists the recommendations of the day, the largest upgrades and downgrades,
+// @Description and the changes on the user's watchlists since the previous digest.
+// @Description "enabled": false pauses it.
+// @Tags Digest
+// @Accept json
+// @Produce json
+// @Param subscription body models.DigestSubscriptionRequest true "Email address"
+// @Success 200 {object} models.DigestSubscription
+// @Failure 400 {object} map[string]string "error"
+// @Failure 401 {object} map[string]string "error"
+// @Failure 500 {object} map[string]string "error"
+// @Security BearerAuth
+// @Router /api/v1/digest/subscription [put]
+func putDigestSubscription(digestService *services.DigestService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var request models.DigestSubscriptionRequest
+		if err := c.ShouldBindJSON(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Missing input parameters"})
+			return
+		}
+
+		sub, err := digestService.Subscribe(c.Request.Context(), currentUsername(c), request)
+		if err != nil {
+			c.JSON(digestErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, sub)
+	}
+}
+
+// @Summary Unsubscribe from the digest
+// @Description Delete the authenticated user's email digest subscription
+// @Tags Digest
+// @Success 204
+// @Failure 401 {object} map[string]string "error"
+// @Failure 404 {object} map[string]string "error"
+// @Failure 500 {object} map[string]string "error"
+// @Security BearerAuth
+// @Router /api/v1/digest/subscription [delete]
+func deleteDigestSubscription(digestService *services.DigestService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := digestService.Unsubscribe(c.Request.Context(), currentUsername(c)); err != nil {
+			c.JSON(digestErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+
+		c.Status(http.StatusNoContent)
+	}
+}
+
+// @Summary Preview the digest
+// @Description Render the digest the authenticated user would receive now, without sending it.
+// @Description Without a subscription it covers the newest changes.
+// @Tags Digest
+// @Produce html
+// @Produce plain
+// @Produce json
+// @Param format query string false "html (default), text or json"
+// @Success 200 {string} string "Rendered digest"
+// @Failure 400 {object} map[string]string "error"
+// @Failure 401 {object} map[string]string "error"
+// @Failure 500 {object} map[string]string "error"
+// @Security BearerAuth
+// @Router /api/v1/digest/preview [get]
+func previewDigest(digestService *services.DigestService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		format := c.DefaultQuery("format", "html")
+		if format != "html" && format != "text" && format != "json" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "format must be html, text or json"})
+			return
+		}
+
+		digest, email, err := digestService.Preview(c.Request.Context(), currentUsername(c))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		switch format {
+		case "json":
+			c.JSON(http.StatusOK, gin.H{"subject": email.Subject, "digest": digest})
+		case "text":
+			c.Data(http.StatusOK, "text/plain; charset=utf-8", []byte(email.Text))
+		default:
+			c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(email.HTML))
+		}
+	}
+}
+
+// digestErrorStatus maps digest subscription errors to HTTP status codes
+func digestErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrInvalidDigestSubscription):
+		return http.StatusBadRequest
+	case errors.Is(err, services.ErrDigestNotSubscribed):
+		return http.StatusNotFound
+	default:
+		return http.StatusInternalServerError
+	}
+}
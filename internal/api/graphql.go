@@ -0,0 +1,35 @@
+package api
+
+import (
+	"Backend/internal/graph"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// @Summary GraphQL query
+// @Description Query stocks, tickers, brokerages and recommendations in one request, selecting only the fields needed.
+// @Description Nested tickers and brokerages are loaded in batches. The schema can be introspected.
+// @Description Query errors are reported in "errors" with status 200, next to any data resolved.
+// @Tags GraphQL
+// @Accept json
+// @Produce json
+// @Param request body graph.Request true "Query, operation name and variables"
+// @Success 200 {object} map[string]interface{} "data and errors"
+// @Failure 400 {object} map[string]string "error"
+// @Failure 401 {object} map[string]string "error"
+// @Security BearerAuth
+// @Router /graphql [post]
+func graphQL(schema *graph.Schema) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req graph.Request
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "Missing input parameters",
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, schema.Exec(c.Request.Context(), req))
+	}
+}
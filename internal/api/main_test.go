@@ -0,0 +1,12 @@
+package api
+
+import (
+	"os"
+	"testing"
+
+	"Backend/internal/testutil"
+)
+
+func TestMain(m *testing.M) {
+	os.Exit(testutil.RunWithPostgres(m))
+}
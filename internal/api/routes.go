@@ -3,38 +3,118 @@ package api
 import (
 	"Backend/internal/config"
 	"Backend/internal/entity"
+	"Backend/internal/graph"
+	"Backend/internal/logger"
 	"Backend/internal/middleware"
 	"Backend/internal/models"
 	"Backend/internal/services"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"mime"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
-func SetupRoutes(r *gin.Engine, stockService *services.StockService, cfg *config.Config) {
+func SetupRoutes(r *gin.Engine, stockService *services.StockService, authService *services.AuthService, healthService *services.HealthService, backfillService *services.BackfillService, changeService *services.ChangeService, changeStream *services.ChangeStream, watchlistService *services.WatchlistService, alertService *services.AlertService, webhookService *services.WebhookService, digestService *services.DigestService, cfg *config.Config) {
 	r.GET("/health", healthCheck)
-	r.POST("/get-token", getToken(cfg))
+	r.GET("/health/live", livenessCheck)
+	r.GET("/health/ready", readinessCheck(healthService))
+	r.GET("/metrics", gin.WrapH(promhttp.Handler()))
+	r.POST("/get-token", getToken(cfg, authService))
+
+	auth := r.Group("/", middleware.AuthMiddleware(cfg, authService))
+	{
+		auth.POST("/refresh-token", refreshToken(cfg, authService))
+		auth.POST("/revoke-token", revokeToken(authService))
+		auth.POST("/graphql", graphQL(graph.NewSchema(stockService)))
+	}
 
 	api := r.Group("/api/v1")
 	{
 		api.GET("/stocks", getStocks(stockService))
+		api.GET("/stocks/export", exportStocks(stockService, cfg.HTTP.WriteTimeout))
 		api.GET("/recommendations", getRecommendations(stockService))
+		api.GET("/changes", getChanges(changeService))
+		api.GET("/stream", streamChanges(changeStream, cfg.Stream.Heartbeat))
+		api.GET("/ws", middleware.AuthMiddleware(cfg, authService), watchChanges(changeStream, cfg))
+	}
+
+	watchlists := api.Group("/watchlists", middleware.AuthMiddleware(cfg, authService))
+	{
+		watchlists.GET("", getWatchlists(watchlistService))
+		watchlists.POST("", createWatchlist(watchlistService))
+		watchlists.GET("/:id", getWatchlist(watchlistService))
+		watchlists.PATCH("/:id", renameWatchlist(watchlistService))
+		watchlists.DELETE("/:id", deleteWatchlist(watchlistService))
+		watchlists.POST("/:id/tickers", addWatchlistTickers(watchlistService))
+		watchlists.DELETE("/:id/tickers/:ticker", removeWatchlistTicker(watchlistService))
+		watchlists.GET("/:id/stocks", getWatchlistStocks(watchlistService))
+	}
+
+	alerts := api.Group("/alerts", middleware.AuthMiddleware(cfg, authService))
+	{
+		alerts.GET("", getAlerts(alertService))
+		alerts.GET("/rules", getAlertRules(alertService))
+		alerts.POST("/rules", createAlertRule(alertService))
+		alerts.GET("/rules/:id", getAlertRule(alertService))
+		alerts.PUT("/rules/:id", updateAlertRule(alertService))
+		alerts.DELETE("/rules/:id", deleteAlertRule(alertService))
+	}
+
+	webhooks := api.Group("/webhooks", middleware.AuthMiddleware(cfg, authService))
+	{
+		webhooks.GET("", getWebhooks(webhookService))
+		webhooks.POST("", createWebhook(webhookService))
+		webhooks.GET("/:id", getWebhook(webhookService))
+		webhooks.DELETE("/:id", deleteWebhook(webhookService))
+		webhooks.POST("/:id/test", testWebhook(webhookService))
+		webhooks.GET("/:id/deliveries", getWebhookDeliveries(webhookService))
+		webhooks.POST("/:id/deliveries/:delivery_id/replay", replayWebhookDelivery(webhookService))
+	}
+
+	digest := api.Group("/digest", middleware.AuthMiddleware(cfg, authService))
+	{
+		digest.GET("/subscription", getDigestSubscription(digestService))
+		digest.PUT("/subscription", putDigestSubscription(digestService))
+		digest.DELETE("/subscription", deleteDigestSubscription(digestService))
+		digest.GET("/preview", previewDigest(digestService))
+	}
+
+	admin := api.Group("/admin", middleware.AuthMiddleware(cfg, authService), middleware.RequireAdmin())
+	{
+		admin.GET("/auth-audit", getAuthAudit(authService))
+		admin.GET("/quarantine", getQuarantined(stockService))
+		admin.GET("/quarantine/:id", getQuarantinedEvent(stockService))
+		admin.POST("/quarantine/:id/replay", replayQuarantined(stockService))
+		admin.POST("/import", importStocks(stockService))
+		admin.GET("/backfill", getBackfillJobs(backfillService))
+		admin.POST("/backfill", startBackfill(backfillService))
+		admin.GET("/backfill/:id", getBackfillJob(backfillService))
+		admin.POST("/backfill/:id/resume", resumeBackfill(backfillService))
 	}
 }
 
 // @Summary Get authentication token
-// @Description Authenticate user and receive JWT token for API access
+// @Description Authenticate a user of AUTH_USERS with its password and receive JWT token for API access.
+// @Description Users listed in AUTH_ADMINS get a token with the admin role.
+// @Description Repeated failures lock the username and the client IP out with an exponential backoff.
 // @Tags Authentication
 // @Accept json
 // @Produce json
 // @Param credentials body entity.LoginRequest true "User credentials"
 // @Success 200 {object} map[string]string "token"
 // @Failure 400 {object} map[string]string "error"
+// @Failure 429 {object} map[string]string "error"
 // @Failure 500 {object} map[string]string "error"
 // @Router /get-token [post]
-func getToken(cfg *config.Config) gin.HandlerFunc {
+func getToken(cfg *config.Config, authService *services.AuthService) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		var loginRequest entity.LoginRequest
 
@@ -45,34 +125,509 @@ func getToken(cfg *config.Config) gin.HandlerFunc {
 			return
 		}
 
-		if loginRequest.Username != "dashboard" && loginRequest.Username != "admin" {
+		ip := c.ClientIP()
+
+		if until, locked := authService.LoginLockedUntil(loginRequest.Username, ip); locked {
+			recordAuthEvent(c, authService, models.AuthEvent{
+				Event:    models.AuthEventLoginLocked,
+				Username: loginRequest.Username,
+				IP:       ip,
+				Detail:   "locked until " + until.UTC().Format(time.RFC3339),
+			})
+
+			retryAfter := int(time.Until(until).Seconds()) + 1
+			c.Header("Retry-After", strconv.Itoa(retryAfter))
+			c.JSON(http.StatusTooManyRequests, gin.H{
+				"error": "Too many failed login attempts, try again later",
+			})
+			return
+		}
+
+		user, err := authService.Authenticate(loginRequest.Username, loginRequest.Password)
+		if err != nil {
+			authService.RegisterLoginFailure(loginRequest.Username, ip)
+			recordAuthEvent(c, authService, models.AuthEvent{
+				Event:    models.AuthEventLoginFailure,
+				Username: loginRequest.Username,
+				IP:       ip,
+				Detail:   err.Error(),
+			})
+
+			// Unknown users and wrong passwords get the same answer
 			c.JSON(http.StatusBadRequest, gin.H{
 				"error": "Unauthorized user",
 			})
 			return
 		}
 
-		if loginRequest.Username == "dashboard" || loginRequest.Username == "admin" {
-			user := &entity.UserJwt{
-				UserId:   1,
-				Username: loginRequest.Username,
+		token, err := middleware.GenerateToken(user, cfg)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Error generating token - " + err.Error(),
+			})
+			return
+		}
+
+		authService.RegisterLoginSuccess(loginRequest.Username, ip)
+		recordAuthEvent(c, authService, models.AuthEvent{
+			Event:    models.AuthEventLoginSuccess,
+			Username: loginRequest.Username,
+			IP:       ip,
+			Success:  true,
+		})
+
+		c.JSON(http.StatusOK, gin.H{
+			"token": token,
+		})
+	}
+}
+
+// @Summary Refresh authentication token
+// @Description Issue a new JWT token for the authenticated user
+// @Tags Authentication
+// @Produce json
+// @Success 200 {object} map[string]string "token"
+// @Failure 401 {object} map[string]string "error"
+// @Failure 500 {object} map[string]string "error"
+// @Security BearerAuth
+// @Router /refresh-token [post]
+func refreshToken(cfg *config.Config, authService *services.AuthService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// The role comes from the configuration again, so a revoked admin loses it
+		user := *c.MustGet("user").(*entity.UserJwt)
+		user.Role = authService.Role(user.Username)
+
+		token, err := middleware.GenerateToken(&user, cfg)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Error generating token - " + err.Error(),
+			})
+			return
+		}
+
+		recordAuthEvent(c, authService, models.AuthEvent{
+			Event:    models.AuthEventTokenRefresh,
+			Username: user.Username,
+			IP:       c.ClientIP(),
+			Success:  true,
+		})
+
+		c.JSON(http.StatusOK, gin.H{
+			"token": token,
+		})
+	}
+}
+
+// @Summary Revoke authentication token
+// @Description Revoke the JWT token used to authenticate this request
+// @Tags Authentication
+// @Produce json
+// @Success 200 {object} map[string]string "message"
+// @Failure 400 {object} map[string]string "error"
+// @Failure 401 {object} map[string]string "error"
+// @Failure 500 {object} map[string]string "error"
+// @Security BearerAuth
+// @Router /revoke-token [post]
+func revokeToken(authService *services.AuthService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claims := c.MustGet("claims").(*entity.Claimes)
+
+		if claims.ID == "" || claims.ExpiresAt == nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "Token cannot be revoked",
+			})
+			return
+		}
+
+		if err := authService.RevokeToken(claims.ID, claims.Username, claims.ExpiresAt.Time); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		recordAuthEvent(c, authService, models.AuthEvent{
+			Event:    models.AuthEventTokenRevoke,
+			Username: claims.Username,
+			IP:       c.ClientIP(),
+			Success:  true,
+		})
+
+		c.JSON(http.StatusOK, gin.H{
+			"message": "Token revoked",
+		})
+	}
+}
+
+// @Summary Get authentication audit log
+// @Description Retrieve login successes and failures, token refreshes and revocations. Admin only.
+// @Tags Admin
+// @Produce json
+// @Param username query string false "Filter by username"
+// @Param event    query string false "Filter by event (login_success, login_failure, login_locked, token_refresh, token_revoke)"
+// @Param ip       query string false "Filter by client IP"
+// @Param limit    query int    false "Maximum number of entries (default 100, max 1000)"
+// @Success 200 {object} map[string][]models.AuthEvent
+// @Failure 401 {object} map[string]string "error"
+// @Failure 403 {object} map[string]string "error"
+// @Failure 500 {object} map[string]string "error"
+// @Security BearerAuth
+// @Router /api/v1/admin/auth-audit [get]
+func getAuthAudit(authService *services.AuthService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var filters models.AuthAuditFilters
+
+		filters.Username = c.Query("username")
+		filters.Event = c.Query("event")
+		filters.IP = c.Query("ip")
+
+		if limit := c.Query("limit"); limit != "" {
+			if l, err := strconv.Atoi(limit); err == nil {
+				filters.Limit = l
 			}
+		}
 
-			fmt.Printf("user: %+v\n", user)
-			fmt.Printf("cfg: %+v\n", cfg)
+		events, err := authService.GetEvents(filters)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
 
-			token, err := middleware.GenerateToken(user, cfg)
-			if err != nil {
-				c.JSON(http.StatusInternalServerError, gin.H{
-					"error": "Error generating token - " + err.Error(),
-				})
+		c.JSON(http.StatusOK, gin.H{"events": events})
+	}
+}
+
+// @Summary List quarantined upstream records
+// @Description Retrieve upstream records that failed validation during a sync, most recently seen first. Admin only.
+// @Tags Admin
+// @Produce json
+// @Param status query string false "Filter by status (pending, replayed)"
+// @Param ticker query string false "Filter by ticker"
+// @Param limit  query int    false "Maximum number of entries (default 100, max 1000)"
+// @Success 200 {object} map[string][]models.QuarantinedEvent
+// @Failure 401 {object} map[string]string "error"
+// @Failure 403 {object} map[string]string "error"
+// @Failure 500 {object} map[string]string "error"
+// @Security BearerAuth
+// @Router /api/v1/admin/quarantine [get]
+func getQuarantined(stockService *services.StockService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var filters models.QuarantineFilters
+
+		filters.Status = c.Query("status")
+		filters.Ticker = c.Query("ticker")
+
+		if limit := c.Query("limit"); limit != "" {
+			if l, err := strconv.Atoi(limit); err == nil {
+				filters.Limit = l
+			}
+		}
+
+		events, err := stockService.ListQuarantined(c.Request.Context(), filters)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"events": events})
+	}
+}
+
+// @Summary Get a quarantined upstream record
+// @Description Retrieve one quarantined record with its payload and the validation failures. Admin only.
+// @Tags Admin
+// @Produce json
+// @Param id path int true "Quarantined event ID"
+// @Success 200 {object} models.QuarantinedEvent
+// @Failure 400 {object} map[string]string "error"
+// @Failure 401 {object} map[string]string "error"
+// @Failure 403 {object} map[string]string "error"
+// @Failure 404 {object} map[string]string "error"
+// @Failure 500 {object} map[string]string "error"
+// @Security BearerAuth
+// @Router /api/v1/admin/quarantine/{id} [get]
+func getQuarantinedEvent(stockService *services.StockService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid event ID"})
+			return
+		}
+
+		event, err := stockService.GetQuarantined(c.Request.Context(), id)
+		if err != nil {
+			c.JSON(quarantineErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, event)
+	}
+}
+
+// @Summary Replay a quarantined upstream record
+// @Description Validate a pending quarantined record again and store it. An optional body replaces the
+// @Description quarantined payload, to correct a record the provider sent malformed. Admin only.
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Param id     path int              true  "Quarantined event ID"
+// @Param record body services.APIStock false "Corrected record"
+// @Success 200 {object} models.QuarantinedEvent
+// @Failure 400 {object} map[string]string "error"
+// @Failure 401 {object} map[string]string "error"
+// @Failure 403 {object} map[string]string "error"
+// @Failure 404 {object} map[string]string "error"
+// @Failure 409 {object} map[string]string "error"
+// @Failure 422 {object} map[string]string "error"
+// @Failure 500 {object} map[string]string "error"
+// @Security BearerAuth
+// @Router /api/v1/admin/quarantine/{id}/replay [post]
+func replayQuarantined(stockService *services.StockService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid event ID"})
+			return
+		}
+
+		var correction *services.APIStock
+		if c.Request.ContentLength != 0 {
+			correction = &services.APIStock{}
+			if err := c.ShouldBindJSON(correction); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid record: " + err.Error()})
 				return
 			}
+		}
 
-			c.JSON(http.StatusOK, gin.H{
-				"token": token,
-			})
+		event, err := stockService.ReplayQuarantined(c.Request.Context(), id, correction)
+		if err != nil {
+			c.JSON(quarantineErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, event)
+	}
+}
+
+// quarantineErrorStatus maps quarantine errors to HTTP status codes
+func quarantineErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrQuarantineNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, services.ErrQuarantineResolved):
+		return http.StatusConflict
+	case errors.Is(err, services.ErrInvalidRecord):
+		return http.StatusUnprocessableEntity
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// @Summary Import stocks
+// @Description Bulk import of rating events the upstream API does not cover, as CSV with a header row or as JSON
+// @Description Lines, both in the shape of the upstream records (ticker, company, brokerage, action, rating_from,
+// @Description rating_to, target_from, target_to, time). The format comes from the format parameter or the Content-Type
+// @Description (text/csv, application/x-ndjson). Rows are validated and scored like the sync and stored with source
+// @Description "import"; invalid rows are skipped and the response reports every row. Admin only.
+// @Tags Admin
+// @Accept plain
+// @Produce json
+// @Param format  query string false "csv or jsonl, instead of the Content-Type"
+// @Param dry_run query bool   false "Only validate and score the rows"
+// @Param file    body  string true  "CSV or JSON Lines"
+// @Success 200 {object} models.ImportReport
+// @Failure 400 {object} map[string]string "error"
+// @Failure 401 {object} map[string]string "error"
+// @Failure 403 {object} map[string]string "error"
+// @Failure 413 {object} map[string]string "error"
+// @Failure 500 {object} map[string]string "error"
+// @Security BearerAuth
+// @Router /api/v1/admin/import [post]
+func importStocks(stockService *services.StockService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		dryRun, err := strconv.ParseBool(c.DefaultQuery("dry_run", "false"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid query parameters"})
+			return
+		}
+
+		format := c.Query("format")
+		if format == "" {
+			switch c.ContentType() {
+			case "text/csv":
+				format = services.ImportFormatCSV
+			case "application/x-ndjson", "application/jsonl", "application/jsonlines":
+				format = services.ImportFormatJSONL
+			}
+		}
+
+		report, err := stockService.Import(c.Request.Context(), c.Request.Body, format, dryRun)
+		if err != nil {
+			var tooLarge *http.MaxBytesError
+			switch {
+			case errors.Is(err, services.ErrInvalidImport):
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			case errors.As(err, &tooLarge):
+				c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "request body too large"})
+			default:
+				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			}
+			return
 		}
+
+		logger.FromContext(c.Request.Context()).Info("stocks imported",
+			"username", currentUsername(c),
+			"rows", report.Rows,
+			"imported", report.Imported,
+			"dry_run", report.DryRun,
+		)
+		c.JSON(http.StatusOK, report)
+	}
+}
+
+// @Summary Start a historical backfill
+// @Description Re-ingest the upstream events between two dates in the background. Dates are YYYY-MM-DD or
+// @Description RFC 3339; a date-only "to" includes that whole day. Only one backfill runs at a time. Admin only.
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Param range body models.BackfillRequest true "Date range"
+// @Success 202 {object} models.BackfillJob
+// @Failure 400 {object} map[string]string "error"
+// @Failure 401 {object} map[string]string "error"
+// @Failure 403 {object} map[string]string "error"
+// @Failure 409 {object} map[string]string "error"
+// @Failure 500 {object} map[string]string "error"
+// @Security BearerAuth
+// @Router /api/v1/admin/backfill [post]
+func startBackfill(backfillService *services.BackfillService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var request models.BackfillRequest
+		if err := c.ShouldBindJSON(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Missing input parameters"})
+			return
+		}
+
+		from, to, err := services.ParseBackfillRange(request.From, request.To)
+		if err != nil {
+			c.JSON(backfillErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+
+		job, err := backfillService.Start(from, to)
+		if err != nil {
+			c.JSON(backfillErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusAccepted, job)
+	}
+}
+
+// @Summary List backfill jobs
+// @Description Retrieve the most recent backfill jobs with their progress. Admin only.
+// @Tags Admin
+// @Produce json
+// @Param limit query int false "Maximum number of jobs (default 20, max 100)"
+// @Success 200 {object} map[string][]models.BackfillJob
+// @Failure 401 {object} map[string]string "error"
+// @Failure 403 {object} map[string]string "error"
+// @Failure 500 {object} map[string]string "error"
+// @Security BearerAuth
+// @Router /api/v1/admin/backfill [get]
+func getBackfillJobs(backfillService *services.BackfillService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		limit, _ := strconv.Atoi(c.Query("limit"))
+
+		jobs, err := backfillService.ListJobs(c.Request.Context(), limit)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"jobs": jobs})
+	}
+}
+
+// @Summary Get a backfill job
+// @Description Retrieve the status, cursor and counters of a backfill job. Admin only.
+// @Tags Admin
+// @Produce json
+// @Param id path int true "Backfill job ID"
+// @Success 200 {object} models.BackfillJob
+// @Failure 400 {object} map[string]string "error"
+// @Failure 401 {object} map[string]string "error"
+// @Failure 403 {object} map[string]string "error"
+// @Failure 404 {object} map[string]string "error"
+// @Failure 500 {object} map[string]string "error"
+// @Security BearerAuth
+// @Router /api/v1/admin/backfill/{id} [get]
+func getBackfillJob(backfillService *services.BackfillService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid job ID"})
+			return
+		}
+
+		job, err := backfillService.Job(c.Request.Context(), id)
+		if err != nil {
+			c.JSON(backfillErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, job)
+	}
+}
+
+// @Summary Resume a backfill job
+// @Description Continue a paused, failed or interrupted backfill from its saved cursor, in the background. Admin only.
+// @Tags Admin
+// @Produce json
+// @Param id path int true "Backfill job ID"
+// @Success 202 {object} models.BackfillJob
+// @Failure 400 {object} map[string]string "error"
+// @Failure 401 {object} map[string]string "error"
+// @Failure 403 {object} map[string]string "error"
+// @Failure 404 {object} map[string]string "error"
+// @Failure 409 {object} map[string]string "error"
+// @Failure 500 {object} map[string]string "error"
+// @Security BearerAuth
+// @Router /api/v1/admin/backfill/{id}/resume [post]
+func resumeBackfill(backfillService *services.BackfillService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid job ID"})
+			return
+		}
+
+		job, err := backfillService.ResumeInBackground(id)
+		if err != nil {
+			c.JSON(backfillErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusAccepted, job)
+	}
+}
+
+// backfillErrorStatus maps backfill errors to HTTP status codes
+func backfillErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrInvalidBackfillRange):
+		return http.StatusBadRequest
+	case errors.Is(err, services.ErrBackfillNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, services.ErrBackfillRunning), errors.Is(err, services.ErrBackfillCompleted):
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// recordAuthEvent stores an audit entry; a failure to audit never blocks authentication
+func recordAuthEvent(c *gin.Context, authService *services.AuthService, event models.AuthEvent) {
+	if err := authService.RecordEvent(event); err != nil {
+		logger.FromContext(c.Request.Context()).Error("error recording auth event", "event", event.Event, "error", err)
 	}
 }
 
@@ -91,44 +646,127 @@ func getToken(cfg *config.Config) gin.HandlerFunc {
 // @Param        page       query  int     false  "Page number for pagination"
 // @Param        limit      query  int     false  "Number of items per page"
 // @Param        today      query  string  false  "Filter for today's data"
+// @Param        source     query  string  false  "Only rows from this source (api, import)"
 // @Success      200        {object}  models.StockResponse  "List of stocks with metadata"
 
 // @Security     BearerAuth
 // @Router       /api/v1/stocks [get]
 func getStocks(stockService *services.StockService) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		var filters models.StockFilters
+		stocks, err := stockService.GetStocks(c.Request.Context(), stockFilters(c))
 
-		// Parse query parameters
-		filters.Ticker = c.Query("ticker")
-		filters.Company = c.Query("company")
-		filters.Action = c.Query("action")
-		filters.Rating = c.Query("rating")
-		filters.SortBy = c.Query("sort_by")
-		filters.Order = c.Query("order")
-		filters.Today = c.Query("today")
-
-		if page := c.Query("page"); page != "" {
-			if p, err := strconv.Atoi(page); err == nil {
-				filters.Page = p
-			}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
 		}
 
-		if limit := c.Query("limit"); limit != "" && limit != "-1" {
-			if l, err := strconv.Atoi(limit); err == nil {
-				filters.Limit = l
+		c.JSON(http.StatusOK, stocks)
+	}
+}
+
+// @Summary      Export stocks
+// @Description  Download the stocks matching the /api/v1/stocks filters as CSV, XLSX or Parquet, written as they are read
+// @Description  from the database. Rows follow sort_by and order (confidence, descending, by default). The file name
+// @Description  carries the time of the export, e.g. stocks-20260302-140507.csv. HTTP_WRITE_TIMEOUT applies to each write
+// @Description  rather than to the whole download.
+// @Tags         Stocks
+// @Produce      text/csv
+// @Produce      application/vnd.openxmlformats-officedocument.spreadsheetml.sheet
+// @Produce      application/vnd.apache.parquet
+// @Param        format     query  string  false  "csv (default), xlsx or parquet"
+// @Param        columns    query  string  false  "Comma-separated columns, in order: id, ticker, company, brokerage, action, rating_from, rating_to, target_from, target_to, score, confidence, source, time, created_at, updated_at (default all)"
+// @Param        ticker     query  string  false  "Stock ticker symbol"
+// @Param        company    query  string  false  "Company name"
+// @Param        sort_by    query  string  false  "Sort field"
+// @Param        order      query  string  false  "Sort order (asc, desc)"
+// @Param        limit      query  int     false  "Maximum number of rows"
+// @Param        today      query  string  false  "Filter for today's data"
+// @Param        source     query  string  false  "Only rows from this source (api, import)"
+// @Success      200        {file}    file  "Exported stocks"
+// @Failure      400        {object}  map[string]string  "error"
+// @Failure      500        {object}  map[string]string  "error"
+// @Router       /api/v1/stocks/export [get]
+func exportStocks(stockService *services.StockService, writeTimeout time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var columns []string
+		for _, column := range strings.Split(c.Query("columns"), ",") {
+			if column = strings.TrimSpace(column); column != "" {
+				columns = append(columns, column)
 			}
 		}
 
-		stocks, err := stockService.GetStocks(filters)
-
+		export, err := services.NewStockExport(c.Query("format"), columns, stockFilters(c))
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 			return
 		}
 
-		c.JSON(http.StatusOK, stocks)
+		c.Header("Content-Type", export.ContentType())
+		c.Header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": export.Filename(time.Now())}))
+		w := &deadlineWriter{w: c.Writer, rc: http.NewResponseController(c.Writer), timeout: writeTimeout}
+		if err := stockService.Export(c.Request.Context(), w, export); err != nil {
+			if c.Writer.Written() {
+				// The status is already sent, so closing the connection before the
+				// end of the body is the only way to tell the client the file is incomplete
+				logger.FromContext(c.Request.Context()).Error("error exporting stocks", "format", export.Format, "error", err)
+				if conn, _, err := c.Writer.Hijack(); err == nil {
+					conn.Close()
+				}
+				return
+			}
+			c.Header("Content-Disposition", "")
+			status := http.StatusInternalServerError
+			if errors.Is(err, services.ErrInvalidExport) {
+				status = http.StatusBadRequest
+			}
+			c.JSON(status, gin.H{"error": err.Error()})
+		}
+	}
+}
+
+// deadlineWriter renews the write deadline before every write, so a long
+// download outlasts the server's write timeout while the client keeps reading
+type deadlineWriter struct {
+	w       io.Writer
+	rc      *http.ResponseController
+	timeout time.Duration
+}
+
+func (d *deadlineWriter) Write(p []byte) (int, error) {
+	if d.timeout > 0 {
+		// Unsupported by test recorders, where there is no deadline to renew
+		_ = d.rc.SetWriteDeadline(time.Now().Add(d.timeout))
+	}
+	return d.w.Write(p)
+}
+
+// stockFilters parses the stock query parameters shared by the stock listings
+func stockFilters(c *gin.Context) models.StockFilters {
+	var filters models.StockFilters
+
+	// Parse query parameters
+	filters.Ticker = c.Query("ticker")
+	filters.Company = c.Query("company")
+	filters.Action = c.Query("action")
+	filters.Rating = c.Query("rating")
+	filters.SortBy = c.Query("sort_by")
+	filters.Order = c.Query("order")
+	filters.Today = c.Query("today")
+	filters.Source = c.Query("source")
+
+	if page := c.Query("page"); page != "" {
+		if p, err := strconv.Atoi(page); err == nil {
+			filters.Page = p
+		}
+	}
+
+	if limit := c.Query("limit"); limit != "" && limit != "-1" {
+		if l, err := strconv.Atoi(limit); err == nil {
+			filters.Limit = l
+		}
 	}
+
+	return filters
 }
 
 // @Summary Get stock recommendations
@@ -142,7 +780,7 @@ func getStocks(stockService *services.StockService) gin.HandlerFunc {
 // @Router /api/v1/recommendations [get]
 func getRecommendations(stockService *services.StockService) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		recommendations, err := stockService.GetRecommendations()
+		recommendations, err := stockService.GetRecommendations(c.Request.Context())
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
@@ -152,6 +790,158 @@ func getRecommendations(stockService *services.StockService) gin.HandlerFunc {
 	}
 }
 
+// @Summary Get the change feed
+// @Description Retrieve new coverage, upgrades, downgrades and target changes in the order they were stored.
+// @Description Pass the returned cursor as since to continue where the previous page ended.
+// @Tags Stocks
+// @Produce json
+// @Param since query int false "Cursor returned by the previous page; omit to start from the beginning"
+// @Param limit query int false "Maximum number of changes (default 100, max 1000)"
+// @Success 200 {object} models.ChangeFeed
+// @Failure 400 {object} map[string]string "error"
+// @Failure 500 {object} map[string]string "error"
+// @Router /api/v1/changes [get]
+func getChanges(changeService *services.ChangeService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		since, err := services.ParseCursor(c.Query("since"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		limit := 0
+		if l, err := strconv.Atoi(c.Query("limit")); err == nil {
+			limit = l
+		}
+
+		feed, err := changeService.Changes(c.Request.Context(), since, limit)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, feed)
+	}
+}
+
+// @Summary Stream live rating changes
+// @Description Server-Sent Events stream of the change feed. "change" events carry a new coverage, upgrade, downgrade,
+// @Description target or score change and have the feed cursor as their ID, so a reconnecting client resumes after
+// @Description the Last-Event-ID header (or last_event_id parameter). "top_pick" is sent on connect and whenever the
+// @Description top recommendation changes; "heartbeat" is sent when the stream is idle.
+// @Tags Stocks
+// @Produce text/event-stream
+// @Param ticker        query  string false "Comma-separated tickers to follow"
+// @Param brokerage     query  string false "Comma-separated brokerages to follow"
+// @Param last_event_id query  int    false "Resume after this change ID, when the Last-Event-ID header cannot be set"
+// @Param Last-Event-ID header int    false "Resume after this change ID"
+// @Success 200 {object} models.StreamEvent
+// @Failure 400 {object} map[string]string "error"
+// @Failure 500 {object} map[string]string "error"
+// @Router /api/v1/stream [get]
+func streamChanges(changeStream *services.ChangeStream, heartbeat time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		since := int64(-1)
+		lastEventID := c.GetHeader("Last-Event-ID")
+		if lastEventID == "" {
+			lastEventID = c.Query("last_event_id")
+		}
+		if lastEventID != "" {
+			cursor, err := services.ParseCursor(lastEventID)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			since = cursor
+		}
+
+		filter := services.ChangeFilter{Tickers: splitList(c.Query("ticker")), Brokerages: splitList(c.Query("brokerage"))}
+
+		ctx := c.Request.Context()
+		sub, err := changeStream.Subscribe(ctx, since, filter)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		defer sub.Close()
+
+		// The stream outlives the server's write timeout
+		if err := http.NewResponseController(c.Writer).SetWriteDeadline(time.Time{}); err != nil {
+			logger.FromContext(ctx).Debug("stream write deadline not cleared", "error", err)
+		}
+
+		c.Header("Content-Type", "text/event-stream")
+		c.Header("Cache-Control", "no-cache")
+		c.Header("Connection", "keep-alive")
+		c.Header("X-Accel-Buffering", "no")
+		c.Status(http.StatusOK)
+		c.Writer.Flush()
+
+		idle := time.NewTimer(heartbeat)
+		defer idle.Stop()
+
+		for {
+			events, err := sub.Next(ctx)
+			if err != nil {
+				if ctx.Err() == nil {
+					logger.FromContext(ctx).Error("error reading change stream", "error", err)
+				}
+				return
+			}
+			if len(events) > 0 {
+				for _, event := range events {
+					if err := writeStreamEvent(c.Writer, event); err != nil {
+						return
+					}
+				}
+				c.Writer.Flush()
+				idle.Reset(heartbeat)
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-sub.Done():
+				return
+			case <-sub.Ready():
+			case now := <-idle.C:
+				if err := writeStreamEvent(c.Writer, models.StreamEvent{Type: models.StreamEventHeartbeat, Time: now}); err != nil {
+					return
+				}
+				c.Writer.Flush()
+				idle.Reset(heartbeat)
+			}
+		}
+	}
+}
+
+// writeStreamEvent writes event in the Server-Sent Events format
+func writeStreamEvent(w io.Writer, event models.StreamEvent) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	if event.ID != 0 {
+		if _, err := fmt.Fprintf(w, "id: %d\n", event.ID); err != nil {
+			return err
+		}
+	}
+	_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Type, data)
+	return err
+}
+
+// splitList splits a comma-separated query parameter, dropping empty items
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
 // @Summary Health check
 // @Description Check if the API is running and healthy
 // @Tags Health
@@ -165,3 +955,36 @@ func healthCheck(c *gin.Context) {
 		"message": "API working correctly",
 	})
 }
+
+// @Summary Liveness check
+// @Description Report that the process is running. Does not check dependencies.
+// @Tags Health
+// @Produce json
+// @Success 200 {object} map[string]string
+// @Router /health/live [get]
+func livenessCheck(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"status": models.HealthStatusUp,
+	})
+}
+
+// @Summary Readiness check
+// @Description Check the database, schema migrations, data freshness and the last sync run.
+// @Description Returns 503 when any component is down; stale data or a failed sync is reported as degraded.
+// @Tags Health
+// @Produce json
+// @Success 200 {object} models.HealthReport
+// @Failure 503 {object} models.HealthReport
+// @Router /health/ready [get]
+func readinessCheck(healthService *services.HealthService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		report := healthService.Readiness(c.Request.Context())
+
+		status := http.StatusOK
+		if report.Status == models.HealthStatusDown {
+			status = http.StatusServiceUnavailable
+		}
+
+		c.JSON(status, report)
+	}
+}
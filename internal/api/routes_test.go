@@ -0,0 +1,966 @@
+package api
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"Backend/internal/config"
+	"Backend/internal/models"
+	"Backend/internal/repository"
+	"Backend/internal/services"
+	"Backend/internal/testutil"
+
+	"github.com/gin-gonic/gin"
+)
+
+type testServer struct {
+	router *gin.Engine
+	repo   *repository.MemoryRepository
+	stream *services.ChangeStream
+	alerts *services.AlertService
+}
+
+// newTestServer wires the routes like main does, with stocks kept in memory and
+// db backing authentication and health checks
+func newTestServer(t *testing.T, db *sql.DB) *testServer {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	cfg := config.Default()
+	cfg.JwtSecretKey = []byte("test-secret-key-with-at-least-32-bytes")
+	cfg.Auth.MaxLoginAttempts = 2
+	// Passwords are the username followed by -secret, hashed at the lowest cost
+	cfg.Auth.Users = []string{
+		"dashboard:$2a$04$XNbTt7yZ0BZrZa9ZUP4u1ukmQB0rGjeqGxO4lXtc5U4g/FgGBWmOG",
+		"admin:$2a$04$5YHIEb9Kl26afEovFTX1DuQc/VKHZ7coOs4lj4RpX1A.QJG1aBlFe",
+	}
+	cfg.Auth.Admins = []string{"admin"}
+	cfg.Stream.Heartbeat = 100 * time.Millisecond
+	cfg.Webhooks.AllowPrivateNetworks = true // The receivers are local test servers
+
+	repo := repository.NewMemoryRepository()
+	stockService := services.NewStockService(repo, repo, repo, cfg.Scoring)
+	authService := services.NewAuthService(db, cfg.Auth)
+	healthService := services.NewHealthService(db, nil, stockService, cfg.Sync.StalenessThreshold)
+	backfillService := services.NewBackfillService(repo, stockService, services.NewAPIClient("key", "http://127.0.0.1:1", 0, 0), cfg.Backfill)
+	t.Cleanup(backfillService.Stop)
+	changeService := services.NewChangeService(repo)
+	changeStream := services.NewChangeStream(repo, stockService, cfg.Stream)
+	watchlistService := services.NewWatchlistService(repo, stockService)
+	alertService := services.NewAlertService(repo, repo, cfg.Alerts)
+	webhookService := services.NewWebhookService(repo, repo, repo, cfg.Webhooks)
+	digestService := services.NewDigestService(repo, repo, repo, stockService, services.NewSMTPMailer(cfg.SMTP), cfg.Digest)
+
+	r := gin.New()
+	SetupRoutes(r, stockService, authService, healthService, backfillService, changeService, changeStream, watchlistService, alertService, webhookService, digestService, cfg)
+
+	return &testServer{router: r, repo: repo, stream: changeStream, alerts: alertService}
+}
+
+func (s *testServer) do(t *testing.T, method, path, token string, body any) *httptest.ResponseRecorder {
+	t.Helper()
+
+	var reader *bytes.Reader
+	if body != nil {
+		payload, err := json.Marshal(body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		reader = bytes.NewReader(payload)
+	} else {
+		reader = bytes.NewReader(nil)
+	}
+
+	req := httptest.NewRequest(method, path, reader)
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, req)
+	return w
+}
+
+func (s *testServer) login(t *testing.T, username string) string {
+	t.Helper()
+
+	w := s.do(t, http.MethodPost, "/get-token", "", map[string]string{"username": username, "password": username + "-secret"})
+	if w.Code != http.StatusOK {
+		t.Fatalf("login as %s: status %d, body %s", username, w.Code, w.Body.String())
+	}
+
+	var resp struct{ Token string }
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil || resp.Token == "" {
+		t.Fatalf("login as %s: no token in %s", username, w.Body.String())
+	}
+	return resp.Token
+}
+
+func TestHealthRoutes(t *testing.T) {
+	s := newTestServer(t, testutil.UnreachableDB(t))
+
+	tests := []struct {
+		path       string
+		wantStatus int
+		wantBody   string
+	}{
+		{"/health", http.StatusOK, `"status":"ok"`},
+		{"/health/live", http.StatusOK, `"status":"up"`},
+		{"/health/ready", http.StatusServiceUnavailable, `"status":"down"`},
+		{"/health/ready", http.StatusServiceUnavailable, `"message":"database unreachable"`},
+		{"/metrics", http.StatusOK, "stock_analyzer_"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			w := s.do(t, http.MethodGet, tt.path, "", nil)
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+			if !strings.Contains(w.Body.String(), tt.wantBody) {
+				t.Errorf("body = %s, want it to contain %s", w.Body.String(), tt.wantBody)
+			}
+			// The readiness report is public, so driver errors stay in the logs
+			if strings.Contains(w.Body.String(), "127.0.0.1") {
+				t.Errorf("body = %s, want no database address", w.Body.String())
+			}
+		})
+	}
+}
+
+func TestGetToken(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       any
+		wantStatus int
+	}{
+		{"dashboard user", map[string]string{"username": "dashboard", "password": "dashboard-secret"}, http.StatusOK},
+		{"admin user", map[string]string{"username": "admin", "password": "admin-secret"}, http.StatusOK},
+		{"wrong password", map[string]string{"username": "admin", "password": "dashboard-secret"}, http.StatusBadRequest},
+		{"missing password", map[string]string{"username": "admin"}, http.StatusBadRequest},
+		{"unknown user", map[string]string{"username": "mallory", "password": "x"}, http.StatusBadRequest},
+		{"invalid body", "not an object", http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Audit writes fail against the unreachable database but never block login
+			s := newTestServer(t, testutil.UnreachableDB(t))
+
+			w := s.do(t, http.MethodPost, "/get-token", "", tt.body)
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d (body %s)", w.Code, tt.wantStatus, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestGetTokenLocksOutRepeatedFailures(t *testing.T) {
+	s := newTestServer(t, testutil.UnreachableDB(t))
+	bad := map[string]string{"username": "mallory", "password": "x"}
+
+	// MaxLoginAttempts is 2: the third failure starts the lockout
+	for i := 0; i < 3; i++ {
+		if w := s.do(t, http.MethodPost, "/get-token", "", bad); w.Code != http.StatusBadRequest {
+			t.Fatalf("attempt %d: status = %d, want 400", i+1, w.Code)
+		}
+	}
+
+	w := s.do(t, http.MethodPost, "/get-token", "", bad)
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("status = %d, want 429", w.Code)
+	}
+	if w.Header().Get("Retry-After") == "" {
+		t.Error("Retry-After header missing")
+	}
+
+	// The lockout also applies to the client IP, whatever the username
+	w = s.do(t, http.MethodPost, "/get-token", "", map[string]string{"username": "dashboard", "password": "dashboard-secret"})
+	if w.Code != http.StatusTooManyRequests {
+		t.Errorf("valid user from locked IP: status = %d, want 429", w.Code)
+	}
+}
+
+func TestGetTokenLocksOutAccountAfterWrongPasswords(t *testing.T) {
+	s := newTestServer(t, testutil.UnreachableDB(t))
+
+	// Each guess comes from another IP, so only the account counter can lock it
+	for i := 0; i < 3; i++ {
+		req := httptest.NewRequest(http.MethodPost, "/get-token", strings.NewReader(`{"username":"admin","password":"guess"}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.RemoteAddr = "203.0.113." + strconv.Itoa(i+1) + ":1234"
+		w := httptest.NewRecorder()
+		s.router.ServeHTTP(w, req)
+		if w.Code != http.StatusBadRequest {
+			t.Fatalf("guess %d: status = %d, want 400", i+1, w.Code)
+		}
+	}
+
+	w := s.do(t, http.MethodPost, "/get-token", "", map[string]string{"username": "admin", "password": "admin-secret"})
+	if w.Code != http.StatusTooManyRequests {
+		t.Errorf("right password on a locked account: status = %d, want 429", w.Code)
+	}
+}
+
+func TestProtectedRoutesRequireToken(t *testing.T) {
+	s := newTestServer(t, testutil.UnreachableDB(t))
+
+	for _, route := range []struct{ method, path string }{
+		{http.MethodPost, "/refresh-token"},
+		{http.MethodPost, "/revoke-token"},
+		{http.MethodPost, "/graphql"},
+		{http.MethodGet, "/api/v1/admin/auth-audit"},
+		{http.MethodGet, "/api/v1/admin/quarantine"},
+		{http.MethodPost, "/api/v1/admin/quarantine/1/replay"},
+		{http.MethodPost, "/api/v1/admin/backfill"},
+		{http.MethodPost, "/api/v1/admin/import"},
+		{http.MethodGet, "/api/v1/ws"},
+		{http.MethodGet, "/api/v1/watchlists"},
+		{http.MethodGet, "/api/v1/watchlists/1/stocks"},
+		{http.MethodGet, "/api/v1/alerts"},
+		{http.MethodPost, "/api/v1/alerts/rules"},
+		{http.MethodGet, "/api/v1/webhooks"},
+		{http.MethodPost, "/api/v1/webhooks/1/test"},
+		{http.MethodGet, "/api/v1/digest/preview"},
+		{http.MethodPut, "/api/v1/digest/subscription"},
+	} {
+		t.Run(route.path, func(t *testing.T) {
+			if w := s.do(t, route.method, route.path, "", nil); w.Code != http.StatusUnauthorized {
+				t.Errorf("without token: status = %d, want 401", w.Code)
+			}
+			if w := s.do(t, route.method, route.path, "garbage.token.value", nil); w.Code != http.StatusUnauthorized {
+				t.Errorf("with invalid token: status = %d, want 401", w.Code)
+			}
+		})
+	}
+}
+
+func TestProtectedRoutesFailClosedWithoutDatabase(t *testing.T) {
+	s := newTestServer(t, testutil.UnreachableDB(t))
+	token := s.login(t, "dashboard")
+
+	// Revocation cannot be checked, so the token is not accepted
+	if w := s.do(t, http.MethodPost, "/refresh-token", token, nil); w.Code != http.StatusInternalServerError {
+		t.Errorf("status = %d, want 500", w.Code)
+	}
+}
+
+func TestStockRoutes(t *testing.T) {
+	s := newTestServer(t, testutil.UnreachableDB(t))
+	now := time.Now()
+
+	_, err := s.repo.UpsertStocks(context.Background(), []models.Stock{
+		{Ticker: "AAPL", Company: "Apple Inc", Brokerage: "Goldman Sachs", RatingTo: "Buy", Score: 80, Confidence: 0.8, Time: now},
+		{Ticker: "MSFT", Company: "Microsoft Corp", Brokerage: "Morgan Stanley", RatingTo: "Hold", Score: 50, Confidence: 0.5, Time: now},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("list", func(t *testing.T) {
+		w := s.do(t, http.MethodGet, "/api/v1/stocks", "", nil)
+		if w.Code != http.StatusOK {
+			t.Fatalf("status = %d, want 200", w.Code)
+		}
+
+		var resp models.StockResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatal(err)
+		}
+		if len(resp.Items) != 2 || resp.Items[0].Ticker != "AAPL" {
+			t.Errorf("items = %+v, want AAPL then MSFT", resp.Items)
+		}
+	})
+
+	t.Run("filter", func(t *testing.T) {
+		w := s.do(t, http.MethodGet, "/api/v1/stocks?ticker=msft&limit=10", "", nil)
+
+		var resp models.StockResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatal(err)
+		}
+		if len(resp.Items) != 1 || resp.Items[0].Ticker != "MSFT" {
+			t.Errorf("items = %+v, want only MSFT", resp.Items)
+		}
+	})
+
+	t.Run("invalid sort column", func(t *testing.T) {
+		if w := s.do(t, http.MethodGet, "/api/v1/stocks?sort_by=nope", "", nil); w.Code != http.StatusInternalServerError {
+			t.Errorf("status = %d, want 500", w.Code)
+		}
+	})
+
+	t.Run("export", func(t *testing.T) {
+		w := s.do(t, http.MethodGet, "/api/v1/stocks/export?columns=ticker,+score&sort_by=ticker&order=ASC", "", nil)
+		if w.Code != http.StatusOK {
+			t.Fatalf("status = %d, want 200 (body %s)", w.Code, w.Body.String())
+		}
+		if got := w.Body.String(); got != "ticker,score\nAAPL,80\nMSFT,50\n" {
+			t.Errorf("body = %q, want AAPL and MSFT tickers and scores", got)
+		}
+		if got := w.Header().Get("Content-Type"); got != "text/csv; charset=utf-8" {
+			t.Errorf("Content-Type = %q, want text/csv", got)
+		}
+		if got := w.Header().Get("Content-Disposition"); !strings.HasPrefix(got, "attachment; filename=stocks-") || !strings.HasSuffix(got, ".csv") {
+			t.Errorf("Content-Disposition = %q, want a timestamped csv attachment", got)
+		}
+
+		w = s.do(t, http.MethodGet, "/api/v1/stocks/export?format=xlsx&ticker=msft", "", nil)
+		if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet" {
+			t.Errorf("xlsx status = %d, Content-Type = %q", w.Code, w.Header().Get("Content-Type"))
+		}
+	})
+
+	t.Run("export errors", func(t *testing.T) {
+		tests := []struct {
+			path       string
+			wantStatus int
+		}{
+			{"/api/v1/stocks/export?format=pdf", http.StatusBadRequest},
+			{"/api/v1/stocks/export?columns=ticker,price", http.StatusBadRequest},
+			{"/api/v1/stocks/export?sort_by=nope", http.StatusInternalServerError},
+		}
+		for _, tt := range tests {
+			w := s.do(t, http.MethodGet, tt.path, "", nil)
+			if w.Code != tt.wantStatus {
+				t.Errorf("GET %s status = %d, want %d", tt.path, w.Code, tt.wantStatus)
+			}
+			if w.Header().Get("Content-Disposition") != "" {
+				t.Errorf("GET %s Content-Disposition = %q, want none", tt.path, w.Header().Get("Content-Disposition"))
+			}
+		}
+	})
+
+	t.Run("recommendations", func(t *testing.T) {
+		w := s.do(t, http.MethodGet, "/api/v1/recommendations", "", nil)
+		if w.Code != http.StatusOK {
+			t.Fatalf("status = %d, want 200", w.Code)
+		}
+
+		var resp struct{ Recommendations []models.Stock }
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatal(err)
+		}
+		// MSFT is below the default minimum score
+		if len(resp.Recommendations) != 1 || resp.Recommendations[0].Ticker != "AAPL" {
+			t.Errorf("recommendations = %+v, want only AAPL", resp.Recommendations)
+		}
+	})
+
+	t.Run("changes", func(t *testing.T) {
+		w := s.do(t, http.MethodGet, "/api/v1/changes?limit=1", "", nil)
+		if w.Code != http.StatusOK {
+			t.Fatalf("status = %d, want 200", w.Code)
+		}
+
+		var feed models.ChangeFeed
+		if err := json.Unmarshal(w.Body.Bytes(), &feed); err != nil {
+			t.Fatal(err)
+		}
+		if len(feed.Changes) != 1 || feed.Changes[0].Type != models.ChangeNewCoverage || !feed.HasMore {
+			t.Fatalf("feed = %+v, want one new coverage change and more to come", feed)
+		}
+
+		w = s.do(t, http.MethodGet, "/api/v1/changes?since="+strconv.FormatInt(feed.Cursor, 10), "", nil)
+		if err := json.Unmarshal(w.Body.Bytes(), &feed); err != nil {
+			t.Fatal(err)
+		}
+		if len(feed.Changes) != 1 || feed.Changes[0].Ticker != "MSFT" || feed.HasMore {
+			t.Errorf("feed = %+v, want only the MSFT change", feed)
+		}
+	})
+
+	t.Run("invalid change cursor", func(t *testing.T) {
+		if w := s.do(t, http.MethodGet, "/api/v1/changes?since=abc", "", nil); w.Code != http.StatusBadRequest {
+			t.Errorf("status = %d, want 400", w.Code)
+		}
+	})
+}
+
+func TestTokenLifecycle(t *testing.T) {
+	s := newTestServer(t, testutil.PostgresDB(t))
+
+	token := s.login(t, "dashboard")
+
+	w := s.do(t, http.MethodPost, "/refresh-token", token, nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("refresh: status = %d, body %s", w.Code, w.Body.String())
+	}
+
+	if w := s.do(t, http.MethodPost, "/revoke-token", token, nil); w.Code != http.StatusOK {
+		t.Fatalf("revoke: status = %d, body %s", w.Code, w.Body.String())
+	}
+	if w := s.do(t, http.MethodPost, "/refresh-token", token, nil); w.Code != http.StatusUnauthorized {
+		t.Errorf("refresh with revoked token: status = %d, want 401", w.Code)
+	}
+
+	dashboardAudit := s.do(t, http.MethodGet, "/api/v1/admin/auth-audit", s.login(t, "dashboard"), nil)
+	if dashboardAudit.Code != http.StatusForbidden {
+		t.Errorf("audit as dashboard: status = %d, want 403", dashboardAudit.Code)
+	}
+
+	w = s.do(t, http.MethodGet, "/api/v1/admin/auth-audit?username=dashboard", s.login(t, "admin"), nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("audit as admin: status = %d, body %s", w.Code, w.Body.String())
+	}
+
+	var resp struct{ Events []models.AuthEvent }
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	seen := make(map[string]bool)
+	for _, event := range resp.Events {
+		seen[event.Event] = true
+	}
+	for _, want := range []string{models.AuthEventLoginSuccess, models.AuthEventTokenRefresh, models.AuthEventTokenRevoke} {
+		if !seen[want] {
+			t.Errorf("audit log has no %s event: %+v", want, resp.Events)
+		}
+	}
+}
+
+func TestWatchlistRoutes(t *testing.T) {
+	s := newTestServer(t, testutil.PostgresDB(t))
+	dashboard, admin := s.login(t, "dashboard"), s.login(t, "admin")
+	now := time.Now()
+
+	_, err := s.repo.UpsertStocks(context.Background(), []models.Stock{
+		{Ticker: "AAPL", Company: "Apple Inc", Brokerage: "Goldman Sachs", RatingTo: "Buy", Confidence: 0.8, Time: now},
+		{Ticker: "MSFT", Company: "Microsoft Corp", Brokerage: "Morgan Stanley", RatingTo: "Hold", Confidence: 0.5, Time: now},
+		{Ticker: "TSLA", Company: "Tesla Inc", Brokerage: "UBS", RatingTo: "Sell", Confidence: 0.2, Time: now},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w := s.do(t, http.MethodPost, "/api/v1/watchlists", dashboard, models.WatchlistRequest{Name: "Tech", Tickers: []string{"msft"}})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("create: status = %d, body %s", w.Code, w.Body.String())
+	}
+	var list models.Watchlist
+	if err := json.Unmarshal(w.Body.Bytes(), &list); err != nil {
+		t.Fatal(err)
+	}
+	path := "/api/v1/watchlists/" + strconv.FormatInt(list.ID, 10)
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		token      string
+		body       any
+		wantStatus int
+		wantBody   string
+	}{
+		{"add tickers", http.MethodPost, path + "/tickers", dashboard, models.WatchlistTickersRequest{Tickers: []string{"AAPL"}}, http.StatusOK, `"tickers":["MSFT","AAPL"]`},
+		{"invalid ticker", http.MethodPost, path + "/tickers", dashboard, models.WatchlistTickersRequest{Tickers: []string{"not a ticker"}}, http.StatusBadRequest, "not a valid symbol"},
+		{"rename", http.MethodPatch, path, dashboard, models.WatchlistRequest{Name: "Megacaps"}, http.StatusOK, `"name":"Megacaps"`},
+		{"duplicate name", http.MethodPost, "/api/v1/watchlists", dashboard, models.WatchlistRequest{Name: "Megacaps"}, http.StatusConflict, "already exists"},
+		{"missing name", http.MethodPost, "/api/v1/watchlists", dashboard, map[string]string{}, http.StatusBadRequest, "error"},
+		{"list", http.MethodGet, "/api/v1/watchlists", dashboard, nil, http.StatusOK, `"name":"Megacaps"`},
+		{"stocks", http.MethodGet, path + "/stocks?sort_by=ticker", dashboard, nil, http.StatusOK, `"ticker":"AAPL"`},
+		{"another user's list", http.MethodGet, path, admin, nil, http.StatusNotFound, "not found"},
+		{"another user's lists", http.MethodGet, "/api/v1/watchlists", admin, nil, http.StatusOK, `"watchlists":[]`},
+		{"invalid id", http.MethodGet, "/api/v1/watchlists/abc", dashboard, nil, http.StatusBadRequest, "Invalid watchlist ID"},
+		{"remove ticker", http.MethodDelete, path + "/tickers/aapl", dashboard, nil, http.StatusOK, `"tickers":["MSFT"]`},
+		{"delete", http.MethodDelete, path, dashboard, nil, http.StatusNoContent, ""},
+		{"deleted", http.MethodGet, path + "/stocks", dashboard, nil, http.StatusNotFound, "not found"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := s.do(t, tt.method, tt.path, tt.token, tt.body)
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d (body %s)", w.Code, tt.wantStatus, w.Body.String())
+			}
+			if !strings.Contains(w.Body.String(), tt.wantBody) {
+				t.Errorf("body = %s, want it to contain %s", w.Body.String(), tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestAlertRoutes(t *testing.T) {
+	s := newTestServer(t, testutil.PostgresDB(t))
+	dashboard, admin := s.login(t, "dashboard"), s.login(t, "admin")
+	ctx := context.Background()
+	now := time.Now()
+
+	upsert := func(stocks ...models.Stock) {
+		t.Helper()
+		if _, err := s.repo.UpsertStocks(ctx, stocks); err != nil {
+			t.Fatal(err)
+		}
+	}
+	upsert(models.Stock{Ticker: "NVDA", Company: "Nvidia", Brokerage: "UBS", RatingTo: "Buy", TargetTo: "$100.00", Time: now.Add(-time.Hour)})
+	if _, err := s.alerts.Evaluate(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	w := s.do(t, http.MethodPost, "/api/v1/alerts/rules", dashboard, models.AlertRuleRequest{Name: "NVDA downgrades", Ticker: "nvda", ChangeType: models.ChangeDowngrade})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("create: status = %d, body %s", w.Code, w.Body.String())
+	}
+	var rule models.AlertRule
+	if err := json.Unmarshal(w.Body.Bytes(), &rule); err != nil {
+		t.Fatal(err)
+	}
+	path := "/api/v1/alerts/rules/" + strconv.FormatInt(rule.ID, 10)
+	cut := -25.0
+
+	upsert(models.Stock{Ticker: "NVDA", Company: "Nvidia", Brokerage: "UBS", RatingTo: "Sell", TargetTo: "$70.00", Time: now})
+	if n, err := s.alerts.Evaluate(ctx); n != 1 || err != nil {
+		t.Fatalf("Evaluate() = %d, %v; want one alert", n, err)
+	}
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		token      string
+		body       any
+		wantStatus int
+		wantBody   string
+	}{
+		{"list rules", http.MethodGet, "/api/v1/alerts/rules", dashboard, nil, http.StatusOK, `"ticker":"NVDA"`},
+		{"get rule", http.MethodGet, path, dashboard, nil, http.StatusOK, `"change_type":"downgrade"`},
+		{"history", http.MethodGet, "/api/v1/alerts", dashboard, nil, http.StatusOK, "UBS downgraded NVDA from Buy to Sell"},
+		{"history of the rule", http.MethodGet, "/api/v1/alerts?rule_id=" + strconv.FormatInt(rule.ID, 10), dashboard, nil, http.StatusOK, `"rule_name":"NVDA downgrades"`},
+		{"invalid history filter", http.MethodGet, "/api/v1/alerts?limit=many", dashboard, nil, http.StatusBadRequest, "Invalid query parameters"},
+		{"invalid rule", http.MethodPost, "/api/v1/alerts/rules", dashboard, models.AlertRuleRequest{Name: "Everything"}, http.StatusBadRequest, "at least one condition"},
+		{"missing name", http.MethodPost, "/api/v1/alerts/rules", dashboard, map[string]string{"ticker": "NVDA"}, http.StatusBadRequest, "Missing input parameters"},
+		{"replace rule", http.MethodPut, path, dashboard, models.AlertRuleRequest{Name: "Big NVDA cuts", Ticker: "NVDA", TargetChangePct: &cut}, http.StatusOK, `"target_change_pct":-25`},
+		{"another user's rule", http.MethodGet, path, admin, nil, http.StatusNotFound, "not found"},
+		{"another user's history", http.MethodGet, "/api/v1/alerts", admin, nil, http.StatusOK, `"alerts":[]`},
+		{"invalid id", http.MethodDelete, "/api/v1/alerts/rules/abc", dashboard, nil, http.StatusBadRequest, "Invalid alert rule ID"},
+		{"delete rule", http.MethodDelete, path, dashboard, nil, http.StatusNoContent, ""},
+		{"deleted", http.MethodGet, path, dashboard, nil, http.StatusNotFound, "not found"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := s.do(t, tt.method, tt.path, tt.token, tt.body)
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d (body %s)", w.Code, tt.wantStatus, w.Body.String())
+			}
+			if !strings.Contains(w.Body.String(), tt.wantBody) {
+				t.Errorf("body = %s, want it to contain %s", w.Body.String(), tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestWebhookRoutes(t *testing.T) {
+	s := newTestServer(t, testutil.PostgresDB(t))
+	dashboard, admin := s.login(t, "dashboard"), s.login(t, "admin")
+
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer receiver.Close()
+
+	w := s.do(t, http.MethodPost, "/api/v1/webhooks", dashboard, models.WebhookRequest{URL: receiver.URL, Events: []string{"alert"}})
+	if w.Code != http.StatusCreated || !strings.Contains(w.Body.String(), `"secret":"`) {
+		t.Fatalf("create: status = %d, body %s", w.Code, w.Body.String())
+	}
+	var hook models.Webhook
+	if err := json.Unmarshal(w.Body.Bytes(), &hook); err != nil {
+		t.Fatal(err)
+	}
+	path := "/api/v1/webhooks/" + strconv.FormatInt(hook.ID, 10)
+
+	w = s.do(t, http.MethodPost, path+"/test", dashboard, nil)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"status":"delivered"`) {
+		t.Fatalf("test: status = %d, body %s", w.Code, w.Body.String())
+	}
+	var delivery models.WebhookDelivery
+	if err := json.Unmarshal(w.Body.Bytes(), &delivery); err != nil {
+		t.Fatal(err)
+	}
+	replay := path + "/deliveries/" + strconv.FormatInt(delivery.ID, 10) + "/replay"
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		token      string
+		body       any
+		wantStatus int
+		wantBody   string
+	}{
+		{"list", http.MethodGet, "/api/v1/webhooks", dashboard, nil, http.StatusOK, `"events":["alert"]`},
+		{"get hides the secret", http.MethodGet, path, dashboard, nil, http.StatusOK, `"enabled":true,`},
+		{"invalid url", http.MethodPost, "/api/v1/webhooks", dashboard, models.WebhookRequest{URL: "ftp://example.com"}, http.StatusBadRequest, "http or https"},
+		{"missing url", http.MethodPost, "/api/v1/webhooks", dashboard, map[string]string{}, http.StatusBadRequest, "Missing input parameters"},
+		{"deliveries", http.MethodGet, path + "/deliveries?status=delivered", dashboard, nil, http.StatusOK, `"last_status_code":202`},
+		{"unknown status", http.MethodGet, path + "/deliveries?status=lost", dashboard, nil, http.StatusBadRequest, "status must be one of"},
+		{"invalid limit", http.MethodGet, path + "/deliveries?limit=many", dashboard, nil, http.StatusBadRequest, "Invalid query parameters"},
+		{"replay", http.MethodPost, replay, dashboard, nil, http.StatusOK, `"replay_of":` + strconv.FormatInt(delivery.ID, 10)},
+		{"unknown delivery", http.MethodPost, path + "/deliveries/999/replay", dashboard, nil, http.StatusNotFound, "not found"},
+		{"invalid delivery id", http.MethodPost, path + "/deliveries/abc/replay", dashboard, nil, http.StatusBadRequest, "Invalid delivery ID"},
+		{"another user's webhook", http.MethodPost, path + "/test", admin, nil, http.StatusNotFound, "not found"},
+		{"invalid id", http.MethodGet, "/api/v1/webhooks/abc", dashboard, nil, http.StatusBadRequest, "Invalid webhook ID"},
+		{"delete", http.MethodDelete, path, dashboard, nil, http.StatusNoContent, ""},
+		{"deleted", http.MethodGet, path + "/deliveries", dashboard, nil, http.StatusNotFound, "not found"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := s.do(t, tt.method, tt.path, tt.token, tt.body)
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d (body %s)", w.Code, tt.wantStatus, w.Body.String())
+			}
+			if !strings.Contains(w.Body.String(), tt.wantBody) {
+				t.Errorf("body = %s, want it to contain %s", w.Body.String(), tt.wantBody)
+			}
+			if strings.Contains(tt.name, "secret") && strings.Contains(w.Body.String(), `"secret"`) {
+				t.Errorf("body = %s, want no secret", w.Body.String())
+			}
+		})
+	}
+}
+
+func TestDigestRoutes(t *testing.T) {
+	s := newTestServer(t, testutil.PostgresDB(t))
+	dashboard := s.login(t, "dashboard")
+
+	_, err := s.repo.UpsertStocks(context.Background(), []models.Stock{
+		{Ticker: "T", Company: "AT&T", Brokerage: "UBS", RatingTo: "Buy", TargetTo: "$30.00", Score: 80, Confidence: 0.8, Time: time.Now()},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name        string
+		method      string
+		path        string
+		body        any
+		wantStatus  int
+		wantBody    string
+		contentType string
+	}{
+		{"not subscribed", http.MethodGet, "/api/v1/digest/subscription", nil, http.StatusNotFound, "not found", ""},
+		{"invalid email", http.MethodPut, "/api/v1/digest/subscription", models.DigestSubscriptionRequest{Email: "manager"}, http.StatusBadRequest, "valid address", ""},
+		{"missing email", http.MethodPut, "/api/v1/digest/subscription", map[string]string{}, http.StatusBadRequest, "Missing input parameters", ""},
+		{"subscribe", http.MethodPut, "/api/v1/digest/subscription", models.DigestSubscriptionRequest{Email: "manager@example.com"}, http.StatusOK, `"enabled":true`, ""},
+		{"subscribed", http.MethodGet, "/api/v1/digest/subscription", nil, http.StatusOK, `"email":"manager@example.com"`, ""},
+		{"html preview", http.MethodGet, "/api/v1/digest/preview", nil, http.StatusOK, "AT&amp;T", "text/html"},
+		{"text preview", http.MethodGet, "/api/v1/digest/preview?format=text", nil, http.StatusOK, "- T (AT&T): Buy by UBS", "text/plain"},
+		{"json preview", http.MethodGet, "/api/v1/digest/preview?format=json", nil, http.StatusOK, `"subject":"Stock digest for`, "application/json"},
+		{"unknown format", http.MethodGet, "/api/v1/digest/preview?format=pdf", nil, http.StatusBadRequest, "format must be", ""},
+		{"unsubscribe", http.MethodDelete, "/api/v1/digest/subscription", nil, http.StatusNoContent, "", ""},
+		{"unsubscribe twice", http.MethodDelete, "/api/v1/digest/subscription", nil, http.StatusNotFound, "not found", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := s.do(t, tt.method, tt.path, dashboard, tt.body)
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d (body %s)", w.Code, tt.wantStatus, w.Body.String())
+			}
+			if !strings.Contains(w.Body.String(), tt.wantBody) {
+				t.Errorf("body = %s, want it to contain %s", w.Body.String(), tt.wantBody)
+			}
+			if !strings.HasPrefix(w.Header().Get("Content-Type"), tt.contentType) {
+				t.Errorf("Content-Type = %q, want %s", w.Header().Get("Content-Type"), tt.contentType)
+			}
+		})
+	}
+}
+
+func TestGraphQLRoutes(t *testing.T) {
+	s := newTestServer(t, testutil.PostgresDB(t))
+	token := s.login(t, "dashboard")
+
+	_, err := s.repo.UpsertStocks(context.Background(), []models.Stock{
+		{Ticker: "AAPL", Company: "Apple Inc", Brokerage: "UBS", RatingTo: "Buy", Score: 80, Confidence: 0.8, Time: time.Now()},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		body       any
+		wantStatus int
+		wantBody   string
+	}{
+		{"query", map[string]any{"query": `{ stocks(limit: 1) { totalCount items { ticker { symbol } brokerage { name } } } }`}, http.StatusOK,
+			`{"data":{"stocks":{"totalCount":1,"items":[{"ticker":{"symbol":"AAPL"},"brokerage":{"name":"UBS"}}]}}}`},
+		{"variables", map[string]any{"query": `query($s: String!) { ticker(symbol: $s) { symbol } }`, "variables": map[string]any{"s": "aapl"}}, http.StatusOK,
+			`{"data":{"ticker":{"symbol":"AAPL"}}}`},
+		{"query error", map[string]any{"query": `{ stocks(limit: 0) { totalCount } }`}, http.StatusOK, "limit must be between 1 and 100"},
+		{"missing query", map[string]any{"variables": map[string]any{}}, http.StatusBadRequest, "Missing input parameters"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := s.do(t, http.MethodPost, "/graphql", token, tt.body)
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d (body %s)", w.Code, tt.wantStatus, w.Body.String())
+			}
+			if !strings.Contains(w.Body.String(), tt.wantBody) {
+				t.Errorf("body = %s, want it to contain %s", w.Body.String(), tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestQuarantineRoutes(t *testing.T) {
+	s := newTestServer(t, testutil.PostgresDB(t))
+	admin := s.login(t, "admin")
+
+	payload, _ := json.Marshal(services.APIStock{Ticker: "aapl", Company: "Apple Inc", Brokerage: "Goldman Sachs", Action: "upgraded by", Time: time.Now()})
+	err := s.repo.QuarantineEvents(context.Background(), []models.QuarantinedEvent{
+		{Source: models.QuarantineSourceSync, Ticker: "aapl", Company: "Apple Inc", Payload: payload, Reason: "ticker is not a valid symbol"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if w := s.do(t, http.MethodGet, "/api/v1/admin/quarantine", s.login(t, "dashboard"), nil); w.Code != http.StatusForbidden {
+		t.Errorf("list as dashboard: status = %d, want 403", w.Code)
+	}
+
+	w := s.do(t, http.MethodGet, "/api/v1/admin/quarantine?status=pending", admin, nil)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"ticker":"aapl"`) {
+		t.Fatalf("list: status = %d, body %s", w.Code, w.Body.String())
+	}
+
+	tests := []struct {
+		name       string
+		path       string
+		body       any
+		wantStatus int
+	}{
+		{"unknown event", "/api/v1/admin/quarantine/99/replay", nil, http.StatusNotFound},
+		{"invalid id", "/api/v1/admin/quarantine/abc/replay", nil, http.StatusBadRequest},
+		{"still invalid", "/api/v1/admin/quarantine/1/replay", nil, http.StatusUnprocessableEntity},
+		{"corrected", "/api/v1/admin/quarantine/1/replay", services.APIStock{Ticker: "AAPL", Company: "Apple Inc", Brokerage: "Goldman Sachs", Action: "upgraded by", Time: time.Now()}, http.StatusOK},
+		{"already replayed", "/api/v1/admin/quarantine/1/replay", nil, http.StatusConflict},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if w := s.do(t, http.MethodPost, tt.path, admin, tt.body); w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d; body %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+		})
+	}
+
+	w = s.do(t, http.MethodGet, "/api/v1/admin/quarantine/1", admin, nil)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"status":"replayed"`) {
+		t.Errorf("get after replay: status = %d, body %s", w.Code, w.Body.String())
+	}
+}
+
+func TestImportRoutes(t *testing.T) {
+	s := newTestServer(t, testutil.PostgresDB(t))
+	admin := s.login(t, "admin")
+	day := time.Now().UTC().AddDate(0, 0, -1).Format(time.DateOnly)
+
+	upload := func(path, contentType, token, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", contentType)
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		s.router.ServeHTTP(w, req)
+		return w
+	}
+	csvFile := "ticker,company,brokerage,action,rating_to,time\n" +
+		"NVDA,Nvidia,UBS,upgraded by,Buy," + day + "\n" +
+		"NVDA,Nvidia,,upgraded by,Buy," + day + "\n"
+	jsonl := `{"ticker":"AMD","company":"AMD","brokerage":"UBS","action":"initiated by","time":"` + day + `T10:00:00Z"}` + "\n"
+
+	tests := []struct {
+		name        string
+		path        string
+		contentType string
+		token       string
+		body        string
+		wantStatus  int
+		wantBody    string
+	}{
+		{"not an admin", "/api/v1/admin/import", "text/csv", s.login(t, "dashboard"), csvFile, http.StatusForbidden, ""},
+		{"csv", "/api/v1/admin/import", "text/csv; charset=utf-8", admin, csvFile, http.StatusOK, `"rows":2,"imported":1,"invalid":1`},
+		{"jsonl dry run", "/api/v1/admin/import?format=jsonl&dry_run=true", "text/plain", admin, jsonl, http.StatusOK, `"status":"valid"`},
+		{"unknown content type", "/api/v1/admin/import", "application/pdf", admin, csvFile, http.StatusBadRequest, "format must be"},
+		{"unknown column", "/api/v1/admin/import", "text/csv", admin, "ticker,price\n", http.StatusBadRequest, "unknown column"},
+		{"invalid dry_run", "/api/v1/admin/import?dry_run=maybe", "text/csv", admin, csvFile, http.StatusBadRequest, "Invalid query parameters"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := upload(tt.path, tt.contentType, tt.token, tt.body)
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d (body %s)", w.Code, tt.wantStatus, w.Body.String())
+			}
+			if !strings.Contains(w.Body.String(), tt.wantBody) {
+				t.Errorf("body = %s, want it to contain %s", w.Body.String(), tt.wantBody)
+			}
+		})
+	}
+
+	w := s.do(t, http.MethodGet, "/api/v1/stocks?source=import", "", nil)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"ticker":"NVDA"`) || strings.Contains(w.Body.String(), `"ticker":"AMD"`) {
+		t.Errorf("imported stocks: status = %d, body %s; want only NVDA", w.Code, w.Body.String())
+	}
+}
+
+// sseEvent is a parsed Server-Sent Event
+type sseEvent struct {
+	id, event string
+	data      models.StreamEvent
+}
+
+// openStream connects to the stream and returns its events as they arrive
+func (s *testServer) openStream(t *testing.T, path, lastEventID string) <-chan sseEvent {
+	t.Helper()
+
+	server := httptest.NewServer(s.router)
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(func() {
+		cancel()
+		server.Close()
+	})
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lastEventID != "" {
+		req.Header.Set("Last-Event-ID", lastEventID)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("status = %d, content type %q; want an event stream", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+
+	events := make(chan sseEvent, 16)
+	go func() {
+		defer resp.Body.Close()
+		defer close(events)
+
+		var event sseEvent
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() {
+			line := scanner.Text()
+			switch {
+			case line == "":
+				events <- event
+				event = sseEvent{}
+			case strings.HasPrefix(line, "id: "):
+				event.id = strings.TrimPrefix(line, "id: ")
+			case strings.HasPrefix(line, "event: "):
+				event.event = strings.TrimPrefix(line, "event: ")
+			case strings.HasPrefix(line, "data: "):
+				json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &event.data)
+			}
+		}
+	}()
+
+	return events
+}
+
+// nextEvent returns the next event that is not a heartbeat
+func nextEvent(t *testing.T, events <-chan sseEvent) sseEvent {
+	t.Helper()
+	for {
+		select {
+		case event, ok := <-events:
+			if !ok {
+				t.Fatal("stream closed")
+			}
+			if event.event != models.StreamEventHeartbeat {
+				return event
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for a stream event")
+		}
+	}
+}
+
+func TestStreamRoutes(t *testing.T) {
+	s := newTestServer(t, testutil.UnreachableDB(t))
+	ctx := context.Background()
+	now := time.Now()
+
+	_, err := s.repo.UpsertStocks(ctx, []models.Stock{
+		{Ticker: "AAPL", Company: "Apple Inc", Brokerage: "Goldman Sachs", RatingTo: "Buy", Score: 80, Confidence: 0.8, Time: now},
+		{Ticker: "MSFT", Company: "Microsoft Corp", Brokerage: "Morgan Stanley", RatingTo: "Hold", Score: 50, Confidence: 0.5, Time: now},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.stream.Poll(ctx)
+
+	t.Run("resume after Last-Event-ID", func(t *testing.T) {
+		events := s.openStream(t, "/api/v1/stream?ticker=msft", "1")
+
+		event := nextEvent(t, events)
+		if event.event != models.StreamEventChange || event.id != "2" || event.data.Change.Ticker != "MSFT" {
+			t.Errorf("first event = %+v, want the MSFT change with ID 2", event)
+		}
+		if event := nextEvent(t, events); event.event != models.StreamEventTopPick || event.data.TopPick.Ticker != "AAPL" {
+			t.Errorf("second event = %+v, want AAPL as top pick", event)
+		}
+	})
+
+	t.Run("live changes and heartbeats", func(t *testing.T) {
+		events := s.openStream(t, "/api/v1/stream?brokerage=Morgan%20Stanley,UBS", "")
+		if event := nextEvent(t, events); event.event != models.StreamEventTopPick {
+			t.Fatalf("first event = %+v, want the top pick", event)
+		}
+
+		// Heartbeats arrive while nothing changes
+		select {
+		case event := <-events:
+			if event.event != models.StreamEventHeartbeat || event.id != "" {
+				t.Errorf("event = %+v, want a heartbeat without ID", event)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for a heartbeat")
+		}
+
+		_, err := s.repo.UpsertStocks(ctx, []models.Stock{
+			{Ticker: "AAPL", Company: "Apple Inc", Brokerage: "Goldman Sachs", RatingTo: "Sell", Score: 20, Time: now},
+			{Ticker: "MSFT", Company: "Microsoft Corp", Brokerage: "Morgan Stanley", RatingTo: "Buy", Score: 90, Confidence: 0.9, Time: now},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		s.stream.Poll(ctx)
+
+		event := nextEvent(t, events)
+		if event.event != models.StreamEventChange || event.data.Change.Ticker != "MSFT" || event.data.Change.Type != models.ChangeUpgrade {
+			t.Errorf("event = %+v, want the MSFT upgrade only", event)
+		}
+		if event := nextEvent(t, events); event.event != models.StreamEventTopPick || event.data.TopPick.Ticker != "MSFT" {
+			t.Errorf("event = %+v, want MSFT as the new top pick", event)
+		}
+	})
+
+	t.Run("invalid Last-Event-ID", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/stream", nil)
+		req.Header.Set("Last-Event-ID", "abc")
+		w := httptest.NewRecorder()
+		s.router.ServeHTTP(w, req)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("status = %d, want 400", w.Code)
+		}
+	})
+}
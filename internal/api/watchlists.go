@@ -0,0 +1,291 @@
+package api
+
+import (
+	"Backend/internal/entity"
+	"Backend/internal/models"
+	"Backend/internal/services"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// @Summary List watchlists
+// @Description Retrieve the watchlists of the authenticated user, by name, with their tickers
+// @Tags Watchlists
+// @Produce json
+// @Success 200 {object} map[string][]models.Watchlist
+// @Failure 401 {object} map[string]string "error"
+// @Failure 500 {object} map[string]string "error"
+// @Security BearerAuth
+// @Router /api/v1/watchlists [get]
+func getWatchlists(watchlistService *services.WatchlistService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		lists, err := watchlistService.List(c.Request.Context(), currentUsername(c))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"watchlists": lists})
+	}
+}
+
+// @Summary Create a watchlist
+// @Description Create a watchlist for the authenticated user, optionally with its first tickers.
+// @Description Names are unique per user.
+// @Tags Watchlists
+// @Accept json
+// @Produce json
+// @Param watchlist body models.WatchlistRequest true "Name and tickers"
+// @Success 201 {object} models.Watchlist
+// @Failure 400 {object} map[string]string "error"
+// @Failure 401 {object} map[string]string "error"
+// @Failure 409 {object} map[string]string "error"
+// @Failure 500 {object} map[string]string "error"
+// @Security BearerAuth
+// @Router /api/v1/watchlists [post]
+func createWatchlist(watchlistService *services.WatchlistService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var request models.WatchlistRequest
+		if err := c.ShouldBindJSON(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Missing input parameters"})
+			return
+		}
+
+		list, err := watchlistService.Create(c.Request.Context(), currentUsername(c), request.Name, request.Tickers)
+		if err != nil {
+			c.JSON(watchlistErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusCreated, list)
+	}
+}
+
+// @Summary Get a watchlist
+// @Description Retrieve one of the authenticated user's watchlists
+// @Tags Watchlists
+// @Produce json
+// @Param id path int true "Watchlist ID"
+// @Success 200 {object} models.Watchlist
+// @Failure 400 {object} map[string]string "error"
+// @Failure 401 {object} map[string]string "error"
+// @Failure 404 {object} map[string]string "error"
+// @Failure 500 {object} map[string]string "error"
+// @Security BearerAuth
+// @Router /api/v1/watchlists/{id} [get]
+func getWatchlist(watchlistService *services.WatchlistService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, ok := watchlistID(c)
+		if !ok {
+			return
+		}
+
+		list, err := watchlistService.Get(c.Request.Context(), currentUsername(c), id)
+		if err != nil {
+			c.JSON(watchlistErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, list)
+	}
+}
+
+// @Summary Rename a watchlist
+// @Description Change the name of one of the authenticated user's watchlists; tickers in the body are ignored
+// @Tags Watchlists
+// @Accept json
+// @Produce json
+// @Param id        path int                     true "Watchlist ID"
+// @Param watchlist body models.WatchlistRequest true "New name"
+// @Success 200 {object} models.Watchlist
+// @Failure 400 {object} map[string]string "error"
+// @Failure 401 {object} map[string]string "error"
+// @Failure 404 {object} map[string]string "error"
+// @Failure 409 {object} map[string]string "error"
+// @Failure 500 {object} map[string]string "error"
+// @Security BearerAuth
+// @Router /api/v1/watchlists/{id} [patch]
+func renameWatchlist(watchlistService *services.WatchlistService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, ok := watchlistID(c)
+		if !ok {
+			return
+		}
+
+		var request models.WatchlistRequest
+		if err := c.ShouldBindJSON(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Missing input parameters"})
+			return
+		}
+
+		list, err := watchlistService.Rename(c.Request.Context(), currentUsername(c), id, request.Name)
+		if err != nil {
+			c.JSON(watchlistErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, list)
+	}
+}
+
+// @Summary Delete a watchlist
+// @Description Delete one of the authenticated user's watchlists with its tickers
+// @Tags Watchlists
+// @Param id path int true "Watchlist ID"
+// @Success 204
+// @Failure 400 {object} map[string]string "error"
+// @Failure 401 {object} map[string]string "error"
+// @Failure 404 {object} map[string]string "error"
+// @Failure 500 {object} map[string]string "error"
+// @Security BearerAuth
+// @Router /api/v1/watchlists/{id} [delete]
+func deleteWatchlist(watchlistService *services.WatchlistService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, ok := watchlistID(c)
+		if !ok {
+			return
+		}
+
+		if err := watchlistService.Delete(c.Request.Context(), currentUsername(c), id); err != nil {
+			c.JSON(watchlistErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+
+		c.Status(http.StatusNoContent)
+	}
+}
+
+// @Summary Add tickers to a watchlist
+// @Description Add tickers to one of the authenticated user's watchlists. Tickers already in it are skipped.
+// @Tags Watchlists
+// @Accept json
+// @Produce json
+// @Param id      path int                            true "Watchlist ID"
+// @Param tickers body models.WatchlistTickersRequest true "Tickers to add"
+// @Success 200 {object} models.Watchlist
+// @Failure 400 {object} map[string]string "error"
+// @Failure 401 {object} map[string]string "error"
+// @Failure 404 {object} map[string]string "error"
+// @Failure 409 {object} map[string]string "error"
+// @Failure 500 {object} map[string]string "error"
+// @Security BearerAuth
+// @Router /api/v1/watchlists/{id}/tickers [post]
+func addWatchlistTickers(watchlistService *services.WatchlistService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, ok := watchlistID(c)
+		if !ok {
+			return
+		}
+
+		var request models.WatchlistTickersRequest
+		if err := c.ShouldBindJSON(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Missing input parameters"})
+			return
+		}
+
+		list, err := watchlistService.AddTickers(c.Request.Context(), currentUsername(c), id, request.Tickers)
+		if err != nil {
+			c.JSON(watchlistErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, list)
+	}
+}
+
+// @Summary Remove a ticker from a watchlist
+// @Description Remove a ticker from one of the authenticated user's watchlists
+// @Tags Watchlists
+// @Produce json
+// @Param id     path int    true "Watchlist ID"
+// @Param ticker path string true "Ticker to remove"
+// @Success 200 {object} models.Watchlist
+// @Failure 400 {object} map[string]string "error"
+// @Failure 401 {object} map[string]string "error"
+// @Failure 404 {object} map[string]string "error"
+// @Failure 500 {object} map[string]string "error"
+// @Security BearerAuth
+// @Router /api/v1/watchlists/{id}/tickers/{ticker} [delete]
+func removeWatchlistTicker(watchlistService *services.WatchlistService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, ok := watchlistID(c)
+		if !ok {
+			return
+		}
+
+		list, err := watchlistService.RemoveTicker(c.Request.Context(), currentUsername(c), id, c.Param("ticker"))
+		if err != nil {
+			c.JSON(watchlistErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, list)
+	}
+}
+
+// @Summary Get the stocks of a watchlist
+// @Description Retrieve the stocks of a watchlist's tickers with the same filters, sorting and scoring as /api/v1/stocks
+// @Tags Watchlists
+// @Produce json
+// @Param id       path   int    true  "Watchlist ID"
+// @Param ticker   query  string false "Stock ticker symbol"
+// @Param company  query  string false "Company name"
+// @Param sort_by  query  string false "Sort field"
+// @Param order    query  string false "Sort order (asc, desc)"
+// @Param limit    query  int    false "Number of items"
+// @Param today    query  string false "Filter for today's data"
+// @Success 200 {object} models.StockResponse
+// @Failure 400 {object} map[string]string "error"
+// @Failure 401 {object} map[string]string "error"
+// @Failure 404 {object} map[string]string "error"
+// @Failure 500 {object} map[string]string "error"
+// @Security BearerAuth
+// @Router /api/v1/watchlists/{id}/stocks [get]
+func getWatchlistStocks(watchlistService *services.WatchlistService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, ok := watchlistID(c)
+		if !ok {
+			return
+		}
+
+		stocks, err := watchlistService.Stocks(c.Request.Context(), currentUsername(c), id, stockFilters(c))
+		if err != nil {
+			c.JSON(watchlistErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, stocks)
+	}
+}
+
+// currentUsername returns the user authenticated by AuthMiddleware
+func currentUsername(c *gin.Context) string {
+	return c.MustGet("user").(*entity.UserJwt).Username
+}
+
+// watchlistID parses the :id parameter, replying 400 when it is invalid
+func watchlistID(c *gin.Context) (int64, bool) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid watchlist ID"})
+		return 0, false
+	}
+	return id, true
+}
+
+// watchlistErrorStatus maps watchlist errors to HTTP status codes
+func watchlistErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrInvalidWatchlist):
+		return http.StatusBadRequest
+	case errors.Is(err, services.ErrWatchlistNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, services.ErrWatchlistExists), errors.Is(err, services.ErrWatchlistFull):
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,254 @@
+package api
+
+import (
+	"Backend/internal/models"
+	"Backend/internal/services"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// @Summary List webhooks
+// @Description Retrieve the authenticated user's webhooks. Secrets are only returned on creation.
+// @Tags Webhooks
+// @Produce json
+// @Success 200 {object} map[string][]models.Webhook
+// @Failure 401 {object} map[string]string "error"
+// @Failure 500 {object} map[string]string "error"
+// @Security BearerAuth
+// @Router /api/v1/webhooks [get]
+func getWebhooks(webhookService *services.WebhookService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		hooks, err := webhookService.List(c.Request.Context(), currentUsername(c))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"webhooks": hooks})
+	}
+}
+
+// @Summary Register a webhook
+// @Description Register a URL that receives the authenticated user's alerts and every change of the
+// @Description change feed as JSON POST requests. Each request carries the X-Webhook-Event,
+// @Description X-Webhook-Delivery and X-Webhook-Timestamp headers, and X-Webhook-Signature:
+// @Description "sha256=" followed by the hex HMAC-SHA256 of the timestamp, a dot and the body, keyed by
+// @Description the secret. A secret is generated when none is given; it is only returned in this response.
+// @Description Failed deliveries are retried with exponential backoff, then dead-lettered.
+// @Tags Webhooks
+// @Accept json
+// @Produce json
+// @Param webhook body models.WebhookRequest true "URL, events (alert, change) and secret"
+// @Success 201 {object} models.Webhook
+// @Failure 400 {object} map[string]string "error"
+// @Failure 401 {object} map[string]string "error"
+// @Failure 409 {object} map[string]string "error"
+// @Failure 500 {object} map[string]string "error"
+// @Security BearerAuth
+// @Router /api/v1/webhooks [post]
+func createWebhook(webhookService *services.WebhookService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var request models.WebhookRequest
+		if err := c.ShouldBindJSON(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Missing input parameters"})
+			return
+		}
+
+		hook, err := webhookService.Create(c.Request.Context(), currentUsername(c), request)
+		if err != nil {
+			c.JSON(webhookErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusCreated, hook)
+	}
+}
+
+// @Summary Get a webhook
+// @Description Retrieve one of the authenticated user's webhooks
+// @Tags Webhooks
+// @Produce json
+// @Param id path int true "Webhook ID"
+// @Success 200 {object} models.Webhook
+// @Failure 400 {object} map[string]string "error"
+// @Failure 401 {object} map[string]string "error"
+// @Failure 404 {object} map[string]string "error"
+// @Failure 500 {object} map[string]string "error"
+// @Security BearerAuth
+// @Router /api/v1/webhooks/{id} [get]
+func getWebhook(webhookService *services.WebhookService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, ok := webhookID(c)
+		if !ok {
+			return
+		}
+
+		hook, err := webhookService.Get(c.Request.Context(), currentUsername(c), id)
+		if err != nil {
+			c.JSON(webhookErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, hook)
+	}
+}
+
+// @Summary Delete a webhook
+// @Description Delete one of the authenticated user's webhooks together with its deliveries
+// @Tags Webhooks
+// @Param id path int true "Webhook ID"
+// @Success 204
+// @Failure 400 {object} map[string]string "error"
+// @Failure 401 {object} map[string]string "error"
+// @Failure 404 {object} map[string]string "error"
+// @Failure 500 {object} map[string]string "error"
+// @Security BearerAuth
+// @Router /api/v1/webhooks/{id} [delete]
+func deleteWebhook(webhookService *services.WebhookService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, ok := webhookID(c)
+		if !ok {
+			return
+		}
+
+		if err := webhookService.Delete(c.Request.Context(), currentUsername(c), id); err != nil {
+			c.JSON(webhookErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+
+		c.Status(http.StatusNoContent)
+	}
+}
+
+// @Summary Send a test event
+// @Description Send a test event to one of the authenticated user's webhooks right away and return the
+// @Description delivery with the webhook's response. A failed test is retried like any delivery.
+// @Tags Webhooks
+// @Produce json
+// @Param id path int true "Webhook ID"
+// @Success 200 {object} models.WebhookDelivery
+// @Failure 400 {object} map[string]string "error"
+// @Failure 401 {object} map[string]string "error"
+// @Failure 404 {object} map[string]string "error"
+// @Failure 500 {object} map[string]string "error"
+// @Security BearerAuth
+// @Router /api/v1/webhooks/{id}/test [post]
+func testWebhook(webhookService *services.WebhookService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, ok := webhookID(c)
+		if !ok {
+			return
+		}
+
+		delivery, err := webhookService.Test(c.Request.Context(), currentUsername(c), id)
+		if err != nil {
+			c.JSON(webhookErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, delivery)
+	}
+}
+
+// @Summary Get the delivery log
+// @Description Retrieve the deliveries of one of the authenticated user's webhooks, newest first,
+// @Description with the outcome of their last attempt
+// @Tags Webhooks
+// @Produce json
+// @Param id     path  int    true  "Webhook ID"
+// @Param status query string false "Only deliveries with this status (pending, delivered, dead)"
+// @Param limit  query int    false "Maximum deliveries returned (default 50, max 500)"
+// @Success 200 {object} map[string][]models.WebhookDelivery
+// @Failure 400 {object} map[string]string "error"
+// @Failure 401 {object} map[string]string "error"
+// @Failure 404 {object} map[string]string "error"
+// @Failure 500 {object} map[string]string "error"
+// @Security BearerAuth
+// @Router /api/v1/webhooks/{id}/deliveries [get]
+func getWebhookDeliveries(webhookService *services.WebhookService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, ok := webhookID(c)
+		if !ok {
+			return
+		}
+
+		var filters models.DeliveryFilters
+		if err := c.ShouldBindQuery(&filters); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid query parameters"})
+			return
+		}
+		filters.Username = currentUsername(c)
+		filters.WebhookID = id
+
+		deliveries, err := webhookService.Deliveries(c.Request.Context(), filters)
+		if err != nil {
+			c.JSON(webhookErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"deliveries": deliveries})
+	}
+}
+
+// @Summary Replay a delivery
+// @Description Send the payload of one of the webhook's deliveries again, whatever its status, as a new
+// @Description delivery that records the one it replays
+// @Tags Webhooks
+// @Produce json
+// @Param id          path int true "Webhook ID"
+// @Param delivery_id path int true "Delivery ID"
+// @Success 200 {object} models.WebhookDelivery
+// @Failure 400 {object} map[string]string "error"
+// @Failure 401 {object} map[string]string "error"
+// @Failure 404 {object} map[string]string "error"
+// @Failure 500 {object} map[string]string "error"
+// @Security BearerAuth
+// @Router /api/v1/webhooks/{id}/deliveries/{delivery_id}/replay [post]
+func replayWebhookDelivery(webhookService *services.WebhookService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, ok := webhookID(c)
+		if !ok {
+			return
+		}
+		deliveryID, err := strconv.ParseInt(c.Param("delivery_id"), 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid delivery ID"})
+			return
+		}
+
+		delivery, err := webhookService.Replay(c.Request.Context(), currentUsername(c), id, deliveryID)
+		if err != nil {
+			c.JSON(webhookErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, delivery)
+	}
+}
+
+// webhookID parses the :id parameter, replying 400 when it is invalid
+func webhookID(c *gin.Context) (int64, bool) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid webhook ID"})
+		return 0, false
+	}
+	return id, true
+}
+
+// webhookErrorStatus maps webhook errors to HTTP status codes
+func webhookErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrInvalidWebhook):
+		return http.StatusBadRequest
+	case errors.Is(err, services.ErrWebhookNotFound), errors.Is(err, services.ErrDeliveryNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, services.ErrWebhookLimit):
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,235 @@
+package api
+
+import (
+	"Backend/internal/config"
+	"Backend/internal/logger"
+	"Backend/internal/models"
+	"Backend/internal/services"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// maxWatchRequestBytes caps a message from a WebSocket client
+	maxWatchRequestBytes = 16 << 10
+
+	// pendingWatchRequests is the number of requests a client can send ahead of the
+	// replies before it is disconnected. Changes are never queued: they are read
+	// from the feed when the connection is ready for them.
+	pendingWatchRequests = 16
+)
+
+// @Summary Watch rating and score changes over a WebSocket
+// @Description Bidirectional version of the stream. After the upgrade the client sends
+// @Description {"action":"subscribe"|"unsubscribe","tickers":[...],"brokerages":[...]} messages and receives a
+// @Description "subscribed" message listing what it follows, or an "error" message. "change" messages are pushed for
+// @Description changes to any followed ticker or brokerage; nothing is pushed until the client subscribes.
+// @Description Browsers, which cannot set headers on the handshake, may pass the token as access_token.
+// @Description A client that does not accept a message within the stream write timeout is disconnected and can
+// @Description reconnect with since set to the ID of the last change it received.
+// @Tags Stocks
+// @Param access_token query string false "JWT, when the Authorization header cannot be set"
+// @Param since        query int    false "Resume after this change ID; omit to start with new changes"
+// @Success 101 {object} models.StreamEvent
+// @Failure 400 {object} map[string]string "error"
+// @Failure 401 {object} map[string]string "error"
+// @Failure 500 {object} map[string]string "error"
+// @Security BearerAuth
+// @Router /api/v1/ws [get]
+func watchChanges(changeStream *services.ChangeStream, cfg *config.Config) gin.HandlerFunc {
+	upgrader := websocket.Upgrader{CheckOrigin: allowedOrigin(cfg.CORS.AllowOrigins)}
+
+	return func(c *gin.Context) {
+		since := int64(-1)
+		if c.Query("since") != "" {
+			cursor, err := services.ParseCursor(c.Query("since"))
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			since = cursor
+		}
+
+		ctx, cancel := context.WithCancel(c.Request.Context())
+		defer cancel()
+
+		sub, err := changeStream.Subscribe(ctx, since, services.ChangeFilter{Any: true})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		defer sub.Close()
+
+		// On failure the upgrader has already replied with an error status
+		conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		w := &watchConn{
+			conn:         conn,
+			sub:          sub,
+			heartbeat:    cfg.Stream.Heartbeat,
+			writeTimeout: cfg.Stream.WriteTimeout,
+			requests:     make(chan []byte, pendingWatchRequests),
+		}
+		go func() {
+			defer cancel()
+			w.readRequests()
+		}()
+
+		if err := w.pushChanges(ctx); err != nil && ctx.Err() == nil {
+			logger.FromContext(ctx).Info("websocket client disconnected", "error", err)
+		}
+	}
+}
+
+// watchConn is a WebSocket client following the change feed. readRequests hands
+// the client's requests to pushChanges, which applies them and writes every
+// message, so a reply always comes before the changes it lets through.
+type watchConn struct {
+	conn         *websocket.Conn
+	sub          *services.ChangeSubscription
+	heartbeat    time.Duration
+	writeTimeout time.Duration
+	requests     chan []byte
+}
+
+// readRequests queues the client's requests until the connection fails or the
+// client stops answering pings
+func (w *watchConn) readRequests() {
+	w.conn.SetReadLimit(maxWatchRequestBytes)
+	w.conn.SetReadDeadline(time.Now().Add(2 * w.heartbeat))
+	w.conn.SetPongHandler(func(string) error {
+		return w.conn.SetReadDeadline(time.Now().Add(2 * w.heartbeat))
+	})
+
+	for {
+		_, message, err := w.conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		w.conn.SetReadDeadline(time.Now().Add(2 * w.heartbeat))
+
+		select {
+		case w.requests <- message:
+		default:
+			// The client sends requests faster than it reads the replies
+			w.close(websocket.ClosePolicyViolation, "too many pending requests")
+			return
+		}
+	}
+}
+
+// apply changes what the subscription follows and returns the reply to the client
+func (w *watchConn) apply(message []byte) models.StreamEvent {
+	var req models.WatchRequest
+	if err := json.Unmarshal(message, &req); err != nil {
+		return watchError("invalid request: " + err.Error())
+	}
+
+	filter := w.sub.Filter()
+	switch req.Action {
+	case models.WatchActionSubscribe:
+		watched, err := filter.Watch(req.Tickers, req.Brokerages)
+		if err != nil {
+			return watchError(err.Error())
+		}
+		filter = watched
+	case models.WatchActionUnsubscribe:
+		filter = filter.Unwatch(req.Tickers, req.Brokerages)
+	default:
+		return watchError(`action must be "subscribe" or "unsubscribe"`)
+	}
+	w.sub.SetFilter(filter)
+
+	watching := &models.Watching{Tickers: []string{}, Brokerages: []string{}}
+	watching.Tickers = append(watching.Tickers, filter.Tickers...)
+	watching.Brokerages = append(watching.Brokerages, filter.Brokerages...)
+	return models.StreamEvent{Type: models.StreamEventSubscribed, Watching: watching, Time: time.Now()}
+}
+
+func watchError(message string) models.StreamEvent {
+	return models.StreamEvent{Type: models.StreamEventError, Error: message, Time: time.Now()}
+}
+
+// pushChanges writes the matching changes, replies and pings until ctx is done,
+// the stream stops or a write fails. The feed is read one page at a time and
+// only after the previous page was written, so a slow client holds back its own
+// cursor and nothing else; one that takes longer than the write timeout to
+// accept a message is disconnected.
+func (w *watchConn) pushChanges(ctx context.Context) error {
+	ping := time.NewTicker(w.heartbeat)
+	defer ping.Stop()
+
+	for {
+		events, err := w.sub.Next(ctx)
+		if err != nil {
+			return err
+		}
+		for _, event := range events {
+			// The top pick is only streamed over SSE
+			if event.Type != models.StreamEventChange {
+				continue
+			}
+			if err := w.write(event); err != nil {
+				return err
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-w.sub.Done():
+			w.close(websocket.CloseGoingAway, "server shutting down")
+			return nil
+		case <-w.sub.Ready():
+		case message := <-w.requests:
+			if err := w.write(w.apply(message)); err != nil {
+				return err
+			}
+		case <-ping.C:
+			if err := w.conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(w.writeTimeout)); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func (w *watchConn) write(event models.StreamEvent) error {
+	w.conn.SetWriteDeadline(time.Now().Add(w.writeTimeout))
+	return w.conn.WriteJSON(event)
+}
+
+// close sends a close message; the caller then drops the connection
+func (w *watchConn) close(code int, reason string) {
+	message := websocket.FormatCloseMessage(code, reason)
+	w.conn.WriteControl(websocket.CloseMessage, message, time.Now().Add(w.writeTimeout))
+}
+
+// allowedOrigin accepts WebSocket handshakes from the CORS origins, from the
+// API's own origin and from clients that send no Origin, which are not browsers
+func allowedOrigin(origins []string) func(*http.Request) bool {
+	return func(r *http.Request) bool {
+		origin := r.Header.Get("Origin")
+		if origin == "" || slices.Contains(origins, "*") {
+			return true
+		}
+		for _, allowed := range origins {
+			if strings.EqualFold(origin, allowed) {
+				return true
+			}
+		}
+		u, err := url.Parse(origin)
+		return err == nil && strings.EqualFold(u.Host, r.Host)
+	}
+}
@@ -0,0 +1,243 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"Backend/internal/config"
+	"Backend/internal/entity"
+	"Backend/internal/middleware"
+	"Backend/internal/models"
+	"Backend/internal/repository"
+	"Backend/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+)
+
+type watchServer struct {
+	url    string
+	token  string
+	repo   *repository.MemoryRepository
+	stream *services.ChangeStream
+}
+
+// newWatchServer serves the WebSocket route on a local port. Tokens are not
+// checked for revocation, which needs the database. Connections get a small send
+// buffer so that writes block soon after a client stops reading.
+func newWatchServer(t *testing.T, writeTimeout time.Duration) *watchServer {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	cfg := config.Default()
+	cfg.JwtSecretKey = []byte("test-secret-key-with-at-least-32-bytes")
+	cfg.Stream.Heartbeat = time.Second
+	cfg.Stream.WriteTimeout = writeTimeout
+
+	repo := repository.NewMemoryRepository()
+	stockService := services.NewStockService(repo, repo, repo, cfg.Scoring)
+	stream := services.NewChangeStream(repo, stockService, cfg.Stream)
+
+	r := gin.New()
+	r.GET("/api/v1/ws", middleware.AuthMiddleware(cfg, nil), watchChanges(stream, cfg))
+	server := httptest.NewUnstartedServer(r)
+	server.Listener = smallBufferListener{server.Listener}
+	server.Start()
+	t.Cleanup(server.Close)
+
+	token, err := middleware.GenerateToken(&entity.UserJwt{UserId: 1, Username: "dashboard"}, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &watchServer{
+		url:    "ws" + strings.TrimPrefix(server.URL, "http") + "/api/v1/ws",
+		token:  token,
+		repo:   repo,
+		stream: stream,
+	}
+}
+
+type smallBufferListener struct{ net.Listener }
+
+func (l smallBufferListener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err == nil {
+		err = conn.(*net.TCPConn).SetWriteBuffer(4 << 10)
+	}
+	return conn, err
+}
+
+// dial connects with the token as access_token, the way a browser does
+func (s *watchServer) dial(t *testing.T, dialer *websocket.Dialer, query string) *websocket.Conn {
+	t.Helper()
+
+	conn, resp, err := dialer.Dial(s.url+"?access_token="+url.QueryEscape(s.token)+query, nil)
+	if err != nil {
+		t.Fatalf("dial: %v (response %+v)", err, resp)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func (s *watchServer) upsert(t *testing.T, stocks ...models.Stock) {
+	t.Helper()
+	ctx := context.Background()
+	if _, err := s.repo.UpsertStocks(ctx, stocks); err != nil {
+		t.Fatal(err)
+	}
+	s.stream.Poll(ctx)
+}
+
+func send(t *testing.T, conn *websocket.Conn, req models.WatchRequest) {
+	t.Helper()
+	if err := conn.WriteJSON(req); err != nil {
+		t.Fatalf("send %+v: %v", req, err)
+	}
+}
+
+func receive(t *testing.T, conn *websocket.Conn) models.StreamEvent {
+	t.Helper()
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	var event models.StreamEvent
+	if err := conn.ReadJSON(&event); err != nil {
+		t.Fatalf("receive: %v", err)
+	}
+	return event
+}
+
+func TestWatchChanges(t *testing.T) {
+	s := newWatchServer(t, 10*time.Second)
+	now := time.Now()
+
+	t.Run("requires a token", func(t *testing.T) {
+		_, resp, err := websocket.DefaultDialer.Dial(s.url, nil)
+		if err == nil || resp == nil || resp.StatusCode != http.StatusUnauthorized {
+			t.Fatalf("dial without token: error %v, response %+v; want 401", err, resp)
+		}
+	})
+
+	t.Run("rejects foreign origins", func(t *testing.T) {
+		header := http.Header{"Origin": {"https://evil.example.com"}}
+		_, resp, err := websocket.DefaultDialer.Dial(s.url+"?access_token="+s.token, header)
+		if err == nil || resp == nil || resp.StatusCode != http.StatusForbidden {
+			t.Fatalf("dial from another origin: error %v, response %+v; want 403", err, resp)
+		}
+	})
+
+	conn := s.dial(t, websocket.DefaultDialer, "")
+
+	t.Run("subscribe and unsubscribe", func(t *testing.T) {
+		send(t, conn, models.WatchRequest{Action: models.WatchActionSubscribe, Tickers: []string{"msft", "aapl"}})
+		event := receive(t, conn)
+		if event.Type != models.StreamEventSubscribed || strings.Join(event.Watching.Tickers, ",") != "MSFT,AAPL" {
+			t.Fatalf("reply = %+v, want MSFT and AAPL followed", event)
+		}
+
+		send(t, conn, models.WatchRequest{Action: models.WatchActionUnsubscribe, Tickers: []string{"AAPL"}})
+		send(t, conn, models.WatchRequest{Action: models.WatchActionSubscribe, Brokerages: []string{"UBS"}})
+		receive(t, conn)
+		event = receive(t, conn)
+		if strings.Join(event.Watching.Tickers, ",") != "MSFT" || strings.Join(event.Watching.Brokerages, ",") != "UBS" {
+			t.Fatalf("reply = %+v, want MSFT and UBS followed", event.Watching)
+		}
+	})
+
+	t.Run("invalid requests", func(t *testing.T) {
+		send(t, conn, models.WatchRequest{Action: "follow"})
+		if event := receive(t, conn); event.Type != models.StreamEventError || !strings.Contains(event.Error, "action") {
+			t.Errorf("reply = %+v, want an error about the action", event)
+		}
+		if err := conn.WriteMessage(websocket.TextMessage, []byte("{")); err != nil {
+			t.Fatal(err)
+		}
+		if event := receive(t, conn); event.Type != models.StreamEventError {
+			t.Errorf("reply = %+v, want an error", event)
+		}
+	})
+
+	t.Run("pushes followed changes", func(t *testing.T) {
+		s.upsert(t,
+			models.Stock{Ticker: "AAPL", Company: "Apple Inc", Brokerage: "Goldman Sachs", RatingTo: "Buy", Time: now},
+			models.Stock{Ticker: "MSFT", Company: "Microsoft Corp", Brokerage: "Morgan Stanley", RatingTo: "Buy", Time: now},
+			models.Stock{Ticker: "TSLA", Company: "Tesla Inc", Brokerage: "UBS", RatingTo: "Sell", Time: now},
+		)
+
+		var tickers []string
+		for range 2 {
+			event := receive(t, conn)
+			if event.Type != models.StreamEventChange {
+				t.Fatalf("event = %+v, want a change", event)
+			}
+			tickers = append(tickers, event.Change.Ticker)
+		}
+		if strings.Join(tickers, ",") != "MSFT,TSLA" {
+			t.Errorf("changes for %v, want MSFT and TSLA", tickers)
+		}
+
+		// A score change is pushed too
+		s.upsert(t, models.Stock{Ticker: "MSFT", Company: "Microsoft Corp", Brokerage: "Morgan Stanley", RatingTo: "Buy", Score: 70, Time: now})
+		if event := receive(t, conn); event.Type != models.StreamEventChange || event.Change.Type != models.ChangeScoreChange {
+			t.Errorf("event = %+v, want the MSFT score change", event)
+		}
+	})
+}
+
+func TestWatchChangesDisconnectsSlowClients(t *testing.T) {
+	s := newWatchServer(t, 50*time.Millisecond)
+	now := time.Now()
+
+	// With a small receive buffer too, little fits in flight
+	slow := *websocket.DefaultDialer
+	slow.NetDialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		conn, err := (&net.Dialer{}).DialContext(ctx, network, addr)
+		if err == nil {
+			err = conn.(*net.TCPConn).SetReadBuffer(4 << 10)
+		}
+		return conn, err
+	}
+
+	conn := s.dial(t, &slow, "")
+	send(t, conn, models.WatchRequest{Action: models.WatchActionSubscribe, Brokerages: []string{"UBS"}})
+	receive(t, conn)
+
+	stocks := make([]models.Stock, 2000)
+	for i := range stocks {
+		stocks[i] = models.Stock{Ticker: fmt.Sprintf("T%05d", i), Company: "Corp", Brokerage: "UBS", RatingTo: "Buy", Time: now}
+	}
+	s.upsert(t, stocks...)
+
+	// The server gives up on the client instead of queueing changes for it
+	time.Sleep(500 * time.Millisecond)
+	var last int64
+	for {
+		conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+		var event models.StreamEvent
+		if err := conn.ReadJSON(&event); err != nil {
+			break
+		}
+		last = event.ID
+	}
+	if last == int64(len(stocks)) {
+		t.Fatal("a client that stopped reading received every change")
+	}
+
+	// It resumes where it left off
+	conn = s.dial(t, websocket.DefaultDialer, fmt.Sprintf("&since=%d", last))
+	send(t, conn, models.WatchRequest{Action: models.WatchActionSubscribe, Brokerages: []string{"ubs"}})
+	if event := receive(t, conn); event.Type != models.StreamEventSubscribed {
+		t.Fatalf("reply = %+v, want the subscription", event)
+	}
+	for want := last + 1; want <= int64(len(stocks)); want++ {
+		if event := receive(t, conn); event.ID != want {
+			t.Fatalf("event ID = %d, want %d", event.ID, want)
+		}
+	}
+}
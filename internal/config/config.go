@@ -1,113 +1,568 @@
-// Package config provides functionality for loading and managing application configuration
+// Package config provides functionality for loading and managing application configuration.
+//
+// Values are resolved from the following sources, each one overriding the previous:
+//
+//  1. Built-in defaults (see Default)
+//  2. A YAML or TOML config file (--config flag or CONFIG_FILE env var)
+//  3. Environment variables, including those loaded from a .env file
+//  4. Command-line flags
 package config
 
 import (
+	"errors"
+	"flag"
 	"fmt"
-	"log"
+	"net"
+	"net/mail"
+	"net/url"
 	"os"
+	"slices"
 	"strconv"
+	"strings"
+	"time"
+
+	"Backend/internal/logger"
 
 	"github.com/joho/godotenv"
+	"golang.org/x/crypto/bcrypt"
 )
 
+// minJwtSecretLength is the minimum accepted length of JWT_SECRET_KEY in bytes (HS256 key size)
+const minJwtSecretLength = 32
+
+// weakJwtSecrets are placeholder values that must never be used as the JWT secret
+var weakJwtSecrets = []string{"your-secret-key", "secret", "changeme", "change-me", "jwt-secret"}
+
 // Config holds all configuration parameters for the application
 type Config struct {
-	DatabaseURL  string // URL for database connection
-	APIKey       string // API key for external services
-	APIBaseURL   string // Base URL for API endpoints
-	Port         string // Server port number
-	DatabaseName string // Name of the database
-	Environment  string // Current environment (development/production/test)
-	GinMode      string // Gin framework mode
-	JwtSecretKey []byte // Secret key for JWT token generation
+	DatabaseURL  string `yaml:"database_url" toml:"database_url"`   // URL for database connection
+	APIKey       string `yaml:"api_key" toml:"api_key"`             // API key for external services
+	APIBaseURL   string `yaml:"api_base_url" toml:"api_base_url"`   // Base URL for API endpoints
+	Port         string `yaml:"port" toml:"port"`                   // Server port number
+	DatabaseName string `yaml:"database_name" toml:"database_name"` // Name of the database
+	Environment  string `yaml:"environment" toml:"environment"`     // Current environment (development/production/test)
+	GinMode      string `yaml:"gin_mode" toml:"gin_mode"`           // Gin framework mode
+	JwtSecretKey []byte `yaml:"-" toml:"-"`                         // Secret key for JWT token generation
+
+	Log      LogConfig      `yaml:"log" toml:"log"`
+	Tracing  TracingConfig  `yaml:"tracing" toml:"tracing"`
+	HTTP     HTTPConfig     `yaml:"http" toml:"http"`
+	GRPC     GRPCConfig     `yaml:"grpc" toml:"grpc"`
+	DB       DBConfig       `yaml:"db" toml:"db"`
+	Sync     SyncConfig     `yaml:"sync" toml:"sync"`
+	Backfill BackfillConfig `yaml:"backfill" toml:"backfill"`
+	Stream   StreamConfig   `yaml:"stream" toml:"stream"`
+	Alerts   AlertsConfig   `yaml:"alerts" toml:"alerts"`
+	Webhooks WebhooksConfig `yaml:"webhooks" toml:"webhooks"`
+	Digest   DigestConfig   `yaml:"digest" toml:"digest"`
+	SMTP     SMTPConfig     `yaml:"smtp" toml:"smtp"`
+	Scoring  ScoringConfig  `yaml:"scoring" toml:"scoring"`
+	Auth     AuthConfig     `yaml:"auth" toml:"auth"`
+	CORS     CORSConfig     `yaml:"cors" toml:"cors"`
+
+	sources map[string]string // Source of each value that is not a default, reported by Print
+}
+
+// LogConfig configures the structured logger
+type LogConfig struct {
+	Level  string `yaml:"level" toml:"level"`   // Minimum log level (debug, info, warn, error)
+	Format string `yaml:"format" toml:"format"` // Log output format (text or json)
 }
 
-// Load reads configuration from .env file or environment variables
+// TracingConfig configures OpenTelemetry tracing
+type TracingConfig struct {
+	Exporter     string  `yaml:"exporter" toml:"exporter"`           // Trace exporter (none, otlp, stdout, file)
+	OTLPEndpoint string  `yaml:"otlp_endpoint" toml:"otlp_endpoint"` // OTLP/HTTP collector endpoint (host:port)
+	OTLPInsecure bool    `yaml:"otlp_insecure" toml:"otlp_insecure"` // Send OTLP traces over plain HTTP
+	File         string  `yaml:"file" toml:"file"`                   // Output file for the file exporter
+	SampleRatio  float64 `yaml:"sample_ratio" toml:"sample_ratio"`   // Fraction of traces sampled (0-1)
+}
+
+// HTTPConfig configures the HTTP server
+type HTTPConfig struct {
+	ReadTimeout       time.Duration `yaml:"read_timeout" toml:"read_timeout"`               // Maximum duration for reading the whole request
+	ReadHeaderTimeout time.Duration `yaml:"read_header_timeout" toml:"read_header_timeout"` // Maximum duration for reading request headers
+	WriteTimeout      time.Duration `yaml:"write_timeout" toml:"write_timeout"`             // Maximum duration before timing out writes
+	IdleTimeout       time.Duration `yaml:"idle_timeout" toml:"idle_timeout"`               // Maximum keep-alive idle time
+	ShutdownTimeout   time.Duration `yaml:"shutdown_timeout" toml:"shutdown_timeout"`       // Grace period for in-flight requests on shutdown
+	MaxHeaderBytes    int           `yaml:"max_header_bytes" toml:"max_header_bytes"`       // Maximum size of request headers
+	MaxBodyBytes      int64         `yaml:"max_body_bytes" toml:"max_body_bytes"`           // Maximum size of request bodies
+	TLSCertFile       string        `yaml:"tls_cert_file" toml:"tls_cert_file"`             // TLS certificate (PEM); enables HTTPS together with TLSKeyFile
+	TLSKeyFile        string        `yaml:"tls_key_file" toml:"tls_key_file"`               // TLS private key (PEM)
+	TrustedProxies    []string      `yaml:"trusted_proxies" toml:"trusted_proxies"`         // Proxy IPs/CIDRs allowed to set X-Forwarded-For (empty = none)
+	SecurityHeaders   bool          `yaml:"security_headers" toml:"security_headers"`       // Add security headers to every response
+	HSTSMaxAge        time.Duration `yaml:"hsts_max_age" toml:"hsts_max_age"`               // Strict-Transport-Security max-age when serving TLS (0 = disabled)
+}
+
+// TLSEnabled reports whether the server should serve HTTPS
+func (h HTTPConfig) TLSEnabled() bool {
+	return h.TLSCertFile != "" && h.TLSKeyFile != ""
+}
+
+// DBConfig configures the database connection pool
+type DBConfig struct {
+	MaxOpenConns    int           `yaml:"max_open_conns" toml:"max_open_conns"`         // Maximum open connections (0 = unlimited)
+	MaxIdleConns    int           `yaml:"max_idle_conns" toml:"max_idle_conns"`         // Maximum idle connections
+	ConnMaxLifetime time.Duration `yaml:"conn_max_lifetime" toml:"conn_max_lifetime"`   // Maximum connection reuse time (0 = forever)
+	ConnMaxIdleTime time.Duration `yaml:"conn_max_idle_time" toml:"conn_max_idle_time"` // Maximum connection idle time (0 = forever)
+	ReplicaURL      string        `yaml:"replica_url" toml:"replica_url"`               // Optional read replica for read-only queries
+	ConnectRetries  int           `yaml:"connect_retries" toml:"connect_retries"`       // Connection attempts on startup before giving up
+	RetryBackoff    time.Duration `yaml:"retry_backoff" toml:"retry_backoff"`           // Initial wait between attempts; doubles each time
+	RetryMaxBackoff time.Duration `yaml:"retry_max_backoff" toml:"retry_max_backoff"`   // Upper bound for the wait between attempts
+}
+
+// SyncConfig configures the upstream stock sync
+type SyncConfig struct {
+	Enabled            bool          `yaml:"enabled" toml:"enabled"`                         // Run the periodic sync
+	Interval           time.Duration `yaml:"interval" toml:"interval"`                       // Time between sync runs
+	PageDelay          time.Duration `yaml:"page_delay" toml:"page_delay"`                   // Pause between upstream pages
+	MaxStocks          int           `yaml:"max_stocks" toml:"max_stocks"`                   // Stop paging after this many stocks (0 = no limit)
+	StalenessThreshold time.Duration `yaml:"staleness_threshold" toml:"staleness_threshold"` // Maximum data age before readiness reports it as stale
+}
+
+// BackfillConfig configures historical backfills. They are throttled separately
+// from the periodic sync so a long backfill does not eat into its rate limit.
+type BackfillConfig struct {
+	PageDelay time.Duration `yaml:"page_delay" toml:"page_delay"` // Pause between upstream pages
+	MaxPages  int           `yaml:"max_pages" toml:"max_pages"`   // Pages fetched per run before pausing the job (0 = no limit)
+}
+
+// GRPCConfig configures the gRPC server
+type GRPCConfig struct {
+	Port string `yaml:"port" toml:"port"` // gRPC server port; empty disables the server
+}
+
+// StreamConfig configures the live change stream
+type StreamConfig struct {
+	PollInterval time.Duration `yaml:"poll_interval" toml:"poll_interval"` // How often the change feed is checked for new entries
+	Heartbeat    time.Duration `yaml:"heartbeat" toml:"heartbeat"`         // Idle time before a heartbeat is sent to a subscriber
+	WriteTimeout time.Duration `yaml:"write_timeout" toml:"write_timeout"` // Time a WebSocket client has to accept a message before it is disconnected
+}
+
+// AlertsConfig configures alert rules
+type AlertsConfig struct {
+	Cooldown time.Duration `yaml:"cooldown" toml:"cooldown"`   // Minimum time between alerts of a rule for the same ticker, unless the rule sets its own
+	MaxRules int           `yaml:"max_rules" toml:"max_rules"` // Alert rules allowed per user
+}
+
+// WebhooksConfig configures outbound webhook deliveries
+type WebhooksConfig struct {
+	PollInterval    time.Duration `yaml:"poll_interval" toml:"poll_interval"`         // How often new events are queued and due deliveries sent
+	Timeout         time.Duration `yaml:"timeout" toml:"timeout"`                     // Time a webhook has to answer a delivery
+	MaxAttempts     int           `yaml:"max_attempts" toml:"max_attempts"`           // Attempts before a delivery is dead-lettered
+	RetryBackoff    time.Duration `yaml:"retry_backoff" toml:"retry_backoff"`         // Wait before the first retry; doubles each time
+	RetryMaxBackoff time.Duration `yaml:"retry_max_backoff" toml:"retry_max_backoff"` // Upper bound for the wait between attempts
+	MaxPerUser      int           `yaml:"max_per_user" toml:"max_per_user"`           // Webhooks allowed per user
+
+	AllowPrivateNetworks bool `yaml:"allow_private_networks" toml:"allow_private_networks"` // Deliver to loopback, private and link-local addresses (development only)
+}
+
+// DigestConfig configures the daily email digest
+type DigestConfig struct {
+	Enabled       bool          `yaml:"enabled" toml:"enabled"`               // Send digests to subscribed users (requires SMTP)
+	SendAt        string        `yaml:"send_at" toml:"send_at"`               // Time of day digests are sent (HH:MM)
+	Timezone      string        `yaml:"timezone" toml:"timezone"`             // IANA time zone of SendAt
+	CheckInterval time.Duration `yaml:"check_interval" toml:"check_interval"` // How often due digests are looked for
+	TopChanges    int           `yaml:"top_changes" toml:"top_changes"`       // Upgrades and downgrades listed in a digest
+}
+
+// SMTPConfig configures the mail server used to send email
+type SMTPConfig struct {
+	Host     string        `yaml:"host" toml:"host"`         // Mail server host
+	Port     int           `yaml:"port" toml:"port"`         // Mail server port; STARTTLS is used when offered
+	Username string        `yaml:"username" toml:"username"` // Login for SMTP authentication (empty = none)
+	Password string        `yaml:"password" toml:"password"` // Password for SMTP authentication
+	From     string        `yaml:"from" toml:"from"`         // Sender address
+	Timeout  time.Duration `yaml:"timeout" toml:"timeout"`   // Time allowed to send one email
+}
+
+// ScoringConfig configures recommendations
+type ScoringConfig struct {
+	MinScore            float64 `yaml:"min_score" toml:"min_score"`                       // Minimum score for a stock to be recommended
+	RecommendationLimit int     `yaml:"recommendation_limit" toml:"recommendation_limit"` // Number of recommendations returned
+}
+
+// AuthConfig configures tokens and login throttling
+type AuthConfig struct {
+	TokenTTL         time.Duration `yaml:"token_ttl" toml:"token_ttl"`                   // JWT lifetime
+	MaxLoginAttempts int           `yaml:"max_login_attempts" toml:"max_login_attempts"` // Failures allowed before a lockout starts
+	LockoutBase      time.Duration `yaml:"lockout_base" toml:"lockout_base"`             // First lockout, doubled on every further failure
+	LockoutMax       time.Duration `yaml:"lockout_max" toml:"lockout_max"`               // Lockout cap
+
+	Users  []string `yaml:"users" toml:"users"`   // Accounts allowed to log in, as username:bcrypt-hash
+	Admins []string `yaml:"admins" toml:"admins"` // Users granted the admin role
+}
+
+// PasswordHash returns the bcrypt hash of a configured user and its position in
+// Users, which serves as its ID
+func (a AuthConfig) PasswordHash(username string) (hash []byte, id int, ok bool) {
+	for i, entry := range a.Users {
+		if name, hash, found := strings.Cut(entry, ":"); found && name == username {
+			return []byte(hash), i + 1, true
+		}
+	}
+	return nil, 0, false
+}
+
+// IsAdmin reports whether username is granted the admin role
+func (a AuthConfig) IsAdmin(username string) bool {
+	return slices.Contains(a.Admins, username)
+}
+
+// CORSConfig configures cross-origin resource sharing
+type CORSConfig struct {
+	AllowOrigins     []string      `yaml:"allow_origins" toml:"allow_origins"`         // Allowed origins
+	AllowMethods     []string      `yaml:"allow_methods" toml:"allow_methods"`         // Allowed methods
+	AllowHeaders     []string      `yaml:"allow_headers" toml:"allow_headers"`         // Allowed request headers
+	AllowCredentials bool          `yaml:"allow_credentials" toml:"allow_credentials"` // Allow cookies and auth headers
+	ExposeHeaders    []string      `yaml:"expose_headers" toml:"expose_headers"`       // Response headers readable by the browser
+	MaxAge           time.Duration `yaml:"max_age" toml:"max_age"`                     // How long preflight results can be cached
+}
+
+// Default returns the built-in configuration defaults
+func Default() *Config {
+	return &Config{
+		Port:         "8080",
+		DatabaseName: "stock_tracking",
+		Environment:  "development",
+		GinMode:      "debug",
+		Log: LogConfig{
+			Level:  "info",
+			Format: "text",
+		},
+		Tracing: TracingConfig{
+			Exporter:    "none",
+			File:        "traces.json",
+			SampleRatio: 1,
+		},
+		HTTP: HTTPConfig{
+			ReadTimeout:       15 * time.Second,
+			ReadHeaderTimeout: 5 * time.Second,
+			WriteTimeout:      30 * time.Second,
+			IdleTimeout:       60 * time.Second,
+			ShutdownTimeout:   15 * time.Second,
+			MaxHeaderBytes:    1 << 20,
+			MaxBodyBytes:      10 << 20,
+			SecurityHeaders:   true,
+			HSTSMaxAge:        365 * 24 * time.Hour,
+		},
+		DB: DBConfig{
+			MaxOpenConns:    25,
+			MaxIdleConns:    10,
+			ConnMaxLifetime: 30 * time.Minute,
+			ConnMaxIdleTime: 5 * time.Minute,
+			ConnectRetries:  5,
+			RetryBackoff:    time.Second,
+			RetryMaxBackoff: 30 * time.Second,
+		},
+		Sync: SyncConfig{
+			Enabled:            true,
+			Interval:           40 * time.Minute,
+			PageDelay:          500 * time.Millisecond,
+			MaxStocks:          1000,
+			StalenessThreshold: 2 * time.Hour,
+		},
+		Backfill: BackfillConfig{
+			PageDelay: 2 * time.Second,
+		},
+		GRPC: GRPCConfig{
+			Port: "9090",
+		},
+		Stream: StreamConfig{
+			PollInterval: time.Second,
+			Heartbeat:    15 * time.Second,
+			WriteTimeout: 10 * time.Second,
+		},
+		Alerts: AlertsConfig{
+			Cooldown: time.Hour,
+			MaxRules: 50,
+		},
+		Webhooks: WebhooksConfig{
+			PollInterval:    5 * time.Second,
+			Timeout:         10 * time.Second,
+			MaxAttempts:     6,
+			RetryBackoff:    30 * time.Second,
+			RetryMaxBackoff: time.Hour,
+			MaxPerUser:      10,
+		},
+		Digest: DigestConfig{
+			SendAt:        "07:00",
+			Timezone:      "UTC",
+			CheckInterval: time.Minute,
+			TopChanges:    10,
+		},
+		SMTP: SMTPConfig{
+			Port:    587,
+			Timeout: 30 * time.Second,
+		},
+		Scoring: ScoringConfig{
+			MinScore:            0,
+			RecommendationLimit: 1,
+		},
+		Auth: AuthConfig{
+			TokenTTL:         7 * 24 * time.Hour,
+			MaxLoginAttempts: 5,
+			LockoutBase:      30 * time.Second,
+			LockoutMax:       time.Hour,
+		},
+		CORS: CORSConfig{
+			AllowOrigins:     []string{"http://localhost:3000", "http://localhost:5173", "http://localhost:8070"},
+			AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
+			AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization", "X-Request-ID"},
+			AllowCredentials: true,
+			ExposeHeaders:    []string{"X-Request-ID", "Retry-After"},
+			MaxAge:           12 * time.Hour,
+		},
+	}
+}
+
+// Load reads configuration from defaults, the config file, .env file or environment variables
+// and the process command-line flags. It exits on any configuration error.
 func Load() *Config {
-	if err := godotenv.Load(); err != nil {
-		log.Printf("No .env file found, using system environment variables: %v", err)
+	config, err := Parse(os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		os.Exit(0)
+	}
+	if err != nil {
+		logger.Component("config").Error("configuration error", "error", err)
+		os.Exit(1)
+	}
+
+	return config
+}
+
+// Parse resolves the configuration using args as command-line flags and validates it.
+// All problems are reported together in the returned error.
+func Parse(args []string) (*Config, error) {
+	if err := godotenv.Load(); err != nil && !errors.Is(err, os.ErrNotExist) {
+		logger.Component("config").Warn("error reading .env file", "error", err)
 	}
 
-	config := &Config{
-		DatabaseURL:  getEnv("DATABASE_URL", ""),
-		APIKey:       getEnv("API_KEY", ""),
-		APIBaseURL:   getEnv("API_BASE_URL", ""),
-		Port:         getEnv("PORT", "8080"),
-		DatabaseName: getEnv("DATABASE_NAME", "stock_tracking"),
-		Environment:  getEnv("ENVIRONMENT", "development"),
-		GinMode:      getEnv("GIN_MODE", "debug"),
-		JwtSecretKey: []byte(getEnv("JWT_SECRET_KEY", "")),
+	config := Default()
+
+	flags, err := parseFlags(args)
+	if err != nil {
+		return nil, err
 	}
 
+	var errs []error
+
+	configFile := flags.configFile
+	if configFile == "" {
+		configFile = os.Getenv("CONFIG_FILE")
+	}
+	if configFile != "" {
+		if err := config.loadFile(configFile); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	errs = append(errs, config.applyEnv()...)
+	errs = append(errs, config.applyFlags(flags)...)
+
 	if err := config.Validate(); err != nil {
-		log.Fatalf("Configuration error: %v", err)
+		errs = append(errs, err)
 	}
 
-	return config
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+
+	return config, nil
 }
 
-// LoadFromFile loads configuration from a specific file
+// LoadFromFile loads a .env file before resolving the configuration
 func LoadFromFile(filename string) *Config {
 	if err := godotenv.Load(filename); err != nil {
-		log.Fatalf("Error loading configuration file %s: %v", filename, err)
+		logger.Component("config").Error("error loading configuration file", "file", filename, "error", err)
+		os.Exit(1)
 	}
 	return Load()
 }
 
-// LoadFromEnv loads configuration only from system environment variables (no .env file)
+// LoadFromEnv loads configuration only from system environment variables (no .env file, config file or flags)
 func LoadFromEnv() *Config {
-	config := &Config{
-		DatabaseURL:  os.Getenv("DATABASE_URL"),
-		APIKey:       os.Getenv("API_KEY"),
-		APIBaseURL:   os.Getenv("API_BASE_URL"),
-		Port:         os.Getenv("PORT"),
-		DatabaseName: os.Getenv("DATABASE_NAME"),
-		Environment:  os.Getenv("ENVIRONMENT"),
-		GinMode:      os.Getenv("GIN_MODE"),
-	}
+	config := Default()
+	config.Environment = "production"
+	config.GinMode = "release"
+	config.Log.Format = "json"
 
-	// Apply defaults if empty
-	if config.Port == "" {
-		config.Port = "8080"
-	}
-	if config.DatabaseName == "" {
-		config.DatabaseName = "stock_tracking"
-	}
-	if config.Environment == "" {
-		config.Environment = "production"
-	}
-	if config.GinMode == "" {
-		config.GinMode = "release"
+	errs := config.applyEnv()
+	if err := config.Validate(); err != nil {
+		errs = append(errs, err)
 	}
 
-	if err := config.Validate(); err != nil {
-		log.Fatalf("Configuration error: %v", err)
+	if len(errs) > 0 {
+		logger.Component("config").Error("configuration error", "error", errors.Join(errs...))
+		os.Exit(1)
 	}
 
 	return config
 }
 
-// Validate checks if the configuration is valid
+// Validate checks if the configuration is valid and reports every problem found
 func (c *Config) Validate() error {
-	if c.DatabaseURL == "" {
-		return fmt.Errorf("DATABASE_URL is required")
+	var errs []error
+	check := func(ok bool, format string, args ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
 	}
+
+	check(c.DatabaseURL != "", "DATABASE_URL is required")
+	check(c.APIKey != "", "API_KEY is required")
+
 	if c.APIBaseURL == "" {
-		return fmt.Errorf("API_BASE_URL is required")
-	}
-	if c.APIKey == "" {
-		return fmt.Errorf("API_KEY is required")
+		errs = append(errs, fmt.Errorf("API_BASE_URL is required"))
+	} else if u, err := url.Parse(c.APIBaseURL); err != nil || u.Scheme == "" || u.Host == "" {
+		errs = append(errs, fmt.Errorf("API_BASE_URL must be an absolute URL"))
 	}
-	if c.APIBaseURL == "" {
-		return fmt.Errorf("API_BASE_URL is required")
+
+	if len(c.JwtSecretKey) == 0 {
+		errs = append(errs, fmt.Errorf("JWT_SECRET_KEY is required"))
+	} else {
+		check(len(c.JwtSecretKey) >= minJwtSecretLength, "JWT_SECRET_KEY must be at least %d bytes long", minJwtSecretLength)
+		for _, weak := range weakJwtSecrets {
+			check(!strings.EqualFold(string(c.JwtSecretKey), weak), "JWT_SECRET_KEY must not be a placeholder value")
+		}
 	}
 
 	// Validate port
-	if _, err := strconv.Atoi(c.Port); err != nil {
-		return fmt.Errorf("PORT must be a valid number: %v", err)
+	if port, err := strconv.Atoi(c.Port); err != nil {
+		errs = append(errs, fmt.Errorf("PORT must be a valid number: %v", err))
+	} else {
+		check(port > 0 && port < 65536, "PORT must be between 1 and 65535")
 	}
 
-	return nil
+	check(oneOf(c.Environment, "development", "dev", "production", "prod", "test", "testing"),
+		"ENVIRONMENT must be one of development, production, test")
+	check(oneOf(c.GinMode, "debug", "release", "test"), "GIN_MODE must be one of debug, release, test")
+
+	check(oneOf(strings.ToLower(c.Log.Level), "debug", "info", "warn", "warning", "error"), "LOG_LEVEL must be one of debug, info, warn, error")
+	check(oneOf(strings.ToLower(c.Log.Format), "text", "json"), "LOG_FORMAT must be text or json")
+
+	check(oneOf(c.Tracing.Exporter, "", "none", "otlp", "stdout", "file"), "TRACING_EXPORTER must be one of none, otlp, stdout, file")
+	check(c.Tracing.SampleRatio >= 0 && c.Tracing.SampleRatio <= 1, "TRACING_SAMPLE_RATIO must be between 0 and 1")
+	check(c.Tracing.Exporter != "file" || c.Tracing.File != "", "TRACING_FILE is required with the file exporter")
+
+	check(c.HTTP.ReadTimeout >= 0, "HTTP_READ_TIMEOUT must not be negative")
+	check(c.HTTP.ReadHeaderTimeout >= 0, "HTTP_READ_HEADER_TIMEOUT must not be negative")
+	check(c.HTTP.WriteTimeout >= 0, "HTTP_WRITE_TIMEOUT must not be negative")
+	check(c.HTTP.IdleTimeout >= 0, "HTTP_IDLE_TIMEOUT must not be negative")
+	check(c.HTTP.ShutdownTimeout > 0, "HTTP_SHUTDOWN_TIMEOUT must be positive")
+	check(c.HTTP.MaxHeaderBytes > 0, "HTTP_MAX_HEADER_BYTES must be positive")
+	check(c.HTTP.MaxBodyBytes > 0, "HTTP_MAX_BODY_BYTES must be positive")
+	check(c.HTTP.HSTSMaxAge >= 0, "HTTP_HSTS_MAX_AGE must not be negative")
+	check((c.HTTP.TLSCertFile == "") == (c.HTTP.TLSKeyFile == ""), "HTTP_TLS_CERT_FILE and HTTP_TLS_KEY_FILE must be set together")
+	for _, file := range []string{c.HTTP.TLSCertFile, c.HTTP.TLSKeyFile} {
+		if file != "" {
+			_, err := os.Stat(file)
+			check(err == nil, "TLS file %s is not readable: %v", file, err)
+		}
+	}
+	for _, proxy := range c.HTTP.TrustedProxies {
+		check(validIPOrCIDR(proxy), "HTTP_TRUSTED_PROXIES entry %q must be an IP or CIDR", proxy)
+	}
+
+	if c.GRPC.Port != "" {
+		port, err := strconv.Atoi(c.GRPC.Port)
+		check(err == nil && port > 0 && port < 65536, "GRPC_PORT must be a port between 1 and 65535")
+		check(c.GRPC.Port != c.Port, "GRPC_PORT must differ from PORT")
+	}
+
+	check(c.DB.MaxOpenConns >= 0, "DB_MAX_OPEN_CONNS must not be negative")
+	check(c.DB.MaxIdleConns >= 0, "DB_MAX_IDLE_CONNS must not be negative")
+	check(c.DB.MaxOpenConns == 0 || c.DB.MaxIdleConns <= c.DB.MaxOpenConns, "DB_MAX_IDLE_CONNS must not exceed DB_MAX_OPEN_CONNS")
+	check(c.DB.ConnMaxLifetime >= 0, "DB_CONN_MAX_LIFETIME must not be negative")
+	check(c.DB.ConnMaxIdleTime >= 0, "DB_CONN_MAX_IDLE_TIME must not be negative")
+	check(c.DB.ConnectRetries > 0, "DB_CONNECT_RETRIES must be positive")
+	check(c.DB.RetryBackoff > 0, "DB_RETRY_BACKOFF must be positive")
+	check(c.DB.RetryMaxBackoff >= c.DB.RetryBackoff, "DB_RETRY_MAX_BACKOFF must not be lower than DB_RETRY_BACKOFF")
+	if c.DB.ReplicaURL != "" {
+		u, err := url.Parse(c.DB.ReplicaURL)
+		check(err == nil && u.Host != "", "DATABASE_REPLICA_URL must be a valid URL")
+	}
+
+	check(c.Sync.Interval >= time.Minute, "SYNC_INTERVAL must be at least 1m")
+	check(c.Sync.PageDelay >= 0, "SYNC_PAGE_DELAY must not be negative")
+	check(c.Sync.MaxStocks >= 0, "SYNC_MAX_STOCKS must not be negative")
+	check(c.Sync.StalenessThreshold > 0, "SYNC_STALENESS_THRESHOLD must be a positive duration")
+
+	check(c.Backfill.PageDelay >= 0, "BACKFILL_PAGE_DELAY must not be negative")
+	check(c.Backfill.MaxPages >= 0, "BACKFILL_MAX_PAGES must not be negative")
+
+	check(c.Stream.PollInterval > 0, "STREAM_POLL_INTERVAL must be a positive duration")
+	check(c.Stream.Heartbeat > 0, "STREAM_HEARTBEAT must be a positive duration")
+	check(c.Stream.WriteTimeout > 0, "STREAM_WRITE_TIMEOUT must be a positive duration")
+
+	check(c.Alerts.Cooldown >= 0, "ALERTS_COOLDOWN must not be negative")
+	check(c.Alerts.MaxRules > 0, "ALERTS_MAX_RULES must be positive")
+
+	check(c.Webhooks.PollInterval > 0, "WEBHOOKS_POLL_INTERVAL must be a positive duration")
+	check(c.Webhooks.Timeout > 0, "WEBHOOKS_TIMEOUT must be a positive duration")
+	check(c.Webhooks.MaxAttempts > 0, "WEBHOOKS_MAX_ATTEMPTS must be positive")
+	check(c.Webhooks.RetryBackoff > 0, "WEBHOOKS_RETRY_BACKOFF must be positive")
+	check(c.Webhooks.RetryMaxBackoff >= c.Webhooks.RetryBackoff, "WEBHOOKS_RETRY_MAX_BACKOFF must not be lower than WEBHOOKS_RETRY_BACKOFF")
+	check(c.Webhooks.MaxPerUser > 0, "WEBHOOKS_MAX_PER_USER must be positive")
+
+	_, err := ParseTimeOfDay(c.Digest.SendAt)
+	check(err == nil, "DIGEST_SEND_AT must be a time of day (HH:MM)")
+	_, err = time.LoadLocation(c.Digest.Timezone)
+	check(err == nil, "DIGEST_TIMEZONE must be an IANA time zone: %v", err)
+	check(c.Digest.CheckInterval > 0, "DIGEST_CHECK_INTERVAL must be a positive duration")
+	check(c.Digest.TopChanges > 0, "DIGEST_TOP_CHANGES must be positive")
+	if c.Digest.Enabled {
+		check(c.SMTP.Host != "", "SMTP_HOST is required when DIGEST_ENABLED is true")
+		check(c.SMTP.From != "", "SMTP_FROM is required when DIGEST_ENABLED is true")
+	}
+	if c.SMTP.From != "" {
+		_, err := mail.ParseAddress(c.SMTP.From)
+		check(err == nil, "SMTP_FROM must be an email address")
+	}
+	check(c.SMTP.Port > 0 && c.SMTP.Port < 65536, "SMTP_PORT must be between 1 and 65535")
+	check(c.SMTP.Timeout > 0, "SMTP_TIMEOUT must be a positive duration")
+
+	check(c.Scoring.MinScore >= 0 && c.Scoring.MinScore <= 100, "SCORING_MIN_SCORE must be between 0 and 100")
+	check(c.Scoring.RecommendationLimit > 0, "SCORING_RECOMMENDATION_LIMIT must be positive")
+
+	check(c.Auth.TokenTTL >= time.Minute, "AUTH_TOKEN_TTL must be at least 1m")
+	check(c.Auth.MaxLoginAttempts > 0, "AUTH_MAX_LOGIN_ATTEMPTS must be positive")
+	check(c.Auth.LockoutBase > 0, "AUTH_LOCKOUT_BASE must be positive")
+	check(c.Auth.LockoutMax >= c.Auth.LockoutBase, "AUTH_LOCKOUT_MAX must not be lower than AUTH_LOCKOUT_BASE")
+	for _, entry := range c.Auth.Users {
+		name, hash, _ := strings.Cut(entry, ":")
+		_, err := bcrypt.Cost([]byte(hash))
+		check(name != "" && err == nil, "AUTH_USERS entry for %q must be username:bcrypt-hash", name)
+	}
+	for _, admin := range c.Auth.Admins {
+		_, _, ok := c.Auth.PasswordHash(admin)
+		check(ok, "AUTH_ADMINS entry %q must be a user of AUTH_USERS", admin)
+	}
+
+	check(len(c.CORS.AllowOrigins) > 0, "CORS_ALLOW_ORIGINS must list at least one origin")
+	check(c.CORS.MaxAge >= 0, "CORS_MAX_AGE must not be negative")
+	for _, header := range c.CORS.AllowHeaders {
+		check(header != "*" || !c.CORS.AllowCredentials, "CORS_ALLOW_HEADERS cannot be * when CORS_ALLOW_CREDENTIALS is true")
+	}
+	for _, origin := range c.CORS.AllowOrigins {
+		if origin == "*" {
+			check(!c.CORS.AllowCredentials, "CORS_ALLOW_ORIGINS cannot be * when CORS_ALLOW_CREDENTIALS is true")
+			continue
+		}
+		u, err := url.Parse(origin)
+		check(err == nil && u.Scheme != "" && u.Host != "" && u.Path == "", "CORS origin %q must be scheme://host[:port]", origin)
+	}
+
+	return errors.Join(errs...)
+}
+
+// ParseTimeOfDay parses an HH:MM time of day and returns it as the time since midnight
+func ParseTimeOfDay(value string) (time.Duration, error) {
+	t, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time of day %q: %w", value, err)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
 }
 
 // IsDevelopment checks if the environment is set to development mode
@@ -141,23 +596,19 @@ func getEnv(key, defaultValue string) string {
 	return defaultValue
 }
 
-// // Print displays the configuration (hiding sensitive data)
-// func (c *Config) Print() {
-// 	log.Println("=== Application Configuration ===")
-// 	log.Printf("Environment: %s", c.Environment)
-// 	log.Printf("Port: %s", c.Port)
-// 	log.Printf("Database Name: %s", c.DatabaseName)
-// 	log.Printf("API Base URL: %s", c.APIBaseURL)
-// 	log.Printf("Gin Mode: %s", c.GinMode)
-// 	log.Printf("Database URL: %s", maskSensitiveData(c.DatabaseURL))
-// 	log.Printf("API Key: %s", maskSensitiveData(c.APIKey))
-// 	log.Println("========================================")
-// }
-
-// // maskSensitiveData masks sensitive information for display purposes
-// func maskSensitiveData(data string) string {
-// 	if len(data) <= 8 {
-// 		return "***"
-// 	}
-// 	return data[:4] + "..." + data[len(data)-4:]
-// }
+func validIPOrCIDR(value string) bool {
+	if net.ParseIP(value) != nil {
+		return true
+	}
+	_, _, err := net.ParseCIDR(value)
+	return err == nil
+}
+
+func oneOf(value string, allowed ...string) bool {
+	for _, a := range allowed {
+		if value == a {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,48 @@
+package config
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+	"text/tabwriter"
+)
+
+// Print writes the effective configuration, its environment variable and the source
+// of each value. Secrets are masked.
+func (c *Config) Print(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "KEY\tVALUE\tENV\tSOURCE")
+
+	for _, b := range bindings {
+		value := b.value(c).String()
+		if b.secret {
+			value = maskSecret(b.key, value)
+		}
+		if value == "" {
+			value = "(empty)"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", b.key, value, b.env, c.source(b.key))
+	}
+
+	return tw.Flush()
+}
+
+// maskSecret masks a secret for display; database URLs keep everything but the password
+func maskSecret(key, value string) string {
+	if value == "" {
+		return ""
+	}
+
+	if key == "database_url" || key == "db.replica_url" {
+		if u, err := url.Parse(value); err == nil && u.Host != "" {
+			return u.Redacted()
+		}
+	}
+
+	return maskSensitiveData(value)
+}
+
+// maskSensitiveData masks sensitive information for display purposes, keeping only its length
+func maskSensitiveData(data string) string {
+	return fmt.Sprintf("*** (%d chars)", len(data))
+}
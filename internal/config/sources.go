@@ -0,0 +1,383 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// binding ties a configuration value to its config file key, environment variable and flag
+type binding struct {
+	key    string // Dotted config file key, also used to derive the flag name
+	env    string
+	usage  string
+	secret bool
+	value  func(c *Config) flag.Value
+}
+
+// flagName returns the command-line flag for the binding, e.g. db.max_open_conns -> db-max-open-conns
+func (b binding) flagName() string {
+	return strings.NewReplacer(".", "-", "_", "-").Replace(b.key)
+}
+
+var bindings = []binding{
+	{key: "environment", env: "ENVIRONMENT", usage: "Environment (development, production, test)", value: func(c *Config) flag.Value { return (*stringValue)(&c.Environment) }},
+	{key: "port", env: "PORT", usage: "HTTP server port", value: func(c *Config) flag.Value { return (*stringValue)(&c.Port) }},
+	{key: "gin_mode", env: "GIN_MODE", usage: "Gin mode (debug, release, test)", value: func(c *Config) flag.Value { return (*stringValue)(&c.GinMode) }},
+	{key: "database_url", env: "DATABASE_URL", usage: "PostgreSQL connection URL", secret: true, value: func(c *Config) flag.Value { return (*stringValue)(&c.DatabaseURL) }},
+	{key: "database_name", env: "DATABASE_NAME", usage: "Database name", value: func(c *Config) flag.Value { return (*stringValue)(&c.DatabaseName) }},
+	{key: "api_base_url", env: "API_BASE_URL", usage: "Upstream stock API URL", value: func(c *Config) flag.Value { return (*stringValue)(&c.APIBaseURL) }},
+	{key: "api_key", env: "API_KEY", usage: "Upstream stock API key", secret: true, value: func(c *Config) flag.Value { return (*stringValue)(&c.APIKey) }},
+	{key: "jwt_secret_key", env: "JWT_SECRET_KEY", usage: "JWT signing secret (at least 32 bytes)", secret: true, value: func(c *Config) flag.Value { return (*bytesValue)(&c.JwtSecretKey) }},
+
+	{key: "log.level", env: "LOG_LEVEL", usage: "Log level (debug, info, warn, error)", value: func(c *Config) flag.Value { return (*stringValue)(&c.Log.Level) }},
+	{key: "log.format", env: "LOG_FORMAT", usage: "Log format (text, json)", value: func(c *Config) flag.Value { return (*stringValue)(&c.Log.Format) }},
+
+	{key: "tracing.exporter", env: "TRACING_EXPORTER", usage: "Trace exporter (none, otlp, stdout, file)", value: func(c *Config) flag.Value { return (*stringValue)(&c.Tracing.Exporter) }},
+	{key: "tracing.otlp_endpoint", env: "TRACING_OTLP_ENDPOINT", usage: "OTLP/HTTP collector endpoint (host:port)", value: func(c *Config) flag.Value { return (*stringValue)(&c.Tracing.OTLPEndpoint) }},
+	{key: "tracing.otlp_insecure", env: "TRACING_OTLP_INSECURE", usage: "Send OTLP traces over plain HTTP", value: func(c *Config) flag.Value { return (*boolValue)(&c.Tracing.OTLPInsecure) }},
+	{key: "tracing.file", env: "TRACING_FILE", usage: "Output file for the file trace exporter", value: func(c *Config) flag.Value { return (*stringValue)(&c.Tracing.File) }},
+	{key: "tracing.sample_ratio", env: "TRACING_SAMPLE_RATIO", usage: "Fraction of traces sampled (0-1)", value: func(c *Config) flag.Value { return (*floatValue)(&c.Tracing.SampleRatio) }},
+
+	{key: "http.read_timeout", env: "HTTP_READ_TIMEOUT", usage: "Maximum duration for reading a request", value: func(c *Config) flag.Value { return (*durationValue)(&c.HTTP.ReadTimeout) }},
+	{key: "http.read_header_timeout", env: "HTTP_READ_HEADER_TIMEOUT", usage: "Maximum duration for reading request headers", value: func(c *Config) flag.Value { return (*durationValue)(&c.HTTP.ReadHeaderTimeout) }},
+	{key: "http.write_timeout", env: "HTTP_WRITE_TIMEOUT", usage: "Maximum duration for writing a response", value: func(c *Config) flag.Value { return (*durationValue)(&c.HTTP.WriteTimeout) }},
+	{key: "http.idle_timeout", env: "HTTP_IDLE_TIMEOUT", usage: "Maximum keep-alive idle time", value: func(c *Config) flag.Value { return (*durationValue)(&c.HTTP.IdleTimeout) }},
+	{key: "http.shutdown_timeout", env: "HTTP_SHUTDOWN_TIMEOUT", usage: "Grace period for in-flight requests on shutdown", value: func(c *Config) flag.Value { return (*durationValue)(&c.HTTP.ShutdownTimeout) }},
+	{key: "http.max_header_bytes", env: "HTTP_MAX_HEADER_BYTES", usage: "Maximum size of request headers", value: func(c *Config) flag.Value { return (*intValue)(&c.HTTP.MaxHeaderBytes) }},
+	{key: "http.max_body_bytes", env: "HTTP_MAX_BODY_BYTES", usage: "Maximum size of request bodies", value: func(c *Config) flag.Value { return (*int64Value)(&c.HTTP.MaxBodyBytes) }},
+	{key: "http.tls_cert_file", env: "HTTP_TLS_CERT_FILE", usage: "TLS certificate file (PEM); enables HTTPS", value: func(c *Config) flag.Value { return (*stringValue)(&c.HTTP.TLSCertFile) }},
+	{key: "http.tls_key_file", env: "HTTP_TLS_KEY_FILE", usage: "TLS private key file (PEM)", value: func(c *Config) flag.Value { return (*stringValue)(&c.HTTP.TLSKeyFile) }},
+	{key: "http.trusted_proxies", env: "HTTP_TRUSTED_PROXIES", usage: "Comma-separated proxy IPs/CIDRs allowed to set X-Forwarded-For", value: func(c *Config) flag.Value { return (*listValue)(&c.HTTP.TrustedProxies) }},
+	{key: "http.security_headers", env: "HTTP_SECURITY_HEADERS", usage: "Add security headers to every response", value: func(c *Config) flag.Value { return (*boolValue)(&c.HTTP.SecurityHeaders) }},
+	{key: "http.hsts_max_age", env: "HTTP_HSTS_MAX_AGE", usage: "Strict-Transport-Security max-age when serving TLS (0 = disabled)", value: func(c *Config) flag.Value { return (*durationValue)(&c.HTTP.HSTSMaxAge) }},
+	{key: "grpc.port", env: "GRPC_PORT", usage: "gRPC server port (empty disables it)", value: func(c *Config) flag.Value { return (*stringValue)(&c.GRPC.Port) }},
+
+	{key: "db.max_open_conns", env: "DB_MAX_OPEN_CONNS", usage: "Maximum open database connections (0 = unlimited)", value: func(c *Config) flag.Value { return (*intValue)(&c.DB.MaxOpenConns) }},
+	{key: "db.max_idle_conns", env: "DB_MAX_IDLE_CONNS", usage: "Maximum idle database connections", value: func(c *Config) flag.Value { return (*intValue)(&c.DB.MaxIdleConns) }},
+	{key: "db.conn_max_lifetime", env: "DB_CONN_MAX_LIFETIME", usage: "Maximum connection reuse time (0 = forever)", value: func(c *Config) flag.Value { return (*durationValue)(&c.DB.ConnMaxLifetime) }},
+	{key: "db.conn_max_idle_time", env: "DB_CONN_MAX_IDLE_TIME", usage: "Maximum connection idle time (0 = forever)", value: func(c *Config) flag.Value { return (*durationValue)(&c.DB.ConnMaxIdleTime) }},
+	{key: "db.replica_url", env: "DATABASE_REPLICA_URL", usage: "Optional read replica URL for read-only queries", secret: true, value: func(c *Config) flag.Value { return (*stringValue)(&c.DB.ReplicaURL) }},
+	{key: "db.connect_retries", env: "DB_CONNECT_RETRIES", usage: "Connection attempts on startup before giving up", value: func(c *Config) flag.Value { return (*intValue)(&c.DB.ConnectRetries) }},
+	{key: "db.retry_backoff", env: "DB_RETRY_BACKOFF", usage: "Initial wait between connection attempts; doubles each time", value: func(c *Config) flag.Value { return (*durationValue)(&c.DB.RetryBackoff) }},
+	{key: "db.retry_max_backoff", env: "DB_RETRY_MAX_BACKOFF", usage: "Upper bound for the wait between connection attempts", value: func(c *Config) flag.Value { return (*durationValue)(&c.DB.RetryMaxBackoff) }},
+
+	{key: "sync.enabled", env: "SYNC_ENABLED", usage: "Run the periodic stock sync", value: func(c *Config) flag.Value { return (*boolValue)(&c.Sync.Enabled) }},
+	{key: "sync.interval", env: "SYNC_INTERVAL", usage: "Time between sync runs", value: func(c *Config) flag.Value { return (*durationValue)(&c.Sync.Interval) }},
+	{key: "sync.page_delay", env: "SYNC_PAGE_DELAY", usage: "Pause between upstream pages", value: func(c *Config) flag.Value { return (*durationValue)(&c.Sync.PageDelay) }},
+	{key: "sync.max_stocks", env: "SYNC_MAX_STOCKS", usage: "Stop paging after this many stocks (0 = no limit)", value: func(c *Config) flag.Value { return (*intValue)(&c.Sync.MaxStocks) }},
+	{key: "sync.staleness_threshold", env: "SYNC_STALENESS_THRESHOLD", usage: "Maximum data age before readiness reports it as stale", value: func(c *Config) flag.Value { return (*durationValue)(&c.Sync.StalenessThreshold) }},
+
+	{key: "backfill.page_delay", env: "BACKFILL_PAGE_DELAY", usage: "Pause between upstream pages during a backfill", value: func(c *Config) flag.Value { return (*durationValue)(&c.Backfill.PageDelay) }},
+	{key: "backfill.max_pages", env: "BACKFILL_MAX_PAGES", usage: "Pages fetched per backfill run before pausing the job (0 = no limit)", value: func(c *Config) flag.Value { return (*intValue)(&c.Backfill.MaxPages) }},
+
+	{key: "stream.poll_interval", env: "STREAM_POLL_INTERVAL", usage: "How often the change feed is checked for live subscribers", value: func(c *Config) flag.Value { return (*durationValue)(&c.Stream.PollInterval) }},
+	{key: "stream.heartbeat", env: "STREAM_HEARTBEAT", usage: "Idle time before a heartbeat is sent to live subscribers", value: func(c *Config) flag.Value { return (*durationValue)(&c.Stream.Heartbeat) }},
+	{key: "stream.write_timeout", env: "STREAM_WRITE_TIMEOUT", usage: "Time a WebSocket client has to accept a message before it is disconnected", value: func(c *Config) flag.Value { return (*durationValue)(&c.Stream.WriteTimeout) }},
+
+	{key: "alerts.cooldown", env: "ALERTS_COOLDOWN", usage: "Minimum time between alerts of a rule for the same ticker", value: func(c *Config) flag.Value { return (*durationValue)(&c.Alerts.Cooldown) }},
+	{key: "alerts.max_rules", env: "ALERTS_MAX_RULES", usage: "Alert rules allowed per user", value: func(c *Config) flag.Value { return (*intValue)(&c.Alerts.MaxRules) }},
+
+	{key: "webhooks.poll_interval", env: "WEBHOOKS_POLL_INTERVAL", usage: "How often webhook events are queued and due deliveries sent", value: func(c *Config) flag.Value { return (*durationValue)(&c.Webhooks.PollInterval) }},
+	{key: "webhooks.timeout", env: "WEBHOOKS_TIMEOUT", usage: "Time a webhook has to answer a delivery", value: func(c *Config) flag.Value { return (*durationValue)(&c.Webhooks.Timeout) }},
+	{key: "webhooks.max_attempts", env: "WEBHOOKS_MAX_ATTEMPTS", usage: "Delivery attempts before a webhook delivery is dead-lettered", value: func(c *Config) flag.Value { return (*intValue)(&c.Webhooks.MaxAttempts) }},
+	{key: "webhooks.retry_backoff", env: "WEBHOOKS_RETRY_BACKOFF", usage: "Wait before the first webhook retry; doubles each time", value: func(c *Config) flag.Value { return (*durationValue)(&c.Webhooks.RetryBackoff) }},
+	{key: "webhooks.retry_max_backoff", env: "WEBHOOKS_RETRY_MAX_BACKOFF", usage: "Upper bound for the wait between webhook attempts", value: func(c *Config) flag.Value { return (*durationValue)(&c.Webhooks.RetryMaxBackoff) }},
+	{key: "webhooks.max_per_user", env: "WEBHOOKS_MAX_PER_USER", usage: "Webhooks allowed per user", value: func(c *Config) flag.Value { return (*intValue)(&c.Webhooks.MaxPerUser) }},
+	{key: "webhooks.allow_private_networks", env: "WEBHOOKS_ALLOW_PRIVATE_NETWORKS", usage: "Deliver webhooks to loopback, private and link-local addresses (development only)", value: func(c *Config) flag.Value { return (*boolValue)(&c.Webhooks.AllowPrivateNetworks) }},
+
+	{key: "digest.enabled", env: "DIGEST_ENABLED", usage: "Send email digests to subscribed users (requires SMTP)", value: func(c *Config) flag.Value { return (*boolValue)(&c.Digest.Enabled) }},
+	{key: "digest.send_at", env: "DIGEST_SEND_AT", usage: "Time of day email digests are sent (HH:MM)", value: func(c *Config) flag.Value { return (*stringValue)(&c.Digest.SendAt) }},
+	{key: "digest.timezone", env: "DIGEST_TIMEZONE", usage: "IANA time zone of the digest send time", value: func(c *Config) flag.Value { return (*stringValue)(&c.Digest.Timezone) }},
+	{key: "digest.check_interval", env: "DIGEST_CHECK_INTERVAL", usage: "How often due email digests are looked for", value: func(c *Config) flag.Value { return (*durationValue)(&c.Digest.CheckInterval) }},
+	{key: "digest.top_changes", env: "DIGEST_TOP_CHANGES", usage: "Upgrades and downgrades listed in an email digest", value: func(c *Config) flag.Value { return (*intValue)(&c.Digest.TopChanges) }},
+
+	{key: "smtp.host", env: "SMTP_HOST", usage: "Mail server host", value: func(c *Config) flag.Value { return (*stringValue)(&c.SMTP.Host) }},
+	{key: "smtp.port", env: "SMTP_PORT", usage: "Mail server port; STARTTLS is used when offered", value: func(c *Config) flag.Value { return (*intValue)(&c.SMTP.Port) }},
+	{key: "smtp.username", env: "SMTP_USERNAME", usage: "Login for SMTP authentication (empty = none)", value: func(c *Config) flag.Value { return (*stringValue)(&c.SMTP.Username) }},
+	{key: "smtp.password", env: "SMTP_PASSWORD", usage: "Password for SMTP authentication", secret: true, value: func(c *Config) flag.Value { return (*stringValue)(&c.SMTP.Password) }},
+	{key: "smtp.from", env: "SMTP_FROM", usage: "Sender address of outgoing email", value: func(c *Config) flag.Value { return (*stringValue)(&c.SMTP.From) }},
+	{key: "smtp.timeout", env: "SMTP_TIMEOUT", usage: "Time allowed to send one email", value: func(c *Config) flag.Value { return (*durationValue)(&c.SMTP.Timeout) }},
+
+	{key: "scoring.min_score", env: "SCORING_MIN_SCORE", usage: "Minimum score for a stock to be recommended", value: func(c *Config) flag.Value { return (*floatValue)(&c.Scoring.MinScore) }},
+	{key: "scoring.recommendation_limit", env: "SCORING_RECOMMENDATION_LIMIT", usage: "Number of recommendations returned", value: func(c *Config) flag.Value { return (*intValue)(&c.Scoring.RecommendationLimit) }},
+
+	{key: "auth.token_ttl", env: "AUTH_TOKEN_TTL", usage: "JWT lifetime", value: func(c *Config) flag.Value { return (*durationValue)(&c.Auth.TokenTTL) }},
+	{key: "auth.max_login_attempts", env: "AUTH_MAX_LOGIN_ATTEMPTS", usage: "Login failures allowed before a lockout starts", value: func(c *Config) flag.Value { return (*intValue)(&c.Auth.MaxLoginAttempts) }},
+	{key: "auth.lockout_base", env: "AUTH_LOCKOUT_BASE", usage: "First login lockout, doubled on every further failure", value: func(c *Config) flag.Value { return (*durationValue)(&c.Auth.LockoutBase) }},
+	{key: "auth.lockout_max", env: "AUTH_LOCKOUT_MAX", usage: "Maximum login lockout", value: func(c *Config) flag.Value { return (*durationValue)(&c.Auth.LockoutMax) }},
+	{key: "auth.users", env: "AUTH_USERS", usage: "Comma-separated accounts allowed to log in, as username:bcrypt-hash", secret: true, value: func(c *Config) flag.Value { return (*listValue)(&c.Auth.Users) }},
+	{key: "auth.admins", env: "AUTH_ADMINS", usage: "Comma-separated users granted the admin role", value: func(c *Config) flag.Value { return (*listValue)(&c.Auth.Admins) }},
+
+	{key: "cors.allow_origins", env: "CORS_ALLOW_ORIGINS", usage: "Comma-separated allowed origins", value: func(c *Config) flag.Value { return (*listValue)(&c.CORS.AllowOrigins) }},
+	{key: "cors.allow_methods", env: "CORS_ALLOW_METHODS", usage: "Comma-separated allowed methods", value: func(c *Config) flag.Value { return (*listValue)(&c.CORS.AllowMethods) }},
+	{key: "cors.allow_headers", env: "CORS_ALLOW_HEADERS", usage: "Comma-separated allowed request headers", value: func(c *Config) flag.Value { return (*listValue)(&c.CORS.AllowHeaders) }},
+	{key: "cors.allow_credentials", env: "CORS_ALLOW_CREDENTIALS", usage: "Allow cookies and auth headers", value: func(c *Config) flag.Value { return (*boolValue)(&c.CORS.AllowCredentials) }},
+	{key: "cors.expose_headers", env: "CORS_EXPOSE_HEADERS", usage: "Comma-separated response headers readable by the browser", value: func(c *Config) flag.Value { return (*listValue)(&c.CORS.ExposeHeaders) }},
+	{key: "cors.max_age", env: "CORS_MAX_AGE", usage: "How long preflight results can be cached", value: func(c *Config) flag.Value { return (*durationValue)(&c.CORS.MaxAge) }},
+}
+
+// Configuration sources reported by Print
+const (
+	sourceDefault = "default"
+	sourceFile    = "file"
+	sourceEnv     = "env"
+	sourceFlag    = "flag"
+)
+
+// flagValues holds the flags given on the command line, applied after the environment
+type flagValues struct {
+	configFile string
+	set        map[string]string
+}
+
+// rawFlag records a flag value without applying it
+type rawFlag struct {
+	name   string
+	values *flagValues
+}
+
+func (f *rawFlag) String() string { return "" }
+
+func (f *rawFlag) Set(value string) error {
+	f.values.set[f.name] = value
+	return nil
+}
+
+// NewFlagSet returns the flag set accepted by the application, for usage output
+func NewFlagSet(name string) *flag.FlagSet {
+	fs, _ := newFlagSet(name)
+	return fs
+}
+
+func newFlagSet(name string) (*flag.FlagSet, *flagValues) {
+	values := &flagValues{set: make(map[string]string)}
+
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.StringVar(&values.configFile, "config", "", "Path to a YAML or TOML config file (env CONFIG_FILE)")
+	for _, b := range bindings {
+		fs.Var(&rawFlag{name: b.flagName(), values: values}, b.flagName(), fmt.Sprintf("%s (env %s)", b.usage, b.env))
+	}
+
+	return fs, values
+}
+
+func parseFlags(args []string) (*flagValues, error) {
+	fs, values := newFlagSet("stock-analyzer")
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	if fs.NArg() > 0 {
+		return nil, fmt.Errorf("unexpected arguments: %s", strings.Join(fs.Args(), " "))
+	}
+
+	return values, nil
+}
+
+// loadFile reads a YAML (.yaml, .yml) or TOML (.toml) config file over the current values.
+// Unknown keys are reported as errors to catch typos.
+func (c *Config) loadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("error reading config file: %w", err)
+	}
+
+	before := c.snapshot()
+
+	// The JWT secret is a []byte in Config but a plain string in files
+	file := struct {
+		Config       `yaml:",inline"`
+		JwtSecretKey string `yaml:"jwt_secret_key" toml:"jwt_secret_key"`
+	}{Config: *c}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		decoder.KnownFields(true)
+		if err := decoder.Decode(&file); err != nil && !errors.Is(err, io.EOF) {
+			return fmt.Errorf("error parsing config file %s: %w", path, err)
+		}
+	case ".toml":
+		md, err := toml.Decode(string(data), &file)
+		if err != nil {
+			return fmt.Errorf("error parsing config file %s: %w", path, err)
+		}
+		if undecoded := md.Undecoded(); len(undecoded) > 0 {
+			keys := make([]string, len(undecoded))
+			for i, k := range undecoded {
+				keys[i] = k.String()
+			}
+			return fmt.Errorf("unknown keys in config file %s: %s", path, strings.Join(keys, ", "))
+		}
+	default:
+		return fmt.Errorf("config file %s must have a .yaml, .yml or .toml extension", path)
+	}
+
+	*c = file.Config
+	c.sources = before.sources
+	if file.JwtSecretKey != "" {
+		c.JwtSecretKey = []byte(file.JwtSecretKey)
+	}
+	c.markChanged(before, sourceFile)
+
+	return nil
+}
+
+// applyEnv overrides values with the environment variables that are set
+func (c *Config) applyEnv() []error {
+	var errs []error
+	for _, b := range bindings {
+		raw, ok := os.LookupEnv(b.env)
+		if !ok || raw == "" {
+			continue
+		}
+		if err := b.value(c).Set(raw); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", b.env, err))
+			continue
+		}
+		c.setSource(b.key, sourceEnv)
+	}
+	return errs
+}
+
+// applyFlags overrides values with the flags given on the command line
+func (c *Config) applyFlags(flags *flagValues) []error {
+	var errs []error
+	for _, b := range bindings {
+		raw, ok := flags.set[b.flagName()]
+		if !ok {
+			continue
+		}
+		if err := b.value(c).Set(raw); err != nil {
+			errs = append(errs, fmt.Errorf("--%s: %w", b.flagName(), err))
+			continue
+		}
+		c.setSource(b.key, sourceFlag)
+	}
+	return errs
+}
+
+// snapshot returns a copy of c used to detect which values a source changed
+func (c *Config) snapshot() *Config {
+	cp := *c
+	return &cp
+}
+
+func (c *Config) markChanged(before *Config, source string) {
+	for _, b := range bindings {
+		if b.value(before).String() != b.value(c).String() {
+			c.setSource(b.key, source)
+		}
+	}
+}
+
+func (c *Config) setSource(key, source string) {
+	if c.sources == nil {
+		c.sources = make(map[string]string)
+	}
+	c.sources[key] = source
+}
+
+// source reports where the effective value of a key came from
+func (c *Config) source(key string) string {
+	if s, ok := c.sources[key]; ok {
+		return s
+	}
+	return sourceDefault
+}
+
+// flag.Value implementations bound to Config fields
+
+type stringValue string
+
+func (v *stringValue) String() string     { return string(*v) }
+func (v *stringValue) Set(s string) error { *v = stringValue(s); return nil }
+
+type bytesValue []byte
+
+func (v *bytesValue) String() string     { return string(*v) }
+func (v *bytesValue) Set(s string) error { *v = bytesValue(s); return nil }
+
+type intValue int
+
+func (v *intValue) String() string { return strconv.Itoa(int(*v)) }
+func (v *intValue) Set(s string) error {
+	i, err := strconv.Atoi(strings.TrimSpace(s))
+	if err != nil {
+		return fmt.Errorf("invalid integer %q", s)
+	}
+	*v = intValue(i)
+	return nil
+}
+
+type int64Value int64
+
+func (v *int64Value) String() string { return strconv.FormatInt(int64(*v), 10) }
+func (v *int64Value) Set(s string) error {
+	i, err := strconv.ParseInt(strings.TrimSpace(s), 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid integer %q", s)
+	}
+	*v = int64Value(i)
+	return nil
+}
+
+type floatValue float64
+
+func (v *floatValue) String() string { return strconv.FormatFloat(float64(*v), 'g', -1, 64) }
+func (v *floatValue) Set(s string) error {
+	f, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+	if err != nil {
+		return fmt.Errorf("invalid number %q", s)
+	}
+	*v = floatValue(f)
+	return nil
+}
+
+type boolValue bool
+
+func (v *boolValue) String() string { return strconv.FormatBool(bool(*v)) }
+func (v *boolValue) Set(s string) error {
+	b, err := strconv.ParseBool(strings.TrimSpace(s))
+	if err != nil {
+		return fmt.Errorf("invalid boolean %q", s)
+	}
+	*v = boolValue(b)
+	return nil
+}
+
+type durationValue time.Duration
+
+func (v *durationValue) String() string { return time.Duration(*v).String() }
+func (v *durationValue) Set(s string) error {
+	d, err := time.ParseDuration(strings.TrimSpace(s))
+	if err != nil {
+		return fmt.Errorf("invalid duration %q (use e.g. 30s, 5m, 2h)", s)
+	}
+	*v = durationValue(d)
+	return nil
+}
+
+type listValue []string
+
+func (v *listValue) String() string { return strings.Join(*v, ",") }
+func (v *listValue) Set(s string) error {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	*v = items
+	return nil
+}
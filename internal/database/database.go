@@ -2,12 +2,24 @@
 package database
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
+	"time"
+
+	"Backend/internal/config"
+	"Backend/internal/logger"
 
 	_ "github.com/lib/pq"
 )
 
+// SchemaVersion is the schema version applied by Migrate. Bump it whenever
+// the migration script changes so readiness checks can detect stale schemas.
+const SchemaVersion = 14
+
+// dataMigrationLock is the advisory lock key held while running dataMigrations
+const dataMigrationLock = 7_241_001
+
 func Connect(databaseURL string) (*sql.DB, error) {
 	db, err := sql.Open("postgres", databaseURL)
 	if err != nil {
@@ -15,12 +27,52 @@ func Connect(databaseURL string) (*sql.DB, error) {
 	}
 
 	if err := db.Ping(); err != nil {
+		db.Close()
 		return nil, fmt.Errorf("error conectando a la base de datos: %w", err)
 	}
 
 	return db, nil
 }
 
+// ConnectWithRetry opens a connection pool and pings it, retrying with exponential
+// backoff so the API can start while the database is still coming up
+func ConnectWithRetry(ctx context.Context, databaseURL string, cfg config.DBConfig) (*sql.DB, error) {
+	log := logger.Component("database")
+	backoff := cfg.RetryBackoff
+
+	var err error
+	for attempt := 1; ; attempt++ {
+		var db *sql.DB
+		db, err = Connect(databaseURL)
+		if err == nil {
+			ConfigurePool(db, cfg)
+			return db, nil
+		}
+
+		if attempt >= cfg.ConnectRetries {
+			break
+		}
+		log.Warn("database not available, retrying", "attempt", attempt, "retry_in", backoff, "error", err)
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, cfg.RetryMaxBackoff)
+	}
+
+	return nil, fmt.Errorf("base de datos no disponible tras %d intentos: %w", cfg.ConnectRetries, err)
+}
+
+// ConfigurePool applies the connection pool settings
+func ConfigurePool(db *sql.DB, cfg config.DBConfig) {
+	db.SetMaxOpenConns(cfg.MaxOpenConns)
+	db.SetMaxIdleConns(cfg.MaxIdleConns)
+	db.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+	db.SetConnMaxIdleTime(cfg.ConnMaxIdleTime)
+}
+
 func Migrate(db *sql.DB) error {
 	query := `
 	 -- DROP TABLE IF EXISTS stocks;
@@ -51,6 +103,250 @@ func Migrate(db *sql.DB) error {
 	CREATE INDEX IF NOT EXISTS idx_stocks_company ON stocks(company);
 	DROP INDEX IF EXISTS idx_stocks_time;
 	CREATE INDEX idx_stocks_time ON stocks(time DESC);
+
+	CREATE TABLE IF NOT EXISTS auth_audit (
+		id BIGSERIAL PRIMARY KEY,
+		event VARCHAR(50) NOT NULL,
+		username VARCHAR(255),
+		ip VARCHAR(64),
+		success BOOLEAN NOT NULL DEFAULT FALSE,
+		detail VARCHAR(255),
+		created_at TIMESTAMP DEFAULT NOW()
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_auth_audit_created_at ON auth_audit(created_at DESC);
+	CREATE INDEX IF NOT EXISTS idx_auth_audit_username ON auth_audit(username);
+
+	CREATE TABLE IF NOT EXISTS revoked_tokens (
+		jti VARCHAR(64) PRIMARY KEY,
+		username VARCHAR(255),
+		expires_at TIMESTAMP NOT NULL,
+		revoked_at TIMESTAMP DEFAULT NOW()
+	);
+
+	CREATE TABLE IF NOT EXISTS sync_runs (
+		id BIGSERIAL PRIMARY KEY,
+		started_at TIMESTAMP NOT NULL DEFAULT NOW(),
+		finished_at TIMESTAMP,
+		status VARCHAR(20) NOT NULL,
+		stocks INT NOT NULL DEFAULT 0,
+		error TEXT
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_sync_runs_started_at ON sync_runs(started_at DESC);
+
+	-- v2: rows rejected by validation during a sync
+	ALTER TABLE sync_runs ADD COLUMN IF NOT EXISTS rejected INT NOT NULL DEFAULT 0;
+
+	-- v3: malformed upstream records kept for review and replay
+	ALTER TABLE sync_runs ADD COLUMN IF NOT EXISTS quarantined INT NOT NULL DEFAULT 0;
+
+	CREATE TABLE IF NOT EXISTS quarantined_events (
+		id BIGSERIAL PRIMARY KEY,
+		fingerprint CHAR(64) NOT NULL,
+		source VARCHAR(20) NOT NULL,
+		ticker TEXT,
+		company TEXT,
+		payload JSONB NOT NULL,
+		reason TEXT NOT NULL,
+		status VARCHAR(20) NOT NULL,
+		seen_count INT NOT NULL DEFAULT 1,
+		first_seen_at TIMESTAMP NOT NULL DEFAULT NOW(),
+		last_seen_at TIMESTAMP NOT NULL DEFAULT NOW(),
+		resolved_at TIMESTAMP
+	);
+
+	CREATE UNIQUE INDEX IF NOT EXISTS idx_quarantined_events_fingerprint ON quarantined_events(fingerprint);
+	CREATE INDEX IF NOT EXISTS idx_quarantined_events_status ON quarantined_events(status, last_seen_at DESC);
+
+	-- v4: resumable historical backfills
+	CREATE TABLE IF NOT EXISTS backfill_jobs (
+		id BIGSERIAL PRIMARY KEY,
+		from_time TIMESTAMP NOT NULL,
+		to_time TIMESTAMP NOT NULL,
+		status VARCHAR(20) NOT NULL,
+		cursor TEXT NOT NULL DEFAULT '',
+		pages INT NOT NULL DEFAULT 0,
+		fetched INT NOT NULL DEFAULT 0,
+		in_range INT NOT NULL DEFAULT 0,
+		stored INT NOT NULL DEFAULT 0,
+		quarantined INT NOT NULL DEFAULT 0,
+		error TEXT,
+		created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+		updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+		finished_at TIMESTAMP
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_backfill_jobs_created_at ON backfill_jobs(created_at DESC);
+
+	-- v5: event identity, so resent upstream events are counted as duplicates
+	ALTER TABLE sync_runs ADD COLUMN IF NOT EXISTS duplicates INT NOT NULL DEFAULT 0;
+
+	CREATE TABLE IF NOT EXISTS stock_events (
+		event_hash CHAR(64) NOT NULL,
+		ticker VARCHAR(10) NOT NULL,
+		company VARCHAR(255) NOT NULL,
+		time TIMESTAMP NOT NULL,
+		first_seen_at TIMESTAMP NOT NULL DEFAULT NOW()
+	);
+
+	CREATE UNIQUE INDEX IF NOT EXISTS idx_stock_events_event_hash ON stock_events(event_hash);
+	CREATE INDEX IF NOT EXISTS idx_stock_events_ticker ON stock_events(ticker, time DESC);
+
+	-- v6: outbox of rating and target changes, read by GET /api/v1/changes
+	CREATE TABLE IF NOT EXISTS stock_changes (
+		id BIGSERIAL PRIMARY KEY,
+		stock_id INT NOT NULL,
+		type VARCHAR(20) NOT NULL,
+		ticker VARCHAR(10) NOT NULL,
+		company VARCHAR(255) NOT NULL,
+		brokerage VARCHAR(255) NOT NULL DEFAULT '',
+		action VARCHAR(50) NOT NULL DEFAULT '',
+		rating_from VARCHAR(50) NOT NULL DEFAULT '',
+		rating_to VARCHAR(50) NOT NULL DEFAULT '',
+		target_from VARCHAR(20) NOT NULL DEFAULT '',
+		target_to VARCHAR(20) NOT NULL DEFAULT '',
+		event_time TIMESTAMP NOT NULL,
+		created_at TIMESTAMP NOT NULL DEFAULT NOW()
+	);
+
+	-- v7: score changes in the change feed, pushed by GET /api/v1/stream
+	ALTER TABLE stock_changes ADD COLUMN IF NOT EXISTS score_from FLOAT NOT NULL DEFAULT 0;
+	ALTER TABLE stock_changes ADD COLUMN IF NOT EXISTS score_to FLOAT NOT NULL DEFAULT 0;
+
+	-- v8: per-user watchlists
+	CREATE TABLE IF NOT EXISTS watchlists (
+		id BIGSERIAL PRIMARY KEY,
+		username VARCHAR(255) NOT NULL,
+		name VARCHAR(100) NOT NULL,
+		created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+		updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+		UNIQUE (username, name)
+	);
+
+	CREATE TABLE IF NOT EXISTS watchlist_items (
+		id BIGSERIAL PRIMARY KEY,
+		watchlist_id BIGINT NOT NULL REFERENCES watchlists(id) ON DELETE CASCADE,
+		ticker VARCHAR(10) NOT NULL,
+		added_at TIMESTAMP NOT NULL DEFAULT NOW(),
+		UNIQUE (watchlist_id, ticker)
+	);
+
+	-- v9: per-user alert rules evaluated against the change feed after each sync
+	CREATE TABLE IF NOT EXISTS alert_rules (
+		id BIGSERIAL PRIMARY KEY,
+		username VARCHAR(255) NOT NULL,
+		name VARCHAR(100) NOT NULL,
+		ticker VARCHAR(10) NOT NULL DEFAULT '',
+		brokerage VARCHAR(255) NOT NULL DEFAULT '',
+		change_type VARCHAR(20) NOT NULL DEFAULT '',
+		rating VARCHAR(50) NOT NULL DEFAULT '',
+		score_above FLOAT,
+		target_change_pct FLOAT,
+		cooldown_minutes INT NOT NULL DEFAULT 0,
+		enabled BOOLEAN NOT NULL DEFAULT TRUE,
+		created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+		updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_alert_rules_username ON alert_rules(username);
+
+	CREATE TABLE IF NOT EXISTS alerts (
+		id BIGSERIAL PRIMARY KEY,
+		rule_id BIGINT NOT NULL REFERENCES alert_rules(id) ON DELETE CASCADE,
+		rule_name VARCHAR(100) NOT NULL,
+		username VARCHAR(255) NOT NULL,
+		change_id BIGINT NOT NULL,
+		change_type VARCHAR(20) NOT NULL,
+		ticker VARCHAR(10) NOT NULL,
+		brokerage VARCHAR(255) NOT NULL DEFAULT '',
+		message TEXT NOT NULL,
+		triggered_at TIMESTAMP NOT NULL DEFAULT NOW(),
+		UNIQUE (rule_id, change_id)
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_alerts_username ON alerts(username, id DESC);
+	CREATE INDEX IF NOT EXISTS idx_alerts_cooldown ON alerts(rule_id, ticker, triggered_at DESC);
+
+	-- Last change evaluated against the alert rules; a single row
+	CREATE TABLE IF NOT EXISTS alert_cursor (
+		id BOOLEAN PRIMARY KEY DEFAULT TRUE CHECK (id),
+		change_id BIGINT NOT NULL
+	);
+
+	-- v10: outbound webhooks and their delivery log
+	CREATE TABLE IF NOT EXISTS webhooks (
+		id BIGSERIAL PRIMARY KEY,
+		username VARCHAR(255) NOT NULL,
+		url TEXT NOT NULL,
+		events TEXT[] NOT NULL,
+		secret VARCHAR(255) NOT NULL,
+		enabled BOOLEAN NOT NULL DEFAULT TRUE,
+		created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+		updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_webhooks_username ON webhooks(username);
+
+	CREATE TABLE IF NOT EXISTS webhook_deliveries (
+		id BIGSERIAL PRIMARY KEY,
+		webhook_id BIGINT NOT NULL REFERENCES webhooks(id) ON DELETE CASCADE,
+		event VARCHAR(20) NOT NULL,
+		payload JSONB NOT NULL,
+		status VARCHAR(20) NOT NULL,
+		attempts INT NOT NULL DEFAULT 0,
+		next_attempt_at TIMESTAMP,
+		last_status_code INT NOT NULL DEFAULT 0,
+		last_error TEXT NOT NULL DEFAULT '',
+		replay_of BIGINT,
+		created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+		delivered_at TIMESTAMP
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_due ON webhook_deliveries(next_attempt_at) WHERE status = 'pending';
+	CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_webhook ON webhook_deliveries(webhook_id, id DESC);
+
+	-- Last change and alert queued for delivery. They start at the newest entry,
+	-- so existing history is not sent to new webhooks.
+	CREATE TABLE IF NOT EXISTS webhook_cursors (
+		source VARCHAR(20) PRIMARY KEY,
+		position BIGINT NOT NULL
+	);
+
+	INSERT INTO webhook_cursors (source, position)
+	VALUES ('change', (SELECT COALESCE(MAX(id), 0) FROM stock_changes)),
+	       ('alert', (SELECT COALESCE(MAX(id), 0) FROM alerts))
+	ON CONFLICT (source) DO NOTHING;
+
+	-- v11: email digest subscriptions. last_change_id is the last change
+	-- summarized, so each digest covers what happened since the previous one.
+	CREATE TABLE IF NOT EXISTS digest_subscriptions (
+		username VARCHAR(255) PRIMARY KEY,
+		email VARCHAR(320) NOT NULL,
+		enabled BOOLEAN NOT NULL DEFAULT TRUE,
+		last_change_id BIGINT NOT NULL DEFAULT 0,
+		last_sent_at TIMESTAMP,
+		created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+		updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+	);
+
+	-- v12: where each stock row came from, the upstream API or a manual import
+	ALTER TABLE stocks ADD COLUMN IF NOT EXISTS source VARCHAR(20) NOT NULL DEFAULT 'api';
+
+	-- v13: latest rating of each brokerage for a ticker, read by the gRPC ticker consensus
+	CREATE INDEX IF NOT EXISTS idx_stock_changes_ticker ON stock_changes(ticker, brokerage, event_time DESC);
+
+	-- v14: rating of every event, so the ticker consensus also sees reiterations,
+	-- which write no change. Existing rows are filled in by dataMigrations.
+	ALTER TABLE stock_events ADD COLUMN IF NOT EXISTS brokerage VARCHAR(255) NOT NULL DEFAULT '';
+	ALTER TABLE stock_events ADD COLUMN IF NOT EXISTS action VARCHAR(50) NOT NULL DEFAULT '';
+	ALTER TABLE stock_events ADD COLUMN IF NOT EXISTS rating_to VARCHAR(50) NOT NULL DEFAULT '';
+	ALTER TABLE stock_events ADD COLUMN IF NOT EXISTS target_to VARCHAR(20) NOT NULL DEFAULT '';
+
+	CREATE TABLE IF NOT EXISTS schema_migrations (
+		version INT PRIMARY KEY,
+		applied_at TIMESTAMP DEFAULT NOW()
+	);
 -- Remove the DO block since ALTER TABLE in functions is not supported
 -- The UNIQUE constraint is already defined in the CREATE TABLE statement above
 
@@ -58,6 +354,102 @@ func Migrate(db *sql.DB) error {
   -- TRUNCATE TABLE stocks;
 
 	`
-	_, err := db.Exec(query)
+	if _, err := db.Exec(query); err != nil {
+		return err
+	}
+
+	if err := runDataMigrations(db); err != nil {
+		return err
+	}
+
+	_, err := db.Exec(`INSERT INTO schema_migrations (version) VALUES ($1) ON CONFLICT (version) DO NOTHING`, SchemaVersion)
 	return err
+}
+
+// dataMigration fills existing rows in once, when a database first reaches version
+type dataMigration struct {
+	version int
+	query   string
+}
+
+// dataMigrations run in order after the schema script, each one only on
+// databases whose schema_migrations is still below its version
+var dataMigrations = []dataMigration{
+	// v14: the rating of the events recorded before, from the change feed or
+	// else from the stock row of the same time. Stock rows older than
+	// stock_events become events of their own, hashed like repository.EventHash
+	// so a resent event is still a duplicate. The stored time keeps microseconds
+	// and the brokerage of the row's first coverage, which is all that is left.
+	{14, `
+		UPDATE stock_events e
+		SET brokerage = c.brokerage, action = c.action, rating_to = c.rating_to, target_to = c.target_to
+		FROM stock_changes c
+		WHERE e.brokerage = '' AND c.ticker = e.ticker AND c.company = e.company AND c.event_time = e.time;
+
+		UPDATE stock_events e
+		SET brokerage = s.brokerage, action = s.action, rating_to = COALESCE(s.rating_to, ''), target_to = COALESCE(s.target_to, '')
+		FROM stocks s
+		WHERE e.brokerage = '' AND s.ticker = e.ticker AND s.company = e.company AND s.time = e.time;
+
+		INSERT INTO stock_events (event_hash, ticker, company, time, brokerage, action, rating_to, target_to)
+		SELECT ENCODE(SHA256(CONVERT_TO(CONCAT_WS(CHR(31),
+		           s.ticker, s.brokerage, s.action,
+		           COALESCE(s.rating_from, ''), COALESCE(s.rating_to, ''),
+		           COALESCE(s.target_from, ''), COALESCE(s.target_to, ''),
+		           REGEXP_REPLACE(TO_CHAR(s.time, 'YYYY-MM-DD"T"HH24:MI:SS.US'), '\.?0+$', '') || 'Z'
+		       ), 'UTF8')), 'hex'),
+		       s.ticker, s.company, s.time, s.brokerage, s.action, COALESCE(s.rating_to, ''), COALESCE(s.target_to, '')
+		FROM stocks s
+		WHERE NOT EXISTS (SELECT 1 FROM stock_events e WHERE e.ticker = s.ticker AND e.company = s.company AND e.time = s.time)
+		ON CONFLICT (event_hash) DO NOTHING;
+	`},
+}
+
+// runDataMigrations applies the pending dataMigrations, each in a transaction
+// that records its version. Instances starting together wait on an advisory
+// lock, so only the first one runs a migration.
+func runDataMigrations(db *sql.DB) error {
+	for _, m := range dataMigrations {
+		if err := runDataMigration(db, m); err != nil {
+			return fmt.Errorf("error running data migration v%d: %w", m.version, err)
+		}
+	}
+	return nil
+}
+
+func runDataMigration(db *sql.DB, m dataMigration) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`SELECT pg_advisory_xact_lock($1)`, dataMigrationLock); err != nil {
+		return err
+	}
+	var applied bool
+	if err := tx.QueryRow(`SELECT EXISTS (SELECT 1 FROM schema_migrations WHERE version >= $1)`, m.version).Scan(&applied); err != nil {
+		return err
+	}
+	if applied {
+		return nil
+	}
+
+	if _, err := tx.Exec(m.query); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`INSERT INTO schema_migrations (version) VALUES ($1) ON CONFLICT (version) DO NOTHING`, m.version); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// CurrentSchemaVersion returns the highest schema version applied to the database
+func CurrentSchemaVersion(ctx context.Context, db *sql.DB) (int, error) {
+	var version sql.NullInt64
+	if err := db.QueryRowContext(ctx, `SELECT MAX(version) FROM schema_migrations`).Scan(&version); err != nil {
+		return 0, fmt.Errorf("error reading schema version: %w", err)
+	}
+
+	return int(version.Int64), nil
 }
\ No newline at end of file
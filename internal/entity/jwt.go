@@ -9,9 +9,13 @@ import (
 
 var JwtSecretKey = []byte(os.Getenv("JWT_SECRET_KEY"))
 
+// RoleAdmin is the role of the users listed in AUTH_ADMINS
+const RoleAdmin = "admin"
+
 type UserJwt struct {
 	UserId int64 `json:"userId"`
 	Username string `json:"username"`
+	Role string `json:"role,omitempty"`
 }
 
 type LoginRequest struct {
@@ -22,5 +26,6 @@ type LoginRequest struct {
 type Claimes struct {
 	UserId int64 `json:"userId"`
 	Username string `json:"username"`
+	Role string `json:"role,omitempty"`
 	jwt.RegisteredClaims
 }
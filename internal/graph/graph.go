@@ -0,0 +1,54 @@
+// Package graph serves stocks, tickers, brokerages and recommendations over
+// GraphQL, batching the nested lookups of a request with dataloaders
+package graph
+
+import (
+	"context"
+	_ "embed"
+
+	"Backend/internal/services"
+
+	"github.com/graph-gophers/graphql-go"
+)
+
+//go:embed schema.graphql
+var schemaSDL string
+
+const (
+	// maxDepth bounds the nesting of a query, which may otherwise cycle
+	// through stocks, tickers and brokerages indefinitely
+	maxDepth = 8
+	// defaultLimit and maxLimit are the default and largest limit argument of lists
+	defaultLimit = 20
+	maxLimit     = 100
+)
+
+// Request is a GraphQL request as sent over HTTP
+type Request struct {
+	Query         string         `json:"query" binding:"required"`
+	OperationName string         `json:"operationName"`
+	Variables     map[string]any `json:"variables"`
+}
+
+// Schema executes GraphQL requests against the stock service
+type Schema struct {
+	schema *graphql.Schema
+	stocks *services.StockService
+}
+
+// NewSchema binds the schema to the stock service. The schema is embedded, so
+// it panics only if the schema and the resolvers disagree, which the tests catch.
+func NewSchema(stocks *services.StockService) *Schema {
+	schema := graphql.MustParseSchema(schemaSDL, &queryResolver{stocks: stocks},
+		graphql.UseStringDescriptions(),
+		graphql.MaxDepth(maxDepth),
+	)
+	return &Schema{schema: schema, stocks: stocks}
+}
+
+// Exec runs a request with its own dataloaders, so lookups are batched and
+// cached only within the request
+func (s *Schema) Exec(ctx context.Context, req Request) *graphql.Response {
+	ctx = withLoaders(ctx, s.stocks)
+	return s.schema.Exec(ctx, req.Query, req.OperationName, req.Variables)
+}
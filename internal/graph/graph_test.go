@@ -0,0 +1,234 @@
+package graph
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"Backend/internal/config"
+	"Backend/internal/models"
+	"Backend/internal/repository"
+	"Backend/internal/services"
+)
+
+// countingRepository counts the stock queries reaching the repository
+type countingRepository struct {
+	*repository.MemoryRepository
+	lists atomic.Int32
+}
+
+func (r *countingRepository) ListStocks(ctx context.Context, filters models.StockFilters) ([]models.Stock, error) {
+	r.lists.Add(1)
+	return r.MemoryRepository.ListStocks(ctx, filters)
+}
+
+func newTestSchema(t *testing.T) (*Schema, *countingRepository) {
+	t.Helper()
+	repo := &countingRepository{MemoryRepository: repository.NewMemoryRepository()}
+	now := time.Now()
+
+	_, err := repo.UpsertStocks(context.Background(), []models.Stock{
+		{Ticker: "AAPL", Company: "Apple Inc", Brokerage: "Goldman Sachs", RatingTo: "Buy", Score: 80, Confidence: 0.9, Time: now, UpdatedAt: now},
+		{Ticker: "MSFT", Company: "Microsoft Corp", Brokerage: "Morgan Stanley", RatingTo: "Hold", Score: 55, Confidence: 0.7, Time: now, UpdatedAt: now},
+		{Ticker: "AMZN", Company: "Amazon.com Inc", Brokerage: "Goldman Sachs", RatingTo: "Buy", Score: 70, Confidence: 0.8, Time: now.Add(-time.Hour), UpdatedAt: now},
+		{Ticker: "TSLA", Company: "Tesla Inc", Brokerage: "Barclays", RatingTo: "Sell", Score: 20, Confidence: 0.2, Time: now.AddDate(0, 0, -7), UpdatedAt: now},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stocks := services.NewStockService(repo, repo, repo, config.ScoringConfig{MinScore: 30, RecommendationLimit: 2})
+	return NewSchema(stocks), repo
+}
+
+// exec runs query and decodes its data into out, returning the error messages
+func exec(t *testing.T, schema *Schema, query string, variables map[string]any, out any) []string {
+	t.Helper()
+	resp := schema.Exec(context.Background(), Request{Query: query, Variables: variables})
+
+	var errs []string
+	for _, err := range resp.Errors {
+		errs = append(errs, err.Message)
+	}
+	if len(resp.Data) > 0 && out != nil {
+		if err := json.Unmarshal(resp.Data, out); err != nil {
+			t.Fatalf("decoding %s: %v", resp.Data, err)
+		}
+	}
+	return errs
+}
+
+func TestStocksQuery(t *testing.T) {
+	schema, _ := newTestSchema(t)
+	const query = `query($filter: StockFilter, $offset: Int) {
+		stocks(filter: $filter, limit: 2, offset: $offset) {
+			items { ticker { symbol } brokerage { name } source }
+			totalCount buyCount brokerageCount lastUpdate hasMore
+		}
+	}`
+
+	type page struct {
+		Stocks struct {
+			Items []struct {
+				Ticker    struct{ Symbol string }
+				Brokerage struct{ Name string }
+				Source    string
+			}
+			TotalCount, BuyCount, BrokerageCount int
+			LastUpdate                           *time.Time
+			HasMore                              bool
+		}
+	}
+	symbols := func(p page) string {
+		var out []string
+		for _, item := range p.Stocks.Items {
+			out = append(out, item.Ticker.Symbol)
+		}
+		return strings.Join(out, ",")
+	}
+
+	tests := []struct {
+		name    string
+		vars    map[string]any
+		want    string
+		total   int
+		hasMore bool
+	}{
+		{"first page", nil, "AAPL,AMZN", 4, true},
+		{"last page", map[string]any{"offset": 2}, "MSFT,TSLA", 4, false},
+		{"past the end keeps the totals", map[string]any{"offset": 10}, "", 4, false},
+		{"filter", map[string]any{"filter": map[string]any{"brokerage": "goldman", "minScore": 75}}, "AAPL", 1, false},
+		{"exact tickers", map[string]any{"filter": map[string]any{"tickers": []any{"TSLA", "MSFT"}}}, "MSFT,TSLA", 2, false},
+		{"no tickers matches nothing", map[string]any{"filter": map[string]any{"tickers": []any{}}}, "", 0, false},
+		{"source", map[string]any{"filter": map[string]any{"source": "IMPORT"}}, "", 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got page
+			if errs := exec(t, schema, query, tt.vars, &got); errs != nil {
+				t.Fatalf("errors = %q", errs)
+			}
+			if symbols(got) != tt.want || got.Stocks.TotalCount != tt.total || got.Stocks.HasMore != tt.hasMore {
+				t.Errorf("stocks = %s, total %d, more %v; want %s, %d, %v", symbols(got), got.Stocks.TotalCount, got.Stocks.HasMore, tt.want, tt.total, tt.hasMore)
+			}
+			if tt.total > 0 && (got.Stocks.BrokerageCount != 3 || got.Stocks.LastUpdate == nil) {
+				t.Errorf("aggregates = %+v, want 3 brokerages and a last update", got.Stocks)
+			}
+		})
+	}
+
+	var first page
+	exec(t, schema, query, nil, &first)
+	if item := first.Stocks.Items[0]; item.Brokerage.Name != "Goldman Sachs" || item.Source != "API" || first.Stocks.BuyCount != 2 {
+		t.Errorf("first page = %+v, want AAPL by Goldman Sachs from the API and 2 buys", first.Stocks)
+	}
+}
+
+func TestNestedQueriesAreBatched(t *testing.T) {
+	schema, repo := newTestSchema(t)
+
+	var got struct {
+		Stocks struct {
+			Items []struct {
+				Ticker struct {
+					Ratings []struct {
+						Brokerage struct {
+							Name        string
+							RatingCount int
+							Ratings     []struct{ Ticker struct{ Symbol string } }
+						}
+					}
+				}
+			}
+		}
+	}
+	errs := exec(t, schema, `{
+		stocks(limit: 4) {
+			items { ticker { ratings { brokerage { name ratingCount ratings(limit: 5) { ticker { symbol } } } } } }
+		}
+	}`, nil, &got)
+	if errs != nil {
+		t.Fatalf("errors = %q", errs)
+	}
+
+	// One query for the page, one for the four tickers and one for the three brokerages
+	if calls := repo.lists.Load(); calls != 3 {
+		t.Errorf("ListStocks calls = %d, want 3", calls)
+	}
+	if len(got.Stocks.Items) != 4 {
+		t.Fatalf("items = %+v, want 4", got.Stocks.Items)
+	}
+	goldman := got.Stocks.Items[0].Ticker.Ratings[0].Brokerage
+	if goldman.Name != "Goldman Sachs" || goldman.RatingCount != 2 || len(goldman.Ratings) != 2 || goldman.Ratings[0].Ticker.Symbol != "AAPL" {
+		t.Errorf("AAPL brokerage = %+v, want Goldman Sachs with AAPL then AMZN", goldman)
+	}
+}
+
+func TestLookups(t *testing.T) {
+	schema, _ := newTestSchema(t)
+
+	var got struct {
+		Ticker    *struct{ Symbol string }
+		Unknown   *struct{ Symbol string }
+		Brokerage *struct {
+			RatingCount int
+			Ratings     []struct{ Company string }
+		}
+		Recommendations []struct {
+			Ticker    struct{ Symbol string }
+			Brokerage struct{ Name string }
+			Score     float64
+		}
+	}
+	errs := exec(t, schema, `{
+		ticker(symbol: "msft") { symbol }
+		unknown: ticker(symbol: "NONE") { symbol }
+		brokerage(name: "Goldman Sachs") { ratingCount ratings(limit: 1) { company } }
+		recommendations { ticker { symbol } brokerage { name } score }
+	}`, nil, &got)
+	if errs != nil {
+		t.Fatalf("errors = %q", errs)
+	}
+
+	if got.Ticker == nil || got.Ticker.Symbol != "MSFT" || got.Unknown != nil {
+		t.Errorf("tickers = %+v and %+v, want MSFT and null", got.Ticker, got.Unknown)
+	}
+	if got.Brokerage == nil || got.Brokerage.RatingCount != 2 || len(got.Brokerage.Ratings) != 1 || got.Brokerage.Ratings[0].Company != "Apple Inc" {
+		t.Errorf("brokerage = %+v, want 2 ratings, limited to Apple", got.Brokerage)
+	}
+	if len(got.Recommendations) != 2 || got.Recommendations[0].Ticker.Symbol != "AAPL" || got.Recommendations[0].Brokerage.Name != "Goldman Sachs" {
+		t.Errorf("recommendations = %+v, want AAPL then AMZN", got.Recommendations)
+	}
+
+	var stock struct{ Stock *struct{ ID, Company string } }
+	if errs := exec(t, schema, `{ stock(id: 2) { id company } }`, nil, &stock); errs != nil || stock.Stock == nil || stock.Stock.Company != "Microsoft Corp" {
+		t.Errorf("stock(id: 2) = %+v, %q; want Microsoft", stock.Stock, errs)
+	}
+}
+
+func TestQueryErrors(t *testing.T) {
+	schema, _ := newTestSchema(t)
+
+	tests := []struct {
+		name  string
+		query string
+		want  string
+	}{
+		{"limit too large", `{ stocks(limit: 1000) { totalCount } }`, "limit must be between 1 and 100"},
+		{"negative offset", `{ stocks(offset: -1) { totalCount } }`, "offset must not be negative"},
+		{"invalid id", `{ stock(id: "abc") { id } }`, `invalid stock id "abc"`},
+		{"unknown field", `{ stocks { items { price } } }`, `Cannot query field "price"`},
+		{"too deep", `{ stocks { items { ticker { ratings { brokerage { ratings { ticker { ratings { id } } } } } } } } }`, "exceeds max depth 8"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errs := exec(t, schema, tt.query, nil, nil)
+			if !strings.Contains(strings.Join(errs, "; "), tt.want) {
+				t.Errorf("errors = %q, want %q", errs, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,49 @@
+package graph
+
+import (
+	"context"
+	"time"
+
+	"Backend/internal/models"
+	"Backend/internal/services"
+
+	"github.com/graph-gophers/dataloader/v7"
+)
+
+// loaderWait is how long a loader collects keys before querying. Sibling fields
+// are resolved concurrently, so their keys arrive within it.
+const loaderWait = 5 * time.Millisecond
+
+// loaders batch the stock lookups of one request
+type loaders struct {
+	byTicker    *dataloader.Loader[string, []models.Stock]
+	byBrokerage *dataloader.Loader[string, []models.Stock]
+}
+
+type loadersKey struct{}
+
+// withLoaders returns ctx carrying new loaders backed by stocks
+func withLoaders(ctx context.Context, stocks *services.StockService) context.Context {
+	return context.WithValue(ctx, loadersKey{}, &loaders{
+		byTicker:    newStocksLoader(stocks.StocksByTicker),
+		byBrokerage: newStocksLoader(stocks.StocksByBrokerage),
+	})
+}
+
+// loadersFrom returns the loaders of the request
+func loadersFrom(ctx context.Context) *loaders {
+	return ctx.Value(loadersKey{}).(*loaders)
+}
+
+// newStocksLoader loads stocks by key with one call of fetch per batch
+func newStocksLoader(fetch func(context.Context, []string) (map[string][]models.Stock, error)) *dataloader.Loader[string, []models.Stock] {
+	batch := func(ctx context.Context, keys []string) []*dataloader.Result[[]models.Stock] {
+		groups, err := fetch(ctx, keys)
+		results := make([]*dataloader.Result[[]models.Stock], len(keys))
+		for i, key := range keys {
+			results[i] = &dataloader.Result[[]models.Stock]{Data: groups[key], Error: err}
+		}
+		return results
+	}
+	return dataloader.NewBatchedLoader(batch, dataloader.WithWait[string, []models.Stock](loaderWait))
+}
@@ -0,0 +1,283 @@
+package graph
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"Backend/internal/models"
+	"Backend/internal/services"
+
+	"github.com/graph-gophers/graphql-go"
+)
+
+// queryResolver resolves the fields of Query
+type queryResolver struct {
+	stocks *services.StockService
+}
+
+// stockFilterInput is the StockFilter input
+type stockFilterInput struct {
+	Ticker    *string
+	Company   *string
+	Brokerage *string
+	Tickers   *[]string
+	MinScore  *float64
+	Today     *bool
+	Source    *string
+}
+
+// stockFilters converts the input to repository filters; a nil input matches everything
+func (in *stockFilterInput) stockFilters() models.StockFilters {
+	var filters models.StockFilters
+	if in == nil {
+		return filters
+	}
+	if in.Ticker != nil {
+		filters.Ticker = *in.Ticker
+	}
+	if in.Company != nil {
+		filters.Company = *in.Company
+	}
+	if in.Brokerage != nil {
+		filters.Brokerage = *in.Brokerage
+	}
+	if in.Tickers != nil {
+		// An empty list would match everything in the repository, not nothing
+		filters.Tickers = append([]string{""}, *in.Tickers...)
+	}
+	if in.MinScore != nil {
+		filters.Score = *in.MinScore
+	}
+	if in.Today != nil && *in.Today {
+		filters.Today = "true"
+	}
+	if in.Source != nil {
+		filters.Source = strings.ToLower(*in.Source)
+	}
+	return filters
+}
+
+// pageLimit returns the limit argument, or defaultLimit when it is null, and
+// rejects one outside 1..maxLimit
+func pageLimit(limit *int32) (int, error) {
+	if limit == nil {
+		return defaultLimit, nil
+	}
+	if *limit < 1 || *limit > maxLimit {
+		return 0, fmt.Errorf("limit must be between 1 and %d", maxLimit)
+	}
+	return int(*limit), nil
+}
+
+// Stocks resolves Query.stocks. One extra row is read to tell whether another page follows.
+func (r *queryResolver) Stocks(ctx context.Context, args struct {
+	Filter *stockFilterInput
+	Limit  *int32
+	Offset *int32
+}) (*stockPageResolver, error) {
+	limit, err := pageLimit(args.Limit)
+	if err != nil {
+		return nil, err
+	}
+	var offset int
+	if args.Offset != nil {
+		offset = int(*args.Offset)
+	}
+	if offset < 0 {
+		return nil, fmt.Errorf("offset must not be negative")
+	}
+
+	filters := args.Filter.stockFilters()
+	filters.Limit, filters.Offset = limit+1, offset
+	resp, err := r.stocks.GetStocks(ctx, filters)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &stockPageResolver{items: resp.Items}
+	if len(page.items) > limit {
+		page.items, page.hasMore = page.items[:limit], true
+	}
+	if len(page.items) > 0 {
+		page.totals = page.items[0]
+	} else if filters.Offset > 0 {
+		// Past the last page the aggregates come from the first row instead
+		filters.Limit, filters.Offset = 1, 0
+		first, err := r.stocks.GetStocks(ctx, filters)
+		if err != nil {
+			return nil, err
+		}
+		if len(first.Items) > 0 {
+			page.totals = first.Items[0]
+		}
+	}
+	return page, nil
+}
+
+// Stock resolves Query.stock
+func (r *queryResolver) Stock(ctx context.Context, args struct{ ID graphql.ID }) (*stockResolver, error) {
+	id, err := strconv.Atoi(string(args.ID))
+	if err != nil || id <= 0 {
+		return nil, fmt.Errorf("invalid stock id %q", args.ID)
+	}
+
+	resp, err := r.stocks.GetStocks(ctx, models.StockFilters{ProductID: id})
+	if err != nil || len(resp.Items) == 0 {
+		return nil, err
+	}
+	return &stockResolver{stock: resp.Items[0]}, nil
+}
+
+// Ticker resolves Query.ticker
+func (r *queryResolver) Ticker(ctx context.Context, args struct{ Symbol string }) (*tickerResolver, error) {
+	ticker := &tickerResolver{symbol: strings.ToUpper(strings.TrimSpace(args.Symbol))}
+	ratings, err := ticker.Ratings(ctx)
+	if err != nil || len(ratings) == 0 {
+		return nil, err
+	}
+	return ticker, nil
+}
+
+// Brokerage resolves Query.brokerage
+func (r *queryResolver) Brokerage(ctx context.Context, args struct{ Name string }) (*brokerageResolver, error) {
+	brokerage := &brokerageResolver{name: args.Name}
+	count, err := brokerage.RatingCount(ctx)
+	if err != nil || count == 0 {
+		return nil, err
+	}
+	return brokerage, nil
+}
+
+// Recommendations resolves Query.recommendations
+func (r *queryResolver) Recommendations(ctx context.Context) ([]*recommendationResolver, error) {
+	stocks, err := r.stocks.GetRecommendations(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	recommendations := make([]*recommendationResolver, len(stocks))
+	for i, stock := range stocks {
+		recommendations[i] = &recommendationResolver{stock: stock}
+	}
+	return recommendations, nil
+}
+
+// stockPageResolver resolves StockPage. The aggregates are read from totals,
+// any row of the matching stocks.
+type stockPageResolver struct {
+	items   []models.Stock
+	totals  models.Stock
+	hasMore bool
+}
+
+func (p *stockPageResolver) Items() []*stockResolver { return stockResolvers(p.items) }
+func (p *stockPageResolver) TotalCount() int32       { return int32(p.totals.TotalRegister) }
+func (p *stockPageResolver) BuyCount() int32         { return int32(p.totals.BuyCount) }
+func (p *stockPageResolver) BrokerageCount() int32   { return int32(p.totals.TotalBrokerages) }
+func (p *stockPageResolver) HasMore() bool           { return p.hasMore }
+
+func (p *stockPageResolver) LastUpdate() *graphql.Time {
+	if p.totals.LastUpdateFilter.IsZero() {
+		return nil
+	}
+	return &graphql.Time{Time: p.totals.LastUpdateFilter}
+}
+
+// stockResolver resolves Stock
+type stockResolver struct {
+	stock models.Stock
+}
+
+// stockResolvers wraps stocks for resolution
+func stockResolvers(stocks []models.Stock) []*stockResolver {
+	resolvers := make([]*stockResolver, len(stocks))
+	for i, stock := range stocks {
+		resolvers[i] = &stockResolver{stock: stock}
+	}
+	return resolvers
+}
+
+func (s *stockResolver) ID() graphql.ID          { return graphql.ID(strconv.Itoa(s.stock.ID)) }
+func (s *stockResolver) Ticker() *tickerResolver { return &tickerResolver{symbol: s.stock.Ticker} }
+func (s *stockResolver) Company() string         { return s.stock.Company }
+func (s *stockResolver) Action() string          { return s.stock.Action }
+func (s *stockResolver) RatingFrom() string      { return s.stock.RatingFrom }
+func (s *stockResolver) RatingTo() string        { return s.stock.RatingTo }
+func (s *stockResolver) TargetFrom() string      { return s.stock.TargetFrom }
+func (s *stockResolver) TargetTo() string        { return s.stock.TargetTo }
+func (s *stockResolver) Score() float64          { return s.stock.Score }
+func (s *stockResolver) Confidence() float64     { return s.stock.Confidence }
+func (s *stockResolver) Time() graphql.Time      { return graphql.Time{Time: s.stock.Time} }
+func (s *stockResolver) CreatedAt() graphql.Time { return graphql.Time{Time: s.stock.CreatedAt} }
+func (s *stockResolver) UpdatedAt() graphql.Time { return graphql.Time{Time: s.stock.UpdatedAt} }
+func (s *stockResolver) Brokerage() *brokerageResolver {
+	return &brokerageResolver{name: s.stock.Brokerage}
+}
+
+func (s *stockResolver) Source() string {
+	if s.stock.Source == "" {
+		return strings.ToUpper(models.StockSourceAPI)
+	}
+	return strings.ToUpper(s.stock.Source)
+}
+
+// tickerResolver resolves Ticker, loading its ratings through the request's loaders
+type tickerResolver struct {
+	symbol string
+}
+
+func (t *tickerResolver) Symbol() string { return t.symbol }
+
+func (t *tickerResolver) Ratings(ctx context.Context) ([]*stockResolver, error) {
+	stocks, err := loadersFrom(ctx).byTicker.Load(ctx, t.symbol)()
+	if err != nil {
+		return nil, err
+	}
+	return stockResolvers(stocks), nil
+}
+
+// brokerageResolver resolves Brokerage, loading its ratings through the request's loaders
+type brokerageResolver struct {
+	name string
+}
+
+func (b *brokerageResolver) Name() string { return b.name }
+
+func (b *brokerageResolver) RatingCount(ctx context.Context) (int32, error) {
+	stocks, err := loadersFrom(ctx).byBrokerage.Load(ctx, b.name)()
+	return int32(len(stocks)), err
+}
+
+func (b *brokerageResolver) Ratings(ctx context.Context, args struct{ Limit *int32 }) ([]*stockResolver, error) {
+	limit, err := pageLimit(args.Limit)
+	if err != nil {
+		return nil, err
+	}
+	stocks, err := loadersFrom(ctx).byBrokerage.Load(ctx, b.name)()
+	if err != nil {
+		return nil, err
+	}
+	return stockResolvers(stocks[:min(len(stocks), limit)]), nil
+}
+
+// recommendationResolver resolves Recommendation
+type recommendationResolver struct {
+	stock models.Stock
+}
+
+func (r *recommendationResolver) Ticker() *tickerResolver {
+	return &tickerResolver{symbol: r.stock.Ticker}
+}
+func (r *recommendationResolver) Company() string     { return r.stock.Company }
+func (r *recommendationResolver) Action() string      { return r.stock.Action }
+func (r *recommendationResolver) RatingFrom() string  { return r.stock.RatingFrom }
+func (r *recommendationResolver) RatingTo() string    { return r.stock.RatingTo }
+func (r *recommendationResolver) TargetTo() string    { return r.stock.TargetTo }
+func (r *recommendationResolver) Score() float64      { return r.stock.Score }
+func (r *recommendationResolver) Confidence() float64 { return r.stock.Confidence }
+func (r *recommendationResolver) Time() graphql.Time  { return graphql.Time{Time: r.stock.Time} }
+func (r *recommendationResolver) Brokerage() *brokerageResolver {
+	return &brokerageResolver{name: r.stock.Brokerage}
+}
@@ -0,0 +1,276 @@
+// Package grpcapi serves stocks, recommendations, ticker consensus and the live
+// change stream over gRPC, from the same services as the REST API
+package grpcapi
+
+//go:generate protoc -I stockspb --go_out=stockspb --go_opt=paths=source_relative --go-grpc_out=stockspb --go-grpc_opt=paths=source_relative stocks.proto
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"Backend/internal/config"
+	"Backend/internal/grpcapi/stockspb"
+	"Backend/internal/logger"
+	"Backend/internal/middleware"
+	"Backend/internal/models"
+	"Backend/internal/services"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// NewServer returns a gRPC server for service that authenticates every call
+// with the JWT of the REST API. It uses the HTTP TLS certificate when one is set.
+func NewServer(cfg *config.Config, revocations middleware.TokenRevocationChecker, service *Service) (*grpc.Server, error) {
+	opts := []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(middleware.UnaryAuthInterceptor(cfg, revocations)),
+		grpc.ChainStreamInterceptor(middleware.StreamAuthInterceptor(cfg, revocations)),
+	}
+	if cfg.HTTP.TLSEnabled() {
+		creds, err := credentials.NewServerTLSFromFile(cfg.HTTP.TLSCertFile, cfg.HTTP.TLSKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, grpc.Creds(creds))
+	}
+
+	srv := grpc.NewServer(opts...)
+	stockspb.RegisterStockServiceServer(srv, service)
+	return srv, nil
+}
+
+// Service implements stockspb.StockServiceServer
+type Service struct {
+	stockspb.UnimplementedStockServiceServer
+
+	stocks    *services.StockService
+	changes   *services.ChangeService
+	stream    *services.ChangeStream
+	heartbeat time.Duration
+}
+
+// NewService returns a Service. heartbeat is the idle time before StreamChanges
+// sends a heartbeat event.
+func NewService(stocks *services.StockService, changes *services.ChangeService, stream *services.ChangeStream, heartbeat time.Duration) *Service {
+	return &Service{stocks: stocks, changes: changes, stream: stream, heartbeat: heartbeat}
+}
+
+// ListStocks returns a page of the matching stocks with the aggregates of all of them
+func (s *Service) ListStocks(ctx context.Context, req *stockspb.ListStocksRequest) (*stockspb.ListStocksResponse, error) {
+	if req.GetLimit() < 0 || req.GetOffset() < 0 {
+		return nil, status.Error(codes.InvalidArgument, "limit and offset must not be negative")
+	}
+	f := req.GetFilters()
+	if source := f.GetSource(); source != "" && source != models.StockSourceAPI && source != models.StockSourceImport {
+		return nil, status.Errorf(codes.InvalidArgument, "unknown source %q", source)
+	}
+
+	filters := models.StockFilters{
+		Ticker:    f.GetTicker(),
+		Company:   f.GetCompany(),
+		Brokerage: f.GetBrokerage(),
+		Tickers:   f.GetTickers(),
+		ProductID: int(f.GetId()),
+		Score:     f.GetMinScore(),
+		Source:    f.GetSource(),
+		Limit:     int(req.GetLimit()),
+		Offset:    int(req.GetOffset()),
+	}
+	if f.GetToday() {
+		filters.Today = "true"
+	}
+
+	resp, err := s.stocks.GetStocks(ctx, filters)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	// Every row carries the aggregates; past the last page they come from the first row instead
+	var totals *models.Stock
+	if len(resp.Items) > 0 {
+		totals = &resp.Items[0]
+	} else if filters.Offset > 0 {
+		filters.Limit, filters.Offset = 1, 0
+		first, err := s.stocks.GetStocks(ctx, filters)
+		if err != nil {
+			return nil, status.Error(codes.Internal, err.Error())
+		}
+		if len(first.Items) > 0 {
+			totals = &first.Items[0]
+		}
+	}
+
+	out := &stockspb.ListStocksResponse{Items: stocksToProto(resp.Items)}
+	if totals != nil {
+		out.TotalCount = int32(totals.TotalRegister)
+		out.BuyCount = int32(totals.BuyCount)
+		out.BrokerageCount = int32(totals.TotalBrokerages)
+		out.LastUpdate = timestamp(totals.LastUpdateFilter)
+	}
+	return out, nil
+}
+
+// GetRecommendations returns the top recommendations
+func (s *Service) GetRecommendations(ctx context.Context, _ *stockspb.GetRecommendationsRequest) (*stockspb.GetRecommendationsResponse, error) {
+	recommendations, err := s.stocks.GetRecommendations(ctx)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return &stockspb.GetRecommendationsResponse{Recommendations: stocksToProto(recommendations)}, nil
+}
+
+// GetTickerConsensus summarizes the latest ratings of a ticker
+func (s *Service) GetTickerConsensus(ctx context.Context, req *stockspb.GetTickerConsensusRequest) (*stockspb.TickerConsensus, error) {
+	consensus, err := s.changes.TickerConsensus(ctx, req.GetTicker())
+	if errors.Is(err, services.ErrInvalidTicker) {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	out := &stockspb.TickerConsensus{
+		Ticker:     consensus.Ticker,
+		Since:      timestamp(consensus.Since),
+		Ratings:    int32(consensus.Ratings),
+		Buy:        int32(consensus.Buy),
+		Hold:       int32(consensus.Hold),
+		Sell:       int32(consensus.Sell),
+		Consensus:  consensus.Consensus,
+		MeanRank:   consensus.MeanRank,
+		MeanTarget: consensus.MeanTarget,
+		LowTarget:  consensus.LowTarget,
+		HighTarget: consensus.HighTarget,
+	}
+	for _, rating := range consensus.Brokerages {
+		out.Brokerages = append(out.Brokerages, &stockspb.BrokerageRating{
+			Brokerage: rating.Brokerage,
+			Company:   rating.Company,
+			Action:    rating.Action,
+			Rating:    rating.Rating,
+			Target:    rating.Target,
+			Time:      timestamp(rating.Time),
+		})
+	}
+	return out, nil
+}
+
+// StreamChanges sends the change feed as it grows, like the SSE stream
+func (s *Service) StreamChanges(req *stockspb.StreamChangesRequest, stream grpc.ServerStreamingServer[stockspb.StreamEvent]) error {
+	since := int64(-1)
+	if req.Since != nil {
+		if req.GetSince() < 0 {
+			return status.Error(codes.InvalidArgument, "since must not be negative")
+		}
+		since = req.GetSince()
+	}
+
+	ctx := stream.Context()
+	sub, err := s.stream.Subscribe(ctx, since, services.ChangeFilter{Tickers: req.GetTickers(), Brokerages: req.GetBrokerages()})
+	if err != nil {
+		return status.Error(codes.Internal, err.Error())
+	}
+	defer sub.Close()
+
+	idle := time.NewTimer(s.heartbeat)
+	defer idle.Stop()
+
+	for {
+		events, err := sub.Next(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return status.FromContextError(ctx.Err()).Err()
+			}
+			logger.FromContext(ctx).Error("error reading change stream", "error", err)
+			return status.Error(codes.Internal, err.Error())
+		}
+		if len(events) > 0 {
+			for _, event := range events {
+				if err := stream.Send(streamEventToProto(event)); err != nil {
+					return err
+				}
+			}
+			idle.Reset(s.heartbeat)
+		}
+
+		select {
+		case <-ctx.Done():
+			return status.FromContextError(ctx.Err()).Err()
+		case <-sub.Done():
+			return status.Error(codes.Unavailable, "change stream stopped")
+		case <-sub.Ready():
+		case now := <-idle.C:
+			if err := stream.Send(&stockspb.StreamEvent{Type: models.StreamEventHeartbeat, Time: timestamp(now)}); err != nil {
+				return err
+			}
+			idle.Reset(s.heartbeat)
+		}
+	}
+}
+
+// timestamp converts t, leaving the zero time unset
+func timestamp(t time.Time) *timestamppb.Timestamp {
+	if t.IsZero() {
+		return nil
+	}
+	return timestamppb.New(t)
+}
+
+func stockToProto(stock models.Stock) *stockspb.Stock {
+	return &stockspb.Stock{
+		Id:         int32(stock.ID),
+		Ticker:     stock.Ticker,
+		Company:    stock.Company,
+		Brokerage:  stock.Brokerage,
+		Action:     stock.Action,
+		RatingFrom: stock.RatingFrom,
+		RatingTo:   stock.RatingTo,
+		TargetFrom: stock.TargetFrom,
+		TargetTo:   stock.TargetTo,
+		Score:      stock.Score,
+		Confidence: stock.Confidence,
+		Source:     stock.Source,
+		Time:       timestamp(stock.Time),
+		CreatedAt:  timestamp(stock.CreatedAt),
+		UpdatedAt:  timestamp(stock.UpdatedAt),
+	}
+}
+
+func stocksToProto(stocks []models.Stock) []*stockspb.Stock {
+	out := make([]*stockspb.Stock, len(stocks))
+	for i, stock := range stocks {
+		out[i] = stockToProto(stock)
+	}
+	return out
+}
+
+func streamEventToProto(event models.StreamEvent) *stockspb.StreamEvent {
+	out := &stockspb.StreamEvent{Id: event.ID, Type: event.Type, Time: timestamp(event.Time)}
+	if change := event.Change; change != nil {
+		out.Change = &stockspb.StockChange{
+			Id:         change.ID,
+			StockId:    int32(change.StockID),
+			Type:       change.Type,
+			Ticker:     change.Ticker,
+			Company:    change.Company,
+			Brokerage:  change.Brokerage,
+			Action:     change.Action,
+			RatingFrom: change.RatingFrom,
+			RatingTo:   change.RatingTo,
+			TargetFrom: change.TargetFrom,
+			TargetTo:   change.TargetTo,
+			ScoreFrom:  change.ScoreFrom,
+			ScoreTo:    change.ScoreTo,
+			EventTime:  timestamp(change.EventTime),
+			CreatedAt:  timestamp(change.CreatedAt),
+		}
+	}
+	if event.TopPick != nil {
+		out.TopPick = stockToProto(*event.TopPick)
+	}
+	return out
+}
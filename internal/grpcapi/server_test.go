@@ -0,0 +1,277 @@
+package grpcapi
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"Backend/internal/config"
+	"Backend/internal/entity"
+	"Backend/internal/grpcapi/stockspb"
+	"Backend/internal/middleware"
+	"Backend/internal/models"
+	"Backend/internal/repository"
+	"Backend/internal/services"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+type testServer struct {
+	client stockspb.StockServiceClient
+	token  string
+	repo   *repository.MemoryRepository
+	stream *services.ChangeStream
+}
+
+// newTestServer serves the API over an in-memory connection. Tokens are not
+// checked for revocation, which needs the database.
+func newTestServer(t *testing.T) *testServer {
+	t.Helper()
+
+	cfg := config.Default()
+	cfg.JwtSecretKey = []byte("test-secret-key-with-at-least-32-bytes")
+	cfg.Scoring.MinScore = 30
+	cfg.Scoring.RecommendationLimit = 2
+
+	repo := repository.NewMemoryRepository()
+	now := time.Now()
+	_, err := repo.UpsertStocks(context.Background(), []models.Stock{
+		{Ticker: "AAPL", Company: "Apple Inc", Brokerage: "Goldman Sachs", RatingTo: "Buy", TargetTo: "$200", Score: 80, Confidence: 0.9, Time: now, UpdatedAt: now},
+		{Ticker: "MSFT", Company: "Microsoft Corp", Brokerage: "Morgan Stanley", RatingTo: "Hold", Score: 55, Confidence: 0.7, Time: now, UpdatedAt: now},
+		{Ticker: "AMZN", Company: "Amazon.com Inc", Brokerage: "Goldman Sachs", RatingTo: "Buy", Score: 70, Confidence: 0.8, Time: now.Add(-time.Hour), UpdatedAt: now},
+		{Ticker: "TSLA", Company: "Tesla Inc", Brokerage: "Barclays", RatingTo: "Sell", Score: 20, Confidence: 0.2, Time: now.AddDate(0, 0, -7), UpdatedAt: now},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stockService := services.NewStockService(repo, repo, repo, cfg.Scoring)
+	stream := services.NewChangeStream(repo, stockService, config.StreamConfig{PollInterval: time.Hour, Heartbeat: 50 * time.Millisecond})
+	srv, err := NewServer(cfg, nil, NewService(stockService, services.NewChangeService(repo), stream, 50*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	listener := bufconn.Listen(1 << 20)
+	go srv.Serve(listener)
+	t.Cleanup(srv.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufconn",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return listener.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	token, err := middleware.GenerateToken(&entity.UserJwt{UserId: 1, Username: "dashboard"}, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &testServer{client: stockspb.NewStockServiceClient(conn), token: token, repo: repo, stream: stream}
+}
+
+// ctx returns a context carrying the token
+func (s *testServer) ctx(t *testing.T) context.Context {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	t.Cleanup(cancel)
+	return metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+s.token)
+}
+
+func tickers(stocks []*stockspb.Stock) []string {
+	out := []string{}
+	for _, stock := range stocks {
+		out = append(out, stock.GetTicker())
+	}
+	return out
+}
+
+func TestAuthentication(t *testing.T) {
+	s := newTestServer(t)
+
+	tests := []struct {
+		name  string
+		token string
+		want  string
+	}{
+		{"no token", "", "Token does not exist"},
+		{"malformed token", "Bearer abc", "Token does not exist"},
+		{"invalid signature", "Bearer " + s.token + "x", "Invalid token"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.token != "" {
+				ctx = metadata.AppendToOutgoingContext(ctx, "authorization", tt.token)
+			}
+
+			_, err := s.client.GetRecommendations(ctx, &stockspb.GetRecommendationsRequest{})
+			if st := status.Convert(err); st.Code() != codes.Unauthenticated || st.Message() != tt.want {
+				t.Errorf("GetRecommendations() error = %v, want Unauthenticated %q", err, tt.want)
+			}
+
+			stream, err := s.client.StreamChanges(ctx, &stockspb.StreamChangesRequest{})
+			if err == nil {
+				_, err = stream.Recv()
+			}
+			if status.Code(err) != codes.Unauthenticated {
+				t.Errorf("StreamChanges() error = %v, want Unauthenticated", err)
+			}
+		})
+	}
+}
+
+func TestListStocks(t *testing.T) {
+	s := newTestServer(t)
+
+	tests := []struct {
+		name  string
+		req   *stockspb.ListStocksRequest
+		want  []string
+		total int32
+	}{
+		{"all by confidence", &stockspb.ListStocksRequest{}, []string{"AAPL", "AMZN", "MSFT", "TSLA"}, 4},
+		{"page", &stockspb.ListStocksRequest{Limit: 2, Offset: 2}, []string{"MSFT", "TSLA"}, 4},
+		{"past the end keeps the totals", &stockspb.ListStocksRequest{Offset: 10}, []string{}, 4},
+		{"filters", &stockspb.ListStocksRequest{Filters: &stockspb.StockFilters{Brokerage: "goldman", MinScore: 75}}, []string{"AAPL"}, 1},
+		{"exact tickers", &stockspb.ListStocksRequest{Filters: &stockspb.StockFilters{Tickers: []string{"TSLA", "MSFT"}}}, []string{"MSFT", "TSLA"}, 2},
+		{"source", &stockspb.ListStocksRequest{Filters: &stockspb.StockFilters{Source: models.StockSourceImport}}, []string{}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := s.client.ListStocks(s.ctx(t), tt.req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := tickers(resp.GetItems()); len(got) != len(tt.want) || (len(got) > 0 && got[0] != tt.want[0]) || resp.GetTotalCount() != tt.total {
+				t.Errorf("ListStocks() = %v, total %d; want %v, %d", got, resp.GetTotalCount(), tt.want, tt.total)
+			}
+			if tt.total > 0 && (resp.GetBrokerageCount() != 3 || resp.GetLastUpdate() == nil) {
+				t.Errorf("aggregates = %v, want 3 brokerages and a last update", resp)
+			}
+		})
+	}
+
+	resp, _ := s.client.ListStocks(s.ctx(t), &stockspb.ListStocksRequest{Limit: 1})
+	if first := resp.GetItems()[0]; first.GetBrokerage() != "Goldman Sachs" || first.GetSource() != models.StockSourceAPI || first.GetTime() == nil || resp.GetBuyCount() != 2 {
+		t.Errorf("first stock = %v, want AAPL by Goldman Sachs from the API and 2 buys", resp)
+	}
+
+	for _, req := range []*stockspb.ListStocksRequest{
+		{Limit: -1},
+		{Filters: &stockspb.StockFilters{Source: "upload"}},
+	} {
+		if _, err := s.client.ListStocks(s.ctx(t), req); status.Code(err) != codes.InvalidArgument {
+			t.Errorf("ListStocks(%v) error = %v, want InvalidArgument", req, err)
+		}
+	}
+}
+
+func TestGetRecommendations(t *testing.T) {
+	s := newTestServer(t)
+
+	resp, err := s.client.GetRecommendations(s.ctx(t), &stockspb.GetRecommendationsRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := tickers(resp.GetRecommendations()); len(got) != 2 || got[0] != "AAPL" || got[1] != "AMZN" {
+		t.Errorf("recommendations = %v, want AAPL then AMZN", got)
+	}
+}
+
+func TestGetTickerConsensus(t *testing.T) {
+	s := newTestServer(t)
+	_, err := s.repo.UpsertStocks(context.Background(), []models.Stock{
+		{Ticker: "AAPL", Company: "Apple Inc", Brokerage: "Jefferies", RatingTo: "Hold", TargetTo: "$100", Time: time.Now()},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := s.client.GetTickerConsensus(s.ctx(t), &stockspb.GetTickerConsensusRequest{Ticker: "aapl"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.GetTicker() != "AAPL" || got.GetRatings() != 2 || got.GetBuy() != 1 || got.GetHold() != 1 || got.GetConsensus() != models.ConsensusBuy {
+		t.Errorf("consensus = %v, want 2 ratings: 1 buy and 1 hold for Buy", got)
+	}
+	if got.GetLowTarget() != 100 || got.GetHighTarget() != 200 || got.GetSince() == nil {
+		t.Errorf("targets = %v-%v since %v, want 100-200", got.GetLowTarget(), got.GetHighTarget(), got.GetSince())
+	}
+	if len(got.GetBrokerages()) != 2 || got.GetBrokerages()[0].GetBrokerage() != "Jefferies" {
+		t.Errorf("brokerages = %v, want Jefferies first", got.GetBrokerages())
+	}
+
+	if _, err := s.client.GetTickerConsensus(s.ctx(t), &stockspb.GetTickerConsensusRequest{Ticker: "not a ticker"}); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("GetTickerConsensus(invalid) error = %v, want InvalidArgument", err)
+	}
+}
+
+func TestStreamChanges(t *testing.T) {
+	s := newTestServer(t)
+
+	t.Run("from the start with a filter", func(t *testing.T) {
+		since := int64(0)
+		stream, err := s.client.StreamChanges(s.ctx(t), &stockspb.StreamChangesRequest{Since: &since, Tickers: []string{"msft"}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		event, err := stream.Recv()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if event.GetType() != models.StreamEventChange || event.GetChange().GetTicker() != "MSFT" || event.GetId() != event.GetChange().GetId() {
+			t.Errorf("event = %v, want the MSFT change", event)
+		}
+	})
+
+	t.Run("live changes and heartbeats", func(t *testing.T) {
+		stream, err := s.client.StreamChanges(s.ctx(t), &stockspb.StreamChangesRequest{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if event, err := stream.Recv(); err != nil || event.GetType() != models.StreamEventHeartbeat || event.GetTime() == nil {
+			t.Fatalf("first event = %v, %v; want a heartbeat", event, err)
+		}
+
+		ctx := context.Background()
+		if _, err := s.repo.UpsertStocks(ctx, []models.Stock{{Ticker: "NVDA", Company: "NVIDIA Corp", Brokerage: "UBS", RatingTo: "Buy", Score: 90, Confidence: 0.95, Time: time.Now()}}); err != nil {
+			t.Fatal(err)
+		}
+		s.stream.Poll(ctx)
+
+		seen := map[string]*stockspb.StreamEvent{}
+		for seen[models.StreamEventChange] == nil || seen[models.StreamEventTopPick] == nil {
+			event, err := stream.Recv()
+			if err != nil {
+				t.Fatal(err)
+			}
+			seen[event.GetType()] = event
+		}
+		if change := seen[models.StreamEventChange].GetChange(); change.GetTicker() != "NVDA" || change.GetEventTime() == nil {
+			t.Errorf("change = %v, want NVDA", change)
+		}
+		if pick := seen[models.StreamEventTopPick].GetTopPick(); pick.GetTicker() != "NVDA" {
+			t.Errorf("top pick = %v, want NVDA", pick)
+		}
+	})
+
+	t.Run("negative cursor", func(t *testing.T) {
+		since := int64(-5)
+		stream, err := s.client.StreamChanges(s.ctx(t), &stockspb.StreamChangesRequest{Since: &since})
+		if err == nil {
+			_, err = stream.Recv()
+		}
+		if status.Code(err) != codes.InvalidArgument {
+			t.Errorf("StreamChanges(since -5) error = %v, want InvalidArgument", err)
+		}
+	})
+}
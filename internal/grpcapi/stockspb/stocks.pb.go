@@ -0,0 +1,1289 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.6
+// 	protoc        v5.29.3
+// source: stocks.proto
+
+package stockspb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// StockFilters are the filters of GET /api/v1/stocks. Unset fields match everything.
+type StockFilters struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Case-insensitive substring of the ticker
+	Ticker string `protobuf:"bytes,1,opt,name=ticker,proto3" json:"ticker,omitempty"`
+	// Case-insensitive substring of the company
+	Company string `protobuf:"bytes,2,opt,name=company,proto3" json:"company,omitempty"`
+	// Case-insensitive substring of the brokerage
+	Brokerage string `protobuf:"bytes,3,opt,name=brokerage,proto3" json:"brokerage,omitempty"`
+	// Exact tickers to include
+	Tickers []string `protobuf:"bytes,4,rep,name=tickers,proto3" json:"tickers,omitempty"`
+	// Only the stock with this ID
+	Id int32 `protobuf:"varint,5,opt,name=id,proto3" json:"id,omitempty"`
+	// Minimum score
+	MinScore float64 `protobuf:"fixed64,6,opt,name=min_score,json=minScore,proto3" json:"min_score,omitempty"`
+	// Only ratings from today, or from yesterday when there is nothing for today
+	Today bool `protobuf:"varint,7,opt,name=today,proto3" json:"today,omitempty"`
+	// Only rows from this source: "api" or "import"
+	Source        string `protobuf:"bytes,8,opt,name=source,proto3" json:"source,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StockFilters) Reset() {
+	*x = StockFilters{}
+	mi := &file_stocks_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StockFilters) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StockFilters) ProtoMessage() {}
+
+func (x *StockFilters) ProtoReflect() protoreflect.Message {
+	mi := &file_stocks_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StockFilters.ProtoReflect.Descriptor instead.
+func (*StockFilters) Descriptor() ([]byte, []int) {
+	return file_stocks_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *StockFilters) GetTicker() string {
+	if x != nil {
+		return x.Ticker
+	}
+	return ""
+}
+
+func (x *StockFilters) GetCompany() string {
+	if x != nil {
+		return x.Company
+	}
+	return ""
+}
+
+func (x *StockFilters) GetBrokerage() string {
+	if x != nil {
+		return x.Brokerage
+	}
+	return ""
+}
+
+func (x *StockFilters) GetTickers() []string {
+	if x != nil {
+		return x.Tickers
+	}
+	return nil
+}
+
+func (x *StockFilters) GetId() int32 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *StockFilters) GetMinScore() float64 {
+	if x != nil {
+		return x.MinScore
+	}
+	return 0
+}
+
+func (x *StockFilters) GetToday() bool {
+	if x != nil {
+		return x.Today
+	}
+	return false
+}
+
+func (x *StockFilters) GetSource() string {
+	if x != nil {
+		return x.Source
+	}
+	return ""
+}
+
+// ListStocksRequest pages through the matching stocks by confidence, highest first
+type ListStocksRequest struct {
+	state   protoimpl.MessageState `protogen:"open.v1"`
+	Filters *StockFilters          `protobuf:"bytes,1,opt,name=filters,proto3" json:"filters,omitempty"`
+	// Maximum number of stocks; 0 returns every match
+	Limit int32 `protobuf:"varint,2,opt,name=limit,proto3" json:"limit,omitempty"`
+	// Stocks skipped before limit
+	Offset        int32 `protobuf:"varint,3,opt,name=offset,proto3" json:"offset,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListStocksRequest) Reset() {
+	*x = ListStocksRequest{}
+	mi := &file_stocks_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListStocksRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListStocksRequest) ProtoMessage() {}
+
+func (x *ListStocksRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_stocks_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListStocksRequest.ProtoReflect.Descriptor instead.
+func (*ListStocksRequest) Descriptor() ([]byte, []int) {
+	return file_stocks_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *ListStocksRequest) GetFilters() *StockFilters {
+	if x != nil {
+		return x.Filters
+	}
+	return nil
+}
+
+func (x *ListStocksRequest) GetLimit() int32 {
+	if x != nil {
+		return x.Limit
+	}
+	return 0
+}
+
+func (x *ListStocksRequest) GetOffset() int32 {
+	if x != nil {
+		return x.Offset
+	}
+	return 0
+}
+
+type ListStocksResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Items []*Stock               `protobuf:"bytes,1,rep,name=items,proto3" json:"items,omitempty"`
+	// Aggregates over every stock matching the filters, not only this page
+	TotalCount int32 `protobuf:"varint,2,opt,name=total_count,json=totalCount,proto3" json:"total_count,omitempty"`
+	// Matching stocks rated Buy
+	BuyCount int32 `protobuf:"varint,3,opt,name=buy_count,json=buyCount,proto3" json:"buy_count,omitempty"`
+	// Brokerages across all stocks
+	BrokerageCount int32 `protobuf:"varint,4,opt,name=brokerage_count,json=brokerageCount,proto3" json:"brokerage_count,omitempty"`
+	// Most recent update among the matching stocks
+	LastUpdate    *timestamppb.Timestamp `protobuf:"bytes,5,opt,name=last_update,json=lastUpdate,proto3" json:"last_update,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListStocksResponse) Reset() {
+	*x = ListStocksResponse{}
+	mi := &file_stocks_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListStocksResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListStocksResponse) ProtoMessage() {}
+
+func (x *ListStocksResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_stocks_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListStocksResponse.ProtoReflect.Descriptor instead.
+func (*ListStocksResponse) Descriptor() ([]byte, []int) {
+	return file_stocks_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *ListStocksResponse) GetItems() []*Stock {
+	if x != nil {
+		return x.Items
+	}
+	return nil
+}
+
+func (x *ListStocksResponse) GetTotalCount() int32 {
+	if x != nil {
+		return x.TotalCount
+	}
+	return 0
+}
+
+func (x *ListStocksResponse) GetBuyCount() int32 {
+	if x != nil {
+		return x.BuyCount
+	}
+	return 0
+}
+
+func (x *ListStocksResponse) GetBrokerageCount() int32 {
+	if x != nil {
+		return x.BrokerageCount
+	}
+	return 0
+}
+
+func (x *ListStocksResponse) GetLastUpdate() *timestamppb.Timestamp {
+	if x != nil {
+		return x.LastUpdate
+	}
+	return nil
+}
+
+// Stock is the latest analyst rating of a company
+type Stock struct {
+	state      protoimpl.MessageState `protogen:"open.v1"`
+	Id         int32                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	Ticker     string                 `protobuf:"bytes,2,opt,name=ticker,proto3" json:"ticker,omitempty"`
+	Company    string                 `protobuf:"bytes,3,opt,name=company,proto3" json:"company,omitempty"`
+	Brokerage  string                 `protobuf:"bytes,4,opt,name=brokerage,proto3" json:"brokerage,omitempty"`
+	Action     string                 `protobuf:"bytes,5,opt,name=action,proto3" json:"action,omitempty"`
+	RatingFrom string                 `protobuf:"bytes,6,opt,name=rating_from,json=ratingFrom,proto3" json:"rating_from,omitempty"`
+	RatingTo   string                 `protobuf:"bytes,7,opt,name=rating_to,json=ratingTo,proto3" json:"rating_to,omitempty"`
+	TargetFrom string                 `protobuf:"bytes,8,opt,name=target_from,json=targetFrom,proto3" json:"target_from,omitempty"`
+	TargetTo   string                 `protobuf:"bytes,9,opt,name=target_to,json=targetTo,proto3" json:"target_to,omitempty"`
+	Score      float64                `protobuf:"fixed64,10,opt,name=score,proto3" json:"score,omitempty"`
+	Confidence float64                `protobuf:"fixed64,11,opt,name=confidence,proto3" json:"confidence,omitempty"`
+	// "api" or "import"
+	Source        string                 `protobuf:"bytes,12,opt,name=source,proto3" json:"source,omitempty"`
+	Time          *timestamppb.Timestamp `protobuf:"bytes,13,opt,name=time,proto3" json:"time,omitempty"`
+	CreatedAt     *timestamppb.Timestamp `protobuf:"bytes,14,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
+	UpdatedAt     *timestamppb.Timestamp `protobuf:"bytes,15,opt,name=updated_at,json=updatedAt,proto3" json:"updated_at,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Stock) Reset() {
+	*x = Stock{}
+	mi := &file_stocks_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Stock) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Stock) ProtoMessage() {}
+
+func (x *Stock) ProtoReflect() protoreflect.Message {
+	mi := &file_stocks_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Stock.ProtoReflect.Descriptor instead.
+func (*Stock) Descriptor() ([]byte, []int) {
+	return file_stocks_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *Stock) GetId() int32 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *Stock) GetTicker() string {
+	if x != nil {
+		return x.Ticker
+	}
+	return ""
+}
+
+func (x *Stock) GetCompany() string {
+	if x != nil {
+		return x.Company
+	}
+	return ""
+}
+
+func (x *Stock) GetBrokerage() string {
+	if x != nil {
+		return x.Brokerage
+	}
+	return ""
+}
+
+func (x *Stock) GetAction() string {
+	if x != nil {
+		return x.Action
+	}
+	return ""
+}
+
+func (x *Stock) GetRatingFrom() string {
+	if x != nil {
+		return x.RatingFrom
+	}
+	return ""
+}
+
+func (x *Stock) GetRatingTo() string {
+	if x != nil {
+		return x.RatingTo
+	}
+	return ""
+}
+
+func (x *Stock) GetTargetFrom() string {
+	if x != nil {
+		return x.TargetFrom
+	}
+	return ""
+}
+
+func (x *Stock) GetTargetTo() string {
+	if x != nil {
+		return x.TargetTo
+	}
+	return ""
+}
+
+func (x *Stock) GetScore() float64 {
+	if x != nil {
+		return x.Score
+	}
+	return 0
+}
+
+func (x *Stock) GetConfidence() float64 {
+	if x != nil {
+		return x.Confidence
+	}
+	return 0
+}
+
+func (x *Stock) GetSource() string {
+	if x != nil {
+		return x.Source
+	}
+	return ""
+}
+
+func (x *Stock) GetTime() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Time
+	}
+	return nil
+}
+
+func (x *Stock) GetCreatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return nil
+}
+
+func (x *Stock) GetUpdatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.UpdatedAt
+	}
+	return nil
+}
+
+type GetRecommendationsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetRecommendationsRequest) Reset() {
+	*x = GetRecommendationsRequest{}
+	mi := &file_stocks_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetRecommendationsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetRecommendationsRequest) ProtoMessage() {}
+
+func (x *GetRecommendationsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_stocks_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetRecommendationsRequest.ProtoReflect.Descriptor instead.
+func (*GetRecommendationsRequest) Descriptor() ([]byte, []int) {
+	return file_stocks_proto_rawDescGZIP(), []int{4}
+}
+
+type GetRecommendationsResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Highest score first
+	Recommendations []*Stock `protobuf:"bytes,1,rep,name=recommendations,proto3" json:"recommendations,omitempty"`
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *GetRecommendationsResponse) Reset() {
+	*x = GetRecommendationsResponse{}
+	mi := &file_stocks_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetRecommendationsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetRecommendationsResponse) ProtoMessage() {}
+
+func (x *GetRecommendationsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_stocks_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetRecommendationsResponse.ProtoReflect.Descriptor instead.
+func (*GetRecommendationsResponse) Descriptor() ([]byte, []int) {
+	return file_stocks_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *GetRecommendationsResponse) GetRecommendations() []*Stock {
+	if x != nil {
+		return x.Recommendations
+	}
+	return nil
+}
+
+type GetTickerConsensusRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Ticker symbol, matched case-insensitively
+	Ticker        string `protobuf:"bytes,1,opt,name=ticker,proto3" json:"ticker,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetTickerConsensusRequest) Reset() {
+	*x = GetTickerConsensusRequest{}
+	mi := &file_stocks_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetTickerConsensusRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetTickerConsensusRequest) ProtoMessage() {}
+
+func (x *GetTickerConsensusRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_stocks_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetTickerConsensusRequest.ProtoReflect.Descriptor instead.
+func (*GetTickerConsensusRequest) Descriptor() ([]byte, []int) {
+	return file_stocks_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *GetTickerConsensusRequest) GetTicker() string {
+	if x != nil {
+		return x.Ticker
+	}
+	return ""
+}
+
+// TickerConsensus summarizes the latest rating of each brokerage covering a
+// ticker. Ratings whose label has no known rank count toward ratings only.
+type TickerConsensus struct {
+	state   protoimpl.MessageState `protogen:"open.v1"`
+	Ticker  string                 `protobuf:"bytes,1,opt,name=ticker,proto3" json:"ticker,omitempty"`
+	Since   *timestamppb.Timestamp `protobuf:"bytes,2,opt,name=since,proto3" json:"since,omitempty"`
+	Ratings int32                  `protobuf:"varint,3,opt,name=ratings,proto3" json:"ratings,omitempty"`
+	Buy     int32                  `protobuf:"varint,4,opt,name=buy,proto3" json:"buy,omitempty"`
+	Hold    int32                  `protobuf:"varint,5,opt,name=hold,proto3" json:"hold,omitempty"`
+	Sell    int32                  `protobuf:"varint,6,opt,name=sell,proto3" json:"sell,omitempty"`
+	// "Buy", "Hold" or "Sell" by mean rank; empty when no rank is known
+	Consensus string `protobuf:"bytes,7,opt,name=consensus,proto3" json:"consensus,omitempty"`
+	// 1 (most bearish) to 9 (most bullish)
+	MeanRank float64 `protobuf:"fixed64,8,opt,name=mean_rank,json=meanRank,proto3" json:"mean_rank,omitempty"`
+	// Over the ratings with a price target
+	MeanTarget float64 `protobuf:"fixed64,9,opt,name=mean_target,json=meanTarget,proto3" json:"mean_target,omitempty"`
+	LowTarget  float64 `protobuf:"fixed64,10,opt,name=low_target,json=lowTarget,proto3" json:"low_target,omitempty"`
+	HighTarget float64 `protobuf:"fixed64,11,opt,name=high_target,json=highTarget,proto3" json:"high_target,omitempty"`
+	// Newest first
+	Brokerages    []*BrokerageRating `protobuf:"bytes,12,rep,name=brokerages,proto3" json:"brokerages,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *TickerConsensus) Reset() {
+	*x = TickerConsensus{}
+	mi := &file_stocks_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TickerConsensus) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TickerConsensus) ProtoMessage() {}
+
+func (x *TickerConsensus) ProtoReflect() protoreflect.Message {
+	mi := &file_stocks_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TickerConsensus.ProtoReflect.Descriptor instead.
+func (*TickerConsensus) Descriptor() ([]byte, []int) {
+	return file_stocks_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *TickerConsensus) GetTicker() string {
+	if x != nil {
+		return x.Ticker
+	}
+	return ""
+}
+
+func (x *TickerConsensus) GetSince() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Since
+	}
+	return nil
+}
+
+func (x *TickerConsensus) GetRatings() int32 {
+	if x != nil {
+		return x.Ratings
+	}
+	return 0
+}
+
+func (x *TickerConsensus) GetBuy() int32 {
+	if x != nil {
+		return x.Buy
+	}
+	return 0
+}
+
+func (x *TickerConsensus) GetHold() int32 {
+	if x != nil {
+		return x.Hold
+	}
+	return 0
+}
+
+func (x *TickerConsensus) GetSell() int32 {
+	if x != nil {
+		return x.Sell
+	}
+	return 0
+}
+
+func (x *TickerConsensus) GetConsensus() string {
+	if x != nil {
+		return x.Consensus
+	}
+	return ""
+}
+
+func (x *TickerConsensus) GetMeanRank() float64 {
+	if x != nil {
+		return x.MeanRank
+	}
+	return 0
+}
+
+func (x *TickerConsensus) GetMeanTarget() float64 {
+	if x != nil {
+		return x.MeanTarget
+	}
+	return 0
+}
+
+func (x *TickerConsensus) GetLowTarget() float64 {
+	if x != nil {
+		return x.LowTarget
+	}
+	return 0
+}
+
+func (x *TickerConsensus) GetHighTarget() float64 {
+	if x != nil {
+		return x.HighTarget
+	}
+	return 0
+}
+
+func (x *TickerConsensus) GetBrokerages() []*BrokerageRating {
+	if x != nil {
+		return x.Brokerages
+	}
+	return nil
+}
+
+// BrokerageRating is the latest rating of a brokerage for a ticker
+type BrokerageRating struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Brokerage     string                 `protobuf:"bytes,1,opt,name=brokerage,proto3" json:"brokerage,omitempty"`
+	Company       string                 `protobuf:"bytes,2,opt,name=company,proto3" json:"company,omitempty"`
+	Action        string                 `protobuf:"bytes,3,opt,name=action,proto3" json:"action,omitempty"`
+	Rating        string                 `protobuf:"bytes,4,opt,name=rating,proto3" json:"rating,omitempty"`
+	Target        string                 `protobuf:"bytes,5,opt,name=target,proto3" json:"target,omitempty"`
+	Time          *timestamppb.Timestamp `protobuf:"bytes,6,opt,name=time,proto3" json:"time,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BrokerageRating) Reset() {
+	*x = BrokerageRating{}
+	mi := &file_stocks_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BrokerageRating) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BrokerageRating) ProtoMessage() {}
+
+func (x *BrokerageRating) ProtoReflect() protoreflect.Message {
+	mi := &file_stocks_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BrokerageRating.ProtoReflect.Descriptor instead.
+func (*BrokerageRating) Descriptor() ([]byte, []int) {
+	return file_stocks_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *BrokerageRating) GetBrokerage() string {
+	if x != nil {
+		return x.Brokerage
+	}
+	return ""
+}
+
+func (x *BrokerageRating) GetCompany() string {
+	if x != nil {
+		return x.Company
+	}
+	return ""
+}
+
+func (x *BrokerageRating) GetAction() string {
+	if x != nil {
+		return x.Action
+	}
+	return ""
+}
+
+func (x *BrokerageRating) GetRating() string {
+	if x != nil {
+		return x.Rating
+	}
+	return ""
+}
+
+func (x *BrokerageRating) GetTarget() string {
+	if x != nil {
+		return x.Target
+	}
+	return ""
+}
+
+func (x *BrokerageRating) GetTime() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Time
+	}
+	return nil
+}
+
+type StreamChangesRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Resume after this change ID; unset starts from the current head
+	Since *int64 `protobuf:"varint,1,opt,name=since,proto3,oneof" json:"since,omitempty"`
+	// Tickers to follow; empty follows all
+	Tickers []string `protobuf:"bytes,2,rep,name=tickers,proto3" json:"tickers,omitempty"`
+	// Brokerages to follow; empty follows all
+	Brokerages    []string `protobuf:"bytes,3,rep,name=brokerages,proto3" json:"brokerages,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StreamChangesRequest) Reset() {
+	*x = StreamChangesRequest{}
+	mi := &file_stocks_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StreamChangesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StreamChangesRequest) ProtoMessage() {}
+
+func (x *StreamChangesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_stocks_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StreamChangesRequest.ProtoReflect.Descriptor instead.
+func (*StreamChangesRequest) Descriptor() ([]byte, []int) {
+	return file_stocks_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *StreamChangesRequest) GetSince() int64 {
+	if x != nil && x.Since != nil {
+		return *x.Since
+	}
+	return 0
+}
+
+func (x *StreamChangesRequest) GetTickers() []string {
+	if x != nil {
+		return x.Tickers
+	}
+	return nil
+}
+
+func (x *StreamChangesRequest) GetBrokerages() []string {
+	if x != nil {
+		return x.Brokerages
+	}
+	return nil
+}
+
+// StockChange is a new coverage, upgrade, downgrade, target or score change
+type StockChange struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            int64                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	StockId       int32                  `protobuf:"varint,2,opt,name=stock_id,json=stockId,proto3" json:"stock_id,omitempty"`
+	Type          string                 `protobuf:"bytes,3,opt,name=type,proto3" json:"type,omitempty"`
+	Ticker        string                 `protobuf:"bytes,4,opt,name=ticker,proto3" json:"ticker,omitempty"`
+	Company       string                 `protobuf:"bytes,5,opt,name=company,proto3" json:"company,omitempty"`
+	Brokerage     string                 `protobuf:"bytes,6,opt,name=brokerage,proto3" json:"brokerage,omitempty"`
+	Action        string                 `protobuf:"bytes,7,opt,name=action,proto3" json:"action,omitempty"`
+	RatingFrom    string                 `protobuf:"bytes,8,opt,name=rating_from,json=ratingFrom,proto3" json:"rating_from,omitempty"`
+	RatingTo      string                 `protobuf:"bytes,9,opt,name=rating_to,json=ratingTo,proto3" json:"rating_to,omitempty"`
+	TargetFrom    string                 `protobuf:"bytes,10,opt,name=target_from,json=targetFrom,proto3" json:"target_from,omitempty"`
+	TargetTo      string                 `protobuf:"bytes,11,opt,name=target_to,json=targetTo,proto3" json:"target_to,omitempty"`
+	ScoreFrom     float64                `protobuf:"fixed64,12,opt,name=score_from,json=scoreFrom,proto3" json:"score_from,omitempty"`
+	ScoreTo       float64                `protobuf:"fixed64,13,opt,name=score_to,json=scoreTo,proto3" json:"score_to,omitempty"`
+	EventTime     *timestamppb.Timestamp `protobuf:"bytes,14,opt,name=event_time,json=eventTime,proto3" json:"event_time,omitempty"`
+	CreatedAt     *timestamppb.Timestamp `protobuf:"bytes,15,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StockChange) Reset() {
+	*x = StockChange{}
+	mi := &file_stocks_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StockChange) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StockChange) ProtoMessage() {}
+
+func (x *StockChange) ProtoReflect() protoreflect.Message {
+	mi := &file_stocks_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StockChange.ProtoReflect.Descriptor instead.
+func (*StockChange) Descriptor() ([]byte, []int) {
+	return file_stocks_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *StockChange) GetId() int64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *StockChange) GetStockId() int32 {
+	if x != nil {
+		return x.StockId
+	}
+	return 0
+}
+
+func (x *StockChange) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+func (x *StockChange) GetTicker() string {
+	if x != nil {
+		return x.Ticker
+	}
+	return ""
+}
+
+func (x *StockChange) GetCompany() string {
+	if x != nil {
+		return x.Company
+	}
+	return ""
+}
+
+func (x *StockChange) GetBrokerage() string {
+	if x != nil {
+		return x.Brokerage
+	}
+	return ""
+}
+
+func (x *StockChange) GetAction() string {
+	if x != nil {
+		return x.Action
+	}
+	return ""
+}
+
+func (x *StockChange) GetRatingFrom() string {
+	if x != nil {
+		return x.RatingFrom
+	}
+	return ""
+}
+
+func (x *StockChange) GetRatingTo() string {
+	if x != nil {
+		return x.RatingTo
+	}
+	return ""
+}
+
+func (x *StockChange) GetTargetFrom() string {
+	if x != nil {
+		return x.TargetFrom
+	}
+	return ""
+}
+
+func (x *StockChange) GetTargetTo() string {
+	if x != nil {
+		return x.TargetTo
+	}
+	return ""
+}
+
+func (x *StockChange) GetScoreFrom() float64 {
+	if x != nil {
+		return x.ScoreFrom
+	}
+	return 0
+}
+
+func (x *StockChange) GetScoreTo() float64 {
+	if x != nil {
+		return x.ScoreTo
+	}
+	return 0
+}
+
+func (x *StockChange) GetEventTime() *timestamppb.Timestamp {
+	if x != nil {
+		return x.EventTime
+	}
+	return nil
+}
+
+func (x *StockChange) GetCreatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return nil
+}
+
+// StreamEvent is a message of StreamChanges. "top_pick" is sent on connect and
+// whenever the top recommendation changes; "heartbeat" when the stream is idle.
+type StreamEvent struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// The change feed cursor of change events, to resume from with since
+	Id int64 `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	// "change", "top_pick" or "heartbeat"
+	Type   string       `protobuf:"bytes,2,opt,name=type,proto3" json:"type,omitempty"`
+	Change *StockChange `protobuf:"bytes,3,opt,name=change,proto3" json:"change,omitempty"`
+	// Unset when there is no recommendation
+	TopPick       *Stock                 `protobuf:"bytes,4,opt,name=top_pick,json=topPick,proto3" json:"top_pick,omitempty"`
+	Time          *timestamppb.Timestamp `protobuf:"bytes,5,opt,name=time,proto3" json:"time,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StreamEvent) Reset() {
+	*x = StreamEvent{}
+	mi := &file_stocks_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StreamEvent) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StreamEvent) ProtoMessage() {}
+
+func (x *StreamEvent) ProtoReflect() protoreflect.Message {
+	mi := &file_stocks_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StreamEvent.ProtoReflect.Descriptor instead.
+func (*StreamEvent) Descriptor() ([]byte, []int) {
+	return file_stocks_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *StreamEvent) GetId() int64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *StreamEvent) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+func (x *StreamEvent) GetChange() *StockChange {
+	if x != nil {
+		return x.Change
+	}
+	return nil
+}
+
+func (x *StreamEvent) GetTopPick() *Stock {
+	if x != nil {
+		return x.TopPick
+	}
+	return nil
+}
+
+func (x *StreamEvent) GetTime() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Time
+	}
+	return nil
+}
+
+var File_stocks_proto protoreflect.FileDescriptor
+
+const file_stocks_proto_rawDesc = "" +
+	"\n" +
+	"\fstocks.proto\x12\tstocks.v1\x1a\x1fgoogle/protobuf/timestamp.proto\"\xd3\x01\n" +
+	"\fStockFilters\x12\x16\n" +
+	"\x06ticker\x18\x01 \x01(\tR\x06ticker\x12\x18\n" +
+	"\acompany\x18\x02 \x01(\tR\acompany\x12\x1c\n" +
+	"\tbrokerage\x18\x03 \x01(\tR\tbrokerage\x12\x18\n" +
+	"\atickers\x18\x04 \x03(\tR\atickers\x12\x0e\n" +
+	"\x02id\x18\x05 \x01(\x05R\x02id\x12\x1b\n" +
+	"\tmin_score\x18\x06 \x01(\x01R\bminScore\x12\x14\n" +
+	"\x05today\x18\a \x01(\bR\x05today\x12\x16\n" +
+	"\x06source\x18\b \x01(\tR\x06source\"t\n" +
+	"\x11ListStocksRequest\x121\n" +
+	"\afilters\x18\x01 \x01(\v2\x17.stocks.v1.StockFiltersR\afilters\x12\x14\n" +
+	"\x05limit\x18\x02 \x01(\x05R\x05limit\x12\x16\n" +
+	"\x06offset\x18\x03 \x01(\x05R\x06offset\"\xe0\x01\n" +
+	"\x12ListStocksResponse\x12&\n" +
+	"\x05items\x18\x01 \x03(\v2\x10.stocks.v1.StockR\x05items\x12\x1f\n" +
+	"\vtotal_count\x18\x02 \x01(\x05R\n" +
+	"totalCount\x12\x1b\n" +
+	"\tbuy_count\x18\x03 \x01(\x05R\bbuyCount\x12'\n" +
+	"\x0fbrokerage_count\x18\x04 \x01(\x05R\x0ebrokerageCount\x12;\n" +
+	"\vlast_update\x18\x05 \x01(\v2\x1a.google.protobuf.TimestampR\n" +
+	"lastUpdate\"\xef\x03\n" +
+	"\x05Stock\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x05R\x02id\x12\x16\n" +
+	"\x06ticker\x18\x02 \x01(\tR\x06ticker\x12\x18\n" +
+	"\acompany\x18\x03 \x01(\tR\acompany\x12\x1c\n" +
+	"\tbrokerage\x18\x04 \x01(\tR\tbrokerage\x12\x16\n" +
+	"\x06action\x18\x05 \x01(\tR\x06action\x12\x1f\n" +
+	"\vrating_from\x18\x06 \x01(\tR\n" +
+	"ratingFrom\x12\x1b\n" +
+	"\trating_to\x18\a \x01(\tR\bratingTo\x12\x1f\n" +
+	"\vtarget_from\x18\b \x01(\tR\n" +
+	"targetFrom\x12\x1b\n" +
+	"\ttarget_to\x18\t \x01(\tR\btargetTo\x12\x14\n" +
+	"\x05score\x18\n" +
+	" \x01(\x01R\x05score\x12\x1e\n" +
+	"\n" +
+	"confidence\x18\v \x01(\x01R\n" +
+	"confidence\x12\x16\n" +
+	"\x06source\x18\f \x01(\tR\x06source\x12.\n" +
+	"\x04time\x18\r \x01(\v2\x1a.google.protobuf.TimestampR\x04time\x129\n" +
+	"\n" +
+	"created_at\x18\x0e \x01(\v2\x1a.google.protobuf.TimestampR\tcreatedAt\x129\n" +
+	"\n" +
+	"updated_at\x18\x0f \x01(\v2\x1a.google.protobuf.TimestampR\tupdatedAt\"\x1b\n" +
+	"\x19GetRecommendationsRequest\"X\n" +
+	"\x1aGetRecommendationsResponse\x12:\n" +
+	"\x0frecommendations\x18\x01 \x03(\v2\x10.stocks.v1.StockR\x0frecommendations\"3\n" +
+	"\x19GetTickerConsensusRequest\x12\x16\n" +
+	"\x06ticker\x18\x01 \x01(\tR\x06ticker\"\x87\x03\n" +
+	"\x0fTickerConsensus\x12\x16\n" +
+	"\x06ticker\x18\x01 \x01(\tR\x06ticker\x120\n" +
+	"\x05since\x18\x02 \x01(\v2\x1a.google.protobuf.TimestampR\x05since\x12\x18\n" +
+	"\aratings\x18\x03 \x01(\x05R\aratings\x12\x10\n" +
+	"\x03buy\x18\x04 \x01(\x05R\x03buy\x12\x12\n" +
+	"\x04hold\x18\x05 \x01(\x05R\x04hold\x12\x12\n" +
+	"\x04sell\x18\x06 \x01(\x05R\x04sell\x12\x1c\n" +
+	"\tconsensus\x18\a \x01(\tR\tconsensus\x12\x1b\n" +
+	"\tmean_rank\x18\b \x01(\x01R\bmeanRank\x12\x1f\n" +
+	"\vmean_target\x18\t \x01(\x01R\n" +
+	"meanTarget\x12\x1d\n" +
+	"\n" +
+	"low_target\x18\n" +
+	" \x01(\x01R\tlowTarget\x12\x1f\n" +
+	"\vhigh_target\x18\v \x01(\x01R\n" +
+	"highTarget\x12:\n" +
+	"\n" +
+	"brokerages\x18\f \x03(\v2\x1a.stocks.v1.BrokerageRatingR\n" +
+	"brokerages\"\xc1\x01\n" +
+	"\x0fBrokerageRating\x12\x1c\n" +
+	"\tbrokerage\x18\x01 \x01(\tR\tbrokerage\x12\x18\n" +
+	"\acompany\x18\x02 \x01(\tR\acompany\x12\x16\n" +
+	"\x06action\x18\x03 \x01(\tR\x06action\x12\x16\n" +
+	"\x06rating\x18\x04 \x01(\tR\x06rating\x12\x16\n" +
+	"\x06target\x18\x05 \x01(\tR\x06target\x12.\n" +
+	"\x04time\x18\x06 \x01(\v2\x1a.google.protobuf.TimestampR\x04time\"u\n" +
+	"\x14StreamChangesRequest\x12\x19\n" +
+	"\x05since\x18\x01 \x01(\x03H\x00R\x05since\x88\x01\x01\x12\x18\n" +
+	"\atickers\x18\x02 \x03(\tR\atickers\x12\x1e\n" +
+	"\n" +
+	"brokerages\x18\x03 \x03(\tR\n" +
+	"brokeragesB\b\n" +
+	"\x06_since\"\xe0\x03\n" +
+	"\vStockChange\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x03R\x02id\x12\x19\n" +
+	"\bstock_id\x18\x02 \x01(\x05R\astockId\x12\x12\n" +
+	"\x04type\x18\x03 \x01(\tR\x04type\x12\x16\n" +
+	"\x06ticker\x18\x04 \x01(\tR\x06ticker\x12\x18\n" +
+	"\acompany\x18\x05 \x01(\tR\acompany\x12\x1c\n" +
+	"\tbrokerage\x18\x06 \x01(\tR\tbrokerage\x12\x16\n" +
+	"\x06action\x18\a \x01(\tR\x06action\x12\x1f\n" +
+	"\vrating_from\x18\b \x01(\tR\n" +
+	"ratingFrom\x12\x1b\n" +
+	"\trating_to\x18\t \x01(\tR\bratingTo\x12\x1f\n" +
+	"\vtarget_from\x18\n" +
+	" \x01(\tR\n" +
+	"targetFrom\x12\x1b\n" +
+	"\ttarget_to\x18\v \x01(\tR\btargetTo\x12\x1d\n" +
+	"\n" +
+	"score_from\x18\f \x01(\x01R\tscoreFrom\x12\x19\n" +
+	"\bscore_to\x18\r \x01(\x01R\ascoreTo\x129\n" +
+	"\n" +
+	"event_time\x18\x0e \x01(\v2\x1a.google.protobuf.TimestampR\teventTime\x129\n" +
+	"\n" +
+	"created_at\x18\x0f \x01(\v2\x1a.google.protobuf.TimestampR\tcreatedAt\"\xbe\x01\n" +
+	"\vStreamEvent\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x03R\x02id\x12\x12\n" +
+	"\x04type\x18\x02 \x01(\tR\x04type\x12.\n" +
+	"\x06change\x18\x03 \x01(\v2\x16.stocks.v1.StockChangeR\x06change\x12+\n" +
+	"\btop_pick\x18\x04 \x01(\v2\x10.stocks.v1.StockR\atopPick\x12.\n" +
+	"\x04time\x18\x05 \x01(\v2\x1a.google.protobuf.TimestampR\x04time2\xe0\x02\n" +
+	"\fStockService\x12I\n" +
+	"\n" +
+	"ListStocks\x12\x1c.stocks.v1.ListStocksRequest\x1a\x1d.stocks.v1.ListStocksResponse\x12a\n" +
+	"\x12GetRecommendations\x12$.stocks.v1.GetRecommendationsRequest\x1a%.stocks.v1.GetRecommendationsResponse\x12V\n" +
+	"\x12GetTickerConsensus\x12$.stocks.v1.GetTickerConsensusRequest\x1a\x1a.stocks.v1.TickerConsensus\x12J\n" +
+	"\rStreamChanges\x12\x1f.stocks.v1.StreamChangesRequest\x1a\x16.stocks.v1.StreamEvent0\x01B#Z!Backend/internal/grpcapi/stockspbb\x06proto3"
+
+var (
+	file_stocks_proto_rawDescOnce sync.Once
+	file_stocks_proto_rawDescData []byte
+)
+
+func file_stocks_proto_rawDescGZIP() []byte {
+	file_stocks_proto_rawDescOnce.Do(func() {
+		file_stocks_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_stocks_proto_rawDesc), len(file_stocks_proto_rawDesc)))
+	})
+	return file_stocks_proto_rawDescData
+}
+
+var file_stocks_proto_msgTypes = make([]protoimpl.MessageInfo, 12)
+var file_stocks_proto_goTypes = []any{
+	(*StockFilters)(nil),               // 0: stocks.v1.StockFilters
+	(*ListStocksRequest)(nil),          // 1: stocks.v1.ListStocksRequest
+	(*ListStocksResponse)(nil),         // 2: stocks.v1.ListStocksResponse
+	(*Stock)(nil),                      // 3: stocks.v1.Stock
+	(*GetRecommendationsRequest)(nil),  // 4: stocks.v1.GetRecommendationsRequest
+	(*GetRecommendationsResponse)(nil), // 5: stocks.v1.GetRecommendationsResponse
+	(*GetTickerConsensusRequest)(nil),  // 6: stocks.v1.GetTickerConsensusRequest
+	(*TickerConsensus)(nil),            // 7: stocks.v1.TickerConsensus
+	(*BrokerageRating)(nil),            // 8: stocks.v1.BrokerageRating
+	(*StreamChangesRequest)(nil),       // 9: stocks.v1.StreamChangesRequest
+	(*StockChange)(nil),                // 10: stocks.v1.StockChange
+	(*StreamEvent)(nil),                // 11: stocks.v1.StreamEvent
+	(*timestamppb.Timestamp)(nil),      // 12: google.protobuf.Timestamp
+}
+var file_stocks_proto_depIdxs = []int32{
+	0,  // 0: stocks.v1.ListStocksRequest.filters:type_name -> stocks.v1.StockFilters
+	3,  // 1: stocks.v1.ListStocksResponse.items:type_name -> stocks.v1.Stock
+	12, // 2: stocks.v1.ListStocksResponse.last_update:type_name -> google.protobuf.Timestamp
+	12, // 3: stocks.v1.Stock.time:type_name -> google.protobuf.Timestamp
+	12, // 4: stocks.v1.Stock.created_at:type_name -> google.protobuf.Timestamp
+	12, // 5: stocks.v1.Stock.updated_at:type_name -> google.protobuf.Timestamp
+	3,  // 6: stocks.v1.GetRecommendationsResponse.recommendations:type_name -> stocks.v1.Stock
+	12, // 7: stocks.v1.TickerConsensus.since:type_name -> google.protobuf.Timestamp
+	8,  // 8: stocks.v1.TickerConsensus.brokerages:type_name -> stocks.v1.BrokerageRating
+	12, // 9: stocks.v1.BrokerageRating.time:type_name -> google.protobuf.Timestamp
+	12, // 10: stocks.v1.StockChange.event_time:type_name -> google.protobuf.Timestamp
+	12, // 11: stocks.v1.StockChange.created_at:type_name -> google.protobuf.Timestamp
+	10, // 12: stocks.v1.StreamEvent.change:type_name -> stocks.v1.StockChange
+	3,  // 13: stocks.v1.StreamEvent.top_pick:type_name -> stocks.v1.Stock
+	12, // 14: stocks.v1.StreamEvent.time:type_name -> google.protobuf.Timestamp
+	1,  // 15: stocks.v1.StockService.ListStocks:input_type -> stocks.v1.ListStocksRequest
+	4,  // 16: stocks.v1.StockService.GetRecommendations:input_type -> stocks.v1.GetRecommendationsRequest
+	6,  // 17: stocks.v1.StockService.GetTickerConsensus:input_type -> stocks.v1.GetTickerConsensusRequest
+	9,  // 18: stocks.v1.StockService.StreamChanges:input_type -> stocks.v1.StreamChangesRequest
+	2,  // 19: stocks.v1.StockService.ListStocks:output_type -> stocks.v1.ListStocksResponse
+	5,  // 20: stocks.v1.StockService.GetRecommendations:output_type -> stocks.v1.GetRecommendationsResponse
+	7,  // 21: stocks.v1.StockService.GetTickerConsensus:output_type -> stocks.v1.TickerConsensus
+	11, // 22: stocks.v1.StockService.StreamChanges:output_type -> stocks.v1.StreamEvent
+	19, // [19:23] is the sub-list for method output_type
+	15, // [15:19] is the sub-list for method input_type
+	15, // [15:15] is the sub-list for extension type_name
+	15, // [15:15] is the sub-list for extension extendee
+	0,  // [0:15] is the sub-list for field type_name
+}
+
+func init() { file_stocks_proto_init() }
+func file_stocks_proto_init() {
+	if File_stocks_proto != nil {
+		return
+	}
+	file_stocks_proto_msgTypes[9].OneofWrappers = []any{}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_stocks_proto_rawDesc), len(file_stocks_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   12,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_stocks_proto_goTypes,
+		DependencyIndexes: file_stocks_proto_depIdxs,
+		MessageInfos:      file_stocks_proto_msgTypes,
+	}.Build()
+	File_stocks_proto = out.File
+	file_stocks_proto_goTypes = nil
+	file_stocks_proto_depIdxs = nil
+}
@@ -0,0 +1,240 @@
+// Package logger provides the structured application logger with secret redaction
+package logger
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/url"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+const redacted = "[REDACTED]"
+
+// sensitiveKeys are attribute keys whose values are always redacted. They match
+// whole segments of a key split on "_", "-", "." and camelCase, so "db_password"
+// and "jwt_secret_key" are redacted but "tokens_issued" and "next_page_token" are not.
+var sensitiveKeys = []string{
+	"password", "secret", "api_key", "apikey", "authorization", "database_url", "dsn",
+	"access_token", "refresh_token", "id_token", "auth_token", "bearer_token",
+}
+
+// sensitiveExactKeys are only redacted as the whole key
+var sensitiveExactKeys = []string{"token", "jwt"}
+
+var (
+	bearerPattern   = regexp.MustCompile(`(?i)(bearer\s+)[A-Za-z0-9\-._~+/]+=*`)
+	userinfoPattern = regexp.MustCompile(`(://[^:/@\s]+:)[^@\s]+(@)`)
+)
+
+var (
+	mu      sync.RWMutex
+	secrets []string
+	base    = slog.New(NewRedactingHandler(slog.NewTextHandler(os.Stdout, nil)))
+)
+
+type contextKey struct{}
+
+// Setup configures the default logger with the given level (debug, info, warn, error)
+// and format (text or json) and returns it
+func Setup(level, format string) *slog.Logger {
+	return SetupWithWriter(os.Stdout, level, format)
+}
+
+// SetupWithWriter is like Setup but writes to w
+func SetupWithWriter(w io.Writer, level, format string) *slog.Logger {
+	opts := &slog.HandlerOptions{Level: ParseLevel(level)}
+
+	var handler slog.Handler
+	if strings.ToLower(format) == "json" {
+		handler = slog.NewJSONHandler(w, opts)
+	} else {
+		handler = slog.NewTextHandler(w, opts)
+	}
+
+	l := slog.New(NewRedactingHandler(handler))
+
+	mu.Lock()
+	base = l
+	mu.Unlock()
+
+	slog.SetDefault(l)
+	return l
+}
+
+// ParseLevel converts a level name to a slog.Level, defaulting to info
+func ParseLevel(level string) slog.Level {
+	switch strings.ToLower(strings.TrimSpace(level)) {
+	case "debug":
+		return slog.LevelDebug
+	case "warn", "warning":
+		return slog.LevelWarn
+	case "error":
+		return slog.LevelError
+	default:
+		return slog.LevelInfo
+	}
+}
+
+// AddSecrets registers values that must never appear in log output.
+// Database URLs also register their password.
+func AddSecrets(values ...string) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	for _, v := range values {
+		if len(v) < 4 {
+			continue
+		}
+		secrets = append(secrets, v)
+
+		if u, err := url.Parse(v); err == nil && u.User != nil {
+			if password, ok := u.User.Password(); ok && len(password) >= 4 {
+				secrets = append(secrets, password)
+			}
+		}
+	}
+}
+
+// L returns the application logger
+func L() *slog.Logger {
+	mu.RLock()
+	defer mu.RUnlock()
+	return base
+}
+
+// Component returns the application logger tagged with a component name
+func Component(name string) *slog.Logger {
+	return L().With("component", name)
+}
+
+// WithContext stores a request-scoped logger in the context
+func WithContext(ctx context.Context, l *slog.Logger) context.Context {
+	return context.WithValue(ctx, contextKey{}, l)
+}
+
+// FromContext returns the request-scoped logger, or the application logger if none is set
+func FromContext(ctx context.Context) *slog.Logger {
+	if ctx != nil {
+		if l, ok := ctx.Value(contextKey{}).(*slog.Logger); ok {
+			return l
+		}
+	}
+	return L()
+}
+
+// Redact masks registered secrets, bearer tokens and URL credentials in s
+func Redact(s string) string {
+	mu.RLock()
+	for _, secret := range secrets {
+		s = strings.ReplaceAll(s, secret, redacted)
+	}
+	mu.RUnlock()
+
+	s = bearerPattern.ReplaceAllString(s, "${1}"+redacted)
+	s = userinfoPattern.ReplaceAllString(s, "${1}"+redacted+"${2}")
+	return s
+}
+
+// RedactingHandler wraps a slog.Handler and redacts secrets from messages and attributes
+type RedactingHandler struct {
+	next slog.Handler
+}
+
+// NewRedactingHandler creates a handler that redacts before delegating to next
+func NewRedactingHandler(next slog.Handler) *RedactingHandler {
+	return &RedactingHandler{next: next}
+}
+
+func (h *RedactingHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+func (h *RedactingHandler) Handle(ctx context.Context, r slog.Record) error {
+	out := slog.NewRecord(r.Time, r.Level, Redact(r.Message), r.PC)
+	r.Attrs(func(a slog.Attr) bool {
+		out.AddAttrs(redactAttr(a))
+		return true
+	})
+	return h.next.Handle(ctx, out)
+}
+
+func (h *RedactingHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	clean := make([]slog.Attr, len(attrs))
+	for i, a := range attrs {
+		clean[i] = redactAttr(a)
+	}
+	return &RedactingHandler{next: h.next.WithAttrs(clean)}
+}
+
+func (h *RedactingHandler) WithGroup(name string) slog.Handler {
+	return &RedactingHandler{next: h.next.WithGroup(name)}
+}
+
+func redactAttr(a slog.Attr) slog.Attr {
+	if isSensitiveKey(a.Key) {
+		return slog.String(a.Key, redacted)
+	}
+
+	v := a.Value.Resolve()
+	switch v.Kind() {
+	case slog.KindString:
+		return slog.String(a.Key, Redact(v.String()))
+	case slog.KindGroup:
+		group := v.Group()
+		clean := make([]any, len(group))
+		for i, ga := range group {
+			clean[i] = redactAttr(ga)
+		}
+		return slog.Group(a.Key, clean...)
+	case slog.KindAny:
+		switch x := v.Any().(type) {
+		case error:
+			return slog.String(a.Key, Redact(x.Error()))
+		case []byte:
+			return slog.String(a.Key, Redact(string(x)))
+		}
+		return slog.String(a.Key, Redact(v.String()))
+	default:
+		return slog.Attr{Key: a.Key, Value: v}
+	}
+}
+
+func isSensitiveKey(key string) bool {
+	key = normalizeKey(key)
+	if slices.Contains(sensitiveExactKeys, key) {
+		return true
+	}
+
+	padded := "_" + key + "_"
+	for _, k := range sensitiveKeys {
+		if strings.Contains(padded, "_"+k+"_") {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizeKey lowercases a key and separates its segments with underscores
+func normalizeKey(key string) string {
+	var b strings.Builder
+	for i, r := range key {
+		switch {
+		case r == '-' || r == '.' || r == ' ':
+			b.WriteByte('_')
+		case unicode.IsUpper(r):
+			if i > 0 && unicode.IsLower(rune(key[i-1])) {
+				b.WriteByte('_')
+			}
+			b.WriteRune(unicode.ToLower(r))
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
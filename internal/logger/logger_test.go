@@ -0,0 +1,51 @@
+package logger
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestIsSensitiveKey(t *testing.T) {
+	tests := []struct {
+		key  string
+		want bool
+	}{
+		{"password", true},
+		{"db_password", true},
+		{"jwt_secret_key", true},
+		{"access_token", true},
+		{"refresh-token", true},
+		{"apiKey", true},
+		{"Authorization", true},
+		{"token", true},
+		{"tokens_issued", false},
+		{"next_page_token", false},
+		{"nextPageToken", false},
+		{"ticker", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			if got := isSensitiveKey(tt.key); got != tt.want {
+				t.Errorf("isSensitiveKey(%q) = %v, want %v", tt.key, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRedactingHandlerRedactsSensitiveKeys(t *testing.T) {
+	var buf bytes.Buffer
+	log := slog.New(NewRedactingHandler(slog.NewTextHandler(&buf, nil)))
+
+	log.Info("page fetched", "next_page_token", "abc123", "refresh_token", "xyz789")
+
+	out := buf.String()
+	if !strings.Contains(out, "next_page_token=abc123") {
+		t.Errorf("output = %s, want the page token kept", out)
+	}
+	if strings.Contains(out, "xyz789") || !strings.Contains(out, "refresh_token="+redacted) {
+		t.Errorf("output = %s, want the refresh token redacted", out)
+	}
+}
@@ -0,0 +1,166 @@
+// Package metrics defines the Prometheus metrics exposed on /metrics
+package metrics
+
+import (
+	"database/sql"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const namespace = "stock_analyzer"
+
+var (
+	// HTTPRequestsTotal counts HTTP requests per route, method and status
+	HTTPRequestsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "requests_total",
+		Help:      "Total number of HTTP requests by route, method and status code.",
+	}, []string{"method", "route", "status"})
+
+	// HTTPRequestDuration observes HTTP request latency per route and method
+	HTTPRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "request_duration_seconds",
+		Help:      "HTTP request latency by route and method.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route"})
+
+	// SyncDuration observes the duration of each sync run by outcome
+	SyncDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "sync",
+		Name:      "duration_seconds",
+		Help:      "Duration of stock sync runs by status.",
+		Buckets:   []float64{1, 5, 15, 30, 60, 120, 300, 600, 1200},
+	}, []string{"status"})
+
+	// SyncPagesFetched counts upstream pages fetched
+	SyncPagesFetched = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "sync",
+		Name:      "pages_fetched_total",
+		Help:      "Total number of upstream API pages fetched.",
+	})
+
+	// SyncRowsUpserted counts rows written by UpsertStocks
+	SyncRowsUpserted = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "sync",
+		Name:      "rows_upserted_total",
+		Help:      "Total number of stock rows upserted.",
+	})
+
+	// SyncRowsRejected counts rows UpsertStocks skipped because they failed validation
+	SyncRowsRejected = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "sync",
+		Name:      "rows_rejected_total",
+		Help:      "Total number of stock rows rejected by validation.",
+	})
+
+	// SyncRowsDuplicate counts rows whose event had already been recorded
+	SyncRowsDuplicate = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "sync",
+		Name:      "rows_duplicate_total",
+		Help:      "Total number of stock rows repeating an event that was already recorded.",
+	})
+
+	// SyncRowsQuarantined counts upstream records moved to quarantine
+	SyncRowsQuarantined = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "sync",
+		Name:      "rows_quarantined_total",
+		Help:      "Total number of malformed upstream records quarantined.",
+	})
+
+	// UpstreamErrors counts failed upstream API calls by status code
+	UpstreamErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "sync",
+		Name:      "upstream_errors_total",
+		Help:      "Total number of upstream API errors by status code (\"error\" for transport failures).",
+	}, []string{"status_code"})
+
+	// LastSuccessfulSync holds the unix timestamp of the last successful sync
+	LastSuccessfulSync = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "sync",
+		Name:      "last_success_timestamp_seconds",
+		Help:      "Unix timestamp of the last successful stock sync.",
+	})
+
+	// AlertsTriggered counts alerts recorded by the alert rules
+	AlertsTriggered = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "alerts",
+		Name:      "triggered_total",
+		Help:      "Total number of alerts triggered by user alert rules.",
+	})
+
+	// WebhookDeliveries counts webhook delivery attempts by result (delivered, retry, dead)
+	WebhookDeliveries = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "webhooks",
+		Name:      "deliveries_total",
+		Help:      "Total number of webhook delivery attempts by result.",
+	}, []string{"result"})
+
+	// DigestsSent counts scheduled email digests by result (sent, empty, error)
+	DigestsSent = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "digests",
+		Name:      "sent_total",
+		Help:      "Total number of scheduled email digests by result.",
+	}, []string{"result"})
+
+	// DBQueryDuration observes the latency of StockService queries
+	DBQueryDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "db",
+		Name:      "query_duration_seconds",
+		Help:      "Database query latency by operation and status.",
+		Buckets:   []float64{.001, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10},
+	}, []string{"operation", "status"})
+)
+
+// RegisterDBStats exposes the connection pool statistics from sql.DB.Stats()
+func RegisterDBStats(db *sql.DB, dbName string) error {
+	return prometheus.Register(collectors.NewDBStatsCollector(db, dbName))
+}
+
+// ObserveQuery records the latency of a database operation started at start.
+// Intended to be deferred with a pointer to the function's error result.
+func ObserveQuery(operation string, start time.Time, err *error) {
+	status := "success"
+	if err != nil && *err != nil {
+		status = "error"
+	}
+	DBQueryDuration.WithLabelValues(operation, status).Observe(time.Since(start).Seconds())
+}
+
+// ObserveSync records a finished sync run
+func ObserveSync(start time.Time, err error) {
+	status := "success"
+	if err != nil {
+		status = "error"
+	} else {
+		LastSuccessfulSync.SetToCurrentTime()
+	}
+	SyncDuration.WithLabelValues(status).Observe(time.Since(start).Seconds())
+}
+
+// UpstreamError counts a failed upstream call; statusCode 0 means a transport failure
+func UpstreamError(statusCode int) {
+	label := "error"
+	if statusCode > 0 {
+		label = strconv.Itoa(statusCode)
+	}
+	UpstreamErrors.WithLabelValues(label).Inc()
+}
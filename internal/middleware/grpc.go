@@ -0,0 +1,82 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+
+	"Backend/internal/config"
+	"Backend/internal/entity"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+type userKey struct{}
+
+// UserFromContext returns the user authenticated by the gRPC interceptors
+func UserFromContext(ctx context.Context) (*entity.UserJwt, bool) {
+	user, ok := ctx.Value(userKey{}).(*entity.UserJwt)
+	return user, ok
+}
+
+// UnaryAuthInterceptor is the gRPC counterpart of AuthMiddleware. The token is
+// read from the "authorization" metadata, as "Bearer <token>".
+func UnaryAuthInterceptor(cfg *config.Config, revocations TokenRevocationChecker) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		ctx, err := authenticate(ctx, cfg, revocations)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamAuthInterceptor is the streaming counterpart of UnaryAuthInterceptor
+func StreamAuthInterceptor(cfg *config.Config, revocations TokenRevocationChecker) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := authenticate(ss.Context(), cfg, revocations)
+		if err != nil {
+			return err
+		}
+		return handler(srv, &authenticatedStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+// authenticate validates the token in the metadata of ctx and returns ctx
+// carrying the user, or a status error
+func authenticate(ctx context.Context, cfg *config.Config, revocations TokenRevocationChecker) (context.Context, error) {
+	var header string
+	if values := metadata.ValueFromIncomingContext(ctx, "authorization"); len(values) > 0 {
+		header = values[0]
+	}
+
+	claims, err := ParseToken(ctx, cfg, revocations, header)
+	switch {
+	case errors.Is(err, ErrMissingToken):
+		return nil, status.Error(codes.Unauthenticated, "Token does not exist")
+	case errors.Is(err, ErrInvalidToken):
+		return nil, status.Error(codes.Unauthenticated, "Invalid token")
+	case errors.Is(err, ErrRevokedToken):
+		return nil, status.Error(codes.Unauthenticated, "Token has been revoked")
+	case err != nil:
+		return nil, status.Error(codes.Internal, "Error validating token")
+	}
+
+	return context.WithValue(ctx, userKey{}, &entity.UserJwt{
+		UserId:   claims.UserId,
+		Username: claims.Username,
+		Role:     claims.Role,
+	}), nil
+}
+
+// authenticatedStream replaces the context of a stream with the authenticated one
+type authenticatedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *authenticatedStream) Context() context.Context {
+	return s.ctx
+}
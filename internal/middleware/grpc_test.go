@@ -0,0 +1,103 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"Backend/internal/entity"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestUnaryAuthInterceptor(t *testing.T) {
+	cfg := testConfig()
+	token, err := GenerateToken(&entity.UserJwt{UserId: 7, Username: "dashboard"}, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	revokedToken, _ := GenerateToken(&entity.UserJwt{UserId: 7, Username: "dashboard"}, cfg)
+	revokedClaims, err := ParseToken(context.Background(), cfg, nil, "Bearer "+revokedToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	revocations := &fakeRevocations{revoked: map[string]bool{revokedClaims.ID: true}}
+
+	tests := []struct {
+		name        string
+		header      string
+		revocations TokenRevocationChecker
+		wantCode    codes.Code
+		wantMessage string
+	}{
+		{"valid token", "Bearer " + token, revocations, codes.OK, ""},
+		{"missing token", "", nil, codes.Unauthenticated, "Token does not exist"},
+		{"missing bearer prefix", token, nil, codes.Unauthenticated, "Token does not exist"},
+		{"invalid token", "Bearer " + token + "x", nil, codes.Unauthenticated, "Invalid token"},
+		{"revoked token", "Bearer " + revokedToken, revocations, codes.Unauthenticated, "Token has been revoked"},
+		{"revocation check fails", "Bearer " + token, &fakeRevocations{err: errors.New("database down")}, codes.Internal, "Error validating token"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.header != "" {
+				ctx = metadata.NewIncomingContext(ctx, metadata.Pairs("authorization", tt.header))
+			}
+
+			var user *entity.UserJwt
+			handler := func(ctx context.Context, req any) (any, error) {
+				user, _ = UserFromContext(ctx)
+				return "ok", nil
+			}
+			_, err := UnaryAuthInterceptor(cfg, tt.revocations)(ctx, nil, &grpc.UnaryServerInfo{}, handler)
+
+			if st := status.Convert(err); st.Code() != tt.wantCode || (err != nil && st.Message() != tt.wantMessage) {
+				t.Fatalf("error = %v, want %v %q", err, tt.wantCode, tt.wantMessage)
+			}
+			if tt.wantCode == codes.OK && (user == nil || user.UserId != 7 || user.Username != "dashboard") {
+				t.Errorf("user = %+v, want user 7 dashboard", user)
+			}
+			if tt.wantCode != codes.OK && user != nil {
+				t.Errorf("handler ran with user %+v", user)
+			}
+		})
+	}
+}
+
+// fakeStream is a ServerStream carrying a context only
+type fakeStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *fakeStream) Context() context.Context { return s.ctx }
+
+func TestStreamAuthInterceptor(t *testing.T) {
+	cfg := testConfig()
+	token, err := GenerateToken(&entity.UserJwt{UserId: 7, Username: "dashboard"}, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var user *entity.UserJwt
+	handler := func(srv any, stream grpc.ServerStream) error {
+		user, _ = UserFromContext(stream.Context())
+		return nil
+	}
+	interceptor := StreamAuthInterceptor(cfg, nil)
+
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "Bearer "+token))
+	if err := interceptor(nil, &fakeStream{ctx: ctx}, &grpc.StreamServerInfo{}, handler); err != nil || user == nil || user.Username != "dashboard" {
+		t.Errorf("valid token: error %v, user %+v; want dashboard", err, user)
+	}
+
+	user = nil
+	err = interceptor(nil, &fakeStream{ctx: context.Background()}, &grpc.StreamServerInfo{}, handler)
+	if status.Code(err) != codes.Unauthenticated || user != nil {
+		t.Errorf("missing token: error %v, user %+v; want Unauthenticated without calling the handler", err, user)
+	}
+}
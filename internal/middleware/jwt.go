@@ -1,115 +1,187 @@
 package middleware
 
 import (
-	"time"
-	"strings"
-	"net/http"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"net/http"
+	"strings"
+	"time"
 
 	"Backend/internal/config"
-	"github.com/gin-gonic/gin"
 	"Backend/internal/entity"
+	"Backend/internal/logger"
+
+	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
+)
 
+// TokenRevocationChecker reports whether a token ID has been revoked
+type TokenRevocationChecker interface {
+	IsTokenRevoked(tokenID string) (bool, error)
+}
 
+// Errors returned by ParseToken
+var (
+	ErrMissingToken = errors.New("token does not exist")
+	ErrInvalidToken = errors.New("invalid token")
+	ErrRevokedToken = errors.New("token has been revoked")
 )
 
-func AuthMiddleware(cfg *config.Config) gin.HandlerFunc {
-	return func(c *gin.Context) {
+// ParseToken validates the bearer token of an Authorization header and returns
+// its claims. Errors other than the ones above come from the revocation check.
+func ParseToken(ctx context.Context, cfg *config.Config, revocations TokenRevocationChecker, header string) (*entity.Claimes, error) {
+	token := strings.TrimSpace(strings.TrimPrefix(header, "Bearer "))
+
+	// Check if header has the correct format
+	parts := strings.Split(token, ".")
+	if !strings.HasPrefix(header, "Bearer ") ||
+		len(parts) != 3 ||
+		len(parts[1]) == 0 ||
+		len(parts[2]) == 0 {
+		return nil, ErrMissingToken
+	}
 
+	// Parse and validate  token
+	tokenJwt, err := jwt.ParseWithClaims(token, &entity.Claimes{}, func(token *jwt.Token) (interface{}, error) {
 
-		// Get token from header
-		// token, err := c.Cookie("token")
-				token := c.GetHeader("Authorization");
-
-		// if err != nil {
-		// 	c.JSON(http.StatusUnauthorized, gin.H{
-		// 		"error": "Unauthorized",
-		// 	})
-		// 	c.Abort()
-		// 	return
-		// }
-
-		// }
-
-		// Check if header has the correct format
-		parts := strings.Split(token, ".")
-		if len(parts) != 3 || 
-				parts[0] != "Bearer" ||
-				len(parts[1]) == 0 || 
-				len(parts[2]) == 0 {
-			c.JSON(http.StatusUnauthorized, gin.H{
-				"error": "Token does not exist",
-			})
-			c.Abort()
-			return
+		// validate signing method
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, jwt.ErrInvalidKeyType
 		}
+		return cfg.JwtSecretKey, nil
 
-		// Parse and validate  token
-		
-		tokenJwt, err := jwt.ParseWithClaims(token, &entity.Claimes{}, func(token *jwt.Token) (interface{}, error) {
+	})
 
-			// validate signing method
-			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-				return nil, jwt.ErrInvalidKeyType
-			}
-			return cfg.JwtSecretKey, nil
+	if err != nil {
+		logger.FromContext(ctx).Warn("invalid token", "error", err)
+		return nil, ErrInvalidToken
+	}
 
-		})
+	// check if the token is valid
+	claims, ok := tokenJwt.Claims.(*entity.Claimes)
+	if !ok || !tokenJwt.Valid {
+		return nil, ErrInvalidToken
+	}
 
+	// check if the token has been revoked
+	if revocations != nil && claims.ID != "" {
+		revoked, err := revocations.IsTokenRevoked(claims.ID)
 		if err != nil {
+			logger.FromContext(ctx).Error("error checking token revocation", "error", err)
+			return nil, err
+		}
+		if revoked {
+			return nil, ErrRevokedToken
+		}
+	}
+
+	return claims, nil
+}
+
+func AuthMiddleware(cfg *config.Config, revocations TokenRevocationChecker) gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		// Get token from header
+		header := c.GetHeader("Authorization")
+		if header == "" && isWebSocketUpgrade(c.Request) && c.Query("access_token") != "" {
+			// Browsers cannot set headers on a WebSocket handshake
+			header = "Bearer " + c.Query("access_token")
+		}
+
+		claims, err := ParseToken(c.Request.Context(), cfg, revocations, header)
+		switch {
+		case errors.Is(err, ErrMissingToken):
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+				"error": "Token does not exist",
+			})
+			return
+		case errors.Is(err, ErrInvalidToken):
 			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
 				"error": "Invalid token",
 			})
 			return
+		case errors.Is(err, ErrRevokedToken):
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+				"error": "Token has been revoked",
+			})
+			return
+		case err != nil:
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+				"error": "Error validating token",
+			})
+			return
+		}
+
+		// if token is valid, create a new userJwt
+		userJwt := &entity.UserJwt{
+			UserId:   claims.UserId,
+			Username: claims.Username,
+			Role:     claims.Role,
+		}
+		c.Set("user", userJwt)
+		c.Set("claims", claims)
+		c.Next()
+	}
+}
+
+// RequireAdmin only lets through requests whose token carries the admin role.
+// It must run after AuthMiddleware.
+func RequireAdmin() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, ok := c.Get("user")
+		if userJwt, isUser := user.(*entity.UserJwt); !ok || !isUser || userJwt.Role != entity.RoleAdmin {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+				"error": "Admin access required",
+			})
+			return
 		}
-			
-			// check if the token is valid
-
-			if claims, ok := tokenJwt.Claims.(*entity.Claimes); ok && tokenJwt.Valid {
-				// if token is valid, create a new userJwt
-				userJwt := &entity.UserJwt{
-					UserId: claims.UserId,
-					Username: claims.Username,
-				}
-				c.Set("user", userJwt)
-				c.Next()
-
-			}else{
-				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
-					"error": "Invalid token",
-				})
-			}
-			// return entity.JwtSecretKey, nil
-	
-
-	
+
+		c.Next()
 	}
 }
 
-func GenerateToken(user *entity.UserJwt,cfg *config.Config) (string, error) {	
-	expirationtime := time.Now().Add(time.Hour * 24 * 7)
+func GenerateToken(user *entity.UserJwt, cfg *config.Config) (string, error) {
+	expirationtime := time.Now().Add(cfg.Auth.TokenTTL)
+
+	tokenID, err := newTokenID()
+	if err != nil {
+		return "", err
+	}
 
 	// Create claims with user data
 	claims := &entity.Claimes{
-		UserId: user.UserId,
+		UserId:   user.UserId,
 		Username: user.Username,
+		Role:     user.Role,
 		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        tokenID,
 			ExpiresAt: jwt.NewNumericDate(expirationtime),
-			IssuedAt: jwt.NewNumericDate(time.Now()),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
 		},
 	}
 
 	// create token with claims
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	
-	// For debugging purposes, print the token details
-	fmt.Printf("Generated Token: %+v\n", token)
-	fmt.Printf("JwtSecretKey: %+v\n", cfg.JwtSecretKey)
-
-		return token.SignedString(cfg.JwtSecretKey) 
-	
-	
-	// return token.SignedString("1234141") 
+
+	logger.Component("auth").Debug("token generated", "username", user.Username, "jti", tokenID, "expires_at", expirationtime)
+
+	return token.SignedString(cfg.JwtSecretKey)
 }
 
+// newTokenID returns a random identifier used as the token's jti claim
+func newTokenID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("error generating token id: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// isWebSocketUpgrade reports whether r asks to switch to the WebSocket protocol
+func isWebSocketUpgrade(r *http.Request) bool {
+	return strings.EqualFold(r.Header.Get("Upgrade"), "websocket") &&
+		strings.Contains(strings.ToLower(r.Header.Get("Connection")), "upgrade")
+}
@@ -0,0 +1,215 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"Backend/internal/config"
+	"Backend/internal/entity"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// fakeRevocations is a TokenRevocationChecker backed by a set of revoked IDs
+type fakeRevocations struct {
+	revoked map[string]bool
+	err     error
+}
+
+func (f *fakeRevocations) IsTokenRevoked(tokenID string) (bool, error) {
+	return f.revoked[tokenID], f.err
+}
+
+func testConfig() *config.Config {
+	cfg := config.Default()
+	cfg.JwtSecretKey = []byte("test-secret-key-with-at-least-32-bytes")
+	return cfg
+}
+
+// signToken signs claims with method and key, for tokens GenerateToken would never produce
+func signToken(t *testing.T, method jwt.SigningMethod, key any, claims *entity.Claimes) string {
+	t.Helper()
+	token, err := jwt.NewWithClaims(method, claims).SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+func newAuthRouter(cfg *config.Config, revocations TokenRevocationChecker, extra ...gin.HandlerFunc) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+
+	handlers := append([]gin.HandlerFunc{AuthMiddleware(cfg, revocations)}, extra...)
+	handlers = append(handlers, func(c *gin.Context) {
+		user := c.MustGet("user").(*entity.UserJwt)
+		c.String(http.StatusOK, user.Username)
+	})
+	r.GET("/protected", handlers...)
+
+	return r
+}
+
+func TestGenerateToken(t *testing.T) {
+	cfg := testConfig()
+
+	token, err := GenerateToken(&entity.UserJwt{UserId: 7, Username: "dashboard"}, cfg)
+	if err != nil {
+		t.Fatalf("GenerateToken() error = %v", err)
+	}
+
+	claims := &entity.Claimes{}
+	if _, err := jwt.ParseWithClaims(token, claims, func(*jwt.Token) (any, error) { return cfg.JwtSecretKey, nil }); err != nil {
+		t.Fatalf("parsing generated token: %v", err)
+	}
+
+	if claims.UserId != 7 || claims.Username != "dashboard" {
+		t.Errorf("claims = %+v, want user 7 dashboard", claims)
+	}
+	if len(claims.ID) != 32 {
+		t.Errorf("jti = %q, want 32 hex characters", claims.ID)
+	}
+	if ttl := time.Until(claims.ExpiresAt.Time); ttl <= cfg.Auth.TokenTTL-time.Minute || ttl > cfg.Auth.TokenTTL {
+		t.Errorf("token expires in %v, want about %v", ttl, cfg.Auth.TokenTTL)
+	}
+
+	other, _ := GenerateToken(&entity.UserJwt{UserId: 7, Username: "dashboard"}, cfg)
+	otherClaims := &entity.Claimes{}
+	jwt.ParseWithClaims(other, otherClaims, func(*jwt.Token) (any, error) { return cfg.JwtSecretKey, nil })
+	if otherClaims.ID == claims.ID {
+		t.Error("two tokens share the same jti")
+	}
+}
+
+func TestAuthMiddleware(t *testing.T) {
+	cfg := testConfig()
+	valid, err := GenerateToken(&entity.UserJwt{UserId: 1, Username: "dashboard"}, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	validClaims := &entity.Claimes{}
+	jwt.ParseWithClaims(valid, validClaims, func(*jwt.Token) (any, error) { return cfg.JwtSecretKey, nil })
+
+	expired := signToken(t, jwt.SigningMethodHS256, cfg.JwtSecretKey, &entity.Claimes{
+		Username: "dashboard",
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        "expired",
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(-time.Minute)),
+		},
+	})
+	wrongKey := signToken(t, jwt.SigningMethodHS256, []byte("another-secret-key-with-32-bytes!!"), &entity.Claimes{Username: "dashboard"})
+	unsigned := signToken(t, jwt.SigningMethodNone, jwt.UnsafeAllowNoneSignatureType, &entity.Claimes{Username: "admin"})
+
+	tests := []struct {
+		name        string
+		header      string
+		revocations *fakeRevocations
+		wantStatus  int
+	}{
+		{"valid token", "Bearer " + valid, &fakeRevocations{}, http.StatusOK},
+		{"valid token without revocation checker", "Bearer " + valid, nil, http.StatusOK},
+		{"missing header", "", &fakeRevocations{}, http.StatusUnauthorized},
+		{"missing bearer prefix", valid, &fakeRevocations{}, http.StatusUnauthorized},
+		{"lowercase bearer prefix", "bearer " + valid, &fakeRevocations{}, http.StatusUnauthorized},
+		{"malformed token", "Bearer not-a-jwt", &fakeRevocations{}, http.StatusUnauthorized},
+		{"expired token", "Bearer " + expired, &fakeRevocations{}, http.StatusUnauthorized},
+		{"wrong signing key", "Bearer " + wrongKey, &fakeRevocations{}, http.StatusUnauthorized},
+		{"unsigned token", "Bearer " + unsigned, &fakeRevocations{}, http.StatusUnauthorized},
+		{"revoked token", "Bearer " + valid, &fakeRevocations{revoked: map[string]bool{validClaims.ID: true}}, http.StatusUnauthorized},
+		{"revocation check fails", "Bearer " + valid, &fakeRevocations{err: errors.New("db down")}, http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var checker TokenRevocationChecker
+			if tt.revocations != nil {
+				checker = tt.revocations
+			}
+			r := newAuthRouter(cfg, checker)
+
+			req := httptest.NewRequest(http.MethodGet, "/protected", nil)
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d (body %s)", w.Code, tt.wantStatus, w.Body.String())
+			}
+			if tt.wantStatus == http.StatusOK && w.Body.String() != "dashboard" {
+				t.Errorf("body = %q, want the authenticated username", w.Body.String())
+			}
+		})
+	}
+}
+
+func TestAuthMiddlewareAcceptsQueryTokenOnWebSocketUpgrade(t *testing.T) {
+	cfg := testConfig()
+	token, err := GenerateToken(&entity.UserJwt{UserId: 1, Username: "dashboard"}, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		upgrade    bool
+		wantStatus int
+	}{
+		{"websocket upgrade", true, http.StatusOK},
+		{"plain request", false, http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/protected?access_token="+token, nil)
+			if tt.upgrade {
+				req.Header.Set("Connection", "keep-alive, Upgrade")
+				req.Header.Set("Upgrade", "websocket")
+			}
+			w := httptest.NewRecorder()
+			newAuthRouter(cfg, &fakeRevocations{}).ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d (body %s)", w.Code, tt.wantStatus, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestRequireAdmin(t *testing.T) {
+	cfg := testConfig()
+
+	tests := []struct {
+		name       string
+		user       entity.UserJwt
+		wantStatus int
+	}{
+		{"admin role", entity.UserJwt{UserId: 2, Username: "ops", Role: entity.RoleAdmin}, http.StatusOK},
+		{"no role", entity.UserJwt{UserId: 1, Username: "dashboard"}, http.StatusForbidden},
+		{"admin username without the role", entity.UserJwt{UserId: 1, Username: "admin"}, http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token, err := GenerateToken(&tt.user, cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			req := httptest.NewRequest(http.MethodGet, "/protected", nil)
+			req.Header.Set("Authorization", "Bearer "+token)
+			w := httptest.NewRecorder()
+			newAuthRouter(cfg, &fakeRevocations{}, RequireAdmin()).ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+		})
+	}
+}
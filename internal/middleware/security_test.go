@@ -0,0 +1,90 @@
+package middleware
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"Backend/internal/config"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestSecurityHeaders(t *testing.T) {
+	tests := []struct {
+		name     string
+		cfg      config.HTTPConfig
+		path     string
+		wantHSTS string
+		wantCSP  string
+	}{
+		{"plain HTTP has no HSTS", config.HTTPConfig{HSTSMaxAge: time.Hour}, "/api/v1/stocks", "", "default-src 'none'"},
+		{"TLS adds HSTS", config.HTTPConfig{TLSCertFile: "c", TLSKeyFile: "k", HSTSMaxAge: time.Hour}, "/api/v1/stocks", "max-age=3600; includeSubDomains", "default-src 'none'"},
+		{"swagger gets a relaxed CSP", config.HTTPConfig{}, "/swagger/index.html", "", "script-src 'self'"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gin.SetMode(gin.TestMode)
+			r := gin.New()
+			r.Use(SecurityHeaders(tt.cfg))
+			r.NoRoute(func(c *gin.Context) { c.Status(http.StatusOK) })
+
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+			if got := w.Header().Get("X-Content-Type-Options"); got != "nosniff" {
+				t.Errorf("X-Content-Type-Options = %q, want nosniff", got)
+			}
+			if got := w.Header().Get("Strict-Transport-Security"); got != tt.wantHSTS {
+				t.Errorf("Strict-Transport-Security = %q, want %q", got, tt.wantHSTS)
+			}
+			if got := w.Header().Get("Content-Security-Policy"); !strings.Contains(got, tt.wantCSP) {
+				t.Errorf("Content-Security-Policy = %q, want it to contain %q", got, tt.wantCSP)
+			}
+		})
+	}
+}
+
+func TestMaxBodySize(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(MaxBodySize(10))
+	r.POST("/", func(c *gin.Context) {
+		if _, err := io.ReadAll(c.Request.Body); err != nil {
+			c.Status(http.StatusRequestEntityTooLarge)
+			return
+		}
+		c.Status(http.StatusOK)
+	})
+
+	tests := []struct {
+		name       string
+		body       string
+		chunked    bool
+		wantStatus int
+	}{
+		{"within the limit", "0123456789", false, http.StatusOK},
+		{"declared length over the limit", "0123456789a", false, http.StatusRequestEntityTooLarge},
+		{"chunked body over the limit", "0123456789a", true, http.StatusRequestEntityTooLarge},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tt.body))
+			if tt.chunked {
+				req.ContentLength = -1
+			}
+
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+		})
+	}
+}
@@ -0,0 +1,62 @@
+package models
+
+import "time"
+
+// AlertRule is a user-defined condition on the change feed. Every condition that
+// is set must hold for a change to trigger the rule.
+type AlertRule struct {
+	ID         int64  `json:"id" db:"id"`
+	Username   string `json:"-" db:"username"`
+	Name       string `json:"name" db:"name"`
+	Ticker     string `json:"ticker,omitempty" db:"ticker"`
+	Brokerage  string `json:"brokerage,omitempty" db:"brokerage"`
+	ChangeType string `json:"change_type,omitempty" db:"change_type"` // One of the Change* types
+	Rating     string `json:"rating,omitempty" db:"rating"`           // Rating given by a new coverage or rating change
+
+	// ScoreAbove triggers when the score rises past it
+	ScoreAbove *float64 `json:"score_above,omitempty" db:"score_above"`
+
+	// TargetChangePct triggers on a target moved by at least that percentage:
+	// -20 is a cut of 20% or more, 10 a raise of 10% or more
+	TargetChangePct *float64 `json:"target_change_pct,omitempty" db:"target_change_pct"`
+
+	CooldownMinutes int       `json:"cooldown_minutes" db:"cooldown_minutes"` // 0 uses the configured cooldown
+	Enabled         bool      `json:"enabled" db:"enabled"`
+	CreatedAt       time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// AlertRuleRequest creates or replaces an alert rule. Enabled defaults to true.
+type AlertRuleRequest struct {
+	Name            string   `json:"name" binding:"required" example:"NVDA downgrades"`
+	Ticker          string   `json:"ticker,omitempty" example:"NVDA"`
+	Brokerage       string   `json:"brokerage,omitempty"`
+	ChangeType      string   `json:"change_type,omitempty" example:"downgrade"`
+	Rating          string   `json:"rating,omitempty"`
+	ScoreAbove      *float64 `json:"score_above,omitempty"`
+	TargetChangePct *float64 `json:"target_change_pct,omitempty"`
+	CooldownMinutes int      `json:"cooldown_minutes,omitempty"`
+	Enabled         *bool    `json:"enabled,omitempty"`
+}
+
+// Alert records a change of the feed that triggered a rule
+type Alert struct {
+	ID          int64     `json:"id" db:"id"`
+	RuleID      int64     `json:"rule_id" db:"rule_id"`
+	RuleName    string    `json:"rule_name" db:"rule_name"`
+	Username    string    `json:"-" db:"username"`
+	ChangeID    int64     `json:"change_id" db:"change_id"`
+	ChangeType  string    `json:"change_type" db:"change_type"`
+	Ticker      string    `json:"ticker" db:"ticker"`
+	Brokerage   string    `json:"brokerage" db:"brokerage"`
+	Message     string    `json:"message" db:"message"`
+	TriggeredAt time.Time `json:"triggered_at" db:"triggered_at"`
+}
+
+// AlertFilters selects a user's alert history, newest first
+type AlertFilters struct {
+	Username string `json:"-" form:"-"`
+	RuleID   int64  `json:"rule_id" form:"rule_id"`
+	Before   int64  `json:"before" form:"before"` // Only alerts with a lower ID, to page back
+	Limit    int    `json:"limit" form:"limit"`
+}
@@ -0,0 +1,31 @@
+package models
+
+import (
+	"time"
+)
+
+// Authentication event types recorded in the auth_audit table
+const (
+	AuthEventLoginSuccess = "login_success"
+	AuthEventLoginFailure = "login_failure"
+	AuthEventLoginLocked  = "login_locked"
+	AuthEventTokenRefresh = "token_refresh"
+	AuthEventTokenRevoke  = "token_revoke"
+)
+
+type AuthEvent struct {
+	ID        int64     `json:"id" db:"id"`
+	Event     string    `json:"event" db:"event"`
+	Username  string    `json:"username" db:"username"`
+	IP        string    `json:"ip" db:"ip"`
+	Success   bool      `json:"success" db:"success"`
+	Detail    string    `json:"detail,omitempty" db:"detail"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
+
+type AuthAuditFilters struct {
+	Username string `json:"username" form:"username"`
+	Event    string `json:"event" form:"event"`
+	IP       string `json:"ip" form:"ip"`
+	Limit    int    `json:"limit" form:"limit"`
+}
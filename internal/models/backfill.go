@@ -0,0 +1,40 @@
+package models
+
+import (
+	"time"
+)
+
+// Backfill job statuses stored in the backfill_jobs table
+const (
+	BackfillStatusRunning   = "running"
+	BackfillStatusPaused    = "paused"
+	BackfillStatusCompleted = "completed"
+	BackfillStatusFailed    = "failed"
+)
+
+// BackfillJob is a historical backfill of the events between From (inclusive) and
+// To (exclusive). Cursor is the upstream page to fetch next, so a paused or
+// failed job resumes where it stopped.
+type BackfillJob struct {
+	ID          int64      `json:"id" db:"id"`
+	From        time.Time  `json:"from" db:"from_time"`
+	To          time.Time  `json:"to" db:"to_time"`
+	Status      string     `json:"status" db:"status"`
+	Cursor      string     `json:"cursor,omitempty" db:"cursor"`
+	Pages       int        `json:"pages" db:"pages"`             // Upstream pages fetched
+	Fetched     int        `json:"fetched" db:"fetched"`         // Records seen, in range or not
+	InRange     int        `json:"in_range" db:"in_range"`       // Records between From and To
+	Stored      int        `json:"stored" db:"stored"`           // Rows written to stocks
+	Quarantined int        `json:"quarantined" db:"quarantined"` // Records moved to quarantined_events
+	Error       string     `json:"error,omitempty" db:"error"`
+	CreatedAt   time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at" db:"updated_at"`
+	FinishedAt  *time.Time `json:"finished_at,omitempty" db:"finished_at"`
+}
+
+// BackfillRequest starts a backfill. Dates are YYYY-MM-DD or RFC 3339; a date-only
+// To includes that whole day.
+type BackfillRequest struct {
+	From string `json:"from" binding:"required" example:"2025-01-01"`
+	To   string `json:"to" binding:"required" example:"2025-01-31"`
+}
@@ -0,0 +1,43 @@
+package models
+
+import "time"
+
+// Change types stored in the stock_changes outbox
+const (
+	ChangeNewCoverage  = "new_coverage"
+	ChangeUpgrade      = "upgrade"
+	ChangeDowngrade    = "downgrade"
+	ChangeTargetChange = "target_change"
+	ChangeScoreChange  = "score_change"
+)
+
+// StockChange is an entry of the change feed, written in the same transaction as
+// the stock row it describes. Its ID is the feed cursor. For new coverage the
+// From fields are the event's own and ScoreFrom is zero; otherwise they hold the
+// values stored before the change.
+type StockChange struct {
+	ID         int64     `json:"id" db:"id"`
+	StockID    int       `json:"stock_id" db:"stock_id"`
+	Type       string    `json:"type" db:"type"`
+	Ticker     string    `json:"ticker" db:"ticker"`
+	Company    string    `json:"company" db:"company"`
+	Brokerage  string    `json:"brokerage" db:"brokerage"`
+	Action     string    `json:"action" db:"action"`
+	RatingFrom string    `json:"rating_from" db:"rating_from"`
+	RatingTo   string    `json:"rating_to" db:"rating_to"`
+	TargetFrom string    `json:"target_from" db:"target_from"`
+	TargetTo   string    `json:"target_to" db:"target_to"`
+	ScoreFrom  float64   `json:"score_from" db:"score_from"`
+	ScoreTo    float64   `json:"score_to" db:"score_to"`
+	EventTime  time.Time `json:"event_time" db:"event_time"`
+	CreatedAt  time.Time `json:"created_at" db:"created_at"`
+}
+
+// ChangeFeed is a page of the change feed. Cursor is the ID of the last change
+// returned, or the requested cursor when there is nothing new; pass it as since
+// to continue.
+type ChangeFeed struct {
+	Changes []StockChange `json:"changes"`
+	Cursor  int64         `json:"cursor"`
+	HasMore bool          `json:"has_more"`
+}
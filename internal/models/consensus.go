@@ -0,0 +1,38 @@
+package models
+
+import "time"
+
+// Consensus labels of TickerConsensus
+const (
+	ConsensusBuy  = "Buy"
+	ConsensusHold = "Hold"
+	ConsensusSell = "Sell"
+)
+
+// TickerConsensus summarizes the latest rating of each brokerage covering a
+// ticker since a given time, read from the change feed. Ratings whose label has
+// no known rank count toward Ratings only.
+type TickerConsensus struct {
+	Ticker     string            `json:"ticker"`
+	Since      time.Time         `json:"since"`
+	Ratings    int               `json:"ratings"`
+	Buy        int               `json:"buy"`
+	Hold       int               `json:"hold"`
+	Sell       int               `json:"sell"`
+	Consensus  string            `json:"consensus,omitempty"`   // by mean rank; empty when no rank is known
+	MeanRank   float64           `json:"mean_rank,omitempty"`   // 1 (most bearish) to 9 (most bullish), see RatingRank
+	MeanTarget float64           `json:"mean_target,omitempty"` // over the ratings with a price target
+	LowTarget  float64           `json:"low_target,omitempty"`
+	HighTarget float64           `json:"high_target,omitempty"`
+	Brokerages []BrokerageRating `json:"brokerages"`
+}
+
+// BrokerageRating is the latest rating of a brokerage for a ticker
+type BrokerageRating struct {
+	Brokerage string    `json:"brokerage"`
+	Company   string    `json:"company"`
+	Action    string    `json:"action"`
+	Rating    string    `json:"rating"`
+	Target    string    `json:"target"`
+	Time      time.Time `json:"time"`
+}
@@ -0,0 +1,33 @@
+package models
+
+import "time"
+
+// DigestSubscription is a user's opt-in to the email digest
+type DigestSubscription struct {
+	Username     string     `json:"-" db:"username"`
+	Email        string     `json:"email" db:"email"`
+	Enabled      bool       `json:"enabled" db:"enabled"`
+	LastChangeID int64      `json:"last_change_id" db:"last_change_id"` // Last change summarized by a digest
+	LastSentAt   *time.Time `json:"last_sent_at,omitempty" db:"last_sent_at"`
+	CreatedAt    time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+// DigestSubscriptionRequest opts in to the digest or changes its address
+type DigestSubscriptionRequest struct {
+	Email   string `json:"email" binding:"required" example:"manager@example.com"`
+	Enabled *bool  `json:"enabled,omitempty"`
+}
+
+// Digest summarizes the recommendations of the day and the changes written to
+// the feed after FromChangeID, up to and including ToChangeID
+type Digest struct {
+	Username         string        `json:"username"`
+	GeneratedAt      time.Time     `json:"generated_at"`
+	FromChangeID     int64         `json:"from_change_id"`
+	ToChangeID       int64         `json:"to_change_id"`
+	Recommendations  []Stock       `json:"recommendations"`
+	Upgrades         []StockChange `json:"upgrades"`          // Largest score increases first
+	Downgrades       []StockChange `json:"downgrades"`        // Largest score decreases first
+	WatchlistChanges []StockChange `json:"watchlist_changes"` // Changes on the user's watchlists, newest first
+}
@@ -0,0 +1,25 @@
+package models
+
+import (
+	"time"
+)
+
+// Component and overall health statuses
+const (
+	HealthStatusUp       = "up"
+	HealthStatusDegraded = "degraded"
+	HealthStatusDown     = "down"
+)
+
+type ComponentHealth struct {
+	Status    string         `json:"status"`
+	Message   string         `json:"message,omitempty"`
+	LatencyMs int64          `json:"latency_ms"`
+	Details   map[string]any `json:"details,omitempty"`
+}
+
+type HealthReport struct {
+	Status     string                     `json:"status"`
+	Timestamp  time.Time                  `json:"timestamp"`
+	Components map[string]ComponentHealth `json:"components"`
+}
@@ -0,0 +1,38 @@
+package models
+
+// Sources of stock rows
+const (
+	StockSourceAPI    = "api"
+	StockSourceImport = "import"
+)
+
+// Statuses of an imported row
+const (
+	ImportStatusImported = "imported"
+	ImportStatusValid    = "valid" // passed validation in a dry run
+	ImportStatusInvalid  = "invalid"
+)
+
+// ImportReport is the outcome of a bulk import, with one entry per data row
+type ImportReport struct {
+	Source     string      `json:"source"`
+	DryRun     bool        `json:"dry_run"`
+	Rows       int         `json:"rows"`
+	Imported   int         `json:"imported"`
+	Invalid    int         `json:"invalid"`
+	Upserted   int         `json:"upserted"`   // rows that replaced or added a stock
+	Duplicates int         `json:"duplicates"` // imported rows whose event was already recorded
+	Results    []ImportRow `json:"results"`
+}
+
+// ImportRow is the outcome of one row, identified by its line in the uploaded file.
+// Score and Reason are those computed for a valid row.
+type ImportRow struct {
+	Line    int      `json:"line"`
+	Ticker  string   `json:"ticker,omitempty"`
+	Company string   `json:"company,omitempty"`
+	Status  string   `json:"status"`
+	Errors  []string `json:"errors,omitempty"`
+	Score   float64  `json:"score,omitempty"`
+	Reason  string   `json:"reason,omitempty"`
+}
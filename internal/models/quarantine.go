@@ -0,0 +1,40 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Quarantine statuses stored in the quarantined_events table
+const (
+	QuarantineStatusPending  = "pending"
+	QuarantineStatusReplayed = "replayed"
+)
+
+// Sources of quarantined events
+const (
+	QuarantineSourceSync     = "sync"
+	QuarantineSourceBackfill = "backfill"
+)
+
+// QuarantinedEvent is an upstream record that failed validation. The same payload
+// seen again is counted instead of stored twice.
+type QuarantinedEvent struct {
+	ID          int64           `json:"id" db:"id"`
+	Source      string          `json:"source" db:"source"`
+	Ticker      string          `json:"ticker" db:"ticker"`
+	Company     string          `json:"company" db:"company"`
+	Payload     json.RawMessage `json:"payload" db:"payload" swaggertype:"object"`
+	Reason      string          `json:"reason" db:"reason"`
+	Status      string          `json:"status" db:"status"`
+	SeenCount   int             `json:"seen_count" db:"seen_count"`
+	FirstSeenAt time.Time       `json:"first_seen_at" db:"first_seen_at"`
+	LastSeenAt  time.Time       `json:"last_seen_at" db:"last_seen_at"`
+	ResolvedAt  *time.Time      `json:"resolved_at,omitempty" db:"resolved_at"`
+}
+
+type QuarantineFilters struct {
+	Status string `json:"status" form:"status"`
+	Ticker string `json:"ticker" form:"ticker"`
+	Limit  int    `json:"limit" form:"limit"`
+}
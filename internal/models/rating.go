@@ -0,0 +1,44 @@
+package models
+
+import (
+	"strconv"
+	"strings"
+)
+
+// ratingRanks orders analyst ratings from most bearish to most bullish
+var ratingRanks = map[string]int{
+	"sell":                1,
+	"strong sell":         1,
+	"underperform":        2,
+	"sector underperform": 3,
+	"underweight":         4,
+	"hold":                5,
+	"neutral":             5,
+	"equal weight":        5,
+	"market perform":      5,
+	"sector perform":      5,
+	"in-line":             5,
+	"peer perform":        5,
+	"sector weight":       5,
+	"positive":            6,
+	"outperformer":        6,
+	"outperform":          7,
+	"market outperform":   7,
+	"sector outperform":   7,
+	"overweight":          8,
+	"buy":                 8,
+	"strong-buy":          9,
+	"speculative buy":     9,
+}
+
+// RatingRank returns the position of a rating on the bearish to bullish scale,
+// ignoring case and surrounding spaces. ok is false for unknown ratings.
+func RatingRank(rating string) (rank int, ok bool) {
+	rank, ok = ratingRanks[strings.ToLower(strings.TrimSpace(rating))]
+	return rank, ok
+}
+
+// ParseTarget parses a price target such as "$1,250.50"
+func ParseTarget(target string) (float64, error) {
+	return strconv.ParseFloat(strings.ReplaceAll(strings.ReplaceAll(target, "$", ""), ",", ""), 64)
+}
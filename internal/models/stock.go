@@ -25,6 +25,10 @@ type StockFilters struct {
 	Score float64 `json:"score" form:"score"`
 	Confidence string `json:"confidence" form:"confidence"`
 	Today string `json:"today" form:"today"`
+	Source string `json:"source" form:"source"` // StockSourceAPI or StockSourceImport; empty means all
+	Tickers []string `json:"-" form:"-"` // Exact tickers to include, set by the watchlist endpoint; empty means all
+	Brokerages []string `json:"-" form:"-"` // Exact brokerages to include, set by the GraphQL loaders; empty means all
+	Offset int `json:"-" form:"-"` // Rows skipped before Limit, set by the GraphQL endpoint
 }
 
 
@@ -41,6 +45,7 @@ type Stock struct {
 	Time       time.Time `json:"time" db:"time"`
 	CreatedAt  time.Time `json:"created_at" db:"created_at"`
 	UpdatedAt  time.Time `json:"updated_at" db:"updated_at"`
+	Source     string    `json:"source,omitempty" db:"source"` // StockSourceAPI or StockSourceImport
 	Score       float64 `json:"score,omitempty"`
 	Reason      string  `json:"reason,omitempty"`
 	TargetPrice string  `json:"target_price,omitempty"`
@@ -0,0 +1,44 @@
+package models
+
+import "time"
+
+// Live stream event types
+const (
+	StreamEventChange     = "change"
+	StreamEventTopPick    = "top_pick"
+	StreamEventHeartbeat  = "heartbeat"
+	StreamEventSubscribed = "subscribed" // WebSocket only: what the client follows after a request
+	StreamEventError      = "error"      // WebSocket only: a request was rejected
+)
+
+// StreamEvent is a message pushed to live subscribers. Only change events have
+// an ID; it is the change feed cursor to resume from after a reconnect.
+type StreamEvent struct {
+	ID       int64        `json:"id,omitempty"`
+	Type     string       `json:"type"`
+	Change   *StockChange `json:"change,omitempty"`
+	TopPick  *Stock       `json:"top_pick,omitempty"` // nil when there is no recommendation
+	Watching *Watching    `json:"watching,omitempty"`
+	Error    string       `json:"error,omitempty"`
+	Time     time.Time    `json:"time"`
+}
+
+// WebSocket request actions
+const (
+	WatchActionSubscribe   = "subscribe"
+	WatchActionUnsubscribe = "unsubscribe"
+)
+
+// WatchRequest is sent by a WebSocket client to follow or stop following
+// tickers and brokerages
+type WatchRequest struct {
+	Action     string   `json:"action" example:"subscribe"`
+	Tickers    []string `json:"tickers,omitempty" example:"AAPL,MSFT"`
+	Brokerages []string `json:"brokerages,omitempty" example:"Goldman Sachs"`
+}
+
+// Watching lists the tickers and brokerages a WebSocket client follows
+type Watching struct {
+	Tickers    []string `json:"tickers"`
+	Brokerages []string `json:"brokerages"`
+}
@@ -0,0 +1,47 @@
+package models
+
+import (
+	"time"
+)
+
+// Sync run statuses stored in the sync_runs table
+const (
+	SyncStatusRunning = "running"
+	SyncStatusSuccess = "success"
+	SyncStatusFailed  = "failed"
+)
+
+type SyncRun struct {
+	ID          int64      `json:"id" db:"id"`
+	StartedAt   time.Time  `json:"started_at" db:"started_at"`
+	FinishedAt  *time.Time `json:"finished_at,omitempty" db:"finished_at"`
+	Status      string     `json:"status" db:"status"`
+	Stocks      int        `json:"stocks" db:"stocks"`
+	Rejected    int        `json:"rejected" db:"rejected"`
+	Quarantined int        `json:"quarantined" db:"quarantined"`
+	Duplicates  int        `json:"duplicates" db:"duplicates"`
+	Error       string     `json:"error,omitempty" db:"error"`
+}
+
+// SyncResult holds the counters stored when a sync run finishes
+type SyncResult struct {
+	Stocks      int // Rows fetched from the upstream API
+	Rejected    int // Rows skipped because they failed validation
+	Quarantined int // Upstream records moved to quarantined_events
+	Duplicates  int // Events already stored by an earlier sync or repeated in this one
+}
+
+// RejectedRow is a row UpsertStocks skipped, identified by its position in the input
+type RejectedRow struct {
+	Index   int    `json:"index"`
+	Ticker  string `json:"ticker"`
+	Company string `json:"company"`
+	Reason  string `json:"reason"`
+}
+
+// UpsertReport summarises a bulk upsert
+type UpsertReport struct {
+	Upserted   int           `json:"upserted"`
+	Duplicates int           `json:"duplicates"` // valid rows whose event was already recorded
+	Rejected   []RejectedRow `json:"rejected,omitempty"`
+}
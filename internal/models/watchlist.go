@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+// Watchlist is a named list of tickers owned by one user
+type Watchlist struct {
+	ID        int64     `json:"id" db:"id"`
+	Username  string    `json:"-" db:"username"`
+	Name      string    `json:"name" db:"name"`
+	Tickers   []string  `json:"tickers"` // In the order they were added
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// WatchlistRequest creates or renames a watchlist. Tickers are only read on creation.
+type WatchlistRequest struct {
+	Name    string   `json:"name" binding:"required" example:"Tech"`
+	Tickers []string `json:"tickers,omitempty" example:"AAPL,MSFT"`
+}
+
+// WatchlistTickersRequest adds tickers to a watchlist
+type WatchlistTickersRequest struct {
+	Tickers []string `json:"tickers" binding:"required" example:"NVDA"`
+}
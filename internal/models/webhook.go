@@ -0,0 +1,78 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Webhook events. Test events are only sent on request.
+const (
+	WebhookEventAlert  = "alert"
+	WebhookEventChange = "change"
+	WebhookEventTest   = "test"
+)
+
+// Delivery statuses stored in the webhook_deliveries table. A failed delivery
+// stays pending until it succeeds or runs out of attempts.
+const (
+	DeliveryStatusPending   = "pending"
+	DeliveryStatusDelivered = "delivered"
+	DeliveryStatusDead      = "dead"
+)
+
+// Webhook is an HTTP endpoint of one user that receives alerts and change events
+type Webhook struct {
+	ID        int64     `json:"id" db:"id"`
+	Username  string    `json:"-" db:"username"`
+	URL       string    `json:"url" db:"url"`
+	Events    []string  `json:"events" db:"events"`
+	Secret    string    `json:"secret,omitempty" db:"secret"` // Signs payloads; only returned on creation
+	Enabled   bool      `json:"enabled" db:"enabled"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// WebhookRequest registers a webhook. Events default to alerts and changes, and
+// a secret is generated when none is given.
+type WebhookRequest struct {
+	URL     string   `json:"url" binding:"required" example:"https://hooks.example.com/stocks"`
+	Events  []string `json:"events,omitempty" example:"alert,change"`
+	Secret  string   `json:"secret,omitempty"`
+	Enabled *bool    `json:"enabled,omitempty"`
+}
+
+// WebhookEvent is the JSON body posted to a webhook
+type WebhookEvent struct {
+	Event     string       `json:"event"`
+	Time      time.Time    `json:"time"`
+	Alert     *Alert       `json:"alert,omitempty"`
+	Change    *StockChange `json:"change,omitempty"`
+	WebhookID int64        `json:"webhook_id,omitempty"` // Set on test events
+}
+
+// WebhookDelivery is an event queued for a webhook, with the outcome of its last attempt
+type WebhookDelivery struct {
+	ID             int64           `json:"id" db:"id"`
+	WebhookID      int64           `json:"webhook_id" db:"webhook_id"`
+	Event          string          `json:"event" db:"event"`
+	Payload        json.RawMessage `json:"payload" db:"payload" swaggertype:"object"`
+	Status         string          `json:"status" db:"status"`
+	Attempts       int             `json:"attempts" db:"attempts"`
+	NextAttemptAt  *time.Time      `json:"next_attempt_at,omitempty" db:"next_attempt_at"`
+	LastStatusCode int             `json:"last_status_code,omitempty" db:"last_status_code"`
+	LastError      string          `json:"last_error,omitempty" db:"last_error"`
+	ReplayOf       *int64          `json:"replay_of,omitempty" db:"replay_of"`
+	CreatedAt      time.Time       `json:"created_at" db:"created_at"`
+	DeliveredAt    *time.Time      `json:"delivered_at,omitempty" db:"delivered_at"`
+
+	URL    string `json:"-" db:"url"`    // Endpoint of the webhook, loaded to send the delivery
+	Secret string `json:"-" db:"secret"` // Secret of the webhook, loaded to sign the delivery
+}
+
+// DeliveryFilters selects the deliveries of one of a user's webhooks, newest first
+type DeliveryFilters struct {
+	Username  string `json:"-" form:"-"`
+	WebhookID int64  `json:"-" form:"-"`
+	Status    string `json:"status" form:"status"`
+	Limit     int    `json:"limit" form:"limit"`
+}
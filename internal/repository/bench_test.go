@@ -0,0 +1,51 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"Backend/internal/models"
+	"Backend/internal/testutil"
+)
+
+// benchmarkStocks returns n distinct stocks, so every run inserts or updates n rows
+func benchmarkStocks(n int) []models.Stock {
+	now := time.Now()
+	stocks := make([]models.Stock, n)
+	for i := range stocks {
+		stocks[i] = models.Stock{
+			Ticker: fmt.Sprintf("T%06d", i), Company: fmt.Sprintf("Company %d", i), Brokerage: "Goldman Sachs",
+			Action: "upgraded by", RatingFrom: "Hold", RatingTo: "Buy", TargetFrom: "$10", TargetTo: "$12",
+			Time: now, Score: 80, Confidence: 0.8, Reason: "benchmark", CurrentRating: "Buy",
+		}
+	}
+	return stocks
+}
+
+// benchmarkUpsert reports rows/s for batches of several sizes. After the first
+// iteration every row already exists, so the numbers are mostly for updates.
+func benchmarkUpsert(b *testing.B, r StockRepository) {
+	for _, n := range []int{100, 1000, 10000} {
+		stocks := benchmarkStocks(n)
+		b.Run(fmt.Sprintf("rows=%d", n), func(b *testing.B) {
+			ctx := context.Background()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := r.UpsertStocks(ctx, stocks); err != nil {
+					b.Fatal(err)
+				}
+			}
+			b.ReportMetric(float64(n*b.N)/b.Elapsed().Seconds(), "rows/s")
+		})
+	}
+}
+
+func BenchmarkMemoryUpsertStocks(b *testing.B) {
+	benchmarkUpsert(b, NewMemoryRepository())
+}
+
+func BenchmarkPostgresUpsertStocks(b *testing.B) {
+	benchmarkUpsert(b, NewPostgresRepository(testutil.PostgresDB(b), nil))
+}
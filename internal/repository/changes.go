@@ -0,0 +1,115 @@
+package repository
+
+import (
+	"math"
+	"strings"
+
+	"Backend/internal/models"
+)
+
+// latestPerStock keeps one row per ticker and company, the way UpsertStocks
+// merges a batch: the newest event wins, the last one on ties. Rows stay in the
+// order their stock first appears.
+func latestPerStock(stocks []models.Stock) []models.Stock {
+	latest := make([]models.Stock, 0, len(stocks))
+	index := make(map[string]int, len(stocks))
+
+	for _, stock := range stocks {
+		key := stock.Ticker + "\x00" + stock.Company
+		i, ok := index[key]
+		if !ok {
+			index[key] = len(latest)
+			latest = append(latest, stock)
+			continue
+		}
+		if !stock.Time.Before(latest[i].Time) {
+			latest[i] = stock
+		}
+	}
+
+	return latest
+}
+
+// minScoreChange is the smallest score difference reported as a score change
+const minScoreChange = 0.01
+
+// detectChanges returns the change feed entries for writing current over
+// previous, which is nil for a stock that was not stored yet. A rating change is
+// an upgrade or a downgrade by rank, or by the action when a rating is unknown.
+// Every entry carries both scores, so a score change is only reported on its own
+// when neither the rating nor the target changed.
+func detectChanges(previous *models.Stock, current models.Stock) []models.StockChange {
+	change := func(changeType string) models.StockChange {
+		c := models.StockChange{
+			StockID: current.ID, Type: changeType,
+			Ticker: current.Ticker, Company: current.Company, Brokerage: current.Brokerage, Action: current.Action,
+			RatingFrom: current.RatingFrom, RatingTo: current.RatingTo,
+			TargetFrom: current.TargetFrom, TargetTo: current.TargetTo,
+			ScoreTo: current.Score, EventTime: current.Time,
+		}
+		if previous != nil {
+			c.RatingFrom, c.TargetFrom, c.ScoreFrom = previous.RatingTo, previous.TargetTo, previous.Score
+		}
+		return c
+	}
+
+	if previous == nil {
+		return []models.StockChange{change(models.ChangeNewCoverage)}
+	}
+
+	var changes []models.StockChange
+	if direction := ratingDirection(previous.RatingTo, current.RatingTo, current.Action); direction != "" {
+		changes = append(changes, change(direction))
+	}
+	if targetChanged(previous.TargetTo, current.TargetTo) {
+		changes = append(changes, change(models.ChangeTargetChange))
+	}
+	if len(changes) == 0 && math.Abs(current.Score-previous.Score) >= minScoreChange {
+		changes = append(changes, change(models.ChangeScoreChange))
+	}
+
+	return changes
+}
+
+func ratingDirection(previous, current, action string) string {
+	if strings.TrimSpace(current) == "" || strings.EqualFold(strings.TrimSpace(previous), strings.TrimSpace(current)) {
+		return ""
+	}
+
+	previousRank, ok1 := models.RatingRank(previous)
+	currentRank, ok2 := models.RatingRank(current)
+	if ok1 && ok2 {
+		switch {
+		case currentRank > previousRank:
+			return models.ChangeUpgrade
+		case currentRank < previousRank:
+			return models.ChangeDowngrade
+		default:
+			return "" // e.g. Hold to Neutral
+		}
+	}
+
+	switch strings.TrimSuffix(strings.ToLower(strings.TrimSpace(action)), " by") {
+	case "upgraded", "upgrade":
+		return models.ChangeUpgrade
+	case "downgraded", "downgrade":
+		return models.ChangeDowngrade
+	}
+	return ""
+}
+
+// targetChanged reports whether the price target moved. An event without a
+// target leaves the stored one in place, so it is not a change.
+func targetChanged(previous, current string) bool {
+	previous, current = strings.TrimSpace(previous), strings.TrimSpace(current)
+	if current == "" || previous == current {
+		return false
+	}
+
+	previousPrice, err1 := models.ParseTarget(previous)
+	currentPrice, err2 := models.ParseTarget(current)
+	if err1 != nil || err2 != nil {
+		return true
+	}
+	return math.Abs(currentPrice-previousPrice) >= 0.005
+}
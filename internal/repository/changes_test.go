@@ -0,0 +1,98 @@
+package repository
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"Backend/internal/models"
+)
+
+func TestDetectChanges(t *testing.T) {
+	at := time.Date(2025, 6, 2, 14, 30, 0, 0, time.UTC)
+	stored := models.Stock{ID: 7, Ticker: "AAPL", Company: "Apple Inc", RatingTo: "Hold", TargetTo: "$150"}
+	event := func(action, ratingTo, targetTo string) models.Stock {
+		return models.Stock{
+			ID: 7, Ticker: "AAPL", Company: "Apple Inc", Brokerage: "Goldman Sachs", Action: action,
+			RatingFrom: "Hold", RatingTo: ratingTo, TargetFrom: "$150", TargetTo: targetTo, Time: at,
+		}
+	}
+
+	tests := []struct {
+		name     string
+		previous *models.Stock
+		current  models.Stock
+		want     []string
+	}{
+		{"new coverage", nil, event("initiated by", "Buy", "$180"), []string{models.ChangeNewCoverage}},
+		{"upgrade", &stored, event("upgraded by", "Buy", "$150"), []string{models.ChangeUpgrade}},
+		{"downgrade", &stored, event("downgraded by", "Sell", "$150"), []string{models.ChangeDowngrade}},
+		{"rank decides over the action", &stored, event("upgraded by", "Underweight", "$150"), []string{models.ChangeDowngrade}},
+		{"unknown rating falls back to the action", &stored, event("upgraded by", "Top Pick", "$150"), []string{models.ChangeUpgrade}},
+		{"unknown rating with a neutral action", &stored, event("reiterated by", "Top Pick", "$150"), nil},
+		{"same rank is no change", &stored, event("reiterated by", "Neutral", "$150"), nil},
+		{"rating case is ignored", &stored, event("reiterated by", "HOLD", "$150"), nil},
+		{"target raised", &stored, event("target raised by", "Hold", "$180"), []string{models.ChangeTargetChange}},
+		{"target formatting is ignored", &stored, event("reiterated by", "Hold", "$150.00"), nil},
+		{"missing target is no change", &stored, event("reiterated by", "Hold", ""), nil},
+		{"upgrade with a new target", &stored, event("upgraded by", "Buy", "$200"), []string{models.ChangeUpgrade, models.ChangeTargetChange}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			changes := detectChanges(tt.previous, tt.current)
+
+			var types []string
+			for _, change := range changes {
+				types = append(types, change.Type)
+				if change.StockID != 7 || change.Brokerage != "Goldman Sachs" || !change.EventTime.Equal(at) {
+					t.Errorf("change = %+v, want it to describe the event", change)
+				}
+			}
+			if !reflect.DeepEqual(types, tt.want) {
+				t.Errorf("detectChanges() types = %v, want %v", types, tt.want)
+			}
+		})
+	}
+
+	t.Run("score change", func(t *testing.T) {
+		rescored := event("reiterated by", "Hold", "$150")
+		rescored.Score = 42.5
+		changes := detectChanges(&stored, rescored)
+		if len(changes) != 1 || changes[0].Type != models.ChangeScoreChange || changes[0].ScoreFrom != 0 || changes[0].ScoreTo != 42.5 {
+			t.Errorf("detectChanges() = %+v, want one score change from 0 to 42.5", changes)
+		}
+
+		// The upgrade already carries the new score
+		upgraded := event("upgraded by", "Buy", "$150")
+		upgraded.Score = 70
+		if changes := detectChanges(&stored, upgraded); len(changes) != 1 || changes[0].ScoreTo != 70 {
+			t.Errorf("detectChanges() = %+v, want only the upgrade, with the new score", changes)
+		}
+	})
+
+	t.Run("from values are the stored ones", func(t *testing.T) {
+		changes := detectChanges(&stored, event("upgraded by", "Buy", "$200"))
+		if changes[0].RatingFrom != "Hold" || changes[0].RatingTo != "Buy" || changes[0].TargetFrom != "$150" || changes[0].TargetTo != "$200" {
+			t.Errorf("change = %+v, want Hold to Buy and $150 to $200", changes[0])
+		}
+	})
+}
+
+func TestLatestPerStock(t *testing.T) {
+	at := time.Now()
+	stock := func(ticker, rating string, when time.Time) models.Stock {
+		return models.Stock{Ticker: ticker, Company: ticker + " Inc", RatingTo: rating, Time: when}
+	}
+
+	got := latestPerStock([]models.Stock{
+		stock("AAA", "Hold", at),
+		stock("BBB", "Buy", at),
+		stock("AAA", "Sell", at.Add(-time.Hour)), // older, ignored
+		stock("AAA", "Buy", at),                  // same time, the last one wins
+	})
+
+	if len(got) != 2 || got[0].Ticker != "AAA" || got[0].RatingTo != "Buy" || got[1].Ticker != "BBB" {
+		t.Errorf("latestPerStock() = %+v, want AAA Buy then BBB", got)
+	}
+}
@@ -0,0 +1,30 @@
+package repository
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"time"
+
+	"Backend/internal/models"
+)
+
+// EventHash identifies an analyst action independently of the stock row it
+// updates: the same ticker, brokerage, action, ratings, targets and time always
+// hash the same, so an event the upstream resends is recognised as a duplicate.
+// The company is left out; a corrected company name is still the same action.
+func EventHash(stock models.Stock) string {
+	key := strings.Join([]string{
+		stock.Ticker,
+		stock.Brokerage,
+		stock.Action,
+		stock.RatingFrom,
+		stock.RatingTo,
+		stock.TargetFrom,
+		stock.TargetTo,
+		stock.Time.UTC().Format(time.RFC3339Nano),
+	}, "\x1f")
+
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,48 @@
+package repository
+
+import (
+	"testing"
+	"time"
+
+	"Backend/internal/models"
+)
+
+func TestEventHash(t *testing.T) {
+	at := time.Date(2025, 6, 2, 14, 30, 0, 0, time.UTC)
+	event := models.Stock{
+		Ticker: "AAPL", Company: "Apple Inc", Brokerage: "Goldman Sachs", Action: "upgraded by",
+		RatingFrom: "Hold", RatingTo: "Buy", TargetFrom: "$150", TargetTo: "$180", Time: at,
+	}
+	base := EventHash(event)
+
+	if len(base) != 64 {
+		t.Errorf("EventHash() = %q, want 64 hex characters", base)
+	}
+
+	tests := []struct {
+		name   string
+		modify func(s *models.Stock)
+		same   bool
+	}{
+		{"derived fields are ignored", func(s *models.Stock) { s.Score, s.Reason, s.ID = 90, "strong", 7 }, true},
+		{"company is ignored", func(s *models.Stock) { s.Company = "Apple Inc." }, true},
+		{"time zone is ignored", func(s *models.Stock) { s.Time = at.In(time.FixedZone("EST", -5*3600)) }, true},
+		{"ticker", func(s *models.Stock) { s.Ticker = "MSFT" }, false},
+		{"brokerage", func(s *models.Stock) { s.Brokerage = "Morgan Stanley" }, false},
+		{"action", func(s *models.Stock) { s.Action = "downgraded by" }, false},
+		{"rating", func(s *models.Stock) { s.RatingTo = "Strong-Buy" }, false},
+		{"target", func(s *models.Stock) { s.TargetTo = "$190" }, false},
+		{"time", func(s *models.Stock) { s.Time = at.Add(time.Second) }, false},
+		{"fields do not run together", func(s *models.Stock) { s.RatingFrom, s.RatingTo = "HoldB", "uy" }, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := event
+			tt.modify(&s)
+			if got := EventHash(s) == base; got != tt.same {
+				t.Errorf("EventHash() equal = %v, want %v", got, tt.same)
+			}
+		})
+	}
+}
@@ -0,0 +1,12 @@
+package repository
+
+import (
+	"os"
+	"testing"
+
+	"Backend/internal/testutil"
+)
+
+func TestMain(m *testing.M) {
+	os.Exit(testutil.RunWithPostgres(m))
+}
@@ -0,0 +1,1202 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"Backend/internal/models"
+)
+
+// MemoryRepository implements the repositories of this package in memory.
+// It mirrors the PostgreSQL semantics and is meant for tests and local runs.
+type MemoryRepository struct {
+	// Now returns the current time; dates are compared in UTC like CURRENT_DATE
+	// on a UTC database. Defaults to time.Now.
+	Now func() time.Time
+
+	mu     sync.RWMutex
+	stocks []models.Stock
+	index  map[string]int
+	runs   []models.SyncRun
+	events map[string]struct{}
+	// ratings are the events in the order recorded, like the stock_events rows
+	ratings []models.Stock
+
+	changes []models.StockChange
+
+	quarantine   []models.QuarantinedEvent
+	fingerprints map[string]int
+
+	backfills     []models.BackfillJob
+	backfillLocks map[int64]bool
+
+	watchlists    map[int64]*models.Watchlist
+	lastWatchlist int64
+
+	alertRules    map[int64]*models.AlertRule
+	lastAlertRule int64
+	alerts        []models.Alert
+	lastAlert     int64
+	alertCursor   *int64
+
+	webhooks       map[int64]*models.Webhook
+	lastWebhook    int64
+	deliveries     []models.WebhookDelivery
+	lastDelivery   int64
+	webhookCursors map[string]int64
+
+	digests map[string]*models.DigestSubscription
+}
+
+// NewMemoryRepository creates an empty MemoryRepository
+func NewMemoryRepository() *MemoryRepository {
+	return &MemoryRepository{
+		Now:            time.Now,
+		index:          make(map[string]int),
+		events:         make(map[string]struct{}),
+		fingerprints:   make(map[string]int),
+		backfillLocks:  make(map[int64]bool),
+		watchlists:     make(map[int64]*models.Watchlist),
+		alertRules:     make(map[int64]*models.AlertRule),
+		webhooks:       make(map[int64]*models.Webhook),
+		webhookCursors: make(map[string]int64),
+		digests:        make(map[string]*models.DigestSubscription),
+	}
+}
+
+// ListStocks implements StockRepository
+func (r *MemoryRepository) ListStocks(ctx context.Context, filters models.StockFilters) ([]models.Stock, error) {
+	less, err := stockOrder(filters.SortBy, filters.Order)
+	if err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	today := dateOf(r.Now())
+	yesterday := today.AddDate(0, 0, -1)
+	hasToday := false
+	brokerages := make(map[string]struct{})
+	for _, stock := range r.stocks {
+		brokerages[stock.Brokerage] = struct{}{}
+		if dateOf(stock.Time).Equal(today) {
+			hasToday = true
+		}
+	}
+
+	var matched []models.Stock
+	for _, stock := range r.stocks {
+		if !containsFold(stock.Ticker, filters.Ticker) ||
+			!containsFold(stock.Company, filters.Company) ||
+			!containsFold(stock.Brokerage, filters.Brokerage) {
+			continue
+		}
+		if filters.ProductID != 0 && stock.ID != filters.ProductID {
+			continue
+		}
+		if len(filters.Tickers) > 0 && !slices.Contains(filters.Tickers, stock.Ticker) {
+			continue
+		}
+		if len(filters.Brokerages) > 0 && !slices.Contains(filters.Brokerages, stock.Brokerage) {
+			continue
+		}
+		if filters.Score > 0 && stock.Score < filters.Score {
+			continue
+		}
+		if filters.Source != "" && stock.Source != filters.Source {
+			continue
+		}
+		if filters.Today == "true" {
+			day := dateOf(stock.Time)
+			if !day.Equal(today) && (hasToday || !day.Equal(yesterday)) {
+				continue
+			}
+		}
+		matched = append(matched, stock)
+	}
+
+	// Aggregates are computed before the limit, like the window functions in SQL
+	var buyCount int
+	var lastUpdate time.Time
+	for _, stock := range matched {
+		if stock.RatingTo == "Buy" {
+			buyCount++
+		}
+		if stock.UpdatedAt.After(lastUpdate) {
+			lastUpdate = stock.UpdatedAt
+		}
+	}
+	for i := range matched {
+		matched[i].TotalRegister = len(matched)
+		matched[i].BuyCount = buyCount
+		matched[i].TotalBrokerages = len(brokerages)
+		matched[i].LastUpdateFilter = lastUpdate
+	}
+
+	sort.SliceStable(matched, func(i, j int) bool { return less(matched[i], matched[j]) })
+	if filters.Offset > 0 {
+		matched = matched[min(filters.Offset, len(matched)):]
+	}
+	if filters.Limit > 0 && len(matched) > filters.Limit {
+		matched = matched[:filters.Limit]
+	}
+
+	return matched, nil
+}
+
+// EachStock implements StockRepository
+func (r *MemoryRepository) EachStock(ctx context.Context, filters models.StockFilters, fn func(models.Stock) error) error {
+	stocks, err := r.ListStocks(ctx, filters)
+	if err != nil {
+		return err
+	}
+
+	for _, stock := range stocks {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		stock.TotalRegister, stock.BuyCount, stock.TotalBrokerages, stock.LastUpdateFilter = 0, 0, 0, time.Time{}
+		if err := fn(stock); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Recommendations implements StockRepository
+func (r *MemoryRepository) Recommendations(ctx context.Context, minScore float64, limit int) ([]models.Stock, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	today := dateOf(r.Now())
+	yesterday := today.AddDate(0, 0, -1)
+
+	var todays, yesterdays []models.Stock
+	for _, stock := range r.stocks {
+		if stock.Score <= minScore {
+			continue
+		}
+		switch day := dateOf(stock.Time); {
+		case day.Equal(today):
+			todays = append(todays, stock)
+		case day.Equal(yesterday):
+			yesterdays = append(yesterdays, stock)
+		}
+	}
+
+	recommendations := todays
+	if len(recommendations) == 0 {
+		recommendations = yesterdays
+	}
+
+	sort.SliceStable(recommendations, func(i, j int) bool {
+		a, b := recommendations[i], recommendations[j]
+		if a.Confidence != b.Confidence {
+			return a.Confidence > b.Confidence
+		}
+		if a.Score != b.Score {
+			return a.Score > b.Score
+		}
+		return a.Time.After(b.Time)
+	})
+	if len(recommendations) > limit {
+		recommendations = recommendations[:limit]
+	}
+
+	return recommendations, nil
+}
+
+// UpsertStocks implements StockRepository. Like the PostgreSQL upsert, an update
+// keeps the row's ID and brokerage and never replaces a newer event, events seen
+// before are counted as duplicates and the changes written are appended to the
+// change feed.
+func (r *MemoryRepository) UpsertStocks(ctx context.Context, stocks []models.Stock) (models.UpsertReport, error) {
+	return r.upsertStocks(stocks, true)
+}
+
+// UpsertHistoricalStocks implements StockRepository
+func (r *MemoryRepository) UpsertHistoricalStocks(ctx context.Context, stocks []models.Stock) (models.UpsertReport, error) {
+	return r.upsertStocks(stocks, false)
+}
+
+// upsertStocks merges stocks, appending their changes to the feed when feed is set
+func (r *MemoryRepository) upsertStocks(stocks []models.Stock, feed bool) (models.UpsertReport, error) {
+	valid, rejected := partitionStocks(stocks)
+	report := models.UpsertReport{Rejected: rejected}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, stock := range valid {
+		hash := EventHash(stock)
+		if _, ok := r.events[hash]; ok {
+			report.Duplicates++
+			continue
+		}
+		r.events[hash] = struct{}{}
+		r.ratings = append(r.ratings, stock)
+	}
+
+	now := r.Now()
+	for _, stock := range latestPerStock(valid) {
+		key := stock.Ticker + "\x00" + stock.Company
+
+		i, exists := r.index[key]
+		switch {
+		case !exists:
+			stock.ID = len(r.stocks) + 1
+			if feed {
+				r.appendChanges(detectChanges(nil, stock))
+			}
+			r.index[key] = len(r.stocks)
+			r.stocks = append(r.stocks, stock)
+		case r.stocks[i].Time.After(stock.Time):
+			continue
+		default:
+			previous := r.stocks[i]
+			stock.ID = previous.ID
+			if feed {
+				r.appendChanges(detectChanges(&previous, stock))
+			}
+			stock.Brokerage = previous.Brokerage
+			stock.UpdatedAt = now
+			r.stocks[i] = stock
+		}
+		report.Upserted++
+	}
+
+	return report, nil
+}
+
+func (r *MemoryRepository) appendChanges(changes []models.StockChange) {
+	for _, change := range changes {
+		change.ID = int64(len(r.changes) + 1)
+		change.CreatedAt = r.Now()
+		r.changes = append(r.changes, change)
+	}
+}
+
+// ListChanges implements ChangeRepository
+func (r *MemoryRepository) ListChanges(ctx context.Context, since int64, limit int) ([]models.StockChange, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	changes := []models.StockChange{}
+	for _, change := range r.changes {
+		if change.ID > since && len(changes) < limit {
+			changes = append(changes, change)
+		}
+	}
+
+	return changes, nil
+}
+
+// LastChangeID implements ChangeRepository
+func (r *MemoryRepository) LastChangeID(ctx context.Context) (int64, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return int64(len(r.changes)), nil
+}
+
+// LatestRatings implements ChangeRepository
+func (r *MemoryRepository) LatestRatings(ctx context.Context, ticker string, since time.Time) ([]models.BrokerageRating, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	latest := make(map[string]models.Stock)
+	for _, event := range r.ratings {
+		if event.Ticker != ticker || event.Brokerage == "" || event.Time.Before(since) {
+			continue
+		}
+		// Events are in the order recorded, so a later one wins a tie on time
+		if prev, ok := latest[event.Brokerage]; !ok || !event.Time.Before(prev.Time) {
+			latest[event.Brokerage] = event
+		}
+	}
+
+	ratings := []models.BrokerageRating{}
+	for _, event := range latest {
+		ratings = append(ratings, models.BrokerageRating{
+			Brokerage: event.Brokerage,
+			Company:   event.Company,
+			Action:    event.Action,
+			Rating:    event.RatingTo,
+			Target:    event.TargetTo,
+			Time:      event.Time,
+		})
+	}
+	sort.Slice(ratings, func(i, j int) bool {
+		if !ratings[i].Time.Equal(ratings[j].Time) {
+			return ratings[i].Time.After(ratings[j].Time)
+		}
+		return ratings[i].Brokerage < ratings[j].Brokerage
+	})
+
+	return ratings, nil
+}
+
+// StartSyncRun implements SyncRunRepository
+func (r *MemoryRepository) StartSyncRun(ctx context.Context) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	run := models.SyncRun{
+		ID:        int64(len(r.runs) + 1),
+		StartedAt: r.Now(),
+		Status:    models.SyncStatusRunning,
+	}
+	r.runs = append(r.runs, run)
+
+	return run.ID, nil
+}
+
+// FinishSyncRun implements SyncRunRepository
+func (r *MemoryRepository) FinishSyncRun(ctx context.Context, id int64, result models.SyncResult, runErr error) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if id < 1 || id > int64(len(r.runs)) {
+		return fmt.Errorf("error finishing sync run: run %d not found", id)
+	}
+
+	run := &r.runs[id-1]
+	finishedAt := r.Now()
+	run.FinishedAt = &finishedAt
+	run.Stocks = result.Stocks
+	run.Rejected = result.Rejected
+	run.Quarantined = result.Quarantined
+	run.Duplicates = result.Duplicates
+	run.Status = models.SyncStatusSuccess
+	if runErr != nil {
+		run.Status = models.SyncStatusFailed
+		run.Error = runErr.Error()
+	}
+
+	return nil
+}
+
+// LastSyncRun implements SyncRunRepository
+func (r *MemoryRepository) LastSyncRun(ctx context.Context) (*models.SyncRun, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if len(r.runs) == 0 {
+		return nil, nil
+	}
+
+	run := r.runs[len(r.runs)-1]
+	return &run, nil
+}
+
+// QuarantineEvents implements QuarantineRepository
+func (r *MemoryRepository) QuarantineEvents(ctx context.Context, events []models.QuarantinedEvent) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := r.Now()
+	for _, event := range events {
+		fingerprint := payloadFingerprint(event.Payload)
+		if i, ok := r.fingerprints[fingerprint]; ok {
+			r.quarantine[i].SeenCount++
+			r.quarantine[i].LastSeenAt = now
+			continue
+		}
+
+		event.ID = int64(len(r.quarantine) + 1)
+		event.Status = models.QuarantineStatusPending
+		event.SeenCount = 1
+		event.FirstSeenAt = now
+		event.LastSeenAt = now
+		event.ResolvedAt = nil
+		r.fingerprints[fingerprint] = len(r.quarantine)
+		r.quarantine = append(r.quarantine, event)
+	}
+
+	return nil
+}
+
+// ListQuarantined implements QuarantineRepository
+func (r *MemoryRepository) ListQuarantined(ctx context.Context, filters models.QuarantineFilters) ([]models.QuarantinedEvent, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	events := []models.QuarantinedEvent{}
+	for _, event := range r.quarantine {
+		if filters.Status != "" && event.Status != filters.Status {
+			continue
+		}
+		if filters.Ticker != "" && event.Ticker != filters.Ticker {
+			continue
+		}
+		events = append(events, event)
+	}
+
+	sort.SliceStable(events, func(i, j int) bool {
+		if !events[i].LastSeenAt.Equal(events[j].LastSeenAt) {
+			return events[i].LastSeenAt.After(events[j].LastSeenAt)
+		}
+		return events[i].ID > events[j].ID
+	})
+	if limit := quarantineLimit(filters.Limit); len(events) > limit {
+		events = events[:limit]
+	}
+
+	return events, nil
+}
+
+// GetQuarantined implements QuarantineRepository
+func (r *MemoryRepository) GetQuarantined(ctx context.Context, id int64) (*models.QuarantinedEvent, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if id < 1 || id > int64(len(r.quarantine)) {
+		return nil, nil
+	}
+
+	event := r.quarantine[id-1]
+	return &event, nil
+}
+
+// ClaimQuarantined implements QuarantineRepository
+func (r *MemoryRepository) ClaimQuarantined(ctx context.Context, id int64, status string) (*models.QuarantinedEvent, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if id < 1 || id > int64(len(r.quarantine)) || r.quarantine[id-1].Status != models.QuarantineStatusPending {
+		return nil, nil
+	}
+
+	resolvedAt := r.Now()
+	r.quarantine[id-1].Status = status
+	r.quarantine[id-1].ResolvedAt = &resolvedAt
+
+	event := r.quarantine[id-1]
+	return &event, nil
+}
+
+// ReopenQuarantined implements QuarantineRepository
+func (r *MemoryRepository) ReopenQuarantined(ctx context.Context, id int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if id < 1 || id > int64(len(r.quarantine)) {
+		return fmt.Errorf("error reopening quarantined event: event %d not found", id)
+	}
+
+	r.quarantine[id-1].Status = models.QuarantineStatusPending
+	r.quarantine[id-1].ResolvedAt = nil
+
+	return nil
+}
+
+// CreateBackfillJob implements BackfillRepository
+func (r *MemoryRepository) CreateBackfillJob(ctx context.Context, job *models.BackfillJob) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	job.ID = int64(len(r.backfills) + 1)
+	job.CreatedAt = r.Now()
+	job.UpdatedAt = job.CreatedAt
+	r.backfills = append(r.backfills, *job)
+
+	return nil
+}
+
+// UpdateBackfillJob implements BackfillRepository
+func (r *MemoryRepository) UpdateBackfillJob(ctx context.Context, job *models.BackfillJob) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if job.ID < 1 || job.ID > int64(len(r.backfills)) {
+		return fmt.Errorf("error updating backfill job: job %d not found", job.ID)
+	}
+
+	// From, To and CreatedAt are fixed when the job is created
+	stored := &r.backfills[job.ID-1]
+	updated := *job
+	updated.From, updated.To, updated.CreatedAt = stored.From, stored.To, stored.CreatedAt
+	updated.UpdatedAt = r.Now()
+	*stored = updated
+	job.UpdatedAt = updated.UpdatedAt
+
+	return nil
+}
+
+// GetBackfillJob implements BackfillRepository
+func (r *MemoryRepository) GetBackfillJob(ctx context.Context, id int64) (*models.BackfillJob, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if id < 1 || id > int64(len(r.backfills)) {
+		return nil, nil
+	}
+
+	job := r.backfills[id-1]
+	return &job, nil
+}
+
+// ListBackfillJobs implements BackfillRepository
+func (r *MemoryRepository) ListBackfillJobs(ctx context.Context, limit int) ([]models.BackfillJob, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	jobs := []models.BackfillJob{}
+	for i := len(r.backfills) - 1; i >= 0 && len(jobs) < limit; i-- {
+		jobs = append(jobs, r.backfills[i])
+	}
+
+	return jobs, nil
+}
+
+// LockBackfillJob implements BackfillRepository
+func (r *MemoryRepository) LockBackfillJob(ctx context.Context, id int64) (func(), bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.backfillLocks[id] {
+		return nil, false, nil
+	}
+	r.backfillLocks[id] = true
+
+	return func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		delete(r.backfillLocks, id)
+	}, true, nil
+}
+
+// CreateWatchlist implements WatchlistRepository
+func (r *MemoryRepository) CreateWatchlist(ctx context.Context, list *models.Watchlist) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.watchlistNamed(list.Username, list.Name) != nil {
+		return ErrWatchlistNameTaken
+	}
+
+	r.lastWatchlist++
+	list.ID = r.lastWatchlist
+	list.CreatedAt = r.Now()
+	list.UpdatedAt = list.CreatedAt
+	list.Tickers = appendNew([]string{}, list.Tickers)
+
+	stored := *list
+	stored.Tickers = slices.Clone(list.Tickers)
+	r.watchlists[list.ID] = &stored
+
+	return nil
+}
+
+// GetWatchlist implements WatchlistRepository
+func (r *MemoryRepository) GetWatchlist(ctx context.Context, username string, id int64) (*models.Watchlist, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	list := r.ownedWatchlist(username, id)
+	if list == nil {
+		return nil, nil
+	}
+	copied := *list
+	copied.Tickers = slices.Clone(list.Tickers)
+	return &copied, nil
+}
+
+// ListWatchlists implements WatchlistRepository
+func (r *MemoryRepository) ListWatchlists(ctx context.Context, username string) ([]models.Watchlist, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	lists := []models.Watchlist{}
+	for _, list := range r.watchlists {
+		if list.Username == username {
+			copied := *list
+			copied.Tickers = slices.Clone(list.Tickers)
+			lists = append(lists, copied)
+		}
+	}
+	sort.Slice(lists, func(i, j int) bool {
+		if lists[i].Name != lists[j].Name {
+			return lists[i].Name < lists[j].Name
+		}
+		return lists[i].ID < lists[j].ID
+	})
+
+	return lists, nil
+}
+
+// RenameWatchlist implements WatchlistRepository
+func (r *MemoryRepository) RenameWatchlist(ctx context.Context, username string, id int64, name string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	list := r.ownedWatchlist(username, id)
+	if list == nil {
+		return false, nil
+	}
+	if other := r.watchlistNamed(username, name); other != nil && other != list {
+		return true, ErrWatchlistNameTaken
+	}
+
+	list.Name = name
+	list.UpdatedAt = r.Now()
+	return true, nil
+}
+
+// DeleteWatchlist implements WatchlistRepository
+func (r *MemoryRepository) DeleteWatchlist(ctx context.Context, username string, id int64) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.ownedWatchlist(username, id) == nil {
+		return false, nil
+	}
+	delete(r.watchlists, id)
+	return true, nil
+}
+
+// AddWatchlistTickers implements WatchlistRepository
+func (r *MemoryRepository) AddWatchlistTickers(ctx context.Context, username string, id int64, tickers []string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	list := r.ownedWatchlist(username, id)
+	if list == nil {
+		return false, nil
+	}
+	list.Tickers = appendNew(list.Tickers, tickers)
+	list.UpdatedAt = r.Now()
+	return true, nil
+}
+
+// RemoveWatchlistTicker implements WatchlistRepository
+func (r *MemoryRepository) RemoveWatchlistTicker(ctx context.Context, username string, id int64, ticker string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	list := r.ownedWatchlist(username, id)
+	if list == nil {
+		return false, nil
+	}
+	list.Tickers = slices.DeleteFunc(list.Tickers, func(t string) bool { return t == ticker })
+	list.UpdatedAt = r.Now()
+	return true, nil
+}
+
+func (r *MemoryRepository) ownedWatchlist(username string, id int64) *models.Watchlist {
+	if list, ok := r.watchlists[id]; ok && list.Username == username {
+		return list
+	}
+	return nil
+}
+
+func (r *MemoryRepository) watchlistNamed(username, name string) *models.Watchlist {
+	for _, list := range r.watchlists {
+		if list.Username == username && list.Name == name {
+			return list
+		}
+	}
+	return nil
+}
+
+// appendNew appends the values not already in values, like the watchlist_items unique key
+func appendNew(values, added []string) []string {
+	for _, value := range added {
+		if !slices.Contains(values, value) {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+// CreateAlertRule implements AlertRepository
+func (r *MemoryRepository) CreateAlertRule(ctx context.Context, rule *models.AlertRule) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.lastAlertRule++
+	rule.ID = r.lastAlertRule
+	rule.CreatedAt = r.Now()
+	rule.UpdatedAt = rule.CreatedAt
+
+	stored := *rule
+	r.alertRules[rule.ID] = &stored
+	return nil
+}
+
+// GetAlertRule implements AlertRepository
+func (r *MemoryRepository) GetAlertRule(ctx context.Context, username string, id int64) (*models.AlertRule, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	rule, ok := r.alertRules[id]
+	if !ok || rule.Username != username {
+		return nil, nil
+	}
+	copied := *rule
+	return &copied, nil
+}
+
+// ListAlertRules implements AlertRepository
+func (r *MemoryRepository) ListAlertRules(ctx context.Context, username string) ([]models.AlertRule, error) {
+	return r.alertRulesWhere(func(rule *models.AlertRule) bool { return rule.Username == username }), nil
+}
+
+// UpdateAlertRule implements AlertRepository
+func (r *MemoryRepository) UpdateAlertRule(ctx context.Context, rule *models.AlertRule) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, ok := r.alertRules[rule.ID]
+	if !ok || stored.Username != rule.Username {
+		return false, nil
+	}
+
+	rule.CreatedAt = stored.CreatedAt
+	rule.UpdatedAt = r.Now()
+	*stored = *rule
+	return true, nil
+}
+
+// DeleteAlertRule implements AlertRepository
+func (r *MemoryRepository) DeleteAlertRule(ctx context.Context, username string, id int64) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	rule, ok := r.alertRules[id]
+	if !ok || rule.Username != username {
+		return false, nil
+	}
+	delete(r.alertRules, id)
+	r.alerts = slices.DeleteFunc(r.alerts, func(a models.Alert) bool { return a.RuleID == id })
+	return true, nil
+}
+
+// EnabledAlertRules implements AlertRepository
+func (r *MemoryRepository) EnabledAlertRules(ctx context.Context) ([]models.AlertRule, error) {
+	return r.alertRulesWhere(func(rule *models.AlertRule) bool { return rule.Enabled }), nil
+}
+
+func (r *MemoryRepository) alertRulesWhere(keep func(rule *models.AlertRule) bool) []models.AlertRule {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	rules := []models.AlertRule{}
+	for _, rule := range r.alertRules {
+		if keep(rule) {
+			rules = append(rules, *rule)
+		}
+	}
+	sort.Slice(rules, func(i, j int) bool { return rules[i].ID < rules[j].ID })
+	return rules
+}
+
+// RecordAlert implements AlertRepository
+func (r *MemoryRepository) RecordAlert(ctx context.Context, alert *models.Alert, cooldown time.Duration) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.alertRules[alert.RuleID]; !ok {
+		return false, fmt.Errorf("alert rule %d does not exist", alert.RuleID)
+	}
+	now := r.Now()
+	for _, a := range r.alerts {
+		if a.RuleID != alert.RuleID {
+			continue
+		}
+		if a.ChangeID == alert.ChangeID || (a.Ticker == alert.Ticker && a.TriggeredAt.After(now.Add(-cooldown))) {
+			return false, nil
+		}
+	}
+
+	r.lastAlert++
+	alert.ID = r.lastAlert
+	alert.TriggeredAt = now
+	r.alerts = append(r.alerts, *alert)
+	return true, nil
+}
+
+// ListAlerts implements AlertRepository
+func (r *MemoryRepository) ListAlerts(ctx context.Context, filters models.AlertFilters) ([]models.Alert, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	alerts := []models.Alert{}
+	for i := len(r.alerts) - 1; i >= 0 && len(alerts) < filters.Limit; i-- {
+		a := r.alerts[i]
+		if a.Username != filters.Username ||
+			(filters.RuleID != 0 && a.RuleID != filters.RuleID) ||
+			(filters.Before != 0 && a.ID >= filters.Before) {
+			continue
+		}
+		alerts = append(alerts, a)
+	}
+	return alerts, nil
+}
+
+// AlertsSince implements AlertRepository
+func (r *MemoryRepository) AlertsSince(ctx context.Context, since int64, limit int) ([]models.Alert, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	alerts := []models.Alert{}
+	for _, a := range r.alerts {
+		if a.ID > since && len(alerts) < limit {
+			alerts = append(alerts, a)
+		}
+	}
+	return alerts, nil
+}
+
+// AlertCursor implements AlertRepository
+func (r *MemoryRepository) AlertCursor(ctx context.Context) (int64, bool, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if r.alertCursor == nil {
+		return 0, false, nil
+	}
+	return *r.alertCursor, true, nil
+}
+
+// SetAlertCursor implements AlertRepository
+func (r *MemoryRepository) SetAlertCursor(ctx context.Context, changeID int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.alertCursor = &changeID
+	return nil
+}
+
+// CreateWebhook implements WebhookRepository
+func (r *MemoryRepository) CreateWebhook(ctx context.Context, hook *models.Webhook) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.lastWebhook++
+	hook.ID = r.lastWebhook
+	hook.CreatedAt = r.Now()
+	hook.UpdatedAt = hook.CreatedAt
+
+	stored := *hook
+	stored.Events = slices.Clone(hook.Events)
+	r.webhooks[hook.ID] = &stored
+	return nil
+}
+
+// GetWebhook implements WebhookRepository
+func (r *MemoryRepository) GetWebhook(ctx context.Context, username string, id int64) (*models.Webhook, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	hook := r.ownedWebhook(username, id)
+	if hook == nil {
+		return nil, nil
+	}
+	copied := *hook
+	copied.Events = slices.Clone(hook.Events)
+	return &copied, nil
+}
+
+// ListWebhooks implements WebhookRepository
+func (r *MemoryRepository) ListWebhooks(ctx context.Context, username string) ([]models.Webhook, error) {
+	return r.webhooksWhere(func(hook *models.Webhook) bool { return hook.Username == username }), nil
+}
+
+// DeleteWebhook implements WebhookRepository
+func (r *MemoryRepository) DeleteWebhook(ctx context.Context, username string, id int64) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.ownedWebhook(username, id) == nil {
+		return false, nil
+	}
+	delete(r.webhooks, id)
+	r.deliveries = slices.DeleteFunc(r.deliveries, func(d models.WebhookDelivery) bool { return d.WebhookID == id })
+	return true, nil
+}
+
+// EnabledWebhooks implements WebhookRepository
+func (r *MemoryRepository) EnabledWebhooks(ctx context.Context, event string) ([]models.Webhook, error) {
+	return r.webhooksWhere(func(hook *models.Webhook) bool {
+		return hook.Enabled && slices.Contains(hook.Events, event)
+	}), nil
+}
+
+func (r *MemoryRepository) webhooksWhere(keep func(hook *models.Webhook) bool) []models.Webhook {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	hooks := []models.Webhook{}
+	for _, hook := range r.webhooks {
+		if keep(hook) {
+			copied := *hook
+			copied.Events = slices.Clone(hook.Events)
+			hooks = append(hooks, copied)
+		}
+	}
+	sort.Slice(hooks, func(i, j int) bool { return hooks[i].ID < hooks[j].ID })
+	return hooks
+}
+
+// WebhookCursor implements WebhookRepository. Cursors start at 0.
+func (r *MemoryRepository) WebhookCursor(ctx context.Context, source string) (int64, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.webhookCursors[source], nil
+}
+
+// EnqueueDeliveries implements WebhookRepository
+func (r *MemoryRepository) EnqueueDeliveries(ctx context.Context, source string, from, to int64, deliveries []models.WebhookDelivery) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.webhookCursors[source] != from {
+		return false, nil
+	}
+	for i := range deliveries {
+		if _, ok := r.webhooks[deliveries[i].WebhookID]; ok {
+			r.addDelivery(&deliveries[i])
+		}
+	}
+	r.webhookCursors[source] = to
+	return true, nil
+}
+
+// CreateDelivery implements WebhookRepository
+func (r *MemoryRepository) CreateDelivery(ctx context.Context, delivery *models.WebhookDelivery) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.webhooks[delivery.WebhookID]; !ok {
+		return fmt.Errorf("webhook %d does not exist", delivery.WebhookID)
+	}
+	r.addDelivery(delivery)
+	return nil
+}
+
+func (r *MemoryRepository) addDelivery(delivery *models.WebhookDelivery) {
+	r.lastDelivery++
+	delivery.ID = r.lastDelivery
+	delivery.CreatedAt = r.Now()
+	stored := *delivery
+	stored.URL, stored.Secret = "", ""
+	r.deliveries = append(r.deliveries, stored)
+}
+
+// ClaimDelivery implements WebhookRepository
+func (r *MemoryRepository) ClaimDelivery(ctx context.Context, now time.Time, lease time.Duration) (*models.WebhookDelivery, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	due := -1
+	for i, d := range r.deliveries {
+		if d.Status != models.DeliveryStatusPending || d.NextAttemptAt == nil || d.NextAttemptAt.After(now) {
+			continue
+		}
+		if due < 0 || d.NextAttemptAt.Before(*r.deliveries[due].NextAttemptAt) {
+			due = i
+		}
+	}
+	if due < 0 {
+		return nil, nil
+	}
+
+	until := now.Add(lease)
+	r.deliveries[due].NextAttemptAt = &until
+	return r.withWebhook(r.deliveries[due]), nil
+}
+
+// UpdateDelivery implements WebhookRepository
+func (r *MemoryRepository) UpdateDelivery(ctx context.Context, delivery *models.WebhookDelivery) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i := range r.deliveries {
+		if r.deliveries[i].ID == delivery.ID {
+			stored := *delivery
+			stored.URL, stored.Secret = "", ""
+			r.deliveries[i] = stored
+			return nil
+		}
+	}
+	return fmt.Errorf("webhook delivery %d not found", delivery.ID)
+}
+
+// GetDelivery implements WebhookRepository
+func (r *MemoryRepository) GetDelivery(ctx context.Context, username string, webhookID, id int64) (*models.WebhookDelivery, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if r.ownedWebhook(username, webhookID) == nil {
+		return nil, nil
+	}
+	for _, d := range r.deliveries {
+		if d.ID == id && d.WebhookID == webhookID {
+			return r.withWebhook(d), nil
+		}
+	}
+	return nil, nil
+}
+
+// ListDeliveries implements WebhookRepository
+func (r *MemoryRepository) ListDeliveries(ctx context.Context, filters models.DeliveryFilters) ([]models.WebhookDelivery, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	deliveries := []models.WebhookDelivery{}
+	if r.ownedWebhook(filters.Username, filters.WebhookID) == nil {
+		return deliveries, nil
+	}
+	for i := len(r.deliveries) - 1; i >= 0 && len(deliveries) < filters.Limit; i-- {
+		d := r.deliveries[i]
+		if d.WebhookID == filters.WebhookID && (filters.Status == "" || d.Status == filters.Status) {
+			deliveries = append(deliveries, d)
+		}
+	}
+	return deliveries, nil
+}
+
+func (r *MemoryRepository) ownedWebhook(username string, id int64) *models.Webhook {
+	if hook, ok := r.webhooks[id]; ok && hook.Username == username {
+		return hook
+	}
+	return nil
+}
+
+// withWebhook returns a copy of a delivery with the URL and secret of its webhook
+func (r *MemoryRepository) withWebhook(delivery models.WebhookDelivery) *models.WebhookDelivery {
+	if hook, ok := r.webhooks[delivery.WebhookID]; ok {
+		delivery.URL, delivery.Secret = hook.URL, hook.Secret
+	}
+	return &delivery
+}
+
+// stockOrder returns the comparison for the sort_by and order filters.
+// Unknown columns are rejected, as PostgreSQL would.
+func stockOrder(sortBy, order string) (func(a, b models.Stock) bool, error) {
+	if sortBy == "" {
+		sortBy = "confidence"
+	}
+
+	var less func(a, b models.Stock) bool
+	switch sortBy {
+	case "id":
+		less = func(a, b models.Stock) bool { return a.ID < b.ID }
+	case "ticker":
+		less = func(a, b models.Stock) bool { return a.Ticker < b.Ticker }
+	case "company":
+		less = func(a, b models.Stock) bool { return a.Company < b.Company }
+	case "brokerage":
+		less = func(a, b models.Stock) bool { return a.Brokerage < b.Brokerage }
+	case "score":
+		less = func(a, b models.Stock) bool { return a.Score < b.Score }
+	case "confidence":
+		less = func(a, b models.Stock) bool { return a.Confidence < b.Confidence }
+	case "time":
+		less = func(a, b models.Stock) bool { return a.Time.Before(b.Time) }
+	case "created_at":
+		less = func(a, b models.Stock) bool { return a.CreatedAt.Before(b.CreatedAt) }
+	case "updated_at":
+		less = func(a, b models.Stock) bool { return a.UpdatedAt.Before(b.UpdatedAt) }
+	default:
+		return nil, fmt.Errorf("%w %q", ErrUnknownSortColumn, sortBy)
+	}
+
+	if order == "ASC" {
+		return less, nil
+	}
+	return func(a, b models.Stock) bool { return less(b, a) }, nil
+}
+
+// dateOf truncates t to its UTC date
+func dateOf(t time.Time) time.Time {
+	y, m, d := t.UTC().Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+func containsFold(value, substr string) bool {
+	return substr == "" || strings.Contains(strings.ToLower(value), strings.ToLower(substr))
+}
+
+// GetDigestSubscription implements DigestRepository
+func (r *MemoryRepository) GetDigestSubscription(ctx context.Context, username string) (*models.DigestSubscription, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	sub, ok := r.digests[username]
+	if !ok {
+		return nil, nil
+	}
+	copied := *sub
+	return &copied, nil
+}
+
+// SaveDigestSubscription implements DigestRepository
+func (r *MemoryRepository) SaveDigestSubscription(ctx context.Context, sub *models.DigestSubscription) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := r.Now()
+	stored, ok := r.digests[sub.Username]
+	if !ok {
+		stored = &models.DigestSubscription{Username: sub.Username, LastChangeID: sub.LastChangeID, CreatedAt: now}
+		r.digests[sub.Username] = stored
+	}
+	stored.Email = sub.Email
+	stored.Enabled = sub.Enabled
+	stored.UpdatedAt = now
+
+	*sub = *stored
+	return nil
+}
+
+// DeleteDigestSubscription implements DigestRepository
+func (r *MemoryRepository) DeleteDigestSubscription(ctx context.Context, username string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	_, ok := r.digests[username]
+	delete(r.digests, username)
+	return ok, nil
+}
+
+// DueDigestSubscriptions implements DigestRepository
+func (r *MemoryRepository) DueDigestSubscriptions(ctx context.Context, due time.Time) ([]models.DigestSubscription, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	subs := []models.DigestSubscription{}
+	for _, sub := range r.digests {
+		last := sub.CreatedAt
+		if sub.LastSentAt != nil {
+			last = *sub.LastSentAt
+		}
+		if sub.Enabled && last.Before(due) {
+			subs = append(subs, *sub)
+		}
+	}
+	sort.Slice(subs, func(i, j int) bool { return subs[i].Username < subs[j].Username })
+	return subs, nil
+}
+
+// MarkDigestSent implements DigestRepository
+func (r *MemoryRepository) MarkDigestSent(ctx context.Context, username string, lastChangeID int64, sentAt time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	sub, ok := r.digests[username]
+	if !ok {
+		return fmt.Errorf("digest subscription of %s does not exist", username)
+	}
+	sub.LastChangeID = lastChangeID
+	sub.LastSentAt = &sentAt
+	return nil
+}
@@ -0,0 +1,1789 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"Backend/internal/logger"
+	"Backend/internal/metrics"
+	"Backend/internal/models"
+	"Backend/internal/tracing"
+
+	"github.com/lib/pq"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// PostgresRepository implements the repositories of this package on PostgreSQL.
+// Writes go to db; stock reads go to readDB, which is the replica when one is configured.
+type PostgresRepository struct {
+	db     *sql.DB
+	readDB *sql.DB
+	log    *slog.Logger
+}
+
+// NewPostgresRepository creates a new PostgresRepository. replica may be nil,
+// in which case reads are served by the primary.
+func NewPostgresRepository(db, replica *sql.DB) *PostgresRepository {
+	readDB := db
+	if replica != nil {
+		readDB = replica
+	}
+	return &PostgresRepository{db: db, readDB: readDB, log: logger.Component("stock_repository")}
+}
+
+// ListStocks implements StockRepository
+func (r *PostgresRepository) ListStocks(ctx context.Context, filters models.StockFilters) (_ []models.Stock, err error) {
+	defer metrics.ObserveQuery("get_stocks", time.Now(), &err)
+
+	ctx, span := tracing.StartDB(ctx, "get_stocks")
+	defer func() { tracing.End(span, err) }()
+
+	query := `
+		SELECT id, ticker, company, brokerage, action, rating_from, rating_to,
+		       target_from, target_to, time, created_at, updated_at, score, confidence, source,
+		       count(*) OVER() AS total_register,
+		       count(CASE WHEN rating_to = 'Buy' THEN 1 END) OVER() AS buy_count,
+		       (SELECT COUNT(DISTINCT brokerage) FROM stocks) AS total_brokerages,
+		       max(updated_at) OVER() AS last_update
+		FROM stocks
+	`
+
+	// The sort column is interpolated, so only the columns stockOrder knows are accepted
+	if _, err := stockOrder(filters.SortBy, filters.Order); err != nil {
+		return nil, err
+	}
+
+	where, args := stockConditions(filters)
+	query += where
+
+	if filters.Confidence != "" {
+		if strings.ToUpper(filters.Confidence) == "ASC" {
+			query += " ORDER BY confidence ASC"
+		} else if strings.ToUpper(filters.Confidence) == "DESC" {
+			query += " ORDER BY confidence DESC"
+		}
+	}
+
+	sortBy := "confidence"
+	if filters.SortBy != "" {
+		sortBy = filters.SortBy
+	}
+
+	order := "DESC"
+	if filters.Order == "ASC" || filters.Order == "DESC" {
+		order = filters.Order
+	}
+
+	query += fmt.Sprintf(" GROUP BY id, ticker, brokerage ORDER BY %s %s, id ", sortBy, order)
+
+	if filters.Limit > 0 {
+		query += fmt.Sprintf(" LIMIT %d ", filters.Limit)
+	}
+
+	if filters.Offset > 0 {
+		query += fmt.Sprintf(" OFFSET %d ", filters.Offset)
+	}
+
+	rows, err := r.readDB.QueryContext(ctx, query, args...)
+	if err != nil {
+		r.log.Error("error querying stocks", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var stocks []models.Stock
+	for rows.Next() {
+		var stock models.Stock
+		err := rows.Scan(
+			&stock.ID, &stock.Ticker, &stock.Company, &stock.Brokerage,
+			&stock.Action, &stock.RatingFrom, &stock.RatingTo,
+			&stock.TargetFrom, &stock.TargetTo, &stock.Time,
+			&stock.CreatedAt, &stock.UpdatedAt, &stock.Score, &stock.Confidence, &stock.Source,
+			&stock.TotalRegister, &stock.BuyCount, &stock.TotalBrokerages, &stock.LastUpdateFilter,
+		)
+		if err != nil {
+			return nil, err
+		}
+		stocks = append(stocks, stock)
+	}
+	span.SetAttributes(attribute.Int("db.rows", len(stocks)))
+
+	return stocks, rows.Err()
+}
+
+// EachStock implements StockRepository
+func (r *PostgresRepository) EachStock(ctx context.Context, filters models.StockFilters, fn func(models.Stock) error) (err error) {
+	defer metrics.ObserveQuery("each_stock", time.Now(), &err)
+
+	ctx, span := tracing.StartDB(ctx, "each_stock")
+	defer func() { tracing.End(span, err) }()
+
+	// The sort column is interpolated, so only the columns stockOrder knows are accepted
+	if _, err := stockOrder(filters.SortBy, filters.Order); err != nil {
+		return err
+	}
+	sortBy := "confidence"
+	if filters.SortBy != "" {
+		sortBy = filters.SortBy
+	}
+	order := "DESC"
+	if filters.Order == "ASC" {
+		order = "ASC"
+	}
+
+	where, args := stockConditions(filters)
+	query := `
+		SELECT id, ticker, company, brokerage, action, rating_from, rating_to,
+		       target_from, target_to, time, created_at, updated_at, score, confidence, source
+		FROM stocks
+	` + where + fmt.Sprintf(" ORDER BY %s %s, id", sortBy, order)
+	if filters.Limit > 0 {
+		query += fmt.Sprintf(" LIMIT %d", filters.Limit)
+	}
+	if filters.Offset > 0 {
+		query += fmt.Sprintf(" OFFSET %d", filters.Offset)
+	}
+
+	// lib/pq reads the rows from the connection as they are scanned
+	rows, err := r.readDB.QueryContext(ctx, query, args...)
+	if err != nil {
+		r.log.Error("error querying stocks", "error", err)
+		return err
+	}
+	defer rows.Close()
+
+	count := 0
+	for rows.Next() {
+		var stock models.Stock
+		err := rows.Scan(
+			&stock.ID, &stock.Ticker, &stock.Company, &stock.Brokerage,
+			&stock.Action, &stock.RatingFrom, &stock.RatingTo,
+			&stock.TargetFrom, &stock.TargetTo, &stock.Time,
+			&stock.CreatedAt, &stock.UpdatedAt, &stock.Score, &stock.Confidence, &stock.Source,
+		)
+		if err != nil {
+			return err
+		}
+		if err := fn(stock); err != nil {
+			return err
+		}
+		count++
+	}
+	span.SetAttributes(attribute.Int("db.rows", count))
+
+	return rows.Err()
+}
+
+// stockConditions builds the WHERE clause and arguments for the stock filters
+func stockConditions(filters models.StockFilters) (string, []any) {
+	query := " WHERE 1=1 "
+	args := []any{}
+	argIndex := 1
+
+	if filters.Ticker != "" {
+		query += fmt.Sprintf(" AND ticker ILIKE $%d", argIndex)
+		args = append(args, "%"+filters.Ticker+"%")
+		argIndex++
+	}
+
+	if filters.Company != "" {
+		query += fmt.Sprintf(" AND company ILIKE $%d", argIndex)
+		args = append(args, "%"+filters.Company+"%")
+		argIndex++
+	}
+
+	if filters.Brokerage != "" {
+		query += fmt.Sprintf(" AND brokerage ILIKE $%d", argIndex)
+		args = append(args, "%"+filters.Brokerage+"%")
+		argIndex++
+	}
+
+	if filters.ProductID != 0 {
+		query += fmt.Sprintf(" AND id = $%d", argIndex)
+		args = append(args, filters.ProductID)
+		argIndex++
+	}
+
+	if len(filters.Tickers) > 0 {
+		query += fmt.Sprintf(" AND ticker = ANY($%d)", argIndex)
+		args = append(args, pq.Array(filters.Tickers))
+		argIndex++
+	}
+
+	if len(filters.Brokerages) > 0 {
+		query += fmt.Sprintf(" AND brokerage = ANY($%d)", argIndex)
+		args = append(args, pq.Array(filters.Brokerages))
+		argIndex++
+	}
+
+	if filters.Score > 0 {
+		query += fmt.Sprintf(" AND score >= $%d", argIndex)
+		args = append(args, filters.Score)
+		argIndex++
+	}
+
+	if filters.Source != "" {
+		query += fmt.Sprintf(" AND source = $%d", argIndex)
+		args = append(args, filters.Source)
+		argIndex++
+	}
+
+	if filters.Today == "true" {
+		query += fmt.Sprintf(` AND (
+				DATE(time) = CURRENT_DATE
+				OR (
+						DATE(time) = CURRENT_DATE - INTERVAL '1 day'
+						AND NOT EXISTS (
+								SELECT 1 FROM stocks
+								WHERE DATE(time) = CURRENT_DATE
+						)
+				)
+		)`)
+		argIndex++
+	}
+
+	return query, args
+}
+
+// Recommendations implements StockRepository
+func (r *PostgresRepository) Recommendations(ctx context.Context, minScore float64, limit int) (_ []models.Stock, err error) {
+	defer metrics.ObserveQuery("get_recommendations", time.Now(), &err)
+
+	ctx, span := tracing.StartDB(ctx, "get_recommendations")
+	defer func() { tracing.End(span, err) }()
+
+	query := `
+		WITH today_records AS (
+			SELECT *
+			FROM stocks
+			WHERE DATE(time) = CURRENT_DATE
+			AND score > $1
+		),
+		yesterday_records AS (
+			SELECT *
+			FROM stocks
+			WHERE DATE(time) = CURRENT_DATE - INTERVAL '1 day'
+			AND score > $1
+		)
+		SELECT ticker, company, rating_to, brokerage, target_to, rating_from, action, time, score, confidence
+		FROM (
+			SELECT *
+			FROM today_records
+			UNION ALL
+			SELECT *
+			FROM yesterday_records
+			WHERE NOT EXISTS (SELECT 1 FROM today_records)
+		) combined_records
+		ORDER BY confidence DESC, score DESC, time DESC
+		LIMIT $2
+	`
+
+	rows, err := r.readDB.QueryContext(ctx, query, minScore, limit)
+	if err != nil {
+		r.log.Error("error querying recommendations", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var recommendations []models.Stock
+	for rows.Next() {
+		var recommendation models.Stock
+		err := rows.Scan(
+			&recommendation.Ticker,
+			&recommendation.Company,
+			&recommendation.RatingTo,
+			&recommendation.Brokerage,
+			&recommendation.TargetTo,
+			&recommendation.RatingFrom,
+			&recommendation.Action,
+			&recommendation.Time,
+			&recommendation.Score,
+			&recommendation.Confidence,
+		)
+		if err != nil {
+			return nil, err
+		}
+		recommendations = append(recommendations, recommendation)
+	}
+
+	return recommendations, rows.Err()
+}
+
+// stockCopyColumns are the columns loaded into the staging table by COPY
+var stockCopyColumns = []string{
+	"seq", "event_hash", "ticker", "company", "brokerage", "action", "rating_from", "rating_to",
+	"target_from", "target_to", "time", "created_at", "updated_at", "score", "reason",
+	"target_price", "current_rating", "confidence", "source",
+}
+
+// UpsertStocks implements StockRepository. Valid rows are loaded with COPY into a
+// temporary staging table and merged with a single INSERT ... ON CONFLICT, all in
+// one transaction. When the input repeats a ticker and company the newest event
+// wins, the last one on ties; a stored event newer than the incoming one is kept.
+// Each event is recorded in stock_events by its EventHash, and rows repeating an
+// event recorded earlier, or earlier in the same batch, are counted as duplicates.
+func (r *PostgresRepository) UpsertStocks(ctx context.Context, stocks []models.Stock) (models.UpsertReport, error) {
+	return r.upsertStocks(ctx, stocks, true)
+}
+
+// UpsertHistoricalStocks implements StockRepository
+func (r *PostgresRepository) UpsertHistoricalStocks(ctx context.Context, stocks []models.Stock) (models.UpsertReport, error) {
+	return r.upsertStocks(ctx, stocks, false)
+}
+
+// upsertStocks merges stocks, appending their changes to the feed when feed is set
+func (r *PostgresRepository) upsertStocks(ctx context.Context, stocks []models.Stock, feed bool) (_ models.UpsertReport, err error) {
+	valid, rejected := partitionStocks(stocks)
+	report := models.UpsertReport{Rejected: rejected}
+	metrics.SyncRowsRejected.Add(float64(len(rejected)))
+
+	if len(valid) == 0 {
+		return report, nil
+	}
+
+	defer metrics.ObserveQuery("insert_stocks", time.Now(), &err)
+
+	ctx, span := tracing.StartDB(ctx, "insert_stocks",
+		attribute.Int("db.rows", len(valid)),
+		attribute.Int("db.rows_rejected", len(rejected)),
+	)
+	defer func() { tracing.End(span, err) }()
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return report, fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `
+		CREATE TEMP TABLE stocks_staging (
+			seq INT NOT NULL,
+			event_hash CHAR(64) NOT NULL,
+			ticker VARCHAR(10) NOT NULL,
+			company VARCHAR(255) NOT NULL,
+			brokerage VARCHAR(255) NOT NULL,
+			action VARCHAR(50) NOT NULL,
+			rating_from VARCHAR(50),
+			rating_to VARCHAR(50),
+			target_from VARCHAR(20),
+			target_to VARCHAR(20),
+			time TIMESTAMP NOT NULL,
+			created_at TIMESTAMP,
+			updated_at TIMESTAMP,
+			score FLOAT,
+			reason VARCHAR(255),
+			target_price VARCHAR(20),
+			current_rating VARCHAR(50),
+			confidence FLOAT,
+			source VARCHAR(20) NOT NULL
+		) ON COMMIT DROP
+	`)
+	if err != nil {
+		return report, fmt.Errorf("error creating staging table: %w", err)
+	}
+
+	stmt, err := tx.PrepareContext(ctx, pq.CopyIn("stocks_staging", stockCopyColumns...))
+	if err != nil {
+		return report, fmt.Errorf("error preparing copy: %w", err)
+	}
+	defer stmt.Close()
+
+	for i, stock := range valid {
+		_, err := stmt.ExecContext(ctx, i, EventHash(stock),
+			stock.Ticker, stock.Company, stock.Brokerage, stock.Action,
+			stock.RatingFrom, stock.RatingTo, stock.TargetFrom, stock.TargetTo,
+			stock.Time, stock.CreatedAt, stock.UpdatedAt, stock.Score, stock.Reason, stock.TargetPrice, stock.CurrentRating, stock.Confidence,
+			stock.Source,
+		)
+		if err != nil {
+			return report, fmt.Errorf("error copying stock %s: %w", stock.Ticker, err)
+		}
+	}
+	if _, err := stmt.ExecContext(ctx); err != nil {
+		return report, fmt.Errorf("error flushing copy: %w", err)
+	}
+
+	recorded, err := tx.ExecContext(ctx, `
+		INSERT INTO stock_events (event_hash, ticker, company, time, brokerage, action, rating_to, target_to)
+		SELECT DISTINCT ON (event_hash) event_hash, ticker, company, time,
+		       brokerage, action, COALESCE(rating_to, ''), COALESCE(target_to, '')
+		FROM stocks_staging
+		ORDER BY event_hash, seq
+		ON CONFLICT (event_hash) DO NOTHING
+	`)
+	if err != nil {
+		return report, fmt.Errorf("error recording stock events: %w", err)
+	}
+	newEvents, err := recorded.RowsAffected()
+	if err != nil {
+		return report, fmt.Errorf("error counting new stock events: %w", err)
+	}
+
+	previous, err := lockStagedStocks(ctx, tx)
+	if err != nil {
+		return report, err
+	}
+
+	rows, err := tx.QueryContext(ctx, `
+		INSERT INTO stocks (ticker, company, brokerage, action, rating_from, rating_to,
+		                   target_from, target_to, time, created_at, updated_at, score, reason, target_price, current_rating, confidence, source)
+		SELECT DISTINCT ON (ticker, company)
+		       ticker, company, brokerage, action, rating_from, rating_to,
+		       target_from, target_to, time, created_at, updated_at, score, reason, target_price, current_rating, confidence, source
+		FROM stocks_staging
+		ORDER BY ticker, company, time DESC, seq DESC
+		ON CONFLICT (ticker, company) DO UPDATE SET
+			action = EXCLUDED.action,
+			rating_from = EXCLUDED.rating_from,
+			rating_to = EXCLUDED.rating_to,
+			target_from = EXCLUDED.target_from,
+			target_to = EXCLUDED.target_to,
+			created_at = EXCLUDED.created_at,
+			updated_at = NOW(),
+			time = EXCLUDED.time,
+			score = EXCLUDED.score,
+			reason = EXCLUDED.reason,
+			target_price = EXCLUDED.target_price,
+			current_rating = EXCLUDED.current_rating,
+			confidence = EXCLUDED.confidence,
+			source = EXCLUDED.source
+		WHERE stocks.time <= EXCLUDED.time
+		RETURNING id, ticker, company
+	`)
+	if err != nil {
+		return report, fmt.Errorf("error merging staged stocks: %w", err)
+	}
+	defer rows.Close()
+
+	// The merged row is the staged one, except for the brokerage an update keeps
+	merged := make(map[string]models.Stock)
+	for _, stock := range latestPerStock(valid) {
+		merged[stock.Ticker+"\x00"+stock.Company] = stock
+	}
+
+	var changes []models.StockChange
+	upserted := 0
+	for rows.Next() {
+		var id int
+		var ticker, company string
+		if err := rows.Scan(&id, &ticker, &company); err != nil {
+			return report, fmt.Errorf("error scanning merged stock: %w", err)
+		}
+		upserted++
+		if !feed {
+			continue
+		}
+
+		key := ticker + "\x00" + company
+		current := merged[key]
+		current.ID = id
+		if stored, ok := previous[key]; ok {
+			changes = append(changes, detectChanges(&stored, current)...)
+		} else {
+			changes = append(changes, detectChanges(nil, current)...)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return report, fmt.Errorf("error merging staged stocks: %w", err)
+	}
+
+	if err := appendChanges(ctx, tx, changes); err != nil {
+		return report, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return report, fmt.Errorf("error committing transaction: %w", err)
+	}
+
+	report.Upserted = upserted
+	report.Duplicates = len(valid) - int(newEvents)
+	metrics.SyncRowsUpserted.Add(float64(upserted))
+	metrics.SyncRowsDuplicate.Add(float64(report.Duplicates))
+
+	return report, nil
+}
+
+// lockStagedStocks locks the stored rows the staged stocks will merge into and
+// returns them by ticker and company, so changes are detected against the state
+// the merge replaces
+func lockStagedStocks(ctx context.Context, tx *sql.Tx) (map[string]models.Stock, error) {
+	rows, err := tx.QueryContext(ctx, `
+		SELECT id, ticker, company, rating_to, target_to, score
+		FROM stocks
+		WHERE (ticker, company) IN (SELECT ticker, company FROM stocks_staging)
+		FOR UPDATE
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("error locking stored stocks: %w", err)
+	}
+	defer rows.Close()
+
+	stored := make(map[string]models.Stock)
+	for rows.Next() {
+		var stock models.Stock
+		var ratingTo, targetTo sql.NullString
+		var score sql.NullFloat64
+		if err := rows.Scan(&stock.ID, &stock.Ticker, &stock.Company, &ratingTo, &targetTo, &score); err != nil {
+			return nil, fmt.Errorf("error scanning stored stock: %w", err)
+		}
+		stock.RatingTo, stock.TargetTo, stock.Score = ratingTo.String, targetTo.String, score.Float64
+		stored[stock.Ticker+"\x00"+stock.Company] = stock
+	}
+
+	return stored, rows.Err()
+}
+
+// changeFeedLock is the advisory lock key serialising writes to stock_changes
+const changeFeedLock = 0x73746f636b // "stock"
+
+// appendChanges writes changes to the stock_changes outbox. The transaction-level
+// advisory lock makes concurrent writers take IDs in commit order, so a reader
+// that has seen ID n will never find a smaller one committed later.
+func appendChanges(ctx context.Context, tx *sql.Tx, changes []models.StockChange) error {
+	if len(changes) == 0 {
+		return nil
+	}
+
+	if _, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock($1)`, changeFeedLock); err != nil {
+		return fmt.Errorf("error locking change feed: %w", err)
+	}
+
+	stmt, err := tx.PrepareContext(ctx, pq.CopyIn("stock_changes",
+		"stock_id", "type", "ticker", "company", "brokerage", "action",
+		"rating_from", "rating_to", "target_from", "target_to", "score_from", "score_to", "event_time",
+	))
+	if err != nil {
+		return fmt.Errorf("error preparing change copy: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, change := range changes {
+		_, err := stmt.ExecContext(ctx, change.StockID, change.Type, change.Ticker, change.Company, change.Brokerage, change.Action,
+			change.RatingFrom, change.RatingTo, change.TargetFrom, change.TargetTo, change.ScoreFrom, change.ScoreTo, change.EventTime,
+		)
+		if err != nil {
+			return fmt.Errorf("error copying change for %s: %w", change.Ticker, err)
+		}
+	}
+	if _, err := stmt.ExecContext(ctx); err != nil {
+		return fmt.Errorf("error flushing change copy: %w", err)
+	}
+
+	return nil
+}
+
+// ListChanges implements ChangeRepository. The feed is read from the primary:
+// a lagging replica could hide changes from a reader that already holds a later cursor.
+func (r *PostgresRepository) ListChanges(ctx context.Context, since int64, limit int) (_ []models.StockChange, err error) {
+	defer metrics.ObserveQuery("list_changes", time.Now(), &err)
+
+	ctx, span := tracing.StartDB(ctx, "list_changes")
+	defer func() { tracing.End(span, err) }()
+
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT id, stock_id, type, ticker, company, brokerage, action,
+		       rating_from, rating_to, target_from, target_to, score_from, score_to, event_time, created_at
+		FROM stock_changes
+		WHERE id > $1
+		ORDER BY id
+		LIMIT $2
+	`, since, limit)
+	if err != nil {
+		return nil, fmt.Errorf("error listing changes: %w", err)
+	}
+	defer rows.Close()
+
+	return scanChanges(rows)
+}
+
+// scanChanges reads the stock_changes columns selected by ListChanges
+func scanChanges(rows *sql.Rows) ([]models.StockChange, error) {
+	changes := []models.StockChange{}
+	for rows.Next() {
+		var change models.StockChange
+		err := rows.Scan(&change.ID, &change.StockID, &change.Type, &change.Ticker, &change.Company, &change.Brokerage, &change.Action,
+			&change.RatingFrom, &change.RatingTo, &change.TargetFrom, &change.TargetTo, &change.ScoreFrom, &change.ScoreTo, &change.EventTime, &change.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning change: %w", err)
+		}
+		changes = append(changes, change)
+	}
+
+	return changes, rows.Err()
+}
+
+// LastChangeID implements ChangeRepository
+func (r *PostgresRepository) LastChangeID(ctx context.Context) (int64, error) {
+	var id int64
+	if err := r.db.QueryRowContext(ctx, `SELECT COALESCE(MAX(id), 0) FROM stock_changes`).Scan(&id); err != nil {
+		return 0, fmt.Errorf("error reading last change: %w", err)
+	}
+	return id, nil
+}
+
+// LatestRatings implements ChangeRepository
+func (r *PostgresRepository) LatestRatings(ctx context.Context, ticker string, since time.Time) (_ []models.BrokerageRating, err error) {
+	defer metrics.ObserveQuery("latest_ratings", time.Now(), &err)
+
+	ctx, span := tracing.StartDB(ctx, "latest_ratings")
+	defer func() { tracing.End(span, err) }()
+
+	// Events recorded together tie on first_seen_at; the hash keeps the pick stable
+	rows, err := r.readDB.QueryContext(ctx, `
+		SELECT brokerage, company, action, rating_to, target_to, time FROM (
+			SELECT DISTINCT ON (brokerage) brokerage, company, action, rating_to, target_to, time
+			FROM stock_events
+			WHERE ticker = $1 AND time >= $2 AND brokerage <> ''
+			ORDER BY brokerage, time DESC, first_seen_at DESC, event_hash
+		) latest
+		ORDER BY time DESC, brokerage
+	`, ticker, since)
+	if err != nil {
+		return nil, fmt.Errorf("error listing latest ratings: %w", err)
+	}
+	defer rows.Close()
+
+	ratings := []models.BrokerageRating{}
+	for rows.Next() {
+		var rating models.BrokerageRating
+		if err := rows.Scan(&rating.Brokerage, &rating.Company, &rating.Action, &rating.Rating, &rating.Target, &rating.Time); err != nil {
+			return nil, fmt.Errorf("error scanning rating: %w", err)
+		}
+		ratings = append(ratings, rating)
+	}
+
+	return ratings, rows.Err()
+}
+
+// StartSyncRun implements SyncRunRepository
+func (r *PostgresRepository) StartSyncRun(ctx context.Context) (int64, error) {
+	var id int64
+	err := r.db.QueryRowContext(ctx,
+		`INSERT INTO sync_runs (status) VALUES ($1) RETURNING id`,
+		models.SyncStatusRunning,
+	).Scan(&id)
+	if err != nil {
+		return 0, fmt.Errorf("error starting sync run: %w", err)
+	}
+
+	return id, nil
+}
+
+// FinishSyncRun implements SyncRunRepository
+func (r *PostgresRepository) FinishSyncRun(ctx context.Context, id int64, result models.SyncResult, runErr error) error {
+	status := models.SyncStatusSuccess
+	var message sql.NullString
+	if runErr != nil {
+		status = models.SyncStatusFailed
+		message = sql.NullString{String: runErr.Error(), Valid: true}
+	}
+
+	_, err := r.db.ExecContext(ctx,
+		`UPDATE sync_runs SET finished_at = NOW(), status = $2, stocks = $3, rejected = $4, quarantined = $5, duplicates = $6, error = $7 WHERE id = $1`,
+		id, status, result.Stocks, result.Rejected, result.Quarantined, result.Duplicates, message,
+	)
+	if err != nil {
+		return fmt.Errorf("error finishing sync run: %w", err)
+	}
+
+	return nil
+}
+
+// LastSyncRun implements SyncRunRepository
+func (r *PostgresRepository) LastSyncRun(ctx context.Context) (*models.SyncRun, error) {
+	var run models.SyncRun
+	var finishedAt sql.NullTime
+	var message sql.NullString
+
+	err := r.db.QueryRowContext(ctx, `
+		SELECT id, started_at, finished_at, status, stocks, rejected, quarantined, duplicates, error
+		FROM sync_runs
+		ORDER BY started_at DESC, id DESC
+		LIMIT 1
+	`).Scan(&run.ID, &run.StartedAt, &finishedAt, &run.Status, &run.Stocks, &run.Rejected, &run.Quarantined, &run.Duplicates, &message)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading last sync run: %w", err)
+	}
+
+	if finishedAt.Valid {
+		run.FinishedAt = &finishedAt.Time
+	}
+	run.Error = message.String
+
+	return &run, nil
+}
+
+// QuarantineEvents implements QuarantineRepository
+func (r *PostgresRepository) QuarantineEvents(ctx context.Context, events []models.QuarantinedEvent) (err error) {
+	if len(events) == 0 {
+		return nil
+	}
+
+	defer metrics.ObserveQuery("quarantine_events", time.Now(), &err)
+
+	ctx, span := tracing.StartDB(ctx, "quarantine_events", attribute.Int("db.rows", len(events)))
+	defer func() { tracing.End(span, err) }()
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, `
+		INSERT INTO quarantined_events (fingerprint, source, ticker, company, payload, reason, status)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (fingerprint) DO UPDATE SET
+			seen_count = quarantined_events.seen_count + 1,
+			last_seen_at = NOW()
+	`)
+	if err != nil {
+		return fmt.Errorf("error preparing statement: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, event := range events {
+		_, err := stmt.ExecContext(ctx,
+			payloadFingerprint(event.Payload), event.Source, event.Ticker, event.Company,
+			string(event.Payload), event.Reason, models.QuarantineStatusPending,
+		)
+		if err != nil {
+			return fmt.Errorf("error quarantining event for %q: %w", event.Ticker, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error committing transaction: %w", err)
+	}
+
+	return nil
+}
+
+// ListQuarantined implements QuarantineRepository
+func (r *PostgresRepository) ListQuarantined(ctx context.Context, filters models.QuarantineFilters) (_ []models.QuarantinedEvent, err error) {
+	defer metrics.ObserveQuery("list_quarantined", time.Now(), &err)
+
+	query := quarantineSelect + ` WHERE 1=1`
+	args := []any{}
+
+	if filters.Status != "" {
+		args = append(args, filters.Status)
+		query += fmt.Sprintf(" AND status = $%d", len(args))
+	}
+	if filters.Ticker != "" {
+		args = append(args, filters.Ticker)
+		query += fmt.Sprintf(" AND ticker = $%d", len(args))
+	}
+
+	args = append(args, quarantineLimit(filters.Limit))
+	query += fmt.Sprintf(" ORDER BY last_seen_at DESC, id DESC LIMIT $%d", len(args))
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("error querying quarantined events: %w", err)
+	}
+	defer rows.Close()
+
+	events := []models.QuarantinedEvent{}
+	for rows.Next() {
+		event, err := scanQuarantined(rows)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, *event)
+	}
+
+	return events, rows.Err()
+}
+
+// GetQuarantined implements QuarantineRepository
+func (r *PostgresRepository) GetQuarantined(ctx context.Context, id int64) (*models.QuarantinedEvent, error) {
+	event, err := scanQuarantined(r.db.QueryRowContext(ctx, quarantineSelect+` WHERE id = $1`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return event, err
+}
+
+// ClaimQuarantined implements QuarantineRepository
+func (r *PostgresRepository) ClaimQuarantined(ctx context.Context, id int64, status string) (*models.QuarantinedEvent, error) {
+	event, err := scanQuarantined(r.db.QueryRowContext(ctx, `
+		UPDATE quarantined_events SET status = $2, resolved_at = NOW()
+		WHERE id = $1 AND status = $3
+		RETURNING `+quarantineColumns,
+		id, status, models.QuarantineStatusPending,
+	))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return event, err
+}
+
+// ReopenQuarantined implements QuarantineRepository
+func (r *PostgresRepository) ReopenQuarantined(ctx context.Context, id int64) error {
+	result, err := r.db.ExecContext(ctx,
+		`UPDATE quarantined_events SET status = $2, resolved_at = NULL WHERE id = $1`,
+		id, models.QuarantineStatusPending,
+	)
+	if err != nil {
+		return fmt.Errorf("error reopening quarantined event: %w", err)
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return fmt.Errorf("error reopening quarantined event: event %d not found", id)
+	}
+
+	return nil
+}
+
+const quarantineColumns = `id, source, ticker, company, payload, reason, status, seen_count, first_seen_at, last_seen_at, resolved_at`
+
+const quarantineSelect = `SELECT ` + quarantineColumns + ` FROM quarantined_events`
+
+func scanQuarantined(row interface{ Scan(...any) error }) (*models.QuarantinedEvent, error) {
+	var event models.QuarantinedEvent
+	var ticker, company sql.NullString
+	var payload []byte
+	var resolvedAt sql.NullTime
+
+	err := row.Scan(&event.ID, &event.Source, &ticker, &company, &payload, &event.Reason,
+		&event.Status, &event.SeenCount, &event.FirstSeenAt, &event.LastSeenAt, &resolvedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error scanning quarantined event: %w", err)
+	}
+
+	event.Ticker = ticker.String
+	event.Company = company.String
+	event.Payload = payload
+	if resolvedAt.Valid {
+		event.ResolvedAt = &resolvedAt.Time
+	}
+
+	return &event, nil
+}
+
+// CreateBackfillJob implements BackfillRepository
+func (r *PostgresRepository) CreateBackfillJob(ctx context.Context, job *models.BackfillJob) error {
+	err := r.db.QueryRowContext(ctx, `
+		INSERT INTO backfill_jobs (from_time, to_time, status, cursor)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at, updated_at
+	`, job.From, job.To, job.Status, job.Cursor).Scan(&job.ID, &job.CreatedAt, &job.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("error creating backfill job: %w", err)
+	}
+
+	return nil
+}
+
+// UpdateBackfillJob implements BackfillRepository
+func (r *PostgresRepository) UpdateBackfillJob(ctx context.Context, job *models.BackfillJob) error {
+	var message sql.NullString
+	if job.Error != "" {
+		message = sql.NullString{String: job.Error, Valid: true}
+	}
+
+	err := r.db.QueryRowContext(ctx, `
+		UPDATE backfill_jobs SET
+			status = $2, cursor = $3, pages = $4, fetched = $5, in_range = $6,
+			stored = $7, quarantined = $8, error = $9, finished_at = $10, updated_at = NOW()
+		WHERE id = $1
+		RETURNING updated_at
+	`, job.ID, job.Status, job.Cursor, job.Pages, job.Fetched, job.InRange,
+		job.Stored, job.Quarantined, message, job.FinishedAt,
+	).Scan(&job.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("error updating backfill job: job %d not found", job.ID)
+	}
+	if err != nil {
+		return fmt.Errorf("error updating backfill job: %w", err)
+	}
+
+	return nil
+}
+
+// GetBackfillJob implements BackfillRepository
+func (r *PostgresRepository) GetBackfillJob(ctx context.Context, id int64) (*models.BackfillJob, error) {
+	job, err := scanBackfillJob(r.db.QueryRowContext(ctx, backfillSelect+` WHERE id = $1`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return job, err
+}
+
+// ListBackfillJobs implements BackfillRepository
+func (r *PostgresRepository) ListBackfillJobs(ctx context.Context, limit int) ([]models.BackfillJob, error) {
+	rows, err := r.db.QueryContext(ctx, backfillSelect+` ORDER BY created_at DESC, id DESC LIMIT $1`, limit)
+	if err != nil {
+		return nil, fmt.Errorf("error querying backfill jobs: %w", err)
+	}
+	defer rows.Close()
+
+	jobs := []models.BackfillJob{}
+	for rows.Next() {
+		job, err := scanBackfillJob(rows)
+		if err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, *job)
+	}
+
+	return jobs, rows.Err()
+}
+
+// backfillLockClass is the first key of the advisory locks on backfill jobs
+const backfillLockClass = 38
+
+// LockBackfillJob implements BackfillRepository with a session advisory lock,
+// held on a connection kept out of the pool until unlock
+func (r *PostgresRepository) LockBackfillJob(ctx context.Context, id int64) (func(), bool, error) {
+	conn, err := r.db.Conn(ctx)
+	if err != nil {
+		return nil, false, fmt.Errorf("error locking backfill job: %w", err)
+	}
+
+	var ok bool
+	err = conn.QueryRowContext(ctx, `SELECT pg_try_advisory_lock($1, $2::INT)`, backfillLockClass, id).Scan(&ok)
+	if err != nil || !ok {
+		conn.Close()
+		if err != nil {
+			return nil, false, fmt.Errorf("error locking backfill job: %w", err)
+		}
+		return nil, false, nil
+	}
+
+	unlock := func() {
+		_, err := conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock($1, $2::INT)`, backfillLockClass, id)
+		if err != nil {
+			// Discarding the connection ends its session, and the lock with it
+			conn.Raw(func(any) error { return driver.ErrBadConn })
+		}
+		conn.Close()
+	}
+	return unlock, true, nil
+}
+
+const backfillSelect = `
+	SELECT id, from_time, to_time, status, cursor, pages, fetched, in_range, stored, quarantined,
+	       error, created_at, updated_at, finished_at
+	FROM backfill_jobs`
+
+func scanBackfillJob(row interface{ Scan(...any) error }) (*models.BackfillJob, error) {
+	var job models.BackfillJob
+	var message sql.NullString
+	var finishedAt sql.NullTime
+
+	err := row.Scan(&job.ID, &job.From, &job.To, &job.Status, &job.Cursor, &job.Pages, &job.Fetched,
+		&job.InRange, &job.Stored, &job.Quarantined, &message, &job.CreatedAt, &job.UpdatedAt, &finishedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error scanning backfill job: %w", err)
+	}
+
+	job.Error = message.String
+	if finishedAt.Valid {
+		job.FinishedAt = &finishedAt.Time
+	}
+
+	return &job, nil
+}
+
+// CreateWatchlist implements WatchlistRepository
+func (r *PostgresRepository) CreateWatchlist(ctx context.Context, list *models.Watchlist) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO watchlists (username, name)
+		VALUES ($1, $2)
+		RETURNING id, created_at, updated_at
+	`, list.Username, list.Name).Scan(&list.ID, &list.CreatedAt, &list.UpdatedAt)
+	if isUniqueViolation(err) {
+		return ErrWatchlistNameTaken
+	}
+	if err != nil {
+		return fmt.Errorf("error creating watchlist: %w", err)
+	}
+
+	if err := insertWatchlistTickers(ctx, tx, list.ID, list.Tickers); err != nil {
+		return err
+	}
+	list.Tickers = appendNew([]string{}, list.Tickers)
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error committing transaction: %w", err)
+	}
+
+	return nil
+}
+
+// GetWatchlist implements WatchlistRepository
+func (r *PostgresRepository) GetWatchlist(ctx context.Context, username string, id int64) (*models.Watchlist, error) {
+	lists, err := r.queryWatchlists(ctx, watchlistSelect+` WHERE username = $1 AND id = $2`, username, id)
+	if err != nil || len(lists) == 0 {
+		return nil, err
+	}
+	return &lists[0], nil
+}
+
+// ListWatchlists implements WatchlistRepository
+func (r *PostgresRepository) ListWatchlists(ctx context.Context, username string) ([]models.Watchlist, error) {
+	return r.queryWatchlists(ctx, watchlistSelect+` WHERE username = $1 ORDER BY name, id`, username)
+}
+
+// RenameWatchlist implements WatchlistRepository
+func (r *PostgresRepository) RenameWatchlist(ctx context.Context, username string, id int64, name string) (bool, error) {
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE watchlists SET name = $3, updated_at = NOW()
+		WHERE username = $1 AND id = $2
+	`, username, id, name)
+	if isUniqueViolation(err) {
+		return true, ErrWatchlistNameTaken
+	}
+	if err != nil {
+		return false, fmt.Errorf("error renaming watchlist: %w", err)
+	}
+	return rowsAffected(result)
+}
+
+// DeleteWatchlist implements WatchlistRepository. Its tickers are deleted by the foreign key.
+func (r *PostgresRepository) DeleteWatchlist(ctx context.Context, username string, id int64) (bool, error) {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM watchlists WHERE username = $1 AND id = $2`, username, id)
+	if err != nil {
+		return false, fmt.Errorf("error deleting watchlist: %w", err)
+	}
+	return rowsAffected(result)
+}
+
+// AddWatchlistTickers implements WatchlistRepository
+func (r *PostgresRepository) AddWatchlistTickers(ctx context.Context, username string, id int64, tickers []string) (bool, error) {
+	return r.updateWatchlistTickers(ctx, username, id, func(tx *sql.Tx) error {
+		return insertWatchlistTickers(ctx, tx, id, tickers)
+	})
+}
+
+// RemoveWatchlistTicker implements WatchlistRepository
+func (r *PostgresRepository) RemoveWatchlistTicker(ctx context.Context, username string, id int64, ticker string) (bool, error) {
+	return r.updateWatchlistTickers(ctx, username, id, func(tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, `DELETE FROM watchlist_items WHERE watchlist_id = $1 AND ticker = $2`, id, ticker)
+		if err != nil {
+			return fmt.Errorf("error removing watchlist ticker: %w", err)
+		}
+		return nil
+	})
+}
+
+// updateWatchlistTickers touches the owner's list and, when it exists, runs update
+// in the same transaction
+func (r *PostgresRepository) updateWatchlistTickers(ctx context.Context, username string, id int64, update func(*sql.Tx) error) (bool, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, `UPDATE watchlists SET updated_at = NOW() WHERE username = $1 AND id = $2`, username, id)
+	if err != nil {
+		return false, fmt.Errorf("error updating watchlist: %w", err)
+	}
+	if found, err := rowsAffected(result); !found || err != nil {
+		return false, err
+	}
+
+	if err := update(tx); err != nil {
+		return true, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return true, fmt.Errorf("error committing transaction: %w", err)
+	}
+
+	return true, nil
+}
+
+// insertWatchlistTickers adds tickers in order, skipping those already in the list
+func insertWatchlistTickers(ctx context.Context, tx *sql.Tx, id int64, tickers []string) error {
+	if len(tickers) == 0 {
+		return nil
+	}
+
+	_, err := tx.ExecContext(ctx, `
+		INSERT INTO watchlist_items (watchlist_id, ticker)
+		SELECT $1, ticker FROM unnest($2::text[]) WITH ORDINALITY AS t(ticker, n)
+		ORDER BY n
+		ON CONFLICT (watchlist_id, ticker) DO NOTHING
+	`, id, pq.Array(tickers))
+	if err != nil {
+		return fmt.Errorf("error adding watchlist tickers: %w", err)
+	}
+	return nil
+}
+
+const watchlistSelect = `
+	SELECT id, username, name, created_at, updated_at
+	FROM watchlists`
+
+// queryWatchlists runs a watchlistSelect query and loads the tickers of every list
+func (r *PostgresRepository) queryWatchlists(ctx context.Context, query string, args ...any) ([]models.Watchlist, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("error querying watchlists: %w", err)
+	}
+	defer rows.Close()
+
+	lists := []models.Watchlist{}
+	var ids []int64
+	for rows.Next() {
+		list := models.Watchlist{Tickers: []string{}}
+		if err := rows.Scan(&list.ID, &list.Username, &list.Name, &list.CreatedAt, &list.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("error scanning watchlist: %w", err)
+		}
+		lists = append(lists, list)
+		ids = append(ids, list.ID)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(lists) == 0 {
+		return lists, nil
+	}
+
+	items, err := r.db.QueryContext(ctx, `
+		SELECT watchlist_id, ticker FROM watchlist_items
+		WHERE watchlist_id = ANY($1)
+		ORDER BY id
+	`, pq.Array(ids))
+	if err != nil {
+		return nil, fmt.Errorf("error querying watchlist tickers: %w", err)
+	}
+	defer items.Close()
+
+	index := make(map[int64]int, len(lists))
+	for i, list := range lists {
+		index[list.ID] = i
+	}
+	for items.Next() {
+		var id int64
+		var ticker string
+		if err := items.Scan(&id, &ticker); err != nil {
+			return nil, fmt.Errorf("error scanning watchlist ticker: %w", err)
+		}
+		lists[index[id]].Tickers = append(lists[index[id]].Tickers, ticker)
+	}
+
+	return lists, items.Err()
+}
+
+// CreateAlertRule implements AlertRepository
+func (r *PostgresRepository) CreateAlertRule(ctx context.Context, rule *models.AlertRule) error {
+	err := r.db.QueryRowContext(ctx, `
+		INSERT INTO alert_rules (username, name, ticker, brokerage, change_type, rating,
+			score_above, target_change_pct, cooldown_minutes, enabled)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		RETURNING id, created_at, updated_at
+	`, rule.Username, rule.Name, rule.Ticker, rule.Brokerage, rule.ChangeType, rule.Rating,
+		rule.ScoreAbove, rule.TargetChangePct, rule.CooldownMinutes, rule.Enabled,
+	).Scan(&rule.ID, &rule.CreatedAt, &rule.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("error creating alert rule: %w", err)
+	}
+
+	return nil
+}
+
+// GetAlertRule implements AlertRepository
+func (r *PostgresRepository) GetAlertRule(ctx context.Context, username string, id int64) (*models.AlertRule, error) {
+	rule, err := scanAlertRule(r.db.QueryRowContext(ctx, alertRuleSelect+` WHERE username = $1 AND id = $2`, username, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return rule, err
+}
+
+// ListAlertRules implements AlertRepository
+func (r *PostgresRepository) ListAlertRules(ctx context.Context, username string) ([]models.AlertRule, error) {
+	return r.queryAlertRules(ctx, alertRuleSelect+` WHERE username = $1 ORDER BY id`, username)
+}
+
+// UpdateAlertRule implements AlertRepository
+func (r *PostgresRepository) UpdateAlertRule(ctx context.Context, rule *models.AlertRule) (bool, error) {
+	err := r.db.QueryRowContext(ctx, `
+		UPDATE alert_rules SET
+			name = $3, ticker = $4, brokerage = $5, change_type = $6, rating = $7,
+			score_above = $8, target_change_pct = $9, cooldown_minutes = $10, enabled = $11,
+			updated_at = NOW()
+		WHERE username = $1 AND id = $2
+		RETURNING created_at, updated_at
+	`, rule.Username, rule.ID, rule.Name, rule.Ticker, rule.Brokerage, rule.ChangeType, rule.Rating,
+		rule.ScoreAbove, rule.TargetChangePct, rule.CooldownMinutes, rule.Enabled,
+	).Scan(&rule.CreatedAt, &rule.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("error updating alert rule: %w", err)
+	}
+
+	return true, nil
+}
+
+// DeleteAlertRule implements AlertRepository. Its alerts are deleted by the foreign key.
+func (r *PostgresRepository) DeleteAlertRule(ctx context.Context, username string, id int64) (bool, error) {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM alert_rules WHERE username = $1 AND id = $2`, username, id)
+	if err != nil {
+		return false, fmt.Errorf("error deleting alert rule: %w", err)
+	}
+	return rowsAffected(result)
+}
+
+// EnabledAlertRules implements AlertRepository
+func (r *PostgresRepository) EnabledAlertRules(ctx context.Context) ([]models.AlertRule, error) {
+	return r.queryAlertRules(ctx, alertRuleSelect+` WHERE enabled ORDER BY id`)
+}
+
+// RecordAlert implements AlertRepository. The cooldown check and the insert are
+// one statement; the unique key on rule and change covers concurrent evaluations.
+func (r *PostgresRepository) RecordAlert(ctx context.Context, alert *models.Alert, cooldown time.Duration) (bool, error) {
+	err := r.db.QueryRowContext(ctx, `
+		INSERT INTO alerts (rule_id, rule_name, username, change_id, change_type, ticker, brokerage, message)
+		SELECT $1, $2, $3, $4, $5, $6, $7, $8
+		WHERE NOT EXISTS (
+			SELECT 1 FROM alerts
+			WHERE rule_id = $1 AND ticker = $6 AND triggered_at > NOW() - $9::float8 * INTERVAL '1 second'
+		)
+		ON CONFLICT (rule_id, change_id) DO NOTHING
+		RETURNING id, triggered_at
+	`, alert.RuleID, alert.RuleName, alert.Username, alert.ChangeID, alert.ChangeType,
+		alert.Ticker, alert.Brokerage, alert.Message, cooldown.Seconds(),
+	).Scan(&alert.ID, &alert.TriggeredAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("error recording alert: %w", err)
+	}
+
+	return true, nil
+}
+
+// ListAlerts implements AlertRepository
+func (r *PostgresRepository) ListAlerts(ctx context.Context, filters models.AlertFilters) (_ []models.Alert, err error) {
+	defer metrics.ObserveQuery("list_alerts", time.Now(), &err)
+
+	query := alertSelect + ` WHERE username = $1`
+	args := []any{filters.Username}
+
+	if filters.RuleID != 0 {
+		args = append(args, filters.RuleID)
+		query += fmt.Sprintf(" AND rule_id = $%d", len(args))
+	}
+	if filters.Before != 0 {
+		args = append(args, filters.Before)
+		query += fmt.Sprintf(" AND id < $%d", len(args))
+	}
+
+	args = append(args, filters.Limit)
+	query += fmt.Sprintf(" ORDER BY id DESC LIMIT $%d", len(args))
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("error querying alerts: %w", err)
+	}
+	return scanAlerts(rows)
+}
+
+// AlertsSince implements AlertRepository
+func (r *PostgresRepository) AlertsSince(ctx context.Context, since int64, limit int) ([]models.Alert, error) {
+	rows, err := r.db.QueryContext(ctx, alertSelect+` WHERE id > $1 ORDER BY id LIMIT $2`, since, limit)
+	if err != nil {
+		return nil, fmt.Errorf("error querying alerts: %w", err)
+	}
+	return scanAlerts(rows)
+}
+
+// AlertCursor implements AlertRepository
+func (r *PostgresRepository) AlertCursor(ctx context.Context) (int64, bool, error) {
+	var changeID int64
+	err := r.db.QueryRowContext(ctx, `SELECT change_id FROM alert_cursor`).Scan(&changeID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, fmt.Errorf("error reading alert cursor: %w", err)
+	}
+	return changeID, true, nil
+}
+
+// SetAlertCursor implements AlertRepository
+func (r *PostgresRepository) SetAlertCursor(ctx context.Context, changeID int64) error {
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO alert_cursor (change_id) VALUES ($1)
+		ON CONFLICT (id) DO UPDATE SET change_id = EXCLUDED.change_id
+	`, changeID)
+	if err != nil {
+		return fmt.Errorf("error storing alert cursor: %w", err)
+	}
+	return nil
+}
+
+const alertSelect = `
+	SELECT id, rule_id, rule_name, username, change_id, change_type, ticker, brokerage, message, triggered_at
+	FROM alerts`
+
+func scanAlerts(rows *sql.Rows) ([]models.Alert, error) {
+	defer rows.Close()
+
+	alerts := []models.Alert{}
+	for rows.Next() {
+		var a models.Alert
+		if err := rows.Scan(&a.ID, &a.RuleID, &a.RuleName, &a.Username, &a.ChangeID, &a.ChangeType,
+			&a.Ticker, &a.Brokerage, &a.Message, &a.TriggeredAt); err != nil {
+			return nil, fmt.Errorf("error scanning alert: %w", err)
+		}
+		alerts = append(alerts, a)
+	}
+
+	return alerts, rows.Err()
+}
+
+const alertRuleSelect = `
+	SELECT id, username, name, ticker, brokerage, change_type, rating, score_above, target_change_pct,
+	       cooldown_minutes, enabled, created_at, updated_at
+	FROM alert_rules`
+
+func (r *PostgresRepository) queryAlertRules(ctx context.Context, query string, args ...any) ([]models.AlertRule, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("error querying alert rules: %w", err)
+	}
+	defer rows.Close()
+
+	rules := []models.AlertRule{}
+	for rows.Next() {
+		rule, err := scanAlertRule(rows)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, *rule)
+	}
+
+	return rules, rows.Err()
+}
+
+func scanAlertRule(row interface{ Scan(...any) error }) (*models.AlertRule, error) {
+	var rule models.AlertRule
+	var scoreAbove, targetChangePct sql.NullFloat64
+
+	err := row.Scan(&rule.ID, &rule.Username, &rule.Name, &rule.Ticker, &rule.Brokerage, &rule.ChangeType,
+		&rule.Rating, &scoreAbove, &targetChangePct, &rule.CooldownMinutes, &rule.Enabled,
+		&rule.CreatedAt, &rule.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error scanning alert rule: %w", err)
+	}
+
+	if scoreAbove.Valid {
+		rule.ScoreAbove = &scoreAbove.Float64
+	}
+	if targetChangePct.Valid {
+		rule.TargetChangePct = &targetChangePct.Float64
+	}
+
+	return &rule, nil
+}
+
+// CreateWebhook implements WebhookRepository
+func (r *PostgresRepository) CreateWebhook(ctx context.Context, hook *models.Webhook) error {
+	err := r.db.QueryRowContext(ctx, `
+		INSERT INTO webhooks (username, url, events, secret, enabled)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at, updated_at
+	`, hook.Username, hook.URL, pq.Array(hook.Events), hook.Secret, hook.Enabled,
+	).Scan(&hook.ID, &hook.CreatedAt, &hook.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("error creating webhook: %w", err)
+	}
+
+	return nil
+}
+
+// GetWebhook implements WebhookRepository
+func (r *PostgresRepository) GetWebhook(ctx context.Context, username string, id int64) (*models.Webhook, error) {
+	hooks, err := r.queryWebhooks(ctx, webhookSelect+` WHERE username = $1 AND id = $2`, username, id)
+	if err != nil || len(hooks) == 0 {
+		return nil, err
+	}
+	return &hooks[0], nil
+}
+
+// ListWebhooks implements WebhookRepository
+func (r *PostgresRepository) ListWebhooks(ctx context.Context, username string) ([]models.Webhook, error) {
+	return r.queryWebhooks(ctx, webhookSelect+` WHERE username = $1 ORDER BY id`, username)
+}
+
+// DeleteWebhook implements WebhookRepository. Its deliveries are deleted by the foreign key.
+func (r *PostgresRepository) DeleteWebhook(ctx context.Context, username string, id int64) (bool, error) {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM webhooks WHERE username = $1 AND id = $2`, username, id)
+	if err != nil {
+		return false, fmt.Errorf("error deleting webhook: %w", err)
+	}
+	return rowsAffected(result)
+}
+
+// EnabledWebhooks implements WebhookRepository
+func (r *PostgresRepository) EnabledWebhooks(ctx context.Context, event string) ([]models.Webhook, error) {
+	return r.queryWebhooks(ctx, webhookSelect+` WHERE enabled AND $1 = ANY(events) ORDER BY id`, event)
+}
+
+// WebhookCursor implements WebhookRepository. The cursors are created by Migrate.
+func (r *PostgresRepository) WebhookCursor(ctx context.Context, source string) (int64, error) {
+	var position int64
+	err := r.db.QueryRowContext(ctx, `SELECT position FROM webhook_cursors WHERE source = $1`, source).Scan(&position)
+	if err != nil {
+		return 0, fmt.Errorf("error reading webhook cursor %q: %w", source, err)
+	}
+	return position, nil
+}
+
+// EnqueueDeliveries implements WebhookRepository. The cursor row is locked, so
+// concurrent instances queue each entry once.
+func (r *PostgresRepository) EnqueueDeliveries(ctx context.Context, source string, from, to int64, deliveries []models.WebhookDelivery) (bool, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var position int64
+	err = tx.QueryRowContext(ctx, `SELECT position FROM webhook_cursors WHERE source = $1 FOR UPDATE`, source).Scan(&position)
+	if err != nil {
+		return false, fmt.Errorf("error locking webhook cursor %q: %w", source, err)
+	}
+	if position != from {
+		return false, nil
+	}
+
+	if len(deliveries) > 0 {
+		stmt, err := tx.PrepareContext(ctx, `
+			INSERT INTO webhook_deliveries (webhook_id, event, payload, status, next_attempt_at)
+			SELECT $1, $2, $3, $4, $5
+			WHERE EXISTS (SELECT 1 FROM webhooks WHERE id = $1)
+		`)
+		if err != nil {
+			return false, fmt.Errorf("error preparing statement: %w", err)
+		}
+		defer stmt.Close()
+
+		for _, d := range deliveries {
+			if _, err := stmt.ExecContext(ctx, d.WebhookID, d.Event, string(d.Payload), d.Status, d.NextAttemptAt); err != nil {
+				return false, fmt.Errorf("error queueing webhook delivery: %w", err)
+			}
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx, `UPDATE webhook_cursors SET position = $2 WHERE source = $1`, source, to); err != nil {
+		return false, fmt.Errorf("error moving webhook cursor %q: %w", source, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("error committing transaction: %w", err)
+	}
+
+	return true, nil
+}
+
+// CreateDelivery implements WebhookRepository
+func (r *PostgresRepository) CreateDelivery(ctx context.Context, delivery *models.WebhookDelivery) error {
+	err := r.db.QueryRowContext(ctx, `
+		INSERT INTO webhook_deliveries (webhook_id, event, payload, status, next_attempt_at, replay_of)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at
+	`, delivery.WebhookID, delivery.Event, string(delivery.Payload), delivery.Status, delivery.NextAttemptAt, delivery.ReplayOf,
+	).Scan(&delivery.ID, &delivery.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("error creating webhook delivery: %w", err)
+	}
+
+	return nil
+}
+
+// ClaimDelivery implements WebhookRepository. Rows locked by another claim are skipped.
+func (r *PostgresRepository) ClaimDelivery(ctx context.Context, now time.Time, lease time.Duration) (*models.WebhookDelivery, error) {
+	delivery, err := scanDelivery(r.db.QueryRowContext(ctx, `
+		UPDATE webhook_deliveries d SET next_attempt_at = $2
+		FROM webhooks w
+		WHERE w.id = d.webhook_id AND d.id = (
+			SELECT id FROM webhook_deliveries
+			WHERE status = $3 AND next_attempt_at <= $1
+			ORDER BY next_attempt_at, id
+			LIMIT 1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING `+deliveryColumns("d")+`, w.url, w.secret
+	`, now, now.Add(lease), models.DeliveryStatusPending), true)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return delivery, err
+}
+
+// UpdateDelivery implements WebhookRepository
+func (r *PostgresRepository) UpdateDelivery(ctx context.Context, delivery *models.WebhookDelivery) error {
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE webhook_deliveries SET
+			status = $2, attempts = $3, next_attempt_at = $4, last_status_code = $5,
+			last_error = $6, delivered_at = $7
+		WHERE id = $1
+	`, delivery.ID, delivery.Status, delivery.Attempts, delivery.NextAttemptAt, delivery.LastStatusCode,
+		delivery.LastError, delivery.DeliveredAt)
+	if err != nil {
+		return fmt.Errorf("error updating webhook delivery: %w", err)
+	}
+	if found, err := rowsAffected(result); err != nil || !found {
+		return fmt.Errorf("error updating webhook delivery: delivery %d not found", delivery.ID)
+	}
+
+	return nil
+}
+
+// GetDelivery implements WebhookRepository
+func (r *PostgresRepository) GetDelivery(ctx context.Context, username string, webhookID, id int64) (*models.WebhookDelivery, error) {
+	delivery, err := scanDelivery(r.db.QueryRowContext(ctx, `
+		SELECT `+deliveryColumns("d")+`, w.url, w.secret
+		FROM webhook_deliveries d JOIN webhooks w ON w.id = d.webhook_id
+		WHERE w.username = $1 AND d.webhook_id = $2 AND d.id = $3
+	`, username, webhookID, id), true)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return delivery, err
+}
+
+// ListDeliveries implements WebhookRepository
+func (r *PostgresRepository) ListDeliveries(ctx context.Context, filters models.DeliveryFilters) (_ []models.WebhookDelivery, err error) {
+	defer metrics.ObserveQuery("list_webhook_deliveries", time.Now(), &err)
+
+	query := `
+		SELECT ` + deliveryColumns("d") + `
+		FROM webhook_deliveries d JOIN webhooks w ON w.id = d.webhook_id
+		WHERE w.username = $1 AND d.webhook_id = $2`
+	args := []any{filters.Username, filters.WebhookID}
+
+	if filters.Status != "" {
+		args = append(args, filters.Status)
+		query += fmt.Sprintf(" AND d.status = $%d", len(args))
+	}
+
+	args = append(args, filters.Limit)
+	query += fmt.Sprintf(" ORDER BY d.id DESC LIMIT $%d", len(args))
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("error querying webhook deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	deliveries := []models.WebhookDelivery{}
+	for rows.Next() {
+		delivery, err := scanDelivery(rows, false)
+		if err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, *delivery)
+	}
+
+	return deliveries, rows.Err()
+}
+
+const webhookSelect = `
+	SELECT id, username, url, events, secret, enabled, created_at, updated_at
+	FROM webhooks`
+
+func (r *PostgresRepository) queryWebhooks(ctx context.Context, query string, args ...any) ([]models.Webhook, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("error querying webhooks: %w", err)
+	}
+	defer rows.Close()
+
+	hooks := []models.Webhook{}
+	for rows.Next() {
+		var hook models.Webhook
+		if err := rows.Scan(&hook.ID, &hook.Username, &hook.URL, pq.Array(&hook.Events), &hook.Secret,
+			&hook.Enabled, &hook.CreatedAt, &hook.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("error scanning webhook: %w", err)
+		}
+		hooks = append(hooks, hook)
+	}
+
+	return hooks, rows.Err()
+}
+
+// deliveryColumns lists the webhook_deliveries columns read by scanDelivery, qualified by alias
+func deliveryColumns(alias string) string {
+	columns := []string{"id", "webhook_id", "event", "payload", "status", "attempts", "next_attempt_at",
+		"last_status_code", "last_error", "replay_of", "created_at", "delivered_at"}
+	for i, column := range columns {
+		columns[i] = alias + "." + column
+	}
+	return strings.Join(columns, ", ")
+}
+
+// scanDelivery scans the deliveryColumns, followed by the webhook URL and secret when withWebhook is set
+func scanDelivery(row interface{ Scan(...any) error }, withWebhook bool) (*models.WebhookDelivery, error) {
+	var d models.WebhookDelivery
+	var payload []byte
+	var nextAttemptAt, deliveredAt sql.NullTime
+	var replayOf sql.NullInt64
+
+	dest := []any{&d.ID, &d.WebhookID, &d.Event, &payload, &d.Status, &d.Attempts, &nextAttemptAt,
+		&d.LastStatusCode, &d.LastError, &replayOf, &d.CreatedAt, &deliveredAt}
+	if withWebhook {
+		dest = append(dest, &d.URL, &d.Secret)
+	}
+
+	err := row.Scan(dest...)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error scanning webhook delivery: %w", err)
+	}
+
+	d.Payload = payload
+	if nextAttemptAt.Valid {
+		d.NextAttemptAt = &nextAttemptAt.Time
+	}
+	if deliveredAt.Valid {
+		d.DeliveredAt = &deliveredAt.Time
+	}
+	if replayOf.Valid {
+		d.ReplayOf = &replayOf.Int64
+	}
+
+	return &d, nil
+}
+
+// GetDigestSubscription implements DigestRepository
+func (r *PostgresRepository) GetDigestSubscription(ctx context.Context, username string) (*models.DigestSubscription, error) {
+	subs, err := r.queryDigestSubscriptions(ctx, digestSubscriptionSelect+` WHERE username = $1`, username)
+	if err != nil || len(subs) == 0 {
+		return nil, err
+	}
+	return &subs[0], nil
+}
+
+// SaveDigestSubscription implements DigestRepository
+func (r *PostgresRepository) SaveDigestSubscription(ctx context.Context, sub *models.DigestSubscription) error {
+	var lastSentAt sql.NullTime
+	err := r.db.QueryRowContext(ctx, `
+		INSERT INTO digest_subscriptions (username, email, enabled, last_change_id)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (username) DO UPDATE SET
+			email = EXCLUDED.email,
+			enabled = EXCLUDED.enabled,
+			updated_at = NOW()
+		RETURNING last_change_id, last_sent_at, created_at, updated_at
+	`, sub.Username, sub.Email, sub.Enabled, sub.LastChangeID,
+	).Scan(&sub.LastChangeID, &lastSentAt, &sub.CreatedAt, &sub.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("error saving digest subscription: %w", err)
+	}
+
+	sub.LastSentAt = nil
+	if lastSentAt.Valid {
+		sub.LastSentAt = &lastSentAt.Time
+	}
+	return nil
+}
+
+// DeleteDigestSubscription implements DigestRepository
+func (r *PostgresRepository) DeleteDigestSubscription(ctx context.Context, username string) (bool, error) {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM digest_subscriptions WHERE username = $1`, username)
+	if err != nil {
+		return false, fmt.Errorf("error deleting digest subscription: %w", err)
+	}
+	return rowsAffected(result)
+}
+
+// DueDigestSubscriptions implements DigestRepository
+func (r *PostgresRepository) DueDigestSubscriptions(ctx context.Context, due time.Time) ([]models.DigestSubscription, error) {
+	return r.queryDigestSubscriptions(ctx, digestSubscriptionSelect+`
+		WHERE enabled AND COALESCE(last_sent_at, created_at) < $1
+		ORDER BY username`, due)
+}
+
+// MarkDigestSent implements DigestRepository
+func (r *PostgresRepository) MarkDigestSent(ctx context.Context, username string, lastChangeID int64, sentAt time.Time) error {
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE digest_subscriptions SET last_change_id = $2, last_sent_at = $3
+		WHERE username = $1
+	`, username, lastChangeID, sentAt)
+	if err != nil {
+		return fmt.Errorf("error marking digest sent: %w", err)
+	}
+	if found, err := rowsAffected(result); err != nil || !found {
+		return fmt.Errorf("error marking digest sent: subscription of %s not found", username)
+	}
+	return nil
+}
+
+const digestSubscriptionSelect = `
+	SELECT username, email, enabled, last_change_id, last_sent_at, created_at, updated_at
+	FROM digest_subscriptions`
+
+func (r *PostgresRepository) queryDigestSubscriptions(ctx context.Context, query string, args ...any) ([]models.DigestSubscription, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("error querying digest subscriptions: %w", err)
+	}
+	defer rows.Close()
+
+	subs := []models.DigestSubscription{}
+	for rows.Next() {
+		var sub models.DigestSubscription
+		var lastSentAt sql.NullTime
+		if err := rows.Scan(&sub.Username, &sub.Email, &sub.Enabled, &sub.LastChangeID, &lastSentAt,
+			&sub.CreatedAt, &sub.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("error scanning digest subscription: %w", err)
+		}
+		if lastSentAt.Valid {
+			sub.LastSentAt = &lastSentAt.Time
+		}
+		subs = append(subs, sub)
+	}
+
+	return subs, rows.Err()
+}
+
+func rowsAffected(result sql.Result) (bool, error) {
+	n, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("error reading affected rows: %w", err)
+	}
+	return n > 0, nil
+}
+
+// isUniqueViolation reports whether err is a PostgreSQL unique constraint violation
+func isUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505"
+}
@@ -0,0 +1,25 @@
+package repository
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// Limits for ListQuarantined
+const (
+	defaultQuarantineLimit = 100
+	maxQuarantineLimit     = 1000
+)
+
+func quarantineLimit(limit int) int {
+	if limit > 0 && limit <= maxQuarantineLimit {
+		return limit
+	}
+	return defaultQuarantineLimit
+}
+
+// payloadFingerprint identifies a quarantined payload so repeated syncs do not store it twice
+func payloadFingerprint(payload []byte) string {
+	sum := sha256.Sum256(payload)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,256 @@
+// Package repository provides storage for stocks and sync runs behind interfaces,
+// with a PostgreSQL implementation and an in-memory one for tests
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"Backend/internal/models"
+)
+
+// ErrWatchlistNameTaken is returned when a user already has a watchlist with the given name
+var ErrWatchlistNameTaken = errors.New("watchlist name already taken")
+
+// ErrUnknownSortColumn is returned when filters.SortBy is not a stock column
+var ErrUnknownSortColumn = errors.New("unknown sort column")
+
+// StockRepository stores analyst rating events for stocks
+type StockRepository interface {
+	// ListStocks returns the stocks matching filters together with the
+	// aggregate fields (TotalRegister, BuyCount, TotalBrokerages, LastUpdateFilter)
+	ListStocks(ctx context.Context, filters models.StockFilters) ([]models.Stock, error)
+
+	// EachStock calls fn with every stock matching filters, in the order of
+	// filters.SortBy and filters.Order, reading the rows as fn consumes them
+	// instead of loading them all. The aggregate fields of ListStocks are not
+	// set. An error from fn stops the iteration and is returned.
+	EachStock(ctx context.Context, filters models.StockFilters, fn func(models.Stock) error) error
+
+	// Recommendations returns up to limit stocks from today with a score above
+	// minScore, falling back to yesterday when there is nothing for today.
+	// Results are ordered by confidence, score and time, all descending.
+	Recommendations(ctx context.Context, minScore float64, limit int) ([]models.Stock, error)
+
+	// UpsertStocks inserts stocks, replacing existing rows with the same ticker and
+	// company unless the stored event is newer, so replaying history is harmless.
+	// Rows that fail ValidateStock are skipped and listed in the report; an error
+	// means nothing was written. Rows repeating an event already recorded are
+	// counted as duplicates, and every new coverage, rating or target change
+	// written is appended to the change feed in the same transaction.
+	UpsertStocks(ctx context.Context, stocks []models.Stock) (models.UpsertReport, error)
+
+	// UpsertHistoricalStocks is UpsertStocks for events replayed from history, such
+	// as a backfill. They are recorded and merged the same way but never appended to
+	// the change feed, so alerts, webhooks, streams and digests don't fire for them.
+	UpsertHistoricalStocks(ctx context.Context, stocks []models.Stock) (models.UpsertReport, error)
+}
+
+// ChangeRepository reads the change feed written by UpsertStocks
+type ChangeRepository interface {
+	// ListChanges returns up to limit changes with an ID greater than since, in
+	// ID order. IDs are assigned in commit order, so a reader never skips a change.
+	ListChanges(ctx context.Context, since int64, limit int) ([]models.StockChange, error)
+
+	// LastChangeID returns the ID of the newest change, or 0 when the feed is empty
+	LastChangeID(ctx context.Context) (int64, error)
+
+	// LatestRatings returns the newest rating of each brokerage for ticker,
+	// matched exactly, among the events with a time at or after since. It reads
+	// every event recorded by UpsertStocks, reiterations included, not only the
+	// ones that wrote a change. Results are ordered by time, newest first.
+	LatestRatings(ctx context.Context, ticker string, since time.Time) ([]models.BrokerageRating, error)
+}
+
+// SyncRunRepository records the outcome of every sync run
+type SyncRunRepository interface {
+	// StartSyncRun records the beginning of a sync run and returns its ID
+	StartSyncRun(ctx context.Context) (int64, error)
+
+	// FinishSyncRun stores the outcome of a sync run
+	FinishSyncRun(ctx context.Context, id int64, result models.SyncResult, runErr error) error
+
+	// LastSyncRun returns the most recent sync run, or nil if none has been recorded
+	LastSyncRun(ctx context.Context) (*models.SyncRun, error)
+}
+
+// QuarantineRepository keeps upstream records that failed validation for review
+type QuarantineRepository interface {
+	// QuarantineEvents stores events. An event whose payload is already quarantined
+	// only increments its seen count, whatever its status.
+	QuarantineEvents(ctx context.Context, events []models.QuarantinedEvent) error
+
+	// ListQuarantined returns quarantined events, most recently seen first
+	ListQuarantined(ctx context.Context, filters models.QuarantineFilters) ([]models.QuarantinedEvent, error)
+
+	// GetQuarantined returns a quarantined event, or nil if it does not exist
+	GetQuarantined(ctx context.Context, id int64) (*models.QuarantinedEvent, error)
+
+	// ClaimQuarantined atomically moves a pending event to status, recording when
+	// it was resolved, and returns it. It returns nil when the event does not exist
+	// or is no longer pending, so concurrent callers claim an event only once.
+	ClaimQuarantined(ctx context.Context, id int64, status string) (*models.QuarantinedEvent, error)
+
+	// ReopenQuarantined returns a claimed event to pending
+	ReopenQuarantined(ctx context.Context, id int64) error
+}
+
+// BackfillRepository stores the progress of historical backfills
+type BackfillRepository interface {
+	// CreateBackfillJob stores a new job and sets its ID and timestamps
+	CreateBackfillJob(ctx context.Context, job *models.BackfillJob) error
+
+	// UpdateBackfillJob saves the status, cursor, counters and error of a job
+	UpdateBackfillJob(ctx context.Context, job *models.BackfillJob) error
+
+	// GetBackfillJob returns a job, or nil if it does not exist
+	GetBackfillJob(ctx context.Context, id int64) (*models.BackfillJob, error)
+
+	// ListBackfillJobs returns up to limit jobs, newest first
+	ListBackfillJobs(ctx context.Context, limit int) ([]models.BackfillJob, error)
+
+	// LockBackfillJob claims a job for a single runner across processes and
+	// replicas. ok is false when someone else holds it. unlock releases the
+	// claim, which also ends when the holder's process dies.
+	LockBackfillJob(ctx context.Context, id int64) (unlock func(), ok bool, err error)
+}
+
+// WatchlistRepository stores the watchlists of each user. Every method is scoped
+// to the owner's username, so a list owned by someone else reads as missing.
+type WatchlistRepository interface {
+	// CreateWatchlist stores a list with its tickers and sets its ID and timestamps.
+	// It returns ErrWatchlistNameTaken when the owner already has a list with that name.
+	CreateWatchlist(ctx context.Context, list *models.Watchlist) error
+
+	// GetWatchlist returns a list with its tickers, or nil if it does not exist
+	GetWatchlist(ctx context.Context, username string, id int64) (*models.Watchlist, error)
+
+	// ListWatchlists returns the owner's lists with their tickers, by name
+	ListWatchlists(ctx context.Context, username string) ([]models.Watchlist, error)
+
+	// RenameWatchlist renames a list and reports whether it exists. It returns
+	// ErrWatchlistNameTaken when the owner already has a list with that name.
+	RenameWatchlist(ctx context.Context, username string, id int64, name string) (bool, error)
+
+	// DeleteWatchlist deletes a list with its tickers and reports whether it existed
+	DeleteWatchlist(ctx context.Context, username string, id int64) (bool, error)
+
+	// AddWatchlistTickers adds tickers to a list, skipping those already in it,
+	// and reports whether the list exists
+	AddWatchlistTickers(ctx context.Context, username string, id int64, tickers []string) (bool, error)
+
+	// RemoveWatchlistTicker removes a ticker from a list and reports whether the list exists
+	RemoveWatchlistTicker(ctx context.Context, username string, id int64, ticker string) (bool, error)
+}
+
+// AlertRepository stores alert rules and the alerts they triggered. Rules and
+// alerts are scoped to the owner's username like watchlists.
+type AlertRepository interface {
+	// CreateAlertRule stores a rule and sets its ID and timestamps
+	CreateAlertRule(ctx context.Context, rule *models.AlertRule) error
+
+	// GetAlertRule returns a rule, or nil if it does not exist
+	GetAlertRule(ctx context.Context, username string, id int64) (*models.AlertRule, error)
+
+	// ListAlertRules returns the owner's rules by ID
+	ListAlertRules(ctx context.Context, username string) ([]models.AlertRule, error)
+
+	// UpdateAlertRule replaces the conditions of the rule with the same ID and
+	// owner, sets its UpdatedAt and reports whether it exists
+	UpdateAlertRule(ctx context.Context, rule *models.AlertRule) (bool, error)
+
+	// DeleteAlertRule deletes a rule with its alerts and reports whether it existed
+	DeleteAlertRule(ctx context.Context, username string, id int64) (bool, error)
+
+	// EnabledAlertRules returns the enabled rules of every user
+	EnabledAlertRules(ctx context.Context) ([]models.AlertRule, error)
+
+	// RecordAlert stores an alert and sets its ID and TriggeredAt, unless the rule
+	// already triggered for the same change, or for the same ticker less than
+	// cooldown ago. It reports whether the alert was stored.
+	RecordAlert(ctx context.Context, alert *models.Alert, cooldown time.Duration) (bool, error)
+
+	// ListAlerts returns up to filters.Limit alerts, newest first
+	ListAlerts(ctx context.Context, filters models.AlertFilters) ([]models.Alert, error)
+
+	// AlertsSince returns up to limit alerts of every user with an ID greater
+	// than since, in ID order
+	AlertsSince(ctx context.Context, since int64, limit int) ([]models.Alert, error)
+
+	// AlertCursor returns the ID of the last change evaluated against the rules,
+	// and false when no evaluation has been recorded yet
+	AlertCursor(ctx context.Context) (int64, bool, error)
+
+	// SetAlertCursor stores the ID of the last change evaluated against the rules
+	SetAlertCursor(ctx context.Context, changeID int64) error
+}
+
+// WebhookRepository stores webhooks and the queue of their deliveries. Webhooks
+// are scoped to the owner's username like watchlists.
+type WebhookRepository interface {
+	// CreateWebhook stores a webhook and sets its ID and timestamps
+	CreateWebhook(ctx context.Context, hook *models.Webhook) error
+
+	// GetWebhook returns a webhook, or nil if it does not exist
+	GetWebhook(ctx context.Context, username string, id int64) (*models.Webhook, error)
+
+	// ListWebhooks returns the owner's webhooks by ID
+	ListWebhooks(ctx context.Context, username string) ([]models.Webhook, error)
+
+	// DeleteWebhook deletes a webhook with its deliveries and reports whether it existed
+	DeleteWebhook(ctx context.Context, username string, id int64) (bool, error)
+
+	// EnabledWebhooks returns the enabled webhooks of every user subscribed to event
+	EnabledWebhooks(ctx context.Context, event string) ([]models.Webhook, error)
+
+	// WebhookCursor returns the ID of the last entry of source (a change or an
+	// alert) queued for delivery
+	WebhookCursor(ctx context.Context, source string) (int64, error)
+
+	// EnqueueDeliveries stores deliveries and moves the cursor of source from
+	// from to to in one transaction. It stores nothing and returns false when
+	// the cursor is no longer at from, because another instance queued them.
+	EnqueueDeliveries(ctx context.Context, source string, from, to int64, deliveries []models.WebhookDelivery) (bool, error)
+
+	// CreateDelivery stores a delivery and sets its ID and CreatedAt
+	CreateDelivery(ctx context.Context, delivery *models.WebhookDelivery) error
+
+	// ClaimDelivery returns the pending delivery due first at now, with the URL
+	// and secret of its webhook, and postpones it until now plus lease so no one
+	// else claims it while it is sent. It returns nil when nothing is due.
+	ClaimDelivery(ctx context.Context, now time.Time, lease time.Duration) (*models.WebhookDelivery, error)
+
+	// UpdateDelivery saves the status, attempts, next attempt, last response and
+	// delivery time of a delivery
+	UpdateDelivery(ctx context.Context, delivery *models.WebhookDelivery) error
+
+	// GetDelivery returns a delivery of one of the owner's webhooks with its URL
+	// and secret, or nil if it does not exist
+	GetDelivery(ctx context.Context, username string, webhookID, id int64) (*models.WebhookDelivery, error)
+
+	// ListDeliveries returns up to filters.Limit deliveries of a webhook, newest first
+	ListDeliveries(ctx context.Context, filters models.DeliveryFilters) ([]models.WebhookDelivery, error)
+}
+
+// DigestRepository stores the users subscribed to the email digest
+type DigestRepository interface {
+	// GetDigestSubscription returns the user's subscription, or nil if there is none
+	GetDigestSubscription(ctx context.Context, username string) (*models.DigestSubscription, error)
+
+	// SaveDigestSubscription stores the email and enabled flag of a subscription,
+	// creating it with sub.LastChangeID when the user has none, and loads the
+	// stored subscription back into sub
+	SaveDigestSubscription(ctx context.Context, sub *models.DigestSubscription) error
+
+	// DeleteDigestSubscription deletes the user's subscription and reports whether it existed
+	DeleteDigestSubscription(ctx context.Context, username string) (bool, error)
+
+	// DueDigestSubscriptions returns the enabled subscriptions that have not been
+	// sent a digest since due, ignoring those created after due
+	DueDigestSubscriptions(ctx context.Context, due time.Time) ([]models.DigestSubscription, error)
+
+	// MarkDigestSent records that a digest covering the changes up to
+	// lastChangeID was sent to the user at sentAt
+	MarkDigestSent(ctx context.Context, username string, lastChangeID int64, sentAt time.Time) error
+}
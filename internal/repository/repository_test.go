@@ -0,0 +1,1086 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"Backend/internal/database"
+	"Backend/internal/models"
+	"Backend/internal/testutil"
+)
+
+// repo is what both implementations provide
+type repo interface {
+	StockRepository
+	SyncRunRepository
+	QuarantineRepository
+	BackfillRepository
+	ChangeRepository
+	WatchlistRepository
+	AlertRepository
+	WebhookRepository
+	DigestRepository
+}
+
+func TestMemoryRepository(t *testing.T) {
+	testRepository(t, func(t *testing.T) repo { return NewMemoryRepository() })
+}
+
+func TestPostgresRepository(t *testing.T) {
+	testRepository(t, func(t *testing.T) repo { return NewPostgresRepository(testutil.PostgresDB(t), nil) })
+}
+
+// testRepository runs the behaviour every implementation must share
+func testRepository(t *testing.T, newRepo func(t *testing.T) repo) {
+	// Midday keeps "today" and "yesterday" stable however long the test takes
+	today := time.Now().UTC().Truncate(24 * time.Hour).Add(12 * time.Hour)
+	yesterday := today.AddDate(0, 0, -1)
+	lastWeek := today.AddDate(0, 0, -7)
+
+	stock := func(ticker, company, brokerage, ratingTo string, score float64, at time.Time) models.Stock {
+		return models.Stock{
+			Ticker: ticker, Company: company, Brokerage: brokerage, Action: "upgraded by",
+			RatingFrom: "Hold", RatingTo: ratingTo, TargetFrom: "$10", TargetTo: "$12",
+			// updated_at stays in the past so upserts visibly refresh it
+			Time: at, CreatedAt: at, UpdatedAt: at.Add(-12 * time.Hour),
+			Score: score, Confidence: score / 100, Reason: "test", CurrentRating: ratingTo,
+		}
+	}
+
+	seed := func(t *testing.T, r repo) {
+		t.Helper()
+		_, err := r.UpsertStocks(context.Background(), []models.Stock{
+			stock("AAPL", "Apple Inc", "Goldman Sachs", "Buy", 80, today),
+			stock("MSFT", "Microsoft Corp", "Morgan Stanley", "Hold", 55, today),
+			stock("AMZN", "Amazon.com Inc", "Goldman Sachs", "Buy", 70, yesterday),
+			stock("TSLA", "Tesla Inc", "Barclays", "Sell", 20, lastWeek),
+		})
+		if err != nil {
+			t.Fatalf("UpsertStocks() error = %v", err)
+		}
+	}
+
+	tickers := func(stocks []models.Stock) string {
+		var out []string
+		for _, s := range stocks {
+			out = append(out, s.Ticker)
+		}
+		return strings.Join(out, ",")
+	}
+
+	t.Run("upsert replaces rows with the same ticker and company", func(t *testing.T) {
+		r := newRepo(t)
+		seed(t, r)
+		ctx := context.Background()
+
+		before, err := r.ListStocks(ctx, models.StockFilters{Ticker: "AAPL"})
+		if err != nil || len(before) != 1 {
+			t.Fatalf("ListStocks() = %v, %v; want one AAPL", before, err)
+		}
+
+		updated := stock("AAPL", "Apple Inc", "JP Morgan", "Strong-Buy", 90, today)
+		report, err := r.UpsertStocks(ctx, []models.Stock{updated})
+		if err != nil || report.Upserted != 1 {
+			t.Fatalf("UpsertStocks() = %+v, %v; want one row upserted", report, err)
+		}
+
+		after, err := r.ListStocks(ctx, models.StockFilters{Ticker: "AAPL"})
+		if err != nil || len(after) != 1 {
+			t.Fatalf("ListStocks() = %v, %v; want one AAPL", after, err)
+		}
+		if after[0].ID != before[0].ID {
+			t.Errorf("ID changed from %d to %d", before[0].ID, after[0].ID)
+		}
+		if after[0].RatingTo != "Strong-Buy" || after[0].Score != 90 {
+			t.Errorf("row not updated: %+v", after[0])
+		}
+		if after[0].Brokerage != "Goldman Sachs" {
+			t.Errorf("brokerage = %q, want the original Goldman Sachs kept", after[0].Brokerage)
+		}
+		if !after[0].UpdatedAt.After(before[0].UpdatedAt) {
+			t.Errorf("updated_at not refreshed: %v -> %v", before[0].UpdatedAt, after[0].UpdatedAt)
+		}
+
+		all, err := r.ListStocks(ctx, models.StockFilters{})
+		if err != nil || len(all) != 4 {
+			t.Errorf("ListStocks() = %d rows, %v; want 4", len(all), err)
+		}
+	})
+
+	t.Run("upsert of nothing is a no-op", func(t *testing.T) {
+		report, err := newRepo(t).UpsertStocks(context.Background(), nil)
+		if err != nil || report.Upserted != 0 || len(report.Rejected) != 0 {
+			t.Errorf("UpsertStocks(nil) = %+v, %v; want an empty report", report, err)
+		}
+	})
+
+	t.Run("upsert skips invalid rows and reports them", func(t *testing.T) {
+		r := newRepo(t)
+		ctx := context.Background()
+
+		long := stock("TOOLONGTICKER", "Long Corp", "Barclays", "Buy", 60, today)
+		missing := stock("NOCO", "", "Barclays", "Buy", 60, today)
+		report, err := r.UpsertStocks(ctx, []models.Stock{
+			stock("AAPL", "Apple Inc", "Goldman Sachs", "Buy", 80, today),
+			long,
+			missing,
+			stock("MSFT", "Microsoft Corp", "Morgan Stanley", "Hold", 55, today),
+		})
+		if err != nil {
+			t.Fatalf("UpsertStocks() error = %v", err)
+		}
+		if report.Upserted != 2 {
+			t.Errorf("Upserted = %d, want 2", report.Upserted)
+		}
+		if len(report.Rejected) != 2 || report.Rejected[0].Index != 1 || report.Rejected[1].Index != 2 {
+			t.Fatalf("Rejected = %+v, want rows 1 and 2", report.Rejected)
+		}
+		if !strings.Contains(report.Rejected[0].Reason, "ticker") || !strings.Contains(report.Rejected[1].Reason, "company is required") {
+			t.Errorf("Rejected reasons = %+v", report.Rejected)
+		}
+
+		stocks, err := r.ListStocks(ctx, models.StockFilters{SortBy: "ticker", Order: "ASC"})
+		if err != nil || tickers(stocks) != "AAPL,MSFT" {
+			t.Errorf("ListStocks() = %s, %v; want AAPL,MSFT", tickers(stocks), err)
+		}
+	})
+
+	t.Run("upsert keeps the last of duplicate rows in a batch", func(t *testing.T) {
+		r := newRepo(t)
+		ctx := context.Background()
+
+		report, err := r.UpsertStocks(ctx, []models.Stock{
+			stock("AAPL", "Apple Inc", "Goldman Sachs", "Hold", 50, today),
+			stock("AAPL", "Apple Inc", "Goldman Sachs", "Buy", 80, today),
+		})
+		if err != nil || report.Upserted != 1 {
+			t.Fatalf("UpsertStocks() = %+v, %v; want one row upserted", report, err)
+		}
+
+		stocks, err := r.ListStocks(ctx, models.StockFilters{})
+		if err != nil || len(stocks) != 1 || stocks[0].RatingTo != "Buy" {
+			t.Errorf("ListStocks() = %+v, %v; want the single Buy row", stocks, err)
+		}
+	})
+
+	t.Run("upsert never replaces a newer event with an older one", func(t *testing.T) {
+		r := newRepo(t)
+		ctx := context.Background()
+
+		if _, err := r.UpsertStocks(ctx, []models.Stock{stock("AAPL", "Apple Inc", "Goldman Sachs", "Buy", 80, today)}); err != nil {
+			t.Fatalf("UpsertStocks() error = %v", err)
+		}
+		// A backfill replaying last week must not undo today's rating
+		report, err := r.UpsertStocks(ctx, []models.Stock{stock("AAPL", "Apple Inc", "Goldman Sachs", "Sell", 10, lastWeek)})
+		if err != nil || report.Upserted != 0 {
+			t.Fatalf("UpsertStocks() = %+v, %v; want nothing upserted", report, err)
+		}
+
+		stocks, err := r.ListStocks(ctx, models.StockFilters{})
+		if err != nil || len(stocks) != 1 || stocks[0].RatingTo != "Buy" || !stocks[0].Time.Equal(today) {
+			t.Errorf("ListStocks() = %+v, %v; want today's Buy row", stocks, err)
+		}
+	})
+
+	t.Run("upsert counts events already recorded as duplicates", func(t *testing.T) {
+		r := newRepo(t)
+		ctx := context.Background()
+
+		report, err := r.UpsertStocks(ctx, []models.Stock{
+			stock("AAPL", "Apple Inc", "Goldman Sachs", "Buy", 80, yesterday),
+			stock("AAPL", "Apple Inc", "Goldman Sachs", "Buy", 80, yesterday),
+			stock("MSFT", "Microsoft Corp", "Morgan Stanley", "Hold", 55, yesterday),
+		})
+		if err != nil || report.Upserted != 2 || report.Duplicates != 1 {
+			t.Fatalf("UpsertStocks() = %+v, %v; want 2 upserted, 1 duplicate", report, err)
+		}
+
+		// A later sync resends one event and brings a new action for the other stock
+		report, err = r.UpsertStocks(ctx, []models.Stock{
+			stock("AAPL", "Apple Inc", "Goldman Sachs", "Buy", 80, yesterday),
+			stock("MSFT", "Microsoft Corp", "Morgan Stanley", "Buy", 75, today),
+		})
+		if err != nil || report.Duplicates != 1 {
+			t.Errorf("UpsertStocks() = %+v, %v; want 1 duplicate", report, err)
+		}
+	})
+
+	t.Run("upsert appends changes to the feed", func(t *testing.T) {
+		r := newRepo(t)
+		ctx := context.Background()
+
+		seed(t, r)
+		initial, err := r.ListChanges(ctx, 0, 100)
+		if err != nil || len(initial) != 4 {
+			t.Fatalf("ListChanges() = %+v, %v; want 4 changes", initial, err)
+		}
+		for _, change := range initial {
+			if change.Type != models.ChangeNewCoverage {
+				t.Errorf("change = %+v, want new coverage", change)
+			}
+		}
+		cursor := initial[len(initial)-1].ID
+
+		upgrade := stock("MSFT", "Microsoft Corp", "Morgan Stanley", "Buy", 75, today)
+		upgrade.TargetTo = "$15"
+		_, err = r.UpsertStocks(ctx, []models.Stock{
+			upgrade,
+			// Resent, and older than what is stored: neither is a change
+			stock("AAPL", "Apple Inc", "Goldman Sachs", "Buy", 80, today),
+			stock("TSLA", "Tesla Inc", "Barclays", "Buy", 30, lastWeek.Add(-time.Hour)),
+		})
+		if err != nil {
+			t.Fatalf("UpsertStocks() error = %v", err)
+		}
+
+		changes, err := r.ListChanges(ctx, cursor, 100)
+		if err != nil || len(changes) != 2 {
+			t.Fatalf("ListChanges(%d) = %+v, %v; want 2 changes", cursor, changes, err)
+		}
+		if changes[0].Type != models.ChangeUpgrade || changes[1].Type != models.ChangeTargetChange {
+			t.Errorf("changes = %+v, want an upgrade then a target change", changes)
+		}
+		if c := changes[0]; c.Ticker != "MSFT" || c.RatingFrom != "Hold" || c.RatingTo != "Buy" || c.StockID == 0 || c.ID <= cursor {
+			t.Errorf("change = %+v, want MSFT from Hold to Buy after the cursor", c)
+		}
+
+		if last, err := r.LastChangeID(ctx); err != nil || last != changes[1].ID {
+			t.Errorf("LastChangeID() = %d, %v; want %d", last, err, changes[1].ID)
+		}
+
+		page, err := r.ListChanges(ctx, cursor, 1)
+		if err != nil || len(page) != 1 || page[0].ID != changes[0].ID {
+			t.Errorf("ListChanges(%d, 1) = %+v, %v; want only the upgrade", cursor, page, err)
+		}
+	})
+
+	t.Run("historical upsert records events without changes", func(t *testing.T) {
+		r := newRepo(t)
+		ctx := context.Background()
+
+		report, err := r.UpsertHistoricalStocks(ctx, []models.Stock{
+			stock("AAPL", "Apple Inc", "Goldman Sachs", "Buy", 80, lastWeek),
+			stock("MSFT", "Microsoft Corp", "Morgan Stanley", "Hold", 55, lastWeek),
+		})
+		if err != nil || report.Upserted != 2 {
+			t.Fatalf("UpsertHistoricalStocks() = %+v, %v; want 2 upserted", report, err)
+		}
+		upgrade := stock("MSFT", "Microsoft Corp", "Morgan Stanley", "Buy", 75, yesterday)
+		if _, err := r.UpsertHistoricalStocks(ctx, []models.Stock{upgrade}); err != nil {
+			t.Fatalf("UpsertHistoricalStocks() error = %v", err)
+		}
+
+		if changes, err := r.ListChanges(ctx, 0, 100); err != nil || len(changes) != 0 {
+			t.Errorf("ListChanges() = %+v, %v; want no changes", changes, err)
+		}
+		if ratings, err := r.LatestRatings(ctx, "MSFT", lastWeek); err != nil || len(ratings) != 1 || ratings[0].Rating != "Buy" {
+			t.Errorf("LatestRatings(MSFT) = %+v, %v; want the historical upgrade", ratings, err)
+		}
+		if report, err := r.UpsertStocks(ctx, []models.Stock{upgrade}); err != nil || report.Duplicates != 1 {
+			t.Errorf("UpsertStocks() of a recorded event = %+v, %v; want a duplicate", report, err)
+		}
+	})
+
+	t.Run("latest ratings keep the newest event of each brokerage", func(t *testing.T) {
+		r := newRepo(t)
+		ctx := context.Background()
+
+		for _, s := range []models.Stock{
+			stock("AAPL", "Apple Inc", "Goldman Sachs", "Buy", 80, lastWeek),
+			stock("AAPL", "Apple Inc", "Barclays", "Sell", 20, yesterday),
+			stock("AAPL", "Apple Inc", "Goldman Sachs", "Hold", 50, today),
+			stock("MSFT", "Microsoft Corp", "UBS", "Buy", 70, today),
+		} {
+			if _, err := r.UpsertStocks(ctx, []models.Stock{s}); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		ratings, err := r.LatestRatings(ctx, "AAPL", lastWeek)
+		if err != nil || len(ratings) != 2 {
+			t.Fatalf("LatestRatings() = %+v, %v; want 2 ratings", ratings, err)
+		}
+		if ratings[0].Brokerage != "Goldman Sachs" || ratings[0].Rating != "Hold" || ratings[1].Brokerage != "Barclays" || ratings[1].Rating != "Sell" {
+			t.Errorf("LatestRatings() = %+v, want Goldman Sachs at Hold then Barclays at Sell", ratings)
+		}
+
+		if ratings, err := r.LatestRatings(ctx, "AAPL", today); err != nil || len(ratings) != 1 || ratings[0].Brokerage != "Goldman Sachs" {
+			t.Errorf("LatestRatings(since today) = %+v, %v; want only Goldman Sachs", ratings, err)
+		}
+		if ratings, err := r.LatestRatings(ctx, "aapl", lastWeek); err != nil || len(ratings) != 0 {
+			t.Errorf("LatestRatings(aapl) = %+v, %v; want none, tickers match exactly", ratings, err)
+		}
+
+		// A reiteration writes no change but is still the brokerage's newest rating
+		last, err := r.LastChangeID(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		reiterated := today.Add(time.Hour)
+		if _, err := r.UpsertStocks(ctx, []models.Stock{stock("AAPL", "Apple Inc", "Goldman Sachs", "Hold", 50, reiterated)}); err != nil {
+			t.Fatal(err)
+		}
+		if id, err := r.LastChangeID(ctx); err != nil || id != last {
+			t.Fatalf("LastChangeID() = %d, %v; want %d, a reiteration is not a change", id, err, last)
+		}
+		ratings, err = r.LatestRatings(ctx, "AAPL", today.Add(time.Minute))
+		if err != nil || len(ratings) != 1 || ratings[0].Brokerage != "Goldman Sachs" || !ratings[0].Time.Equal(reiterated) {
+			t.Errorf("LatestRatings(after the change) = %+v, %v; want the Goldman Sachs reiteration", ratings, err)
+		}
+	})
+
+	t.Run("filters", func(t *testing.T) {
+		r := newRepo(t)
+		seed(t, r)
+
+		tests := []struct {
+			name    string
+			filters models.StockFilters
+			want    string
+		}{
+			{"no filters sorts by confidence", models.StockFilters{}, "AAPL,AMZN,MSFT,TSLA"},
+			{"ticker is a case-insensitive substring", models.StockFilters{Ticker: "am"}, "AMZN"},
+			{"company", models.StockFilters{Company: "inc"}, "AAPL,AMZN,TSLA"},
+			{"brokerage", models.StockFilters{Brokerage: "goldman"}, "AAPL,AMZN"},
+			{"minimum score", models.StockFilters{Score: 55}, "AAPL,AMZN,MSFT"},
+			{"today", models.StockFilters{Today: "true"}, "AAPL,MSFT"},
+			{"sort ascending by ticker", models.StockFilters{SortBy: "ticker", Order: "ASC"}, "AAPL,AMZN,MSFT,TSLA"},
+			{"limit", models.StockFilters{Limit: 2}, "AAPL,AMZN"},
+			{"combined", models.StockFilters{Brokerage: "Goldman", Score: 75}, "AAPL"},
+			{"exact tickers", models.StockFilters{Tickers: []string{"TSLA", "MSFT", "AM"}}, "MSFT,TSLA"},
+			{"exact brokerages", models.StockFilters{Brokerages: []string{"Barclays", "Morgan Stanley", "Goldman"}}, "MSFT,TSLA"},
+			{"offset", models.StockFilters{Limit: 2, Offset: 1}, "AMZN,MSFT"},
+			{"offset past the end", models.StockFilters{Offset: 4}, ""},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				stocks, err := r.ListStocks(context.Background(), tt.filters)
+				if err != nil {
+					t.Fatalf("ListStocks() error = %v", err)
+				}
+				if got := tickers(stocks); got != tt.want {
+					t.Errorf("ListStocks() = %s, want %s", got, tt.want)
+				}
+			})
+		}
+	})
+
+	t.Run("each stock follows the filters and order", func(t *testing.T) {
+		r := newRepo(t)
+		seed(t, r)
+		ctx := context.Background()
+
+		each := func(filters models.StockFilters) ([]models.Stock, error) {
+			var stocks []models.Stock
+			err := r.EachStock(ctx, filters, func(stock models.Stock) error {
+				stocks = append(stocks, stock)
+				return nil
+			})
+			return stocks, err
+		}
+
+		for _, filters := range []models.StockFilters{
+			{},
+			{Brokerage: "goldman"},
+			{SortBy: "ticker", Order: "ASC", Limit: 3},
+			{Today: "true", Score: 55},
+		} {
+			want, err := r.ListStocks(ctx, filters)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := each(filters)
+			if err != nil || tickers(got) != tickers(want) {
+				t.Errorf("EachStock(%+v) = %s, %v; want %s", filters, tickers(got), err, tickers(want))
+			}
+			if len(got) > 0 && got[0].TotalRegister != 0 {
+				t.Errorf("EachStock(%+v) set TotalRegister = %d, want 0", filters, got[0].TotalRegister)
+			}
+		}
+
+		if _, err := each(models.StockFilters{SortBy: "ticker; DROP TABLE stocks"}); !errors.Is(err, ErrUnknownSortColumn) {
+			t.Errorf("EachStock() with an unknown sort column error = %v, want ErrUnknownSortColumn", err)
+		}
+		if _, err := r.ListStocks(ctx, models.StockFilters{SortBy: "ticker; DROP TABLE stocks"}); !errors.Is(err, ErrUnknownSortColumn) {
+			t.Errorf("ListStocks() with an unknown sort column error = %v, want ErrUnknownSortColumn", err)
+		}
+
+		stop := errors.New("stop")
+		calls := 0
+		err := r.EachStock(ctx, models.StockFilters{}, func(models.Stock) error {
+			calls++
+			return stop
+		})
+		if !errors.Is(err, stop) || calls != 1 {
+			t.Errorf("EachStock() with a failing fn = %v after %d calls, want stop after 1", err, calls)
+		}
+	})
+
+	t.Run("source defaults to the API and follows the stored event", func(t *testing.T) {
+		r := newRepo(t)
+		ctx := context.Background()
+
+		imported := stock("MSFT", "Microsoft Corp", "UBS", "Buy", 70, yesterday)
+		imported.Source = models.StockSourceImport
+		if _, err := r.UpsertStocks(ctx, []models.Stock{stock("AAPL", "Apple Inc", "UBS", "Buy", 80, yesterday), imported}); err != nil {
+			t.Fatal(err)
+		}
+
+		stocks, err := r.ListStocks(ctx, models.StockFilters{SortBy: "ticker", Order: "ASC"})
+		if err != nil || len(stocks) != 2 || stocks[0].Source != models.StockSourceAPI || stocks[1].Source != models.StockSourceImport {
+			t.Fatalf("ListStocks() = %+v, %v; want AAPL from the API and MSFT imported", stocks, err)
+		}
+		if stocks, err := r.ListStocks(ctx, models.StockFilters{Source: models.StockSourceImport}); err != nil || tickers(stocks) != "MSFT" {
+			t.Errorf("ListStocks(source=import) = %s, %v; want MSFT", tickers(stocks), err)
+		}
+
+		// A newer event from the API replaces the imported row
+		if _, err := r.UpsertStocks(ctx, []models.Stock{stock("MSFT", "Microsoft Corp", "UBS", "Hold", 50, today)}); err != nil {
+			t.Fatal(err)
+		}
+		if stocks, err := r.ListStocks(ctx, models.StockFilters{Source: models.StockSourceImport}); err != nil || len(stocks) != 0 {
+			t.Errorf("ListStocks(source=import) after an API update = %s, %v; want none", tickers(stocks), err)
+		}
+	})
+
+	t.Run("aggregates count every matching row before the limit", func(t *testing.T) {
+		r := newRepo(t)
+		seed(t, r)
+
+		stocks, err := r.ListStocks(context.Background(), models.StockFilters{Brokerage: "goldman", Limit: 1})
+		if err != nil || len(stocks) != 1 {
+			t.Fatalf("ListStocks() = %v, %v; want one row", stocks, err)
+		}
+
+		got := stocks[0]
+		if got.TotalRegister != 2 || got.BuyCount != 2 || got.TotalBrokerages != 3 {
+			t.Errorf("aggregates = total %d, buy %d, brokerages %d; want 2, 2, 3", got.TotalRegister, got.BuyCount, got.TotalBrokerages)
+		}
+		if want := today.Add(-12 * time.Hour); !got.LastUpdateFilter.Equal(want) {
+			t.Errorf("LastUpdateFilter = %v, want %v", got.LastUpdateFilter, want)
+		}
+	})
+
+	t.Run("filter by id", func(t *testing.T) {
+		r := newRepo(t)
+		seed(t, r)
+
+		msft, err := r.ListStocks(context.Background(), models.StockFilters{Ticker: "MSFT"})
+		if err != nil || len(msft) != 1 {
+			t.Fatalf("ListStocks() = %v, %v; want one MSFT", msft, err)
+		}
+
+		stocks, err := r.ListStocks(context.Background(), models.StockFilters{ProductID: msft[0].ID})
+		if err != nil || tickers(stocks) != "MSFT" {
+			t.Errorf("ListStocks(id=%d) = %s, %v; want MSFT", msft[0].ID, tickers(stocks), err)
+		}
+	})
+
+	t.Run("today falls back to yesterday", func(t *testing.T) {
+		r := newRepo(t)
+		_, err := r.UpsertStocks(context.Background(), []models.Stock{
+			stock("AMZN", "Amazon.com Inc", "Goldman Sachs", "Buy", 70, yesterday),
+			stock("TSLA", "Tesla Inc", "Barclays", "Sell", 20, lastWeek),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		stocks, err := r.ListStocks(context.Background(), models.StockFilters{Today: "true"})
+		if err != nil || tickers(stocks) != "AMZN" {
+			t.Errorf("ListStocks(today) = %s, %v; want AMZN", tickers(stocks), err)
+		}
+	})
+
+	t.Run("recommendations", func(t *testing.T) {
+		r := newRepo(t)
+		seed(t, r)
+		ctx := context.Background()
+
+		got, err := r.Recommendations(ctx, 50, 10)
+		if err != nil || tickers(got) != "AAPL,MSFT" {
+			t.Errorf("Recommendations(50, 10) = %s, %v; want today's AAPL,MSFT", tickers(got), err)
+		}
+
+		got, err = r.Recommendations(ctx, 50, 1)
+		if err != nil || tickers(got) != "AAPL" {
+			t.Errorf("Recommendations(50, 1) = %s, %v; want AAPL", tickers(got), err)
+		}
+
+		// Yesterday's AMZN also scores above 60 but today has results
+		got, err = r.Recommendations(ctx, 60, 10)
+		if err != nil || tickers(got) != "AAPL" {
+			t.Errorf("Recommendations(60, 10) = %s, %v; want AAPL", tickers(got), err)
+		}
+	})
+
+	t.Run("recommendations fall back to yesterday", func(t *testing.T) {
+		r := newRepo(t)
+		_, err := r.UpsertStocks(context.Background(), []models.Stock{
+			stock("AMZN", "Amazon.com Inc", "Goldman Sachs", "Buy", 70, yesterday),
+			stock("TSLA", "Tesla Inc", "Barclays", "Sell", 90, lastWeek),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		got, err := r.Recommendations(context.Background(), 50, 10)
+		if err != nil || tickers(got) != "AMZN" {
+			t.Errorf("Recommendations() = %s, %v; want AMZN", tickers(got), err)
+		}
+	})
+
+	t.Run("sync runs", func(t *testing.T) {
+		r := newRepo(t)
+		ctx := context.Background()
+
+		if run, err := r.LastSyncRun(ctx); err != nil || run != nil {
+			t.Fatalf("LastSyncRun() = %+v, %v; want nil before any run", run, err)
+		}
+
+		first, err := r.StartSyncRun(ctx)
+		if err != nil {
+			t.Fatalf("StartSyncRun() error = %v", err)
+		}
+		if run, _ := r.LastSyncRun(ctx); run == nil || run.Status != models.SyncStatusRunning || run.FinishedAt != nil {
+			t.Errorf("LastSyncRun() = %+v, want the running run", run)
+		}
+		if err := r.FinishSyncRun(ctx, first, models.SyncResult{Stocks: 12, Rejected: 3, Duplicates: 4}, nil); err != nil {
+			t.Fatalf("FinishSyncRun() error = %v", err)
+		}
+
+		if run, _ := r.LastSyncRun(ctx); run == nil || run.Stocks != 12 || run.Rejected != 3 || run.Duplicates != 4 {
+			t.Errorf("LastSyncRun() = %+v, want 12 stocks, 3 rejected and 4 duplicates", run)
+		}
+
+		second, err := r.StartSyncRun(ctx)
+		if err != nil {
+			t.Fatalf("StartSyncRun() error = %v", err)
+		}
+		if err := r.FinishSyncRun(ctx, second, models.SyncResult{}, errors.New("upstream down")); err != nil {
+			t.Fatalf("FinishSyncRun() error = %v", err)
+		}
+
+		run, err := r.LastSyncRun(ctx)
+		if err != nil {
+			t.Fatalf("LastSyncRun() error = %v", err)
+		}
+		if run.ID != second || run.Status != models.SyncStatusFailed || run.Error != "upstream down" || run.FinishedAt == nil {
+			t.Errorf("LastSyncRun() = %+v, want the failed second run", run)
+		}
+	})
+
+	t.Run("quarantine", func(t *testing.T) {
+		r := newRepo(t)
+		ctx := context.Background()
+
+		event := func(ticker string) models.QuarantinedEvent {
+			return models.QuarantinedEvent{
+				Source: models.QuarantineSourceSync, Ticker: ticker, Company: ticker + " Inc",
+				Payload: []byte(`{"ticker":"` + ticker + `"}`), Reason: "ticker is not a valid symbol",
+			}
+		}
+
+		if err := r.QuarantineEvents(ctx, []models.QuarantinedEvent{event("bad1"), event("bad2")}); err != nil {
+			t.Fatalf("QuarantineEvents() error = %v", err)
+		}
+		// A payload seen again is counted, not stored twice
+		if err := r.QuarantineEvents(ctx, []models.QuarantinedEvent{event("bad1")}); err != nil {
+			t.Fatalf("QuarantineEvents() error = %v", err)
+		}
+
+		events, err := r.ListQuarantined(ctx, models.QuarantineFilters{})
+		if err != nil || len(events) != 2 {
+			t.Fatalf("ListQuarantined() = %+v, %v; want 2 events", events, err)
+		}
+
+		bad1, err := r.ListQuarantined(ctx, models.QuarantineFilters{Ticker: "bad1"})
+		if err != nil || len(bad1) != 1 {
+			t.Fatalf("ListQuarantined(bad1) = %+v, %v; want one event", bad1, err)
+		}
+		got := bad1[0]
+		if got.SeenCount != 2 || got.Status != models.QuarantineStatusPending || got.Source != models.QuarantineSourceSync || got.ResolvedAt != nil {
+			t.Errorf("event = %+v, want a pending event seen twice", got)
+		}
+		if !strings.Contains(string(got.Payload), `"bad1"`) {
+			t.Errorf("payload = %s, want the original record", got.Payload)
+		}
+
+		claimed, err := r.ClaimQuarantined(ctx, got.ID, models.QuarantineStatusReplayed)
+		if err != nil || claimed == nil || claimed.Status != models.QuarantineStatusReplayed || claimed.ResolvedAt == nil || claimed.Ticker != "bad1" {
+			t.Fatalf("ClaimQuarantined() = %+v, %v; want the replayed event", claimed, err)
+		}
+		// Only one caller claims a pending event
+		if again, err := r.ClaimQuarantined(ctx, got.ID, models.QuarantineStatusReplayed); err != nil || again != nil {
+			t.Errorf("second ClaimQuarantined() = %+v, %v; want nil", again, err)
+		}
+		resolved, err := r.GetQuarantined(ctx, got.ID)
+		if err != nil || resolved == nil || resolved.Status != models.QuarantineStatusReplayed || resolved.ResolvedAt == nil {
+			t.Errorf("GetQuarantined() = %+v, %v; want the replayed event", resolved, err)
+		}
+
+		if err := r.ReopenQuarantined(ctx, got.ID); err != nil {
+			t.Fatalf("ReopenQuarantined() error = %v", err)
+		}
+		reopened, err := r.GetQuarantined(ctx, got.ID)
+		if err != nil || reopened == nil || reopened.Status != models.QuarantineStatusPending || reopened.ResolvedAt != nil {
+			t.Errorf("GetQuarantined() = %+v, %v; want the event pending again", reopened, err)
+		}
+		if _, err := r.ClaimQuarantined(ctx, got.ID, models.QuarantineStatusReplayed); err != nil {
+			t.Fatalf("ClaimQuarantined() error = %v", err)
+		}
+
+		pending, err := r.ListQuarantined(ctx, models.QuarantineFilters{Status: models.QuarantineStatusPending})
+		if err != nil || len(pending) != 1 || pending[0].Ticker != "bad2" {
+			t.Errorf("ListQuarantined(pending) = %+v, %v; want only bad2", pending, err)
+		}
+
+		if missing, err := r.GetQuarantined(ctx, 999); err != nil || missing != nil {
+			t.Errorf("GetQuarantined(999) = %+v, %v; want nil", missing, err)
+		}
+		if missing, err := r.ClaimQuarantined(ctx, 999, models.QuarantineStatusReplayed); err != nil || missing != nil {
+			t.Errorf("ClaimQuarantined(999) = %+v, %v; want nil", missing, err)
+		}
+		if err := r.ReopenQuarantined(ctx, 999); err == nil {
+			t.Error("ReopenQuarantined(999) error = nil, want not found")
+		}
+	})
+
+	t.Run("backfill jobs", func(t *testing.T) {
+		r := newRepo(t)
+		ctx := context.Background()
+
+		first := &models.BackfillJob{From: lastWeek, To: today, Status: models.BackfillStatusRunning}
+		second := &models.BackfillJob{From: lastWeek, To: yesterday, Status: models.BackfillStatusRunning}
+		for _, job := range []*models.BackfillJob{first, second} {
+			if err := r.CreateBackfillJob(ctx, job); err != nil {
+				t.Fatalf("CreateBackfillJob() error = %v", err)
+			}
+		}
+		if first.ID == 0 || second.ID == first.ID || first.CreatedAt.IsZero() {
+			t.Fatalf("created jobs %+v and %+v, want distinct IDs and timestamps", first, second)
+		}
+
+		finished := time.Now()
+		first.Status, first.Cursor, first.Pages, first.Fetched, first.InRange, first.Stored, first.Quarantined =
+			models.BackfillStatusCompleted, "page-3", 3, 30, 20, 18, 2
+		first.FinishedAt = &finished
+		if err := r.UpdateBackfillJob(ctx, first); err != nil {
+			t.Fatalf("UpdateBackfillJob() error = %v", err)
+		}
+
+		got, err := r.GetBackfillJob(ctx, first.ID)
+		if err != nil || got == nil {
+			t.Fatalf("GetBackfillJob() = %+v, %v", got, err)
+		}
+		if got.Status != models.BackfillStatusCompleted || got.Cursor != "page-3" || got.Pages != 3 ||
+			got.Stored != 18 || got.Quarantined != 2 || got.FinishedAt == nil || !got.From.Equal(lastWeek) {
+			t.Errorf("GetBackfillJob() = %+v, want the updated job", got)
+		}
+
+		jobs, err := r.ListBackfillJobs(ctx, 1)
+		if err != nil || len(jobs) != 1 || jobs[0].ID != second.ID {
+			t.Errorf("ListBackfillJobs(1) = %+v, %v; want the newest job", jobs, err)
+		}
+
+		if missing, err := r.GetBackfillJob(ctx, 999); err != nil || missing != nil {
+			t.Errorf("GetBackfillJob(999) = %+v, %v; want nil", missing, err)
+		}
+		if err := r.UpdateBackfillJob(ctx, &models.BackfillJob{ID: 999}); err == nil {
+			t.Error("UpdateBackfillJob(999) error = nil, want not found")
+		}
+	})
+
+	t.Run("backfill job locks", func(t *testing.T) {
+		r := newRepo(t)
+		ctx := context.Background()
+
+		unlock, ok, err := r.LockBackfillJob(ctx, 1)
+		if err != nil || !ok {
+			t.Fatalf("LockBackfillJob(1) = %v, %v; want locked", ok, err)
+		}
+		if _, ok, err := r.LockBackfillJob(ctx, 1); err != nil || ok {
+			t.Errorf("second LockBackfillJob(1) = %v, %v; want held", ok, err)
+		}
+		other, ok, err := r.LockBackfillJob(ctx, 2)
+		if err != nil || !ok {
+			t.Fatalf("LockBackfillJob(2) = %v, %v; want locked", ok, err)
+		}
+		other()
+
+		unlock()
+		again, ok, err := r.LockBackfillJob(ctx, 1)
+		if err != nil || !ok {
+			t.Fatalf("LockBackfillJob(1) after unlock = %v, %v; want locked", ok, err)
+		}
+		again()
+	})
+
+	t.Run("watchlists", func(t *testing.T) {
+		r := newRepo(t)
+		ctx := context.Background()
+
+		tech := &models.Watchlist{Username: "dashboard", Name: "Tech", Tickers: []string{"MSFT", "AAPL", "MSFT"}}
+		if err := r.CreateWatchlist(ctx, tech); err != nil {
+			t.Fatalf("CreateWatchlist() error = %v", err)
+		}
+		if tech.ID == 0 || tech.CreatedAt.IsZero() || strings.Join(tech.Tickers, ",") != "MSFT,AAPL" {
+			t.Fatalf("created %+v, want an ID, timestamps and each ticker once", tech)
+		}
+		empty := &models.Watchlist{Username: "dashboard", Name: "Autos"}
+		if err := r.CreateWatchlist(ctx, empty); err != nil {
+			t.Fatalf("CreateWatchlist() error = %v", err)
+		}
+		// Names are unique per user only
+		if err := r.CreateWatchlist(ctx, &models.Watchlist{Username: "dashboard", Name: "Tech"}); !errors.Is(err, ErrWatchlistNameTaken) {
+			t.Errorf("CreateWatchlist(duplicate name) error = %v, want ErrWatchlistNameTaken", err)
+		}
+		if err := r.CreateWatchlist(ctx, &models.Watchlist{Username: "admin", Name: "Tech"}); err != nil {
+			t.Errorf("CreateWatchlist(other user) error = %v", err)
+		}
+
+		if found, err := r.AddWatchlistTickers(ctx, "dashboard", tech.ID, []string{"AAPL", "NVDA"}); !found || err != nil {
+			t.Fatalf("AddWatchlistTickers() = %v, %v", found, err)
+		}
+		if found, err := r.RemoveWatchlistTicker(ctx, "dashboard", tech.ID, "MSFT"); !found || err != nil {
+			t.Fatalf("RemoveWatchlistTicker() = %v, %v", found, err)
+		}
+		got, err := r.GetWatchlist(ctx, "dashboard", tech.ID)
+		if err != nil || got == nil || strings.Join(got.Tickers, ",") != "AAPL,NVDA" {
+			t.Fatalf("GetWatchlist() = %+v, %v; want AAPL,NVDA", got, err)
+		}
+
+		if found, err := r.RenameWatchlist(ctx, "dashboard", tech.ID, "Autos"); !found || !errors.Is(err, ErrWatchlistNameTaken) {
+			t.Errorf("RenameWatchlist(taken name) = %v, %v; want ErrWatchlistNameTaken", found, err)
+		}
+		if found, err := r.RenameWatchlist(ctx, "dashboard", tech.ID, "Chips"); !found || err != nil {
+			t.Errorf("RenameWatchlist() = %v, %v", found, err)
+		}
+
+		lists, err := r.ListWatchlists(ctx, "dashboard")
+		if err != nil || len(lists) != 2 || lists[0].Name != "Autos" || lists[1].Name != "Chips" {
+			t.Fatalf("ListWatchlists() = %+v, %v; want Autos and Chips", lists, err)
+		}
+		if lists[0].Tickers == nil || len(lists[1].Tickers) != 2 {
+			t.Errorf("ListWatchlists() tickers = %q and %q, want none and two", lists[0].Tickers, lists[1].Tickers)
+		}
+
+		// Another user's list reads as missing
+		if other, err := r.GetWatchlist(ctx, "admin", tech.ID); err != nil || other != nil {
+			t.Errorf("GetWatchlist(other user) = %+v, %v; want nil", other, err)
+		}
+		if found, err := r.DeleteWatchlist(ctx, "admin", tech.ID); found || err != nil {
+			t.Errorf("DeleteWatchlist(other user) = %v, %v; want not found", found, err)
+		}
+		if found, err := r.AddWatchlistTickers(ctx, "admin", tech.ID, []string{"TSLA"}); found || err != nil {
+			t.Errorf("AddWatchlistTickers(other user) = %v, %v; want not found", found, err)
+		}
+
+		if found, err := r.DeleteWatchlist(ctx, "dashboard", tech.ID); !found || err != nil {
+			t.Fatalf("DeleteWatchlist() = %v, %v", found, err)
+		}
+		if deleted, err := r.GetWatchlist(ctx, "dashboard", tech.ID); err != nil || deleted != nil {
+			t.Errorf("GetWatchlist(deleted) = %+v, %v; want nil", deleted, err)
+		}
+	})
+
+	t.Run("alerts", func(t *testing.T) {
+		r := newRepo(t)
+		ctx := context.Background()
+
+		cut := -20.0
+		rule := &models.AlertRule{Username: "dashboard", Name: "NVDA cuts", Ticker: "NVDA", TargetChangePct: &cut, Enabled: true}
+		if err := r.CreateAlertRule(ctx, rule); err != nil {
+			t.Fatalf("CreateAlertRule() error = %v", err)
+		}
+		disabled := &models.AlertRule{Username: "admin", Name: "Downgrades", ChangeType: models.ChangeDowngrade}
+		if err := r.CreateAlertRule(ctx, disabled); err != nil {
+			t.Fatalf("CreateAlertRule() error = %v", err)
+		}
+
+		got, err := r.GetAlertRule(ctx, "dashboard", rule.ID)
+		if err != nil || got == nil || got.TargetChangePct == nil || *got.TargetChangePct != cut || got.ScoreAbove != nil {
+			t.Fatalf("GetAlertRule() = %+v, %v; want the target cut and no score", got, err)
+		}
+		if other, err := r.GetAlertRule(ctx, "admin", rule.ID); err != nil || other != nil {
+			t.Errorf("GetAlertRule(other user) = %+v, %v; want nil", other, err)
+		}
+		if enabled, err := r.EnabledAlertRules(ctx); err != nil || len(enabled) != 1 || enabled[0].ID != rule.ID {
+			t.Errorf("EnabledAlertRules() = %+v, %v; want only %d", enabled, err, rule.ID)
+		}
+
+		disabled.Enabled = true
+		if found, err := r.UpdateAlertRule(ctx, disabled); !found || err != nil {
+			t.Fatalf("UpdateAlertRule() = %v, %v", found, err)
+		}
+		if enabled, err := r.EnabledAlertRules(ctx); err != nil || len(enabled) != 2 {
+			t.Errorf("EnabledAlertRules() after enabling = %+v, %v; want both", enabled, err)
+		}
+		stolen := *disabled
+		stolen.Username = "dashboard"
+		if found, err := r.UpdateAlertRule(ctx, &stolen); found || err != nil {
+			t.Errorf("UpdateAlertRule(other user) = %v, %v; want not found", found, err)
+		}
+
+		record := func(changeID int64, ticker string, cooldown time.Duration) bool {
+			t.Helper()
+			alert := &models.Alert{
+				RuleID: rule.ID, RuleName: rule.Name, Username: "dashboard",
+				ChangeID: changeID, ChangeType: models.ChangeTargetChange, Ticker: ticker, Message: "target cut",
+			}
+			stored, err := r.RecordAlert(ctx, alert, cooldown)
+			if err != nil {
+				t.Fatalf("RecordAlert(%d) error = %v", changeID, err)
+			}
+			if stored && (alert.ID == 0 || alert.TriggeredAt.IsZero()) {
+				t.Errorf("RecordAlert(%d) stored %+v without an ID and time", changeID, alert)
+			}
+			return stored
+		}
+		if !record(1, "NVDA", 0) {
+			t.Fatal("RecordAlert() did not store the first alert")
+		}
+		if record(1, "NVDA", 0) {
+			t.Error("RecordAlert() stored the same change twice")
+		}
+		if record(2, "NVDA", time.Hour) {
+			t.Error("RecordAlert() stored an alert within the cooldown")
+		}
+		if !record(3, "AMD", time.Hour) {
+			t.Error("RecordAlert() applied the cooldown to another ticker")
+		}
+		if !record(4, "NVDA", 0) {
+			t.Error("RecordAlert() without cooldown did not store a new change")
+		}
+
+		alerts, err := r.ListAlerts(ctx, models.AlertFilters{Username: "dashboard", Limit: 10})
+		if err != nil || len(alerts) != 3 || alerts[0].ChangeID != 4 || alerts[2].ChangeID != 1 {
+			t.Fatalf("ListAlerts() = %+v, %v; want changes 4, 3 and 1", alerts, err)
+		}
+		page, err := r.ListAlerts(ctx, models.AlertFilters{Username: "dashboard", Before: alerts[0].ID, Limit: 1})
+		if err != nil || len(page) != 1 || page[0].ChangeID != 3 {
+			t.Errorf("ListAlerts(before) = %+v, %v; want change 3", page, err)
+		}
+		if other, err := r.ListAlerts(ctx, models.AlertFilters{Username: "admin", Limit: 10}); err != nil || len(other) != 0 {
+			t.Errorf("ListAlerts(other user) = %+v, %v; want none", other, err)
+		}
+
+		if found, err := r.DeleteAlertRule(ctx, "dashboard", rule.ID); !found || err != nil {
+			t.Fatalf("DeleteAlertRule() = %v, %v", found, err)
+		}
+		if alerts, err := r.ListAlerts(ctx, models.AlertFilters{Username: "dashboard", Limit: 10}); err != nil || len(alerts) != 0 {
+			t.Errorf("ListAlerts() after deleting the rule = %+v, %v; want none", alerts, err)
+		}
+
+		if _, ok, err := r.AlertCursor(ctx); ok || err != nil {
+			t.Errorf("AlertCursor() before any evaluation = %v, %v; want none", ok, err)
+		}
+		for _, cursor := range []int64{5, 9} {
+			if err := r.SetAlertCursor(ctx, cursor); err != nil {
+				t.Fatalf("SetAlertCursor(%d) error = %v", cursor, err)
+			}
+		}
+		if cursor, ok, err := r.AlertCursor(ctx); cursor != 9 || !ok || err != nil {
+			t.Errorf("AlertCursor() = %d, %v, %v; want 9", cursor, ok, err)
+		}
+	})
+	t.Run("webhooks", func(t *testing.T) {
+		r := newRepo(t)
+		ctx := context.Background()
+
+		hook := &models.Webhook{Username: "dashboard", URL: "https://hooks.example.com/a", Events: []string{"alert", "change"}, Secret: "s3cret", Enabled: true}
+		if err := r.CreateWebhook(ctx, hook); err != nil {
+			t.Fatalf("CreateWebhook() error = %v", err)
+		}
+		alertsOnly := &models.Webhook{Username: "admin", URL: "https://hooks.example.com/b", Events: []string{"alert"}, Secret: "s3cret", Enabled: true}
+		if err := r.CreateWebhook(ctx, alertsOnly); err != nil {
+			t.Fatalf("CreateWebhook() error = %v", err)
+		}
+
+		got, err := r.GetWebhook(ctx, "dashboard", hook.ID)
+		if err != nil || got == nil || got.URL != hook.URL || len(got.Events) != 2 || got.Secret != "s3cret" {
+			t.Fatalf("GetWebhook() = %+v, %v; want %+v", got, err, hook)
+		}
+		if other, err := r.GetWebhook(ctx, "admin", hook.ID); err != nil || other != nil {
+			t.Errorf("GetWebhook(other user) = %+v, %v; want nil", other, err)
+		}
+		if hooks, err := r.EnabledWebhooks(ctx, models.WebhookEventChange); err != nil || len(hooks) != 1 || hooks[0].ID != hook.ID {
+			t.Errorf("EnabledWebhooks(change) = %+v, %v; want only %d", hooks, err, hook.ID)
+		}
+		if hooks, err := r.EnabledWebhooks(ctx, models.WebhookEventAlert); err != nil || len(hooks) != 2 {
+			t.Errorf("EnabledWebhooks(alert) = %+v, %v; want both", hooks, err)
+		}
+
+		if cursor, err := r.WebhookCursor(ctx, "change"); cursor != 0 || err != nil {
+			t.Fatalf("WebhookCursor() = %d, %v; want 0", cursor, err)
+		}
+		now := time.Now().UTC().Truncate(time.Second)
+		due := func(at time.Time) *time.Time { return &at }
+		queued := []models.WebhookDelivery{
+			{WebhookID: hook.ID, Event: "change", Payload: []byte(`"first"`), Status: models.DeliveryStatusPending, NextAttemptAt: due(now.Add(-time.Minute))},
+			{WebhookID: hook.ID, Event: "change", Payload: []byte(`"second"`), Status: models.DeliveryStatusPending, NextAttemptAt: due(now.Add(time.Hour))},
+		}
+		if ok, err := r.EnqueueDeliveries(ctx, "change", 0, 7, queued); !ok || err != nil {
+			t.Fatalf("EnqueueDeliveries() = %v, %v", ok, err)
+		}
+		if ok, err := r.EnqueueDeliveries(ctx, "change", 0, 9, queued); ok || err != nil {
+			t.Errorf("EnqueueDeliveries() from a stale cursor = %v, %v; want false", ok, err)
+		}
+		if cursor, err := r.WebhookCursor(ctx, "change"); cursor != 7 || err != nil {
+			t.Errorf("WebhookCursor() = %d, %v; want 7", cursor, err)
+		}
+
+		claimed, err := r.ClaimDelivery(ctx, now, time.Minute)
+		if err != nil || claimed == nil || string(claimed.Payload) != `"first"` || claimed.URL != hook.URL || claimed.Secret != "s3cret" {
+			t.Fatalf("ClaimDelivery() = %+v, %v; want the first delivery with its webhook", claimed, err)
+		}
+		if again, err := r.ClaimDelivery(ctx, now, time.Minute); err != nil || again != nil {
+			t.Errorf("ClaimDelivery() of a leased delivery = %+v, %v; want nil", again, err)
+		}
+
+		claimed.Status = models.DeliveryStatusDelivered
+		claimed.Attempts = 1
+		claimed.LastStatusCode = 204
+		claimed.NextAttemptAt = nil
+		claimed.DeliveredAt = due(now)
+		if err := r.UpdateDelivery(ctx, claimed); err != nil {
+			t.Fatalf("UpdateDelivery() error = %v", err)
+		}
+
+		replayOf := claimed.ID
+		replay := &models.WebhookDelivery{WebhookID: hook.ID, Event: "change", Payload: claimed.Payload, Status: models.DeliveryStatusPending, NextAttemptAt: due(now), ReplayOf: &replayOf}
+		if err := r.CreateDelivery(ctx, replay); err != nil || replay.ID == 0 {
+			t.Fatalf("CreateDelivery() = %d, %v", replay.ID, err)
+		}
+
+		stored, err := r.GetDelivery(ctx, "dashboard", hook.ID, claimed.ID)
+		if err != nil || stored == nil || stored.Status != models.DeliveryStatusDelivered || stored.Attempts != 1 || stored.DeliveredAt == nil || stored.Secret != "s3cret" {
+			t.Fatalf("GetDelivery() = %+v, %v; want the delivered delivery", stored, err)
+		}
+		if other, err := r.GetDelivery(ctx, "admin", hook.ID, claimed.ID); err != nil || other != nil {
+			t.Errorf("GetDelivery(other user) = %+v, %v; want nil", other, err)
+		}
+
+		deliveries, err := r.ListDeliveries(ctx, models.DeliveryFilters{Username: "dashboard", WebhookID: hook.ID, Limit: 10})
+		if err != nil || len(deliveries) != 3 || deliveries[0].ID != replay.ID || deliveries[0].ReplayOf == nil || *deliveries[0].ReplayOf != claimed.ID {
+			t.Fatalf("ListDeliveries() = %+v, %v; want the replay first", deliveries, err)
+		}
+		pending, err := r.ListDeliveries(ctx, models.DeliveryFilters{Username: "dashboard", WebhookID: hook.ID, Status: models.DeliveryStatusPending, Limit: 10})
+		if err != nil || len(pending) != 2 {
+			t.Errorf("ListDeliveries(pending) = %+v, %v; want 2", pending, err)
+		}
+
+		if found, err := r.DeleteWebhook(ctx, "admin", hook.ID); found || err != nil {
+			t.Errorf("DeleteWebhook(other user) = %v, %v; want not found", found, err)
+		}
+		if found, err := r.DeleteWebhook(ctx, "dashboard", hook.ID); !found || err != nil {
+			t.Fatalf("DeleteWebhook() = %v, %v", found, err)
+		}
+		if claimed, err := r.ClaimDelivery(ctx, now.Add(2*time.Hour), time.Minute); err != nil || claimed != nil {
+			t.Errorf("ClaimDelivery() after deleting the webhook = %+v, %v; want nil", claimed, err)
+		}
+	})
+	t.Run("digest subscriptions", func(t *testing.T) {
+		r := newRepo(t)
+		ctx := context.Background()
+
+		if sub, err := r.GetDigestSubscription(ctx, "dashboard"); err != nil || sub != nil {
+			t.Fatalf("GetDigestSubscription() before subscribing = %+v, %v; want nil", sub, err)
+		}
+
+		sub := &models.DigestSubscription{Username: "dashboard", Email: "old@example.com", Enabled: true, LastChangeID: 5}
+		if err := r.SaveDigestSubscription(ctx, sub); err != nil || sub.CreatedAt.IsZero() {
+			t.Fatalf("SaveDigestSubscription() = %+v, %v", sub, err)
+		}
+		sub = &models.DigestSubscription{Username: "dashboard", Email: "manager@example.com", Enabled: true, LastChangeID: 9}
+		if err := r.SaveDigestSubscription(ctx, sub); err != nil || sub.LastChangeID != 5 {
+			t.Fatalf("SaveDigestSubscription() of an existing subscription = %+v, %v; want the stored cursor", sub, err)
+		}
+		if err := r.SaveDigestSubscription(ctx, &models.DigestSubscription{Username: "admin", Email: "admin@example.com"}); err != nil {
+			t.Fatal(err)
+		}
+
+		got, err := r.GetDigestSubscription(ctx, "dashboard")
+		if err != nil || got == nil || got.Email != "manager@example.com" || got.LastChangeID != 5 || got.LastSentAt != nil {
+			t.Fatalf("GetDigestSubscription() = %+v, %v; want the new email and cursor 5", got, err)
+		}
+
+		due := time.Now().UTC().Add(time.Hour)
+		if subs, err := r.DueDigestSubscriptions(ctx, due); err != nil || len(subs) != 1 || subs[0].Username != "dashboard" {
+			t.Fatalf("DueDigestSubscriptions() = %+v, %v; want only the enabled subscription", subs, err)
+		}
+		if subs, err := r.DueDigestSubscriptions(ctx, due.Add(-2*time.Hour)); err != nil || len(subs) != 0 {
+			t.Errorf("DueDigestSubscriptions() before the subscription = %+v, %v; want none", subs, err)
+		}
+
+		sentAt := due.Add(time.Minute).Truncate(time.Second)
+		if err := r.MarkDigestSent(ctx, "dashboard", 12, sentAt); err != nil {
+			t.Fatalf("MarkDigestSent() error = %v", err)
+		}
+		if subs, err := r.DueDigestSubscriptions(ctx, due); err != nil || len(subs) != 0 {
+			t.Errorf("DueDigestSubscriptions() after sending = %+v, %v; want none", subs, err)
+		}
+		subs, err := r.DueDigestSubscriptions(ctx, sentAt.Add(24*time.Hour))
+		if err != nil || len(subs) != 1 || subs[0].LastChangeID != 12 || subs[0].LastSentAt == nil || !subs[0].LastSentAt.Equal(sentAt) {
+			t.Errorf("DueDigestSubscriptions() the next day = %+v, %v; want cursor 12 sent at %v", subs, err, sentAt)
+		}
+		if err := r.MarkDigestSent(ctx, "nobody", 1, sentAt); err == nil {
+			t.Error("MarkDigestSent() without a subscription error = nil")
+		}
+
+		if found, err := r.DeleteDigestSubscription(ctx, "dashboard"); !found || err != nil {
+			t.Fatalf("DeleteDigestSubscription() = %v, %v", found, err)
+		}
+		if found, err := r.DeleteDigestSubscription(ctx, "dashboard"); found || err != nil {
+			t.Errorf("DeleteDigestSubscription() twice = %v, %v; want not found", found, err)
+		}
+	})
+}
+
+func TestMemoryRepositoryRejectsUnknownSortColumn(t *testing.T) {
+	_, err := NewMemoryRepository().ListStocks(context.Background(), models.StockFilters{SortBy: "password"})
+	if !errors.Is(err, ErrUnknownSortColumn) {
+		t.Errorf("ListStocks() error = %v, want ErrUnknownSortColumn", err)
+	}
+}
+
+func TestPostgresMigrationRecordsOlderStocksAsEvents(t *testing.T) {
+	db := testutil.PostgresDB(t)
+	r := NewPostgresRepository(db, nil)
+	ctx := context.Background()
+
+	// Rows stored before stock_events existed, one with a fraction of a second
+	at := time.Date(2025, 6, 2, 14, 30, 0, 0, time.UTC)
+	older := []models.Stock{
+		{Ticker: "AAPL", Company: "Apple Inc", Brokerage: "Goldman Sachs", Action: "reiterated by", RatingFrom: "Buy", RatingTo: "Buy", TargetFrom: "$200", TargetTo: "$210", Time: at},
+		{Ticker: "MSFT", Company: "Microsoft Corp", Brokerage: "UBS", Action: "upgraded by", RatingTo: "Buy", Time: at.Add(120 * time.Millisecond)},
+	}
+	for _, s := range older {
+		_, err := db.ExecContext(ctx,
+			`INSERT INTO stocks (ticker, company, brokerage, action, rating_from, rating_to, target_from, target_to, time) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`,
+			s.Ticker, s.Company, s.Brokerage, s.Action, s.RatingFrom, s.RatingTo, s.TargetFrom, s.TargetTo, s.Time)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := db.ExecContext(ctx, `DELETE FROM schema_migrations WHERE version >= 14`); err != nil {
+		t.Fatal(err)
+	}
+	if err := database.Migrate(db); err != nil {
+		t.Fatal(err)
+	}
+
+	// The upstream resending them is a duplicate, not a new event
+	report, err := r.UpsertStocks(ctx, older)
+	if err != nil || report.Duplicates != len(older) {
+		t.Errorf("UpsertStocks() = %+v, %v; want %d duplicates", report, err, len(older))
+	}
+	ratings, err := r.LatestRatings(ctx, "AAPL", at.AddDate(0, 0, -1))
+	if err != nil || len(ratings) != 1 || ratings[0].Brokerage != "Goldman Sachs" || ratings[0].Rating != "Buy" {
+		t.Errorf("LatestRatings() = %+v, %v; want the Goldman Sachs Buy once", ratings, err)
+	}
+}
@@ -0,0 +1,90 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"unicode/utf8"
+
+	"Backend/internal/models"
+)
+
+// stockColumnLimits are the VARCHAR sizes of the stocks table
+var stockColumnLimits = []struct {
+	column string
+	limit  int
+	value  func(s *models.Stock) string
+}{
+	{"ticker", 10, func(s *models.Stock) string { return s.Ticker }},
+	{"company", 255, func(s *models.Stock) string { return s.Company }},
+	{"brokerage", 255, func(s *models.Stock) string { return s.Brokerage }},
+	{"action", 50, func(s *models.Stock) string { return s.Action }},
+	{"rating_from", 50, func(s *models.Stock) string { return s.RatingFrom }},
+	{"rating_to", 50, func(s *models.Stock) string { return s.RatingTo }},
+	{"target_from", 20, func(s *models.Stock) string { return s.TargetFrom }},
+	{"target_to", 20, func(s *models.Stock) string { return s.TargetTo }},
+	{"reason", 255, func(s *models.Stock) string { return s.Reason }},
+	{"target_price", 20, func(s *models.Stock) string { return s.TargetPrice }},
+	{"current_rating", 50, func(s *models.Stock) string { return s.CurrentRating }},
+	{"source", 20, func(s *models.Stock) string { return s.Source }},
+}
+
+// ValidateStock checks a stock against the constraints of the stocks table so a
+// bad row can be skipped instead of failing the whole batch
+func ValidateStock(s *models.Stock) error {
+	var problems []string
+
+	if s.Ticker == "" {
+		problems = append(problems, "ticker is required")
+	}
+	if s.Company == "" {
+		problems = append(problems, "company is required")
+	}
+	if s.Time.IsZero() {
+		problems = append(problems, "time is required")
+	}
+	for _, c := range stockColumnLimits {
+		if n := utf8.RuneCountInString(c.value(s)); n > c.limit {
+			problems = append(problems, fmt.Sprintf("%s is %d characters long, maximum %d", c.column, n, c.limit))
+		}
+	}
+	if !finite(s.Score) || !finite(s.Confidence) {
+		problems = append(problems, "score and confidence must be finite")
+	}
+
+	if len(problems) > 0 {
+		return errors.New(strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// partitionStocks splits stocks into the rows to write and a report of the rejected
+// ones. Rows without a source are taken to come from the upstream API.
+func partitionStocks(stocks []models.Stock) ([]models.Stock, []models.RejectedRow) {
+	valid := make([]models.Stock, 0, len(stocks))
+	var rejected []models.RejectedRow
+
+	for i := range stocks {
+		if err := ValidateStock(&stocks[i]); err != nil {
+			rejected = append(rejected, models.RejectedRow{
+				Index:   i,
+				Ticker:  stocks[i].Ticker,
+				Company: stocks[i].Company,
+				Reason:  err.Error(),
+			})
+			continue
+		}
+		stock := stocks[i]
+		if stock.Source == "" {
+			stock.Source = models.StockSourceAPI
+		}
+		valid = append(valid, stock)
+	}
+
+	return valid, rejected
+}
+
+func finite(f float64) bool {
+	return !math.IsNaN(f) && !math.IsInf(f, 0)
+}
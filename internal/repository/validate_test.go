@@ -0,0 +1,48 @@
+package repository
+
+import (
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"Backend/internal/models"
+)
+
+func TestValidateStock(t *testing.T) {
+	valid := func() models.Stock {
+		return models.Stock{Ticker: "AAPL", Company: "Apple Inc", Brokerage: "Goldman Sachs", Time: time.Now(), Score: 80, Confidence: 0.8}
+	}
+
+	tests := []struct {
+		name    string
+		modify  func(s *models.Stock)
+		wantErr string
+	}{
+		{"valid", func(s *models.Stock) {}, ""},
+		{"missing ticker", func(s *models.Stock) { s.Ticker = "" }, "ticker is required"},
+		{"missing company", func(s *models.Stock) { s.Company = "" }, "company is required"},
+		{"missing time", func(s *models.Stock) { s.Time = time.Time{} }, "time is required"},
+		{"ticker too long", func(s *models.Stock) { s.Ticker = "ABCDEFGHIJK" }, "ticker is 11 characters long, maximum 10"},
+		{"limits count characters, not bytes", func(s *models.Stock) { s.Ticker = "ÄÖÜÄÖÜÄÖÜÄ" }, ""},
+		{"target too long", func(s *models.Stock) { s.TargetTo = strings.Repeat("9", 21) }, "target_to"},
+		{"NaN score", func(s *models.Stock) { s.Score = math.NaN() }, "finite"},
+		{"infinite confidence", func(s *models.Stock) { s.Confidence = math.Inf(1) }, "finite"},
+		{"every problem is reported", func(s *models.Stock) { s.Ticker, s.Company = "", "" }, "ticker is required; company is required"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := valid()
+			tt.modify(&s)
+
+			err := ValidateStock(&s)
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Errorf("ValidateStock() error = %v, want nil", err)
+			case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+				t.Errorf("ValidateStock() error = %v, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
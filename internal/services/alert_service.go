@@ -0,0 +1,367 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"Backend/internal/config"
+	"Backend/internal/logger"
+	"Backend/internal/metrics"
+	"Backend/internal/models"
+	"Backend/internal/repository"
+	"Backend/internal/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+var (
+	// ErrAlertRuleNotFound is returned for a rule that does not exist or belongs to another user
+	ErrAlertRuleNotFound = errors.New("alert rule not found")
+
+	// ErrInvalidAlertRule is wrapped by errors for invalid rule conditions
+	ErrInvalidAlertRule = errors.New("invalid alert rule")
+
+	// ErrAlertRuleLimit is returned when a user already has the configured maximum of rules
+	ErrAlertRuleLimit = errors.New("too many alert rules")
+)
+
+// Limits for alert rules and the alert history
+const (
+	maxAlertRuleName     = 100
+	maxAlertRuleCooldown = 7 * 24 * 60 // minutes
+	defaultAlertLimit    = 50
+	maxAlertLimit        = 500
+
+	// alertBatchSize is the number of changes read from the feed at a time
+	alertBatchSize = 500
+)
+
+// alertChangeTypes are the change types a rule can select
+var alertChangeTypes = []string{
+	models.ChangeNewCoverage,
+	models.ChangeUpgrade,
+	models.ChangeDowngrade,
+	models.ChangeTargetChange,
+	models.ChangeScoreChange,
+}
+
+// AlertService manages user alert rules and evaluates them against the change feed
+type AlertService struct {
+	alerts  repository.AlertRepository
+	changes repository.ChangeRepository
+	cfg     config.AlertsConfig
+	log     *slog.Logger
+
+	mu sync.Mutex // serializes Evaluate
+}
+
+// NewAlertService creates a new instance of AlertService
+func NewAlertService(alerts repository.AlertRepository, changes repository.ChangeRepository, cfg config.AlertsConfig) *AlertService {
+	return &AlertService{
+		alerts:  alerts,
+		changes: changes,
+		cfg:     cfg,
+		log:     logger.Component("alert_service"),
+	}
+}
+
+// ListRules returns the user's alert rules
+func (s *AlertService) ListRules(ctx context.Context, username string) ([]models.AlertRule, error) {
+	return s.alerts.ListAlertRules(ctx, username)
+}
+
+// GetRule returns one of the user's alert rules or ErrAlertRuleNotFound
+func (s *AlertService) GetRule(ctx context.Context, username string, id int64) (*models.AlertRule, error) {
+	rule, err := s.alerts.GetAlertRule(ctx, username, id)
+	if err != nil {
+		return nil, err
+	}
+	if rule == nil {
+		return nil, ErrAlertRuleNotFound
+	}
+	return rule, nil
+}
+
+// CreateRule validates and stores a new rule for the user
+func (s *AlertService) CreateRule(ctx context.Context, username string, request models.AlertRuleRequest) (*models.AlertRule, error) {
+	rule, err := alertRule(request)
+	if err != nil {
+		return nil, err
+	}
+
+	rules, err := s.alerts.ListAlertRules(ctx, username)
+	if err != nil {
+		return nil, err
+	}
+	if len(rules) >= s.cfg.MaxRules {
+		return nil, fmt.Errorf("%w: a user can have at most %d", ErrAlertRuleLimit, s.cfg.MaxRules)
+	}
+
+	rule.Username = username
+	if err := s.alerts.CreateAlertRule(ctx, rule); err != nil {
+		return nil, err
+	}
+	return rule, nil
+}
+
+// UpdateRule replaces the conditions of one of the user's rules
+func (s *AlertService) UpdateRule(ctx context.Context, username string, id int64, request models.AlertRuleRequest) (*models.AlertRule, error) {
+	rule, err := alertRule(request)
+	if err != nil {
+		return nil, err
+	}
+
+	rule.ID, rule.Username = id, username
+	found, err := s.alerts.UpdateAlertRule(ctx, rule)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, ErrAlertRuleNotFound
+	}
+	return rule, nil
+}
+
+// DeleteRule deletes one of the user's rules together with its alerts
+func (s *AlertService) DeleteRule(ctx context.Context, username string, id int64) error {
+	found, err := s.alerts.DeleteAlertRule(ctx, username, id)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return ErrAlertRuleNotFound
+	}
+	return nil
+}
+
+// Alerts returns the user's alert history, newest first
+func (s *AlertService) Alerts(ctx context.Context, filters models.AlertFilters) ([]models.Alert, error) {
+	if filters.Limit <= 0 || filters.Limit > maxAlertLimit {
+		filters.Limit = defaultAlertLimit
+	}
+	return s.alerts.ListAlerts(ctx, filters)
+}
+
+// Evaluate checks the changes written since the previous evaluation against the
+// enabled rules and records the alerts they trigger, which it returns the number
+// of. It runs after each sync. The first evaluation starts from the newest
+// change, so the history stored before alerts existed triggers nothing.
+func (s *AlertService) Evaluate(ctx context.Context) (triggered int, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ctx, span := tracing.Start(ctx, "AlertService.Evaluate")
+	defer func() {
+		span.SetAttributes(attribute.Int("alerts.triggered", triggered))
+		tracing.End(span, err)
+	}()
+
+	since, ok, err := s.alerts.AlertCursor(ctx)
+	if err != nil {
+		return 0, err
+	}
+	if !ok {
+		last, err := s.changes.LastChangeID(ctx)
+		if err != nil {
+			return 0, err
+		}
+		return 0, s.alerts.SetAlertCursor(ctx, last)
+	}
+
+	rules, err := s.alerts.EnabledAlertRules(ctx)
+	if err != nil {
+		return 0, err
+	}
+	if len(rules) == 0 {
+		last, err := s.changes.LastChangeID(ctx)
+		if err != nil || last == since {
+			return 0, err
+		}
+		return 0, s.alerts.SetAlertCursor(ctx, last)
+	}
+
+	evaluated := 0
+	for {
+		changes, err := s.changes.ListChanges(ctx, since, alertBatchSize)
+		if err != nil {
+			return triggered, err
+		}
+
+		for _, change := range changes {
+			for _, rule := range rules {
+				if !ruleMatches(rule, change) {
+					continue
+				}
+				stored, err := s.alerts.RecordAlert(ctx, newAlert(rule, change), s.cooldown(rule))
+				if err != nil {
+					return triggered, err
+				}
+				if stored {
+					triggered++
+					metrics.AlertsTriggered.Inc()
+				}
+			}
+		}
+
+		if len(changes) == 0 {
+			break
+		}
+		since = changes[len(changes)-1].ID
+		evaluated += len(changes)
+		// Alerts already recorded are not recorded again if this fails and the
+		// batch is evaluated a second time
+		if err := s.alerts.SetAlertCursor(ctx, since); err != nil {
+			return triggered, err
+		}
+		if len(changes) < alertBatchSize {
+			break
+		}
+	}
+
+	s.log.Info("alert rules evaluated", "rules", len(rules), "changes", evaluated, "triggered", triggered)
+	return triggered, nil
+}
+
+// cooldown is the minimum time between alerts of a rule for the same ticker
+func (s *AlertService) cooldown(rule models.AlertRule) time.Duration {
+	if rule.CooldownMinutes > 0 {
+		return time.Duration(rule.CooldownMinutes) * time.Minute
+	}
+	return s.cfg.Cooldown
+}
+
+// alertRule validates a request and normalizes its conditions
+func alertRule(request models.AlertRuleRequest) (*models.AlertRule, error) {
+	invalid := func(format string, args ...any) error {
+		return fmt.Errorf("%w: "+format, append([]any{ErrInvalidAlertRule}, args...)...)
+	}
+
+	rule := &models.AlertRule{
+		Name:            strings.TrimSpace(request.Name),
+		Ticker:          strings.ToUpper(strings.TrimSpace(request.Ticker)),
+		Brokerage:       strings.TrimSpace(request.Brokerage),
+		ChangeType:      strings.ToLower(strings.TrimSpace(request.ChangeType)),
+		Rating:          strings.TrimSpace(request.Rating),
+		ScoreAbove:      request.ScoreAbove,
+		TargetChangePct: request.TargetChangePct,
+		CooldownMinutes: request.CooldownMinutes,
+		Enabled:         request.Enabled == nil || *request.Enabled,
+	}
+
+	switch {
+	case rule.Name == "":
+		return nil, invalid("name is required")
+	case utf8.RuneCountInString(rule.Name) > maxAlertRuleName:
+		return nil, invalid("name is longer than %d characters", maxAlertRuleName)
+	case rule.Ticker != "" && (!tickerPattern.MatchString(rule.Ticker) || len(rule.Ticker) > maxTickerLength):
+		return nil, invalid("ticker %q is not a valid symbol", rule.Ticker)
+	case len(rule.Brokerage) > 255:
+		return nil, invalid("brokerage is too long")
+	case rule.ChangeType != "" && !slices.Contains(alertChangeTypes, rule.ChangeType):
+		return nil, invalid("change_type must be one of %s", strings.Join(alertChangeTypes, ", "))
+	case len(rule.Rating) > 50:
+		return nil, invalid("rating is too long")
+	case rule.ScoreAbove != nil && (*rule.ScoreAbove < 0 || *rule.ScoreAbove >= 100):
+		return nil, invalid("score_above must be between 0 and 100")
+	case rule.TargetChangePct != nil && (*rule.TargetChangePct == 0 || *rule.TargetChangePct <= -100):
+		return nil, invalid("target_change_pct must be a non-zero percentage above -100")
+	case rule.CooldownMinutes < 0 || rule.CooldownMinutes > maxAlertRuleCooldown:
+		return nil, invalid("cooldown_minutes must be between 0 and %d", maxAlertRuleCooldown)
+	case rule.Ticker == "" && rule.Brokerage == "" && rule.ChangeType == "" && rule.Rating == "" &&
+		rule.ScoreAbove == nil && rule.TargetChangePct == nil:
+		return nil, invalid("at least one condition is required")
+	}
+
+	return rule, nil
+}
+
+// ruleMatches reports whether a change meets every condition the rule sets
+func ruleMatches(rule models.AlertRule, change models.StockChange) bool {
+	if rule.Ticker != "" && !strings.EqualFold(rule.Ticker, change.Ticker) {
+		return false
+	}
+	if rule.Brokerage != "" && !strings.EqualFold(rule.Brokerage, strings.TrimSpace(change.Brokerage)) {
+		return false
+	}
+	if rule.ChangeType != "" && rule.ChangeType != change.Type {
+		return false
+	}
+	if rule.Rating != "" {
+		// Only a rating given by this change, not one carried by a target or score change
+		rated := change.Type == models.ChangeNewCoverage || change.Type == models.ChangeUpgrade || change.Type == models.ChangeDowngrade
+		if !rated || !strings.EqualFold(rule.Rating, strings.TrimSpace(change.RatingTo)) {
+			return false
+		}
+	}
+	if rule.ScoreAbove != nil {
+		// Crossing the threshold, so a stock staying above it does not trigger again
+		threshold := *rule.ScoreAbove
+		crossed := change.Type == models.ChangeNewCoverage || change.ScoreFrom <= threshold
+		if change.ScoreTo <= threshold || !crossed {
+			return false
+		}
+	}
+	if rule.TargetChangePct != nil {
+		pct, ok := targetChangePct(change)
+		if change.Type != models.ChangeTargetChange || !ok {
+			return false
+		}
+		if threshold := *rule.TargetChangePct; (threshold < 0 && pct > threshold) || (threshold > 0 && pct < threshold) {
+			return false
+		}
+	}
+	return true
+}
+
+// targetChangePct returns the change of the price target in percent, when both
+// targets are prices
+func targetChangePct(change models.StockChange) (float64, bool) {
+	from, err1 := models.ParseTarget(change.TargetFrom)
+	to, err2 := models.ParseTarget(change.TargetTo)
+	if err1 != nil || err2 != nil || from <= 0 {
+		return 0, false
+	}
+	return (to - from) / from * 100, true
+}
+
+func newAlert(rule models.AlertRule, change models.StockChange) *models.Alert {
+	return &models.Alert{
+		RuleID:     rule.ID,
+		RuleName:   rule.Name,
+		Username:   rule.Username,
+		ChangeID:   change.ID,
+		ChangeType: change.Type,
+		Ticker:     change.Ticker,
+		Brokerage:  change.Brokerage,
+		Message:    alertMessage(change),
+	}
+}
+
+// alertMessage describes a change in one line
+func alertMessage(change models.StockChange) string {
+	switch change.Type {
+	case models.ChangeNewCoverage:
+		return fmt.Sprintf("%s started coverage of %s at %s", change.Brokerage, change.Ticker, change.RatingTo)
+	case models.ChangeUpgrade, models.ChangeDowngrade:
+		verb := "upgraded"
+		if change.Type == models.ChangeDowngrade {
+			verb = "downgraded"
+		}
+		return fmt.Sprintf("%s %s %s from %s to %s", change.Brokerage, verb, change.Ticker, change.RatingFrom, change.RatingTo)
+	case models.ChangeTargetChange:
+		message := fmt.Sprintf("%s moved the %s target from %s to %s", change.Brokerage, change.Ticker, change.TargetFrom, change.TargetTo)
+		if pct, ok := targetChangePct(change); ok {
+			message += fmt.Sprintf(" (%+.1f%%)", pct)
+		}
+		return message
+	default:
+		return fmt.Sprintf("%s score moved from %.1f to %.1f", change.Ticker, change.ScoreFrom, change.ScoreTo)
+	}
+}
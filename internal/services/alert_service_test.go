@@ -0,0 +1,155 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"Backend/internal/config"
+	"Backend/internal/models"
+	"Backend/internal/repository"
+)
+
+func float(v float64) *float64 { return &v }
+
+func TestRuleMatches(t *testing.T) {
+	downgrade := models.StockChange{
+		Type: models.ChangeDowngrade, Ticker: "NVDA", Brokerage: "Morgan Stanley",
+		RatingFrom: "Buy", RatingTo: "Hold", ScoreFrom: 70, ScoreTo: 40,
+	}
+	targetCut := models.StockChange{
+		Type: models.ChangeTargetChange, Ticker: "AAPL", Brokerage: "UBS",
+		RatingFrom: "Buy", RatingTo: "Buy", TargetFrom: "$200.00", TargetTo: "$150.00",
+	}
+	scoreUp := models.StockChange{Type: models.ChangeScoreChange, Ticker: "MSFT", RatingTo: "Buy", ScoreFrom: 75, ScoreTo: 82}
+
+	tests := []struct {
+		name   string
+		rule   models.AlertRule
+		change models.StockChange
+		want   bool
+	}{
+		{"ticker and type", models.AlertRule{Ticker: "NVDA", ChangeType: models.ChangeDowngrade}, downgrade, true},
+		{"other type", models.AlertRule{Ticker: "NVDA", ChangeType: models.ChangeUpgrade}, downgrade, false},
+		{"brokerage ignores case", models.AlertRule{Brokerage: "morgan stanley"}, downgrade, true},
+		{"rating given", models.AlertRule{Rating: "hold"}, downgrade, true},
+		{"rating carried by a target change", models.AlertRule{Rating: "Buy"}, targetCut, false},
+		{"score crosses the threshold", models.AlertRule{ScoreAbove: float(80)}, scoreUp, true},
+		{"score already above", models.AlertRule{ScoreAbove: float(70)}, scoreUp, false},
+		{"score below", models.AlertRule{ScoreAbove: float(90)}, scoreUp, false},
+		{"target cut beyond the limit", models.AlertRule{TargetChangePct: float(-20)}, targetCut, true},
+		{"target cut within the limit", models.AlertRule{TargetChangePct: float(-30)}, targetCut, false},
+		{"target raise wanted", models.AlertRule{TargetChangePct: float(10)}, targetCut, false},
+		{"target of another change type", models.AlertRule{TargetChangePct: float(-20)}, downgrade, false},
+		{"every condition must hold", models.AlertRule{Ticker: "AAPL", TargetChangePct: float(-20), Brokerage: "Citi"}, targetCut, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ruleMatches(tt.rule, tt.change); got != tt.want {
+				t.Errorf("ruleMatches() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAlertRuleValidation(t *testing.T) {
+	tests := []struct {
+		name    string
+		request models.AlertRuleRequest
+		wantErr bool
+	}{
+		{"ticker", models.AlertRuleRequest{Name: "NVDA", Ticker: " nvda "}, false},
+		{"no name", models.AlertRuleRequest{Name: " ", Ticker: "NVDA"}, true},
+		{"no condition", models.AlertRuleRequest{Name: "Everything"}, true},
+		{"invalid ticker", models.AlertRuleRequest{Name: "Bad", Ticker: "$$$"}, true},
+		{"unknown change type", models.AlertRuleRequest{Name: "Bad", ChangeType: "split"}, true},
+		{"score out of range", models.AlertRuleRequest{Name: "Bad", ScoreAbove: float(100)}, true},
+		{"zero target change", models.AlertRuleRequest{Name: "Bad", TargetChangePct: float(0)}, true},
+		{"target cut of everything", models.AlertRuleRequest{Name: "Bad", TargetChangePct: float(-100)}, true},
+		{"negative cooldown", models.AlertRuleRequest{Name: "Bad", Ticker: "NVDA", CooldownMinutes: -1}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule, err := alertRule(tt.request)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidAlertRule) {
+					t.Errorf("alertRule() error = %v, want ErrInvalidAlertRule", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("alertRule() error = %v", err)
+			}
+			if rule.Ticker != "NVDA" || !rule.Enabled {
+				t.Errorf("alertRule() = %+v, want an enabled rule on NVDA", rule)
+			}
+		})
+	}
+}
+
+func TestEvaluateAlerts(t *testing.T) {
+	repo := repository.NewMemoryRepository()
+	service := NewAlertService(repo, repo, config.AlertsConfig{Cooldown: time.Hour, MaxRules: 2})
+	ctx := context.Background()
+	now := time.Now()
+
+	// Stored before the first evaluation, so it never triggers
+	upsert(t, repo, models.Stock{Ticker: "NVDA", Company: "Nvidia", Brokerage: "UBS", RatingTo: "Buy", TargetTo: "$100.00", Time: now.Add(-time.Hour)})
+	if n, err := service.Evaluate(ctx); n != 0 || err != nil {
+		t.Fatalf("first Evaluate() = %d, %v; want nothing", n, err)
+	}
+
+	downgrades, err := service.CreateRule(ctx, "dashboard", models.AlertRuleRequest{Name: "NVDA downgrades", Ticker: "NVDA", ChangeType: models.ChangeDowngrade})
+	if err != nil {
+		t.Fatal(err)
+	}
+	anything := models.AlertRuleRequest{Name: "UBS on NVDA", Ticker: "NVDA", Brokerage: "UBS"}
+	if _, err := service.CreateRule(ctx, "dashboard", anything); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := service.CreateRule(ctx, "dashboard", anything); !errors.Is(err, ErrAlertRuleLimit) {
+		t.Errorf("CreateRule() past the limit error = %v, want ErrAlertRuleLimit", err)
+	}
+
+	// A downgrade with a target cut is two changes
+	upsert(t, repo, models.Stock{Ticker: "NVDA", Company: "Nvidia", Brokerage: "UBS", RatingTo: "Sell", TargetTo: "$70.00", Time: now})
+	n, err := service.Evaluate(ctx)
+	if err != nil {
+		t.Fatalf("Evaluate() error = %v", err)
+	}
+	// One per rule: the cooldown holds back the second change for the UBS rule
+	if n != 2 {
+		t.Errorf("Evaluate() = %d alerts, want 2", n)
+	}
+	if n, err := service.Evaluate(ctx); n != 0 || err != nil {
+		t.Errorf("Evaluate() with nothing new = %d, %v; want nothing", n, err)
+	}
+
+	alerts, err := service.Alerts(ctx, models.AlertFilters{Username: "dashboard", RuleID: downgrades.ID})
+	if err != nil || len(alerts) != 1 {
+		t.Fatalf("Alerts() = %+v, %v; want the downgrade", alerts, err)
+	}
+	if want := "UBS downgraded NVDA from Buy to Sell"; alerts[0].Message != want {
+		t.Errorf("alert message = %q, want %q", alerts[0].Message, want)
+	}
+
+	// A disabled rule is not evaluated
+	disabled := false
+	if _, err := service.UpdateRule(ctx, "dashboard", downgrades.ID, models.AlertRuleRequest{Name: "NVDA downgrades", Ticker: "NVDA", Enabled: &disabled}); err != nil {
+		t.Fatal(err)
+	}
+	upsert(t, repo, models.Stock{Ticker: "NVDA", Company: "Nvidia", Brokerage: "UBS", RatingTo: "Strong Sell", Time: now.Add(time.Minute)})
+	if n, err := service.Evaluate(ctx); n != 0 || err != nil {
+		t.Errorf("Evaluate() with a disabled rule and a cooldown = %d, %v; want nothing", n, err)
+	}
+
+	if _, err := service.UpdateRule(ctx, "admin", downgrades.ID, anything); !errors.Is(err, ErrAlertRuleNotFound) {
+		t.Errorf("UpdateRule() of another user's rule error = %v, want ErrAlertRuleNotFound", err)
+	}
+	if err := service.DeleteRule(ctx, "admin", downgrades.ID); !errors.Is(err, ErrAlertRuleNotFound) {
+		t.Errorf("DeleteRule() of another user's rule error = %v, want ErrAlertRuleNotFound", err)
+	}
+}
@@ -2,20 +2,33 @@
 package services
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
+	"log/slog"
 	"net/http"
 	"net/url"
 	"time"
 
-	"Backend/internal/models"
+	"Backend/internal/logger"
+	"Backend/internal/metrics"
+	"Backend/internal/tracing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type APIClient struct {
 	baseURL    string
 	apiKey     string
 	httpClient *http.Client
+	log        *slog.Logger
+	pageDelay  time.Duration
+	maxStocks  int
 }
 
 type APIResponse struct {
@@ -35,17 +48,28 @@ type APIStock struct {
 	Time       time.Time `json:"time"`
 }
 
-func NewAPIClient(apiKey string, baseURL string) *APIClient {
+// NewAPIClient creates a client for the upstream stock API. FetchAllStocks waits pageDelay
+// between pages and stops after maxStocks stocks (0 means no limit).
+func NewAPIClient(apiKey string, baseURL string, pageDelay time.Duration, maxStocks int) *APIClient {
 	return &APIClient{
-		baseURL: baseURL,
-		apiKey:  apiKey,
+		baseURL:   baseURL,
+		apiKey:    apiKey,
+		pageDelay: pageDelay,
+		maxStocks: maxStocks,
 		httpClient: &http.Client{
 			Timeout: 30 * time.Second,
 		},
+		log: logger.Component("api_client"),
 	}
 }
 
-func (c *APIClient) FetchStocks(page string) (*APIResponse, error) {
+func (c *APIClient) FetchStocks(ctx context.Context, page string) (_ *APIResponse, err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "APIClient.FetchStocks",
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attribute.String("upstream.page", page)),
+	)
+	defer func() { tracing.End(span, err) }()
+
 	reqURL := c.baseURL
 	
 	if page != "" {
@@ -60,27 +84,33 @@ func (c *APIClient) FetchStocks(page string) (*APIResponse, error) {
 		reqURL = u.String()
 	}
 
-	fmt.Println("reqURL: ", reqURL)
+	c.log.Debug("fetching stocks page", "url", reqURL, "page", page)
 
-	req, err := http.NewRequest("GET", reqURL, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", reqURL, nil)
 	if err != nil {
 		return nil, fmt.Errorf("error creating request: %w", err)
 	}
 
 	req.Header.Set("Authorization", "Bearer "+c.apiKey)
 	req.Header.Set("Content-Type", "application/json")
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
 	// req.Header.Set("User-Agent", "karla/1.0")
 
 	// fmt.Println("req: ", req)
 
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
+		metrics.UpstreamError(0)
 		return nil, fmt.Errorf("error making request: %w", err)
 	}
 	defer resp.Body.Close()
 
+	span.SetAttributes(semconv.HTTPResponseStatusCode(resp.StatusCode))
+
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
+		c.log.Warn("upstream API returned an error", "status", resp.StatusCode, "page", page)
+		metrics.UpstreamError(resp.StatusCode)
 		return nil, fmt.Errorf("API returned status %d: %s", resp.StatusCode, string(body))
 	}
 
@@ -94,50 +124,49 @@ func (c *APIClient) FetchStocks(page string) (*APIResponse, error) {
 		return nil, fmt.Errorf("error unmarshaling response: %w", err)
 	}
 
+	span.SetAttributes(
+		attribute.Int("upstream.items", len(apiResponse.Items)),
+		attribute.Bool("upstream.has_next_page", apiResponse.NextPage != ""),
+	)
+
 	return &apiResponse, nil
 }
 
-func (c *APIClient) FetchAllStocks() ([]models.Stock, error) {
-	var allStocks []models.Stock
+// FetchAllStocks pages through the upstream API and returns the raw records.
+// They are validated and converted by StockService.SyncAllData.
+func (c *APIClient) FetchAllStocks(ctx context.Context) ([]APIStock, error) {
+	var allStocks []APIStock
 	nextPage := ""
 
 
 for {
-    response, err := c.FetchStocks(nextPage)
+    response, err := c.FetchStocks(ctx, nextPage)
 		// fmt.Println("response: ", response)
     if err != nil {
         return nil, fmt.Errorf("error fetching stocks: %w", err)
     }
+    metrics.SyncPagesFetched.Inc()
 
-    // Convert APIStock to models.Stock
-    for _, apiStock := range response.Items {
-        stock := models.Stock{
-            Ticker:     apiStock.Ticker,
-            Company:    apiStock.Company,
-            Brokerage:  apiStock.Brokerage,
-            Action:     apiStock.Action,
-            RatingFrom: apiStock.RatingFrom,
-            RatingTo:   apiStock.RatingTo,
-            TargetFrom: apiStock.TargetFrom,
-            TargetTo:   apiStock.TargetTo,
-            Time:       apiStock.Time,
-            CreatedAt:  time.Now(),
-            UpdatedAt:  time.Now(),
-        }
-        allStocks = append(allStocks, stock)
-    }
+    allStocks = append(allStocks, response.Items...)
+
+    c.log.Debug("fetched stocks page", "items", len(response.Items), "total", len(allStocks))
 
 		  // Break the loop if there are no more pages
     if response.NextPage == "" {
         break
     }
 
-    time.Sleep(500 * time.Millisecond) 
+    // Wait between pages, but stop as soon as the sync is cancelled
+    select {
+    case <-ctx.Done():
+        return nil, fmt.Errorf("error fetching stocks: %w", ctx.Err())
+    case <-time.After(c.pageDelay):
+    }
     nextPage = response.NextPage
 
 
     // Return results if we have accumulated a significant number of stocks
-    if len(allStocks) >= 1000 {
+    if c.maxStocks > 0 && len(allStocks) >= c.maxStocks {
         return allStocks, nil
     }
 }
@@ -145,60 +174,3 @@ for {
 
 	return allStocks, nil
 }
-
-
-func (s *StockService) InsertStocks(stocks []models.Stock) error {
-
-	if len(stocks) == 0 {
-		return nil
-	}
-
-	query := `
-		INSERT INTO stocks (ticker, company, brokerage, action, rating_from, rating_to, 
-		                   target_from, target_to, time, created_at, updated_at, score, reason, target_price, current_rating, confidence)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16)
-		ON CONFLICT (ticker, company) DO UPDATE SET
-			action = EXCLUDED.action,
-			rating_from = EXCLUDED.rating_from,
-			rating_to = EXCLUDED.rating_to,
-			target_from = EXCLUDED.target_from,
-			target_to = EXCLUDED.target_to,
-			created_at = EXCLUDED.created_at,
-			updated_at = NOW(),
-			time = EXCLUDED.time,
-			score = EXCLUDED.score,
-			reason = EXCLUDED.reason,
-			target_price = EXCLUDED.target_price,
-			current_rating = EXCLUDED.current_rating,
-			confidence = EXCLUDED.confidence
-	`
-
-	tx, err := s.db.Begin()
-	if err != nil {
-		fmt.Printf("error starting transaction: %w", err)
-		return fmt.Errorf("error starting transaction: %w", err)
-	}
-	defer tx.Rollback()
-
-	stmt, err := tx.Prepare(query)
-	if err != nil {
-		fmt.Printf("error preparing statement: %w", err)
-		return fmt.Errorf("error preparing statement: %w", err)
-	}
-	defer stmt.Close()
-
-
-	for _, stock := range stocks {
-		_, err := stmt.Exec(
-			stock.Ticker, stock.Company, stock.Brokerage, stock.Action,
-			stock.RatingFrom, stock.RatingTo, stock.TargetFrom, stock.TargetTo,
-			stock.Time, stock.CreatedAt, stock.UpdatedAt, stock.Score, stock.Reason, stock.TargetPrice, stock.CurrentRating, stock.Confidence,
-		)
-		
-		if err != nil {
-			return fmt.Errorf("error inserting stock %s: %w", stock.Ticker, err)
-		}
-	}
-
-	return tx.Commit()
-}
@@ -0,0 +1,192 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeUpstream serves pages of the upstream stock API keyed by the next_page parameter
+type fakeUpstream struct {
+	*httptest.Server
+
+	pages  map[string]APIResponse
+	status int // when set, every request fails with this status
+	body   string
+
+	mu       sync.Mutex
+	requests []*http.Request
+}
+
+func newFakeUpstream(t *testing.T, pages map[string]APIResponse) *fakeUpstream {
+	t.Helper()
+
+	f := &fakeUpstream{pages: pages}
+	f.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		f.mu.Lock()
+		f.requests = append(f.requests, r)
+		f.mu.Unlock()
+
+		if f.status != 0 {
+			http.Error(w, "upstream unavailable", f.status)
+			return
+		}
+		if f.body != "" {
+			w.Write([]byte(f.body))
+			return
+		}
+
+		page, ok := f.pages[r.URL.Query().Get("next_page")]
+		if !ok {
+			http.Error(w, "unknown page", http.StatusNotFound)
+			return
+		}
+		json.NewEncoder(w).Encode(page)
+	}))
+	t.Cleanup(f.Close)
+
+	return f
+}
+
+func (f *fakeUpstream) requested() []*http.Request {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]*http.Request(nil), f.requests...)
+}
+
+func stockPage(next string, tickers ...string) APIResponse {
+	page := APIResponse{NextPage: next}
+	for _, ticker := range tickers {
+		page.Items = append(page.Items, APIStock{Ticker: ticker, Company: ticker + " Inc", Brokerage: "Broker", Action: "reiterated by", Time: time.Now()})
+	}
+	return page
+}
+
+func TestFetchAllStocksFollowsPagination(t *testing.T) {
+	upstream := newFakeUpstream(t, map[string]APIResponse{
+		"":       stockPage("page-2", "AAA", "BBB"),
+		"page-2": stockPage("page-3", "CCC"),
+		"page-3": stockPage("", "DDD"),
+	})
+
+	stocks, err := NewAPIClient("secret-key", upstream.URL, 0, 0).FetchAllStocks(context.Background())
+	if err != nil {
+		t.Fatalf("FetchAllStocks() error = %v", err)
+	}
+
+	var tickers []string
+	for _, s := range stocks {
+		tickers = append(tickers, s.Ticker)
+	}
+	if got := strings.Join(tickers, ","); got != "AAA,BBB,CCC,DDD" {
+		t.Errorf("tickers = %s, want AAA,BBB,CCC,DDD", got)
+	}
+
+	requests := upstream.requested()
+	if len(requests) != 3 {
+		t.Fatalf("made %d requests, want 3", len(requests))
+	}
+	for i, want := range []string{"", "page-2", "page-3"} {
+		if got := requests[i].URL.Query().Get("next_page"); got != want {
+			t.Errorf("request %d next_page = %q, want %q", i, got, want)
+		}
+		if got := requests[i].Header.Get("Authorization"); got != "Bearer secret-key" {
+			t.Errorf("request %d Authorization = %q, want the bearer API key", i, got)
+		}
+	}
+}
+
+func TestFetchAllStocksStopsAtMaxStocks(t *testing.T) {
+	upstream := newFakeUpstream(t, map[string]APIResponse{
+		"":   stockPage("p2", "A", "B"),
+		"p2": stockPage("p3", "C", "D"),
+		"p3": stockPage("", "E", "F"),
+	})
+
+	stocks, err := NewAPIClient("key", upstream.URL, 0, 3).FetchAllStocks(context.Background())
+	if err != nil {
+		t.Fatalf("FetchAllStocks() error = %v", err)
+	}
+
+	if len(stocks) != 4 {
+		t.Errorf("got %d stocks, want the 4 from the first two pages", len(stocks))
+	}
+	if n := len(upstream.requested()); n != 2 {
+		t.Errorf("made %d requests, want 2", n)
+	}
+}
+
+func TestFetchAllStocksErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		setup   func(f *fakeUpstream)
+		wantErr string
+	}{
+		{
+			name:    "non-200 status",
+			setup:   func(f *fakeUpstream) { f.status = http.StatusServiceUnavailable },
+			wantErr: "API returned status 503",
+		},
+		{
+			name:    "invalid JSON",
+			setup:   func(f *fakeUpstream) { f.body = "{not json" },
+			wantErr: "error unmarshaling response",
+		},
+		{
+			name:    "missing later page",
+			setup:   func(f *fakeUpstream) { f.pages = map[string]APIResponse{"": stockPage("gone", "A")} },
+			wantErr: "API returned status 404",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			upstream := newFakeUpstream(t, nil)
+			tt.setup(upstream)
+
+			stocks, err := NewAPIClient("key", upstream.URL, 0, 0).FetchAllStocks(context.Background())
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("FetchAllStocks() error = %v, want it to contain %q", err, tt.wantErr)
+			}
+			if stocks != nil {
+				t.Errorf("FetchAllStocks() returned %d stocks alongside the error", len(stocks))
+			}
+		})
+	}
+}
+
+func TestFetchAllStocksHonoursContext(t *testing.T) {
+	upstream := newFakeUpstream(t, map[string]APIResponse{"": stockPage("", "A")})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := NewAPIClient("key", upstream.URL, 0, 0).FetchAllStocks(ctx)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("FetchAllStocks() error = %v, want context.Canceled", err)
+	}
+}
+
+func TestFetchAllStocksStopsWaitingForThePageDelay(t *testing.T) {
+	upstream := newFakeUpstream(t, map[string]APIResponse{
+		"":   stockPage("p2", "A"),
+		"p2": stockPage("", "B"),
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	_, err := NewAPIClient("key", upstream.URL, time.Hour, 0).FetchAllStocks(ctx)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("FetchAllStocks() error = %v, want context.DeadlineExceeded", err)
+	}
+	if n := len(upstream.requested()); n != 1 {
+		t.Errorf("made %d requests, want 1", n)
+	}
+}
@@ -0,0 +1,287 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"Backend/internal/config"
+	"Backend/internal/entity"
+	"Backend/internal/models"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	// loginAttemptTTL is how long a failure counter is kept without new failures
+	loginAttemptTTL = 24 * time.Hour
+	// maxLoginCounters bounds the failure counters kept, so failures from many
+	// IPs cannot grow them without limit; the oldest is dropped first
+	maxLoginCounters = 10000
+)
+
+// unknownUserHash is compared against the password of an unknown username, so
+// the answer takes as long as for a configured user
+var unknownUserHash = []byte("$2a$10$3kJp4tWML8C01MXV0Ir8b.WwI97cJzFqLOZhTIVFdTur7z9urg5Ki")
+
+// Errors returned by Authenticate
+var (
+	ErrUnknownUser   = errors.New("unknown user")
+	ErrWrongPassword = errors.New("wrong password")
+)
+
+// loginAttempt tracks consecutive failures for a username or an IP
+type loginAttempt struct {
+	failures    int
+	lastFailure time.Time
+	lockedUntil time.Time
+}
+
+// AuthService handles login throttling, token revocation and the authentication audit log
+type AuthService struct {
+	db  *sql.DB
+	cfg config.AuthConfig
+
+	mu       sync.Mutex
+	attempts map[string]*loginAttempt
+}
+
+// NewAuthService creates a new instance of AuthService
+func NewAuthService(db *sql.DB, cfg config.AuthConfig) *AuthService {
+	return &AuthService{
+		db:       db,
+		cfg:      cfg,
+		attempts: make(map[string]*loginAttempt),
+	}
+}
+
+// Authenticate checks the password of a user of AUTH_USERS and returns the user
+// to issue a token for, with the admin role when it is listed in AUTH_ADMINS
+func (s *AuthService) Authenticate(username, password string) (*entity.UserJwt, error) {
+	hash, id, ok := s.cfg.PasswordHash(username)
+	if !ok {
+		bcrypt.CompareHashAndPassword(unknownUserHash, []byte(password))
+		return nil, ErrUnknownUser
+	}
+	if err := bcrypt.CompareHashAndPassword(hash, []byte(password)); err != nil {
+		return nil, ErrWrongPassword
+	}
+
+	return &entity.UserJwt{UserId: int64(id), Username: username, Role: s.Role(username)}, nil
+}
+
+// Role returns the role granted to username by the configuration
+func (s *AuthService) Role(username string) string {
+	if s.cfg.IsAdmin(username) {
+		return entity.RoleAdmin
+	}
+	return ""
+}
+
+// LoginLockedUntil reports whether the username or the IP is currently locked out
+// and, if so, until when
+func (s *AuthService) LoginLockedUntil(username, ip string) (time.Time, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	var until time.Time
+	for _, key := range attemptKeys(username, ip) {
+		if a, ok := s.attempts[key]; ok && a.lockedUntil.After(now) && a.lockedUntil.After(until) {
+			until = a.lockedUntil
+		}
+	}
+
+	return until, !until.IsZero()
+}
+
+// RegisterLoginFailure increments the failure counters for the username and the IP.
+// Once MaxLoginAttempts is exceeded each further failure doubles the lockout.
+// Only configured users get a username counter; other names count for the IP.
+func (s *AuthService) RegisterLoginFailure(username, ip string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	s.pruneAttempts(now)
+
+	if _, _, ok := s.cfg.PasswordHash(username); !ok {
+		username = ""
+	}
+	for _, key := range attemptKeys(username, ip) {
+		a, ok := s.attempts[key]
+		if !ok {
+			if len(s.attempts) >= maxLoginCounters {
+				s.dropOldestAttempt()
+			}
+			a = &loginAttempt{}
+			s.attempts[key] = a
+		}
+
+		a.failures++
+		a.lastFailure = now
+
+		if a.failures > s.cfg.MaxLoginAttempts {
+			a.lockedUntil = now.Add(s.lockoutFor(a.failures - s.cfg.MaxLoginAttempts))
+		}
+	}
+}
+
+// RegisterLoginSuccess clears the failure counter for the username. The IP
+// counter is kept until it expires, so a client cannot reset it by mixing
+// successful logins into its failures.
+func (s *AuthService) RegisterLoginSuccess(username, ip string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if username != "" {
+		delete(s.attempts, "user:"+username)
+	}
+}
+
+// pruneAttempts drops counters that have not seen a failure in loginAttemptTTL
+func (s *AuthService) pruneAttempts(now time.Time) {
+	for key, a := range s.attempts {
+		if now.Sub(a.lastFailure) > loginAttemptTTL && !a.lockedUntil.After(now) {
+			delete(s.attempts, key)
+		}
+	}
+}
+
+// dropOldestAttempt removes the counter with the oldest failure
+func (s *AuthService) dropOldestAttempt() {
+	var oldest string
+	for key, a := range s.attempts {
+		if oldest == "" || a.lastFailure.Before(s.attempts[oldest].lastFailure) {
+			oldest = key
+		}
+	}
+	delete(s.attempts, oldest)
+}
+
+// lockoutFor returns the lockout for the n-th failure past the free attempts
+func (s *AuthService) lockoutFor(n int) time.Duration {
+	lockout := s.cfg.LockoutBase
+	for i := 1; i < n; i++ {
+		lockout *= 2
+		if lockout >= s.cfg.LockoutMax {
+			return s.cfg.LockoutMax
+		}
+	}
+	return lockout
+}
+
+func attemptKeys(username, ip string) []string {
+	keys := make([]string, 0, 2)
+	if username != "" {
+		keys = append(keys, "user:"+username)
+	}
+	if ip != "" {
+		keys = append(keys, "ip:"+ip)
+	}
+	return keys
+}
+
+// RecordEvent stores an authentication event in the audit log
+func (s *AuthService) RecordEvent(event models.AuthEvent) error {
+	query := `
+		INSERT INTO auth_audit (event, username, ip, success, detail)
+		VALUES ($1, $2, $3, $4, $5)
+	`
+
+	if _, err := s.db.Exec(query, event.Event, event.Username, event.IP, event.Success, event.Detail); err != nil {
+		return fmt.Errorf("error recording auth event: %w", err)
+	}
+
+	return nil
+}
+
+// GetEvents retrieves audit log entries, most recent first
+func (s *AuthService) GetEvents(filters models.AuthAuditFilters) ([]models.AuthEvent, error) {
+	query := `
+		SELECT id, event, COALESCE(username, ''), COALESCE(ip, ''), success, COALESCE(detail, ''), created_at
+		FROM auth_audit
+		WHERE 1=1
+	`
+
+	args := []any{}
+	argIndex := 1
+
+	if filters.Username != "" {
+		query += fmt.Sprintf(" AND username = $%d", argIndex)
+		args = append(args, filters.Username)
+		argIndex++
+	}
+
+	if filters.Event != "" {
+		query += fmt.Sprintf(" AND event = $%d", argIndex)
+		args = append(args, filters.Event)
+		argIndex++
+	}
+
+	if filters.IP != "" {
+		query += fmt.Sprintf(" AND ip = $%d", argIndex)
+		args = append(args, filters.IP)
+		argIndex++
+	}
+
+	limit := 100
+	if filters.Limit > 0 && filters.Limit <= 1000 {
+		limit = filters.Limit
+	}
+	query += fmt.Sprintf(" ORDER BY created_at DESC, id DESC LIMIT %d", limit)
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	events := []models.AuthEvent{}
+	for rows.Next() {
+		var event models.AuthEvent
+		err := rows.Scan(
+			&event.ID, &event.Event, &event.Username, &event.IP,
+			&event.Success, &event.Detail, &event.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+	}
+
+	return events, rows.Err()
+}
+
+// RevokeToken adds a token ID to the revocation list until the token expires
+func (s *AuthService) RevokeToken(tokenID, username string, expiresAt time.Time) error {
+	query := `
+		INSERT INTO revoked_tokens (jti, username, expires_at)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (jti) DO NOTHING
+	`
+
+	if _, err := s.db.Exec(query, tokenID, username, expiresAt); err != nil {
+		return fmt.Errorf("error revoking token: %w", err)
+	}
+
+	// Expired tokens are rejected by the JWT validation anyway
+	if _, err := s.db.Exec(`DELETE FROM revoked_tokens WHERE expires_at < NOW()`); err != nil {
+		return fmt.Errorf("error cleaning revoked tokens: %w", err)
+	}
+
+	return nil
+}
+
+// IsTokenRevoked checks whether a token ID has been revoked
+func (s *AuthService) IsTokenRevoked(tokenID string) (bool, error) {
+	var exists bool
+	err := s.db.QueryRow(`SELECT EXISTS (SELECT 1 FROM revoked_tokens WHERE jti = $1)`, tokenID).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("error checking revoked token: %w", err)
+	}
+
+	return exists, nil
+}
@@ -0,0 +1,100 @@
+package services
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"Backend/internal/config"
+	"Backend/internal/entity"
+)
+
+// testAuthConfig has the users dashboard and admin, whose passwords are the
+// username followed by -secret, and admin as the only admin
+func testAuthConfig() config.AuthConfig {
+	return config.AuthConfig{
+		MaxLoginAttempts: 2, LockoutBase: time.Minute, LockoutMax: time.Hour,
+		Users: []string{
+			"dashboard:$2a$04$XNbTt7yZ0BZrZa9ZUP4u1ukmQB0rGjeqGxO4lXtc5U4g/FgGBWmOG",
+			"admin:$2a$04$5YHIEb9Kl26afEovFTX1DuQc/VKHZ7coOs4lj4RpX1A.QJG1aBlFe",
+		},
+		Admins: []string{"admin"},
+	}
+}
+
+func TestAuthenticate(t *testing.T) {
+	service := NewAuthService(nil, testAuthConfig())
+
+	tests := []struct {
+		username, password string
+		want               *entity.UserJwt
+		wantErr            error
+	}{
+		{"dashboard", "dashboard-secret", &entity.UserJwt{UserId: 1, Username: "dashboard"}, nil},
+		{"admin", "admin-secret", &entity.UserJwt{UserId: 2, Username: "admin", Role: entity.RoleAdmin}, nil},
+		{"admin", "dashboard-secret", nil, ErrWrongPassword},
+		{"admin", "", nil, ErrWrongPassword},
+		{"mallory", "admin-secret", nil, ErrUnknownUser},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.username+"/"+tt.password, func(t *testing.T) {
+			got, err := service.Authenticate(tt.username, tt.password)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Authenticate() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.want != nil && *got != *tt.want {
+				t.Errorf("Authenticate() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoginLockout(t *testing.T) {
+	service := NewAuthService(nil, testAuthConfig())
+
+	for i := 0; i < 2; i++ {
+		service.RegisterLoginFailure("dashboard", "10.0.0.1")
+	}
+	if _, locked := service.LoginLockedUntil("dashboard", "10.0.0.1"); locked {
+		t.Fatal("locked after the free attempts, want no lockout yet")
+	}
+
+	service.RegisterLoginFailure("dashboard", "10.0.0.1")
+	if _, locked := service.LoginLockedUntil("other", "10.0.0.1"); !locked {
+		t.Fatal("IP not locked after exceeding the free attempts")
+	}
+
+	// A successful login clears the user but not the IP, so alternating
+	// failures with successes still reaches the IP lockout
+	service.RegisterLoginSuccess("dashboard", "10.0.0.1")
+	if _, locked := service.LoginLockedUntil("dashboard", "10.0.0.2"); locked {
+		t.Error("user still locked after a successful login")
+	}
+	if _, locked := service.LoginLockedUntil("", "10.0.0.1"); !locked {
+		t.Error("IP lockout cleared by a successful login")
+	}
+}
+
+func TestLoginFailuresOfUnknownUsers(t *testing.T) {
+	service := NewAuthService(nil, testAuthConfig())
+
+	// Unknown names only count for the IP, so they cannot add counters of their own
+	for i := 0; i < 5; i++ {
+		service.RegisterLoginFailure("random-"+strconv.Itoa(i), "10.0.0.1")
+	}
+	if len(service.attempts) != 1 {
+		t.Errorf("counters = %d, want only the IP one", len(service.attempts))
+	}
+
+	for i := 0; i < maxLoginCounters+10; i++ {
+		service.RegisterLoginFailure("", "10.1."+strconv.Itoa(i/256)+"."+strconv.Itoa(i%256))
+	}
+	if len(service.attempts) != maxLoginCounters {
+		t.Errorf("counters = %d, want the cap of %d", len(service.attempts), maxLoginCounters)
+	}
+	if _, ok := service.attempts["ip:10.0.0.1"]; ok {
+		t.Error("oldest counter kept past the cap")
+	}
+}
@@ -0,0 +1,406 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"Backend/internal/config"
+	"Backend/internal/logger"
+	"Backend/internal/models"
+	"Backend/internal/repository"
+)
+
+var (
+	// ErrInvalidBackfillRange is wrapped by errors for unparseable or empty date ranges
+	ErrInvalidBackfillRange = errors.New("invalid backfill range")
+
+	// ErrBackfillNotFound is returned for an unknown backfill job ID
+	ErrBackfillNotFound = errors.New("backfill job not found")
+
+	// ErrBackfillRunning is returned when a backfill is already running in this process,
+	// or when another process or replica holds the job
+	ErrBackfillRunning = errors.New("a backfill is already running")
+
+	// ErrBackfillCompleted is returned when resuming a job that has finished
+	ErrBackfillCompleted = errors.New("backfill job already completed")
+)
+
+// Limits for ListJobs
+const (
+	defaultBackfillLimit = 20
+	maxBackfillLimit     = 100
+)
+
+// BackfillService re-ingests the upstream history between two dates. It pages
+// through the provider from the newest events until a page falls entirely before
+// the start date, saving the page cursor after every page so a job can resume.
+// At most one backfill runs per process, and a job is locked in the database
+// while it runs so no other process or replica advances it at the same time.
+type BackfillService struct {
+	jobs     repository.BackfillRepository
+	stocks   *StockService
+	client   *APIClient
+	maxPages int
+	log      *slog.Logger
+
+	mu      sync.Mutex
+	running int64  // ID of the job running in this process, 0 when idle
+	unlock  func() // releases the database lock on the running job
+	cancel  context.CancelFunc
+	done    chan struct{}
+}
+
+// NewBackfillService creates a BackfillService. client should be a dedicated APIClient
+// with the backfill page delay, so backfills are throttled separately from the sync.
+func NewBackfillService(jobs repository.BackfillRepository, stocks *StockService, client *APIClient, cfg config.BackfillConfig) *BackfillService {
+	return &BackfillService{
+		jobs:     jobs,
+		stocks:   stocks,
+		client:   client,
+		maxPages: cfg.MaxPages,
+		log:      logger.Component("backfill"),
+	}
+}
+
+// ParseBackfillRange parses a range given as YYYY-MM-DD or RFC 3339 dates.
+// A date-only end includes that whole day.
+func ParseBackfillRange(from, to string) (time.Time, time.Time, error) {
+	start, _, err := parseBackfillTime(from)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("%w: from: %v", ErrInvalidBackfillRange, err)
+	}
+	end, dateOnly, err := parseBackfillTime(to)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("%w: to: %v", ErrInvalidBackfillRange, err)
+	}
+	if dateOnly {
+		end = end.AddDate(0, 0, 1)
+	}
+
+	if !start.Before(end) {
+		return time.Time{}, time.Time{}, fmt.Errorf("%w: from must be before to", ErrInvalidBackfillRange)
+	}
+	return start, end, nil
+}
+
+func parseBackfillTime(value string) (t time.Time, dateOnly bool, err error) {
+	if t, err := time.Parse(time.DateOnly, value); err == nil {
+		return t, true, nil
+	}
+	t, err = time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("%q is not a YYYY-MM-DD or RFC 3339 date", value)
+	}
+	return t.UTC(), false, nil
+}
+
+// Run creates a job for [from, to) and runs it until it completes, fails, is paused
+// by the page limit or ctx is cancelled. The returned job holds the final progress.
+func (b *BackfillService) Run(ctx context.Context, from, to time.Time) (*models.BackfillJob, error) {
+	job, err := b.create(ctx, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer b.release()
+
+	b.run(ctx, job)
+	return job, nil
+}
+
+// Resume runs a paused, failed or interrupted job from its saved cursor
+func (b *BackfillService) Resume(ctx context.Context, id int64) (*models.BackfillJob, error) {
+	job, err := b.claim(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	defer b.release()
+
+	b.run(ctx, job)
+	return job, nil
+}
+
+// Start creates a job like Run but runs it in the background. It returns as soon as
+// the job is stored; Stop cancels it.
+func (b *BackfillService) Start(from, to time.Time) (*models.BackfillJob, error) {
+	job, err := b.create(context.Background(), from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	snapshot := *job
+	b.runInBackground(job)
+	return &snapshot, nil
+}
+
+// ResumeInBackground resumes a job like Resume but runs it in the background
+func (b *BackfillService) ResumeInBackground(id int64) (*models.BackfillJob, error) {
+	job, err := b.claim(context.Background(), id)
+	if err != nil {
+		return nil, err
+	}
+
+	snapshot := *job
+	b.runInBackground(job)
+	return &snapshot, nil
+}
+
+// Stop pauses the backfill running in the background, if any, and waits for it
+// to save its progress
+func (b *BackfillService) Stop() {
+	b.mu.Lock()
+	cancel, done := b.cancel, b.done
+	b.mu.Unlock()
+
+	if cancel != nil {
+		cancel()
+		<-done
+	}
+}
+
+// Job returns a backfill job or ErrBackfillNotFound
+func (b *BackfillService) Job(ctx context.Context, id int64) (*models.BackfillJob, error) {
+	job, err := b.jobs.GetBackfillJob(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if job == nil {
+		return nil, ErrBackfillNotFound
+	}
+	return job, nil
+}
+
+// ListJobs returns the most recent backfill jobs
+func (b *BackfillService) ListJobs(ctx context.Context, limit int) ([]models.BackfillJob, error) {
+	if limit <= 0 || limit > maxBackfillLimit {
+		limit = defaultBackfillLimit
+	}
+	return b.jobs.ListBackfillJobs(ctx, limit)
+}
+
+// create takes the process-wide backfill slot and stores a new running job
+func (b *BackfillService) create(ctx context.Context, from, to time.Time) (*models.BackfillJob, error) {
+	if !from.Before(to) {
+		return nil, fmt.Errorf("%w: from must be before to", ErrInvalidBackfillRange)
+	}
+	// The slot is held under a placeholder ID until the job is stored
+	if err := b.acquire(-1); err != nil {
+		return nil, err
+	}
+
+	job := &models.BackfillJob{From: from.UTC(), To: to.UTC(), Status: models.BackfillStatusRunning}
+	if err := b.jobs.CreateBackfillJob(ctx, job); err != nil {
+		b.release()
+		return nil, err
+	}
+	if err := b.lock(ctx, job.ID); err != nil {
+		b.release()
+		return nil, err
+	}
+
+	b.mu.Lock()
+	b.running = job.ID
+	b.mu.Unlock()
+
+	return job, nil
+}
+
+// claim takes the process-wide backfill slot for an existing job
+func (b *BackfillService) claim(ctx context.Context, id int64) (*models.BackfillJob, error) {
+	if err := b.acquire(id); err != nil {
+		return nil, err
+	}
+	// The job is read after locking so it holds the progress saved by the last runner
+	if err := b.lock(ctx, id); err != nil {
+		b.release()
+		return nil, err
+	}
+
+	job, err := b.Job(ctx, id)
+	if err == nil && job.Status == models.BackfillStatusCompleted {
+		err = ErrBackfillCompleted
+	}
+	if err != nil {
+		b.release()
+		return nil, err
+	}
+
+	return job, nil
+}
+
+func (b *BackfillService) acquire(id int64) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.running != 0 {
+		return ErrBackfillRunning
+	}
+	b.running = id
+	return nil
+}
+
+// lock takes the database lock on a job for the slot held by this process
+func (b *BackfillService) lock(ctx context.Context, id int64) error {
+	unlock, ok, err := b.jobs.LockBackfillJob(ctx, id)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrBackfillRunning
+	}
+
+	b.mu.Lock()
+	b.unlock = unlock
+	b.mu.Unlock()
+	return nil
+}
+
+func (b *BackfillService) release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.unlock != nil {
+		b.unlock()
+	}
+	b.running = 0
+	b.unlock = nil
+	b.cancel = nil
+	b.done = nil
+}
+
+func (b *BackfillService) runInBackground(job *models.BackfillJob) {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+
+	b.mu.Lock()
+	b.cancel, b.done = cancel, done
+	b.mu.Unlock()
+
+	go func() {
+		defer close(done)
+		defer cancel()
+		defer b.release()
+		b.run(ctx, job)
+	}()
+}
+
+// run pages through the provider from job.Cursor, saving progress after every page
+func (b *BackfillService) run(ctx context.Context, job *models.BackfillJob) {
+	log := b.log.With("job", job.ID)
+	log.Info("backfill started", "from", job.From, "to", job.To, "cursor", job.Cursor)
+
+	job.Status = models.BackfillStatusRunning
+	job.Error = ""
+	job.FinishedAt = nil
+	b.save(ctx, job)
+
+	for pages := 0; ; pages++ {
+		if b.maxPages > 0 && pages >= b.maxPages {
+			b.finish(ctx, job, models.BackfillStatusPaused, nil)
+			break
+		}
+		if pages > 0 && !b.wait(ctx) {
+			b.finish(ctx, job, models.BackfillStatusPaused, nil)
+			break
+		}
+
+		done, err := b.page(ctx, job)
+		if err != nil {
+			status := models.BackfillStatusFailed
+			if ctx.Err() != nil {
+				status, err = models.BackfillStatusPaused, nil
+			}
+			b.finish(ctx, job, status, err)
+			break
+		}
+
+		log.Info("backfill progress",
+			"pages", job.Pages,
+			"fetched", job.Fetched,
+			"in_range", job.InRange,
+			"stored", job.Stored,
+			"quarantined", job.Quarantined,
+		)
+
+		if done {
+			b.finish(ctx, job, models.BackfillStatusCompleted, nil)
+			break
+		}
+		b.save(ctx, job)
+	}
+
+	log.Info("backfill stopped", "status", job.Status, "pages", job.Pages, "stored", job.Stored, "error", job.Error)
+}
+
+// page fetches and ingests the page at job.Cursor and advances the cursor. It reports
+// whether the backfill is done: the provider has no more pages or the whole page
+// is older than the start of the range.
+func (b *BackfillService) page(ctx context.Context, job *models.BackfillJob) (bool, error) {
+	response, err := b.client.FetchStocks(ctx, job.Cursor)
+	if err != nil {
+		return false, fmt.Errorf("error fetching page %q: %w", job.Cursor, err)
+	}
+
+	var inRange []APIStock
+	dated, older := 0, 0
+	for _, record := range response.Items {
+		if record.Time.IsZero() {
+			// Undated records cannot be placed in the range; validation quarantines them
+			inRange = append(inRange, record)
+			continue
+		}
+		dated++
+		switch {
+		case record.Time.Before(job.From):
+			older++
+		case record.Time.Before(job.To):
+			inRange = append(inRange, record)
+		}
+	}
+
+	ingested, err := b.stocks.ingest(ctx, inRange, models.QuarantineSourceBackfill)
+	if err != nil {
+		return false, err
+	}
+
+	job.Pages++
+	job.Fetched += len(response.Items)
+	job.InRange += len(inRange)
+	job.Stored += ingested.Report.Upserted
+	job.Quarantined += ingested.Quarantined
+	job.Cursor = response.NextPage
+
+	return response.NextPage == "" || (dated > 0 && older == dated), nil
+}
+
+// wait sleeps for the backfill page delay and reports whether ctx is still live
+func (b *BackfillService) wait(ctx context.Context) bool {
+	select {
+	case <-ctx.Done():
+		return false
+	case <-time.After(b.client.pageDelay):
+		return true
+	}
+}
+
+func (b *BackfillService) finish(ctx context.Context, job *models.BackfillJob, status string, err error) {
+	job.Status = status
+	if err != nil {
+		job.Error = err.Error()
+	}
+	if status == models.BackfillStatusCompleted || status == models.BackfillStatusFailed {
+		finishedAt := time.Now()
+		job.FinishedAt = &finishedAt
+	}
+	b.save(ctx, job)
+}
+
+// save stores the job's progress, even when ctx was cancelled
+func (b *BackfillService) save(ctx context.Context, job *models.BackfillJob) {
+	if err := b.jobs.UpdateBackfillJob(context.WithoutCancel(ctx), job); err != nil {
+		b.log.Warn("error saving backfill progress", "job", job.ID, "error", err)
+	}
+}
@@ -0,0 +1,209 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"Backend/internal/config"
+	"Backend/internal/models"
+	"Backend/internal/repository"
+)
+
+func TestParseBackfillRange(t *testing.T) {
+	day := func(s string) time.Time { d, _ := time.Parse(time.DateOnly, s); return d }
+
+	tests := []struct {
+		name      string
+		from, to  string
+		wantFrom  time.Time
+		wantTo    time.Time
+		wantError bool
+	}{
+		{"dates include the last day", "2025-01-01", "2025-01-31", day("2025-01-01"), day("2025-02-01"), false},
+		{"single day", "2025-01-01", "2025-01-01", day("2025-01-01"), day("2025-01-02"), false},
+		{"RFC 3339 end is exclusive", "2025-01-01", "2025-01-01T12:00:00Z", day("2025-01-01"), day("2025-01-01").Add(12 * time.Hour), false},
+		{"RFC 3339 is converted to UTC", "2025-01-01T02:00:00+02:00", "2025-01-02", day("2025-01-01"), day("2025-01-03"), false},
+		{"reversed", "2025-02-01", "2025-01-01", time.Time{}, time.Time{}, true},
+		{"empty range", "2025-01-01T00:00:00Z", "2025-01-01T00:00:00Z", time.Time{}, time.Time{}, true},
+		{"unparseable", "yesterday", "2025-01-01", time.Time{}, time.Time{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			from, to, err := ParseBackfillRange(tt.from, tt.to)
+			if tt.wantError {
+				if !errors.Is(err, ErrInvalidBackfillRange) {
+					t.Errorf("ParseBackfillRange() error = %v, want ErrInvalidBackfillRange", err)
+				}
+				return
+			}
+			if err != nil || !from.Equal(tt.wantFrom) || !to.Equal(tt.wantTo) {
+				t.Errorf("ParseBackfillRange() = %v, %v, %v; want %v, %v", from, to, err, tt.wantFrom, tt.wantTo)
+			}
+		})
+	}
+}
+
+// historyPages serves three pages, newest first: June, May, and April 2025
+func historyPages() map[string]APIResponse {
+	record := func(ticker string, at time.Time) APIStock {
+		return APIStock{Ticker: ticker, Company: ticker + " Inc", Brokerage: "Broker", Action: "upgraded by", RatingTo: "Buy", Time: at}
+	}
+	month := func(m time.Month) time.Time { return time.Date(2025, m, 15, 12, 0, 0, 0, time.UTC) }
+
+	return map[string]APIResponse{
+		"":      {Items: []APIStock{record("JUN", month(time.June))}, NextPage: "may"},
+		"may":   {Items: []APIStock{record("MAY", month(time.May)), record("bad", month(time.May))}, NextPage: "april"},
+		"april": {Items: []APIStock{record("APR", month(time.April))}, NextPage: "march"},
+		"march": {Items: []APIStock{record("MAR", month(time.March))}},
+	}
+}
+
+func newTestBackfillService(t *testing.T, upstream *fakeUpstream, cfg config.BackfillConfig) (*BackfillService, *repository.MemoryRepository) {
+	t.Helper()
+	service, repo := newTestStockService(t)
+	backfill := NewBackfillService(repo, service, NewAPIClient("key", upstream.URL, cfg.PageDelay, 0), cfg)
+	t.Cleanup(backfill.Stop)
+	return backfill, repo
+}
+
+func TestBackfillRun(t *testing.T) {
+	upstream := newFakeUpstream(t, historyPages())
+	backfill, repo := newTestBackfillService(t, upstream, config.BackfillConfig{})
+	from, to, _ := ParseBackfillRange("2025-05-01", "2025-05-31")
+
+	job, err := backfill.Run(context.Background(), from, to)
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	if job.Status != models.BackfillStatusCompleted || job.FinishedAt == nil {
+		t.Errorf("job = %+v, want completed", job)
+	}
+	// April is entirely before the range, so March is never requested
+	if job.Pages != 3 || job.Fetched != 4 || job.InRange != 2 || job.Stored != 1 || job.Quarantined != 1 {
+		t.Errorf("counters = pages %d, fetched %d, in range %d, stored %d, quarantined %d; want 3, 4, 2, 1, 1",
+			job.Pages, job.Fetched, job.InRange, job.Stored, job.Quarantined)
+	}
+	if n := len(upstream.requested()); n != 3 {
+		t.Errorf("made %d requests, want 3", n)
+	}
+
+	stocks, err := repo.ListStocks(context.Background(), models.StockFilters{})
+	if err != nil || len(stocks) != 1 || stocks[0].Ticker != "MAY" || stocks[0].Score == 0 {
+		t.Errorf("stored %+v, %v; want only the scored MAY event", stocks, err)
+	}
+
+	stored, err := backfill.Job(context.Background(), job.ID)
+	if err != nil || stored.Status != models.BackfillStatusCompleted || stored.Stored != 1 {
+		t.Errorf("Job() = %+v, %v; want the completed job saved", stored, err)
+	}
+
+	// Backfilled events are history, not news for alerts, webhooks or streams
+	if changes, err := repo.ListChanges(context.Background(), 0, 100); err != nil || len(changes) != 0 {
+		t.Errorf("ListChanges() = %+v, %v; want no changes from a backfill", changes, err)
+	}
+}
+
+func TestBackfillPausesAndResumes(t *testing.T) {
+	upstream := newFakeUpstream(t, historyPages())
+	backfill, repo := newTestBackfillService(t, upstream, config.BackfillConfig{MaxPages: 2})
+	from, to, _ := ParseBackfillRange("2025-03-01", "2025-06-30")
+	ctx := context.Background()
+
+	job, err := backfill.Run(ctx, from, to)
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if job.Status != models.BackfillStatusPaused || job.Cursor != "april" || job.Pages != 2 {
+		t.Fatalf("job = %+v, want paused before the april page", job)
+	}
+
+	job, err = backfill.Resume(ctx, job.ID)
+	if err != nil {
+		t.Fatalf("Resume() error = %v", err)
+	}
+	if job.Status != models.BackfillStatusCompleted || job.Pages != 4 || job.Stored != 4 {
+		t.Errorf("job = %+v, want completed after 4 pages with 4 stocks stored", job)
+	}
+
+	stocks, _ := repo.ListStocks(ctx, models.StockFilters{})
+	if len(stocks) != 4 {
+		t.Errorf("stored %d stocks, want 4", len(stocks))
+	}
+
+	if _, err := backfill.Resume(ctx, job.ID); !errors.Is(err, ErrBackfillCompleted) {
+		t.Errorf("Resume() of a completed job error = %v, want ErrBackfillCompleted", err)
+	}
+	if _, err := backfill.Resume(ctx, 99); !errors.Is(err, ErrBackfillNotFound) {
+		t.Errorf("Resume(99) error = %v, want ErrBackfillNotFound", err)
+	}
+}
+
+func TestBackfillRecordsUpstreamFailure(t *testing.T) {
+	pages := historyPages()
+	delete(pages, "may")
+	upstream := newFakeUpstream(t, pages)
+	backfill, _ := newTestBackfillService(t, upstream, config.BackfillConfig{})
+	from, to, _ := ParseBackfillRange("2025-01-01", "2025-12-31")
+
+	job, err := backfill.Run(context.Background(), from, to)
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if job.Status != models.BackfillStatusFailed || job.Cursor != "may" || job.Error == "" {
+		t.Errorf("job = %+v, want failed at the may page", job)
+	}
+}
+
+func TestBackfillRunsOneJobAtATime(t *testing.T) {
+	upstream := newFakeUpstream(t, historyPages())
+	backfill, _ := newTestBackfillService(t, upstream, config.BackfillConfig{PageDelay: time.Hour})
+	from, to, _ := ParseBackfillRange("2025-01-01", "2025-12-31")
+
+	job, err := backfill.Start(from, to)
+	if err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	if _, err := backfill.Start(from, to); !errors.Is(err, ErrBackfillRunning) {
+		t.Errorf("second Start() error = %v, want ErrBackfillRunning", err)
+	}
+
+	// Stop interrupts the page delay and leaves the job resumable
+	backfill.Stop()
+	stopped, err := backfill.Job(context.Background(), job.ID)
+	if err != nil || stopped.Status != models.BackfillStatusPaused || stopped.FinishedAt != nil {
+		t.Errorf("job after Stop() = %+v, %v; want paused", stopped, err)
+	}
+
+	if _, err := backfill.ResumeInBackground(job.ID); err != nil {
+		t.Errorf("ResumeInBackground() error = %v, want the slot free after Stop", err)
+	}
+}
+
+func TestBackfillJobRunsInOneProcessAtATime(t *testing.T) {
+	upstream := newFakeUpstream(t, historyPages())
+	cfg := config.BackfillConfig{PageDelay: time.Hour}
+	backfill, repo := newTestBackfillService(t, upstream, cfg)
+	// A second process, such as the backfill command or another replica, on the same database
+	service, _ := newTestStockService(t)
+	other := NewBackfillService(repo, service, NewAPIClient("key", upstream.URL, cfg.PageDelay, 0), cfg)
+	t.Cleanup(other.Stop)
+	from, to, _ := ParseBackfillRange("2025-01-01", "2025-12-31")
+
+	job, err := backfill.Start(from, to)
+	if err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	if _, err := other.ResumeInBackground(job.ID); !errors.Is(err, ErrBackfillRunning) {
+		t.Errorf("ResumeInBackground() in another process error = %v, want ErrBackfillRunning", err)
+	}
+
+	// The other process keeps its own slot free for other jobs
+	backfill.Stop()
+	if _, err := other.ResumeInBackground(job.ID); err != nil {
+		t.Errorf("ResumeInBackground() after Stop() error = %v, want the job free", err)
+	}
+}
@@ -0,0 +1,154 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"Backend/internal/models"
+	"Backend/internal/repository"
+	"Backend/internal/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// ErrInvalidCursor is returned for a change feed cursor that is not a non-negative integer
+var ErrInvalidCursor = errors.New("invalid change feed cursor")
+
+// ErrInvalidTicker is returned for a ticker that is not a valid symbol
+var ErrInvalidTicker = errors.New("invalid ticker")
+
+// consensusWindow is how far back TickerConsensus looks for ratings
+const consensusWindow = 90 * 24 * time.Hour
+
+// Limits for Changes
+const (
+	defaultChangeLimit = 100
+	maxChangeLimit     = 1000
+)
+
+// ChangeService serves the feed of new coverage, rating and target changes that
+// UpsertStocks writes, so consumers can follow the stocks without diffing them
+type ChangeService struct {
+	changes repository.ChangeRepository
+}
+
+// NewChangeService creates a new instance of ChangeService
+func NewChangeService(changes repository.ChangeRepository) *ChangeService {
+	return &ChangeService{changes: changes}
+}
+
+// ParseCursor parses a cursor returned in ChangeFeed. An empty cursor starts
+// from the beginning of the feed.
+func ParseCursor(cursor string) (int64, error) {
+	if cursor == "" {
+		return 0, nil
+	}
+	since, err := strconv.ParseInt(cursor, 10, 64)
+	if err != nil || since < 0 {
+		return 0, fmt.Errorf("%w: %q", ErrInvalidCursor, cursor)
+	}
+	return since, nil
+}
+
+// Changes returns up to limit changes after the since cursor, oldest first
+func (s *ChangeService) Changes(ctx context.Context, since int64, limit int) (_ *models.ChangeFeed, err error) {
+	ctx, span := tracing.Start(ctx, "ChangeService.Changes", attribute.Int64("since", since))
+	defer func() { tracing.End(span, err) }()
+
+	if limit <= 0 || limit > maxChangeLimit {
+		limit = defaultChangeLimit
+	}
+
+	// One extra row tells whether another page follows
+	changes, err := s.changes.ListChanges(ctx, since, limit+1)
+	if err != nil {
+		return nil, err
+	}
+
+	feed := &models.ChangeFeed{Changes: changes, Cursor: since}
+	if len(changes) > limit {
+		feed.Changes, feed.HasMore = changes[:limit], true
+	}
+	if n := len(feed.Changes); n > 0 {
+		feed.Cursor = feed.Changes[n-1].ID
+	}
+
+	return feed, nil
+}
+
+// TickerConsensus summarizes the latest rating of each brokerage that rated
+// ticker in the last 90 days. The consensus is Buy, Hold or Sell by the mean
+// rank of the ratings, rounded to the nearest side of Hold.
+func (s *ChangeService) TickerConsensus(ctx context.Context, ticker string) (_ *models.TickerConsensus, err error) {
+	ctx, span := tracing.Start(ctx, "ChangeService.TickerConsensus", attribute.String("ticker", ticker))
+	defer func() { tracing.End(span, err) }()
+
+	ticker = strings.ToUpper(strings.TrimSpace(ticker))
+	if !tickerPattern.MatchString(ticker) {
+		return nil, fmt.Errorf("%w: %q is not a valid symbol", ErrInvalidTicker, ticker)
+	}
+
+	since := time.Now().Add(-consensusWindow).UTC()
+	ratings, err := s.changes.LatestRatings(ctx, ticker, since)
+	if err != nil {
+		return nil, err
+	}
+
+	consensus := &models.TickerConsensus{
+		Ticker:     ticker,
+		Since:      since,
+		Ratings:    len(ratings),
+		Brokerages: ratings,
+	}
+	var rankSum float64
+	var ranked, targeted int
+	for _, rating := range ratings {
+		if rank, ok := models.RatingRank(rating.Rating); ok {
+			ranked++
+			rankSum += float64(rank)
+			switch {
+			case rank > holdRank:
+				consensus.Buy++
+			case rank < holdRank:
+				consensus.Sell++
+			default:
+				consensus.Hold++
+			}
+		}
+
+		if target, err := models.ParseTarget(rating.Target); err == nil && target > 0 {
+			if targeted == 0 || target < consensus.LowTarget {
+				consensus.LowTarget = target
+			}
+			if target > consensus.HighTarget {
+				consensus.HighTarget = target
+			}
+			consensus.MeanTarget += target
+			targeted++
+		}
+	}
+
+	if ranked > 0 {
+		consensus.MeanRank = rankSum / float64(ranked)
+		switch {
+		case consensus.MeanRank > float64(holdRank)+0.5:
+			consensus.Consensus = models.ConsensusBuy
+		case consensus.MeanRank < float64(holdRank)-0.5:
+			consensus.Consensus = models.ConsensusSell
+		default:
+			consensus.Consensus = models.ConsensusHold
+		}
+	}
+	if targeted > 0 {
+		consensus.MeanTarget /= float64(targeted)
+	}
+
+	return consensus, nil
+}
+
+// holdRank is the rank of Hold and the other neutral ratings
+var holdRank, _ = models.RatingRank("hold")
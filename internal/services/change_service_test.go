@@ -0,0 +1,134 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"Backend/internal/models"
+	"Backend/internal/repository"
+)
+
+func TestParseCursor(t *testing.T) {
+	tests := []struct {
+		cursor  string
+		want    int64
+		wantErr bool
+	}{
+		{"", 0, false},
+		{"0", 0, false},
+		{"42", 42, false},
+		{"-1", 0, true},
+		{"abc", 0, true},
+		{"1.5", 0, true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseCursor(tt.cursor)
+		if tt.wantErr {
+			if !errors.Is(err, ErrInvalidCursor) {
+				t.Errorf("ParseCursor(%q) error = %v, want ErrInvalidCursor", tt.cursor, err)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("ParseCursor(%q) = %d, %v; want %d", tt.cursor, got, err, tt.want)
+		}
+	}
+}
+
+func TestChangesPagesThroughTheFeed(t *testing.T) {
+	repo := repository.NewMemoryRepository()
+	service := NewChangeService(repo)
+	ctx := context.Background()
+
+	_, err := repo.UpsertStocks(ctx, []models.Stock{
+		{Ticker: "AAA", Company: "A Corp", Brokerage: "X", RatingTo: "Hold", Time: time.Now()},
+		{Ticker: "BBB", Company: "B Corp", Brokerage: "Y", RatingTo: "Buy", Time: time.Now()},
+		{Ticker: "CCC", Company: "C Corp", Brokerage: "Z", RatingTo: "Sell", Time: time.Now()},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	feed, err := service.Changes(ctx, 0, 2)
+	if err != nil || len(feed.Changes) != 2 || !feed.HasMore || feed.Cursor != feed.Changes[1].ID {
+		t.Fatalf("Changes(0, 2) = %+v, %v; want 2 changes and more to come", feed, err)
+	}
+
+	feed, err = service.Changes(ctx, feed.Cursor, 2)
+	if err != nil || len(feed.Changes) != 1 || feed.Changes[0].Ticker != "CCC" || feed.HasMore {
+		t.Fatalf("Changes() = %+v, %v; want only CCC", feed, err)
+	}
+
+	// Nothing new keeps the cursor where it was
+	cursor := feed.Cursor
+	feed, err = service.Changes(ctx, cursor, 0)
+	if err != nil || len(feed.Changes) != 0 || feed.Cursor != cursor || feed.HasMore {
+		t.Errorf("Changes(%d) = %+v, %v; want an empty page at the same cursor", cursor, feed, err)
+	}
+}
+
+func TestTickerConsensus(t *testing.T) {
+	repo := repository.NewMemoryRepository()
+	service := NewChangeService(repo)
+	ctx := context.Background()
+	now := time.Now()
+
+	for _, stock := range []models.Stock{
+		{Ticker: "AAPL", Company: "Apple Inc", Brokerage: "UBS", RatingTo: "Sell", TargetTo: "$150", Time: now.AddDate(0, 0, -200)},
+		{Ticker: "AAPL", Company: "Apple Inc", Brokerage: "Goldman Sachs", RatingTo: "Buy", TargetTo: "$200", Time: now.Add(-3 * time.Hour)},
+		{Ticker: "AAPL", Company: "Apple Inc", Brokerage: "Barclays", RatingTo: "Outperform", TargetTo: "$1,000.00", Time: now.Add(-2 * time.Hour)},
+		{Ticker: "AAPL", Company: "Apple Inc", Brokerage: "Jefferies", RatingTo: "Hold", Time: now.Add(-time.Hour)},
+		{Ticker: "AAPL", Company: "Apple Inc", Brokerage: "Boutique", RatingTo: "Accumulate", TargetTo: "$100", Time: now},
+	} {
+		if _, err := repo.UpsertStocks(ctx, []models.Stock{stock}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got, err := service.TickerConsensus(ctx, " aapl ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// UBS rated outside the window; Accumulate has no rank but its target counts
+	if got.Ticker != "AAPL" || got.Ratings != 4 || got.Buy != 2 || got.Hold != 1 || got.Sell != 0 {
+		t.Errorf("counts = %+v, want 4 ratings: 2 buy and 1 hold", got)
+	}
+	if got.Consensus != models.ConsensusBuy || got.MeanRank != float64(8+7+5)/3 {
+		t.Errorf("consensus = %s at %v, want Buy at 6.67", got.Consensus, got.MeanRank)
+	}
+	if got.LowTarget != 100 || got.HighTarget != 1000 || got.MeanTarget != (200+1000+100)/3.0 {
+		t.Errorf("targets = %v-%v mean %v, want 100-1000 mean 433.33", got.LowTarget, got.HighTarget, got.MeanTarget)
+	}
+	if len(got.Brokerages) != 4 || got.Brokerages[0].Brokerage != "Boutique" || got.Brokerages[3].Rating != "Buy" {
+		t.Errorf("brokerages = %+v, want the newest first", got.Brokerages)
+	}
+
+	// Reiterating Buy writes no change, yet keeps Goldman Sachs in the window
+	// after the rating it repeats has left it
+	reiteration := models.Stock{Ticker: "NVDA", Company: "NVIDIA Corp", Brokerage: "Goldman Sachs", RatingTo: "Buy", TargetTo: "$150", Time: now.AddDate(0, 0, -120)}
+	if _, err := repo.UpsertStocks(ctx, []models.Stock{reiteration}); err != nil {
+		t.Fatal(err)
+	}
+	last, _ := repo.LastChangeID(ctx)
+	reiteration.Time = now.AddDate(0, 0, -7)
+	if _, err := repo.UpsertStocks(ctx, []models.Stock{reiteration}); err != nil {
+		t.Fatal(err)
+	}
+	if id, _ := repo.LastChangeID(ctx); id != last {
+		t.Fatalf("LastChangeID() = %d, want %d: a reiteration is not a change", id, last)
+	}
+	got, err = service.TickerConsensus(ctx, "NVDA")
+	if err != nil || got.Ratings != 1 || got.Buy != 1 || got.Consensus != models.ConsensusBuy || !got.Brokerages[0].Time.Equal(reiteration.Time) {
+		t.Errorf("TickerConsensus(NVDA) = %+v, %v; want the reiterated Buy", got, err)
+	}
+
+	if got, err := service.TickerConsensus(ctx, "MSFT"); err != nil || got.Ratings != 0 || got.Consensus != "" || got.Brokerages == nil {
+		t.Errorf("TickerConsensus(MSFT) = %+v, %v; want no ratings", got, err)
+	}
+	if _, err := service.TickerConsensus(ctx, "not a ticker"); !errors.Is(err, ErrInvalidTicker) {
+		t.Errorf("TickerConsensus(invalid) error = %v, want ErrInvalidTicker", err)
+	}
+}
@@ -0,0 +1,333 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"Backend/internal/config"
+	"Backend/internal/logger"
+	"Backend/internal/models"
+	"Backend/internal/repository"
+)
+
+// streamPageSize is the number of changes a subscription reads at a time. A
+// client that falls behind catches up page by page instead of being buffered.
+const streamPageSize = 100
+
+// maxWatched caps the tickers and brokerages a single subscription can follow
+const maxWatched = 200
+
+// ErrWatchLimit is returned when a subscription would follow too many tickers and brokerages
+var ErrWatchLimit = fmt.Errorf("a subscription can follow at most %d tickers and brokerages", maxWatched)
+
+// ChangeFilter selects change feed entries by ticker and brokerage, ignoring
+// case. By default a change must match both lists and an empty list matches
+// every value. With Any, as for a watchlist, a change matching either list is
+// selected and an empty filter selects nothing.
+type ChangeFilter struct {
+	Tickers    []string
+	Brokerages []string
+	Any        bool
+}
+
+func (f ChangeFilter) matches(change models.StockChange) bool {
+	if f.Any {
+		return contains(f.Tickers, change.Ticker) || contains(f.Brokerages, change.Brokerage)
+	}
+	return matchesAny(f.Tickers, change.Ticker) && matchesAny(f.Brokerages, change.Brokerage)
+}
+
+// Watch returns a copy of the filter that also follows tickers and brokerages.
+// Tickers are upper-cased and values already followed are skipped.
+func (f ChangeFilter) Watch(tickers, brokerages []string) (ChangeFilter, error) {
+	watched := ChangeFilter{Tickers: slices.Clone(f.Tickers), Brokerages: slices.Clone(f.Brokerages), Any: f.Any}
+	for _, ticker := range tickers {
+		if ticker = strings.ToUpper(strings.TrimSpace(ticker)); ticker != "" && !contains(watched.Tickers, ticker) {
+			watched.Tickers = append(watched.Tickers, ticker)
+		}
+	}
+	for _, brokerage := range brokerages {
+		if brokerage = strings.TrimSpace(brokerage); brokerage != "" && !contains(watched.Brokerages, brokerage) {
+			watched.Brokerages = append(watched.Brokerages, brokerage)
+		}
+	}
+
+	if len(watched.Tickers)+len(watched.Brokerages) > maxWatched {
+		return f, ErrWatchLimit
+	}
+	return watched, nil
+}
+
+// Unwatch returns a copy of the filter that no longer follows tickers and brokerages
+func (f ChangeFilter) Unwatch(tickers, brokerages []string) ChangeFilter {
+	remove := func(values, removed []string) []string {
+		kept := []string{}
+		for _, v := range values {
+			if !contains(removed, v) {
+				kept = append(kept, v)
+			}
+		}
+		return kept
+	}
+	return ChangeFilter{Tickers: remove(f.Tickers, tickers), Brokerages: remove(f.Brokerages, brokerages), Any: f.Any}
+}
+
+// matchesAny reports whether value is in values, or values is empty
+func matchesAny(values []string, value string) bool {
+	return len(values) == 0 || contains(values, value)
+}
+
+// contains reports whether value is in values, ignoring case and surrounding spaces
+func contains(values []string, value string) bool {
+	value = strings.TrimSpace(value)
+	for _, v := range values {
+		if strings.EqualFold(strings.TrimSpace(v), value) {
+			return true
+		}
+	}
+	return false
+}
+
+// ChangeStream pushes the change feed to live subscribers. It polls the feed, so
+// changes written by any instance or by the backfill command reach everyone, and
+// tracks the top recommendation so subscribers hear when it changes.
+type ChangeStream struct {
+	changes repository.ChangeRepository
+	stocks  *StockService
+	cfg     config.StreamConfig
+	log     *slog.Logger
+	done    chan struct{}
+
+	mu          sync.RWMutex
+	polled      bool
+	head        int64
+	topPick     *models.Stock
+	subscribers map[*ChangeSubscription]struct{}
+}
+
+// NewChangeStream creates a new instance of ChangeStream. Call Run to start polling.
+func NewChangeStream(changes repository.ChangeRepository, stocks *StockService, cfg config.StreamConfig) *ChangeStream {
+	return &ChangeStream{
+		changes:     changes,
+		stocks:      stocks,
+		cfg:         cfg,
+		log:         logger.Component("change_stream"),
+		done:        make(chan struct{}),
+		subscribers: make(map[*ChangeSubscription]struct{}),
+	}
+}
+
+// Run polls the change feed until ctx is cancelled, then ends every subscription
+// so long-lived connections do not hold up a graceful shutdown
+func (s *ChangeStream) Run(ctx context.Context) {
+	defer close(s.done)
+
+	ticker := time.NewTicker(s.cfg.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		s.Poll(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Poll checks the change feed once. When it grew, the top pick is refreshed and
+// every subscriber is woken up to read the new entries.
+func (s *ChangeStream) Poll(ctx context.Context) {
+	head, err := s.changes.LastChangeID(ctx)
+	if err != nil {
+		s.log.Warn("error polling change feed", "error", err)
+		return
+	}
+
+	s.mu.RLock()
+	moved := !s.polled || head != s.head
+	s.mu.RUnlock()
+	if !moved {
+		return
+	}
+
+	topPick, err := s.currentTopPick(ctx)
+
+	s.mu.Lock()
+	s.polled, s.head = true, head
+	if err != nil {
+		s.log.Warn("error refreshing top pick", "error", err)
+	} else {
+		s.topPick = topPick
+	}
+	subscribers := make([]*ChangeSubscription, 0, len(s.subscribers))
+	for sub := range s.subscribers {
+		subscribers = append(subscribers, sub)
+	}
+	s.mu.Unlock()
+
+	for _, sub := range subscribers {
+		sub.wake()
+	}
+}
+
+func (s *ChangeStream) currentTopPick(ctx context.Context) (*models.Stock, error) {
+	recommendations, err := s.stocks.GetRecommendations(ctx)
+	if err != nil || len(recommendations) == 0 {
+		return nil, err
+	}
+	return &recommendations[0], nil
+}
+
+// Subscribe follows the change feed after the since cursor, or from the current
+// end of the feed when since is negative. The subscriber is woken up right away
+// to receive the current top pick.
+func (s *ChangeStream) Subscribe(ctx context.Context, since int64, filter ChangeFilter) (*ChangeSubscription, error) {
+	if since < 0 {
+		head, err := s.changes.LastChangeID(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("error reading change feed: %w", err)
+		}
+		since = head
+	}
+
+	sub := &ChangeSubscription{
+		stream:  s,
+		notify:  make(chan struct{}, 1),
+		filter:  filter,
+		cursor:  since,
+		topPick: -1,
+	}
+	sub.wake()
+
+	s.mu.Lock()
+	s.subscribers[sub] = struct{}{}
+	s.mu.Unlock()
+
+	return sub, nil
+}
+
+// Subscribers returns the number of live subscriptions
+func (s *ChangeStream) Subscribers() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.subscribers)
+}
+
+// currentTopPickState returns the top pick and whether it is known yet
+func (s *ChangeStream) currentTopPickState() (*models.Stock, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.topPick, s.polled
+}
+
+// ChangeSubscription follows the change feed for one client. It keeps its own
+// cursor and reads the feed itself when woken up, so a slow client never holds
+// up the others and nothing is buffered on its behalf.
+type ChangeSubscription struct {
+	stream *ChangeStream
+	notify chan struct{}
+
+	mu      sync.Mutex
+	filter  ChangeFilter
+	cursor  int64
+	topPick int // ID of the last top pick sent; 0 for none, -1 before the first
+}
+
+// wake signals Ready without blocking; pending signals coalesce
+func (sub *ChangeSubscription) wake() {
+	select {
+	case sub.notify <- struct{}{}:
+	default:
+	}
+}
+
+// Ready receives a value when Next may have events to return
+func (sub *ChangeSubscription) Ready() <-chan struct{} {
+	return sub.notify
+}
+
+// Done is closed when the stream stops running
+func (sub *ChangeSubscription) Done() <-chan struct{} {
+	return sub.stream.done
+}
+
+// Cursor returns the ID of the last change read
+func (sub *ChangeSubscription) Cursor() int64 {
+	sub.mu.Lock()
+	defer sub.mu.Unlock()
+	return sub.cursor
+}
+
+// Filter returns the current filter
+func (sub *ChangeSubscription) Filter() ChangeFilter {
+	sub.mu.Lock()
+	defer sub.mu.Unlock()
+	return sub.filter
+}
+
+// SetFilter replaces the filter. It applies from the next change read, which
+// happens right away.
+func (sub *ChangeSubscription) SetFilter(filter ChangeFilter) {
+	sub.mu.Lock()
+	sub.filter = filter
+	sub.mu.Unlock()
+	sub.wake()
+}
+
+// Next returns the events available now without waiting: the matching changes of
+// the next page of the feed, then the top pick if it changed since the last call.
+// When a full page was read it wakes the subscription again, so the caller keeps
+// reading until it has caught up. Wait on Ready when it returns nothing.
+func (sub *ChangeSubscription) Next(ctx context.Context) ([]models.StreamEvent, error) {
+	sub.mu.Lock()
+	defer sub.mu.Unlock()
+
+	// A watchlist that follows nothing leaves its cursor in place, so changes
+	// since the cursor are delivered once the client subscribes
+	var changes []models.StockChange
+	if !sub.filter.Any || len(sub.filter.Tickers)+len(sub.filter.Brokerages) > 0 {
+		var err error
+		changes, err = sub.stream.changes.ListChanges(ctx, sub.cursor, streamPageSize)
+		if err != nil {
+			return nil, fmt.Errorf("error reading change feed: %w", err)
+		}
+	}
+
+	now := time.Now()
+	var events []models.StreamEvent
+	for i := range changes {
+		sub.cursor = changes[i].ID
+		if sub.filter.matches(changes[i]) {
+			events = append(events, models.StreamEvent{ID: changes[i].ID, Type: models.StreamEventChange, Change: &changes[i], Time: now})
+		}
+	}
+	if len(changes) == streamPageSize {
+		sub.wake()
+	}
+
+	topPick, known := sub.stream.currentTopPickState()
+	topPickID := 0
+	if topPick != nil {
+		topPickID = topPick.ID
+	}
+	if known && topPickID != sub.topPick {
+		sub.topPick = topPickID
+		events = append(events, models.StreamEvent{Type: models.StreamEventTopPick, TopPick: topPick, Time: now})
+	}
+
+	return events, nil
+}
+
+// Close stops the subscription
+func (sub *ChangeSubscription) Close() {
+	sub.stream.mu.Lock()
+	defer sub.stream.mu.Unlock()
+	delete(sub.stream.subscribers, sub)
+}
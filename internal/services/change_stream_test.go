@@ -0,0 +1,226 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+
+	"Backend/internal/config"
+	"Backend/internal/models"
+	"Backend/internal/repository"
+)
+
+func newTestChangeStream(t *testing.T) (*ChangeStream, *repository.MemoryRepository) {
+	t.Helper()
+	service, repo := newTestStockService(t)
+	return NewChangeStream(repo, service, config.StreamConfig{PollInterval: time.Hour, Heartbeat: time.Hour}), repo
+}
+
+func upsert(t *testing.T, repo *repository.MemoryRepository, stocks ...models.Stock) {
+	t.Helper()
+	if _, err := repo.UpsertStocks(context.Background(), stocks); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func ready(sub *ChangeSubscription) bool {
+	select {
+	case <-sub.Ready():
+		return true
+	default:
+		return false
+	}
+}
+
+func TestChangeSubscription(t *testing.T) {
+	stream, repo := newTestChangeStream(t)
+	ctx := context.Background()
+	now := time.Now()
+
+	upsert(t, repo, models.Stock{Ticker: "OLD", Company: "Old Corp", Brokerage: "X", RatingTo: "Buy", Score: 80, Time: now})
+
+	sub, err := stream.Subscribe(ctx, -1, ChangeFilter{Tickers: []string{"aaa"}})
+	if err != nil {
+		t.Fatalf("Subscribe() error = %v", err)
+	}
+	defer sub.Close()
+
+	// Without a cursor the subscription starts at the end of the feed, and the
+	// top pick is unknown until the stream has polled
+	if !ready(sub) {
+		t.Fatal("subscription not ready after Subscribe")
+	}
+	if events, err := sub.Next(ctx); err != nil || len(events) != 0 {
+		t.Fatalf("Next() = %+v, %v; want nothing", events, err)
+	}
+
+	upsert(t, repo,
+		models.Stock{Ticker: "AAA", Company: "A Corp", Brokerage: "X", RatingTo: "Buy", Score: 90, Time: now},
+		models.Stock{Ticker: "BBB", Company: "B Corp", Brokerage: "Y", RatingTo: "Hold", Score: 50, Time: now},
+	)
+	stream.Poll(ctx)
+	if !ready(sub) {
+		t.Fatal("subscription not woken up by Poll")
+	}
+
+	events, err := sub.Next(ctx)
+	if err != nil || len(events) != 2 {
+		t.Fatalf("Next() = %+v, %v; want the AAA change and the top pick", events, err)
+	}
+	if e := events[0]; e.Type != models.StreamEventChange || e.ID != 2 || e.Change.Ticker != "AAA" {
+		t.Errorf("first event = %+v, want the AAA change with ID 2", e)
+	}
+	if e := events[1]; e.Type != models.StreamEventTopPick || e.TopPick == nil || e.TopPick.Ticker != "AAA" {
+		t.Errorf("second event = %+v, want AAA as top pick", e)
+	}
+	if sub.Cursor() != 3 {
+		t.Errorf("Cursor() = %d, want 3: filtered changes are skipped, not replayed", sub.Cursor())
+	}
+
+	// An unchanged top pick is not sent again
+	sub.SetFilter(ChangeFilter{Brokerages: []string{"y"}})
+	upsert(t, repo, models.Stock{Ticker: "BBB", Company: "B Corp", Brokerage: "Y", RatingTo: "Buy", Score: 60, Time: now})
+	stream.Poll(ctx)
+
+	events, err = sub.Next(ctx)
+	if err != nil || len(events) != 1 || events[0].Change.Type != models.ChangeUpgrade {
+		t.Errorf("Next() = %+v, %v; want only the BBB upgrade", events, err)
+	}
+}
+
+func TestChangeSubscriptionResumesAndCatchesUp(t *testing.T) {
+	stream, repo := newTestChangeStream(t)
+	ctx := context.Background()
+
+	stocks := make([]models.Stock, streamPageSize+5)
+	for i := range stocks {
+		stocks[i] = models.Stock{Ticker: fmt.Sprintf("T%d", i), Company: "Corp", Brokerage: "X", Time: time.Now()}
+	}
+	upsert(t, repo, stocks...)
+
+	sub, err := stream.Subscribe(ctx, 0, ChangeFilter{})
+	if err != nil {
+		t.Fatalf("Subscribe() error = %v", err)
+	}
+	defer sub.Close()
+	<-sub.Ready()
+
+	events, err := sub.Next(ctx)
+	if err != nil || len(events) != streamPageSize {
+		t.Fatalf("Next() returned %d events, %v; want a full page", len(events), err)
+	}
+	if !ready(sub) {
+		t.Fatal("subscription not ready again after a full page")
+	}
+
+	events, err = sub.Next(ctx)
+	if err != nil || len(events) != 5 || events[4].ID != int64(len(stocks)) {
+		t.Errorf("Next() returned %d events, %v; want the last 5", len(events), err)
+	}
+}
+
+func TestChangeStreamRunEndsSubscriptions(t *testing.T) {
+	stream, _ := newTestChangeStream(t)
+	ctx, cancel := context.WithCancel(context.Background())
+
+	sub, err := stream.Subscribe(ctx, -1, ChangeFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stream.Subscribers() != 1 {
+		t.Errorf("Subscribers() = %d, want 1", stream.Subscribers())
+	}
+
+	done := make(chan struct{})
+	go func() {
+		stream.Run(ctx)
+		close(done)
+	}()
+	cancel()
+	<-done
+
+	select {
+	case <-sub.Done():
+	default:
+		t.Error("subscription not done after Run returned")
+	}
+
+	sub.Close()
+	if stream.Subscribers() != 0 {
+		t.Errorf("Subscribers() = %d after Close, want 0", stream.Subscribers())
+	}
+}
+
+func TestChangeFilterWatch(t *testing.T) {
+	filter, err := ChangeFilter{Any: true}.Watch([]string{" aapl", "MSFT", "msft", ""}, []string{"UBS"})
+	if err != nil {
+		t.Fatalf("Watch() error = %v", err)
+	}
+	if !slices.Equal(filter.Tickers, []string{"AAPL", "MSFT"}) || !slices.Equal(filter.Brokerages, []string{"UBS"}) {
+		t.Errorf("Watch() = %+v, want AAPL and MSFT once and UBS", filter)
+	}
+
+	tests := []struct {
+		ticker, brokerage string
+		want              bool
+	}{
+		{"aapl", "Goldman Sachs", true},
+		{"TSLA", "ubs", true},
+		{"TSLA", "Goldman Sachs", false},
+	}
+	for _, tt := range tests {
+		if got := filter.matches(models.StockChange{Ticker: tt.ticker, Brokerage: tt.brokerage}); got != tt.want {
+			t.Errorf("matches(%s, %s) = %v, want %v", tt.ticker, tt.brokerage, got, tt.want)
+		}
+	}
+
+	filter = filter.Unwatch([]string{"aapl"}, []string{"ubs"})
+	if !slices.Equal(filter.Tickers, []string{"MSFT"}) || len(filter.Brokerages) != 0 {
+		t.Errorf("Unwatch() = %+v, want only MSFT", filter)
+	}
+	if (ChangeFilter{Any: true}).matches(models.StockChange{Ticker: "MSFT"}) {
+		t.Error("an empty watchlist matches a change")
+	}
+
+	tickers := make([]string, maxWatched+1)
+	for i := range tickers {
+		tickers[i] = fmt.Sprintf("T%d", i)
+	}
+	if _, err := (ChangeFilter{Any: true}).Watch(tickers, nil); !errors.Is(err, ErrWatchLimit) {
+		t.Errorf("Watch(%d tickers) error = %v, want ErrWatchLimit", len(tickers), err)
+	}
+}
+
+func TestWatchlistSubscriptionWaitsForSubscribe(t *testing.T) {
+	stream, repo := newTestChangeStream(t)
+	ctx := context.Background()
+	now := time.Now()
+
+	sub, err := stream.Subscribe(ctx, -1, ChangeFilter{Any: true})
+	if err != nil {
+		t.Fatalf("Subscribe() error = %v", err)
+	}
+	defer sub.Close()
+
+	upsert(t, repo,
+		models.Stock{Ticker: "AAA", Company: "A Corp", Brokerage: "X", RatingTo: "Buy", Time: now},
+		models.Stock{Ticker: "BBB", Company: "B Corp", Brokerage: "Y", RatingTo: "Buy", Time: now},
+	)
+	if events, err := sub.Next(ctx); err != nil || len(events) != 0 || sub.Cursor() != 0 {
+		t.Fatalf("Next() = %+v, %v with cursor %d; want nothing read before a subscribe", events, err, sub.Cursor())
+	}
+
+	// Changes stored before the subscribe are still delivered
+	filter, _ := sub.Filter().Watch([]string{"bbb"}, nil)
+	sub.SetFilter(filter)
+	if !ready(sub) {
+		t.Fatal("subscription not woken up by SetFilter")
+	}
+	events, err := sub.Next(ctx)
+	if err != nil || len(events) != 1 || events[0].Change.Ticker != "BBB" {
+		t.Errorf("Next() = %+v, %v; want the BBB change", events, err)
+	}
+}
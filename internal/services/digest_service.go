@@ -0,0 +1,413 @@
+package services
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"log/slog"
+	"net/mail"
+	"slices"
+	"strings"
+	"sync"
+	texttemplate "text/template"
+	"time"
+
+	"Backend/internal/config"
+	"Backend/internal/logger"
+	"Backend/internal/metrics"
+	"Backend/internal/models"
+	"Backend/internal/repository"
+	"Backend/internal/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+var (
+	// ErrDigestNotSubscribed is returned when the user has no digest subscription
+	ErrDigestNotSubscribed = errors.New("digest subscription not found")
+
+	// ErrInvalidDigestSubscription is wrapped by errors for an invalid subscription
+	ErrInvalidDigestSubscription = errors.New("invalid digest subscription")
+)
+
+// Limits for the changes summarized by a digest
+const (
+	// maxDigestChanges bounds the changes read for one digest; older ones are skipped
+	maxDigestChanges = 5000
+	// maxDigestWatchlistChanges bounds the watchlist changes listed in a digest
+	maxDigestWatchlistChanges = 50
+	// digestBatchSize is the number of changes read from the feed at a time
+	digestBatchSize = 500
+	// maxDigestEmail is the longest address accepted, as stored by the database
+	maxDigestEmail = 320
+)
+
+// DigestService builds the email digest of each subscribed user: the day's
+// recommendations, the largest upgrades and downgrades, and the changes on the
+// user's watchlists since the previous digest. Run sends them every day at the
+// configured time.
+type DigestService struct {
+	digests    repository.DigestRepository
+	changes    repository.ChangeRepository
+	watchlists repository.WatchlistRepository
+	stocks     *StockService
+	mailer     Mailer
+	cfg        config.DigestConfig
+	sendAt     time.Duration
+	location   *time.Location
+	now        func() time.Time
+	log        *slog.Logger
+
+	mu sync.Mutex // serializes SendDue
+}
+
+// NewDigestService creates a new instance of DigestService. The configuration must
+// have passed config.Validate.
+func NewDigestService(digests repository.DigestRepository, changes repository.ChangeRepository, watchlists repository.WatchlistRepository, stocks *StockService, mailer Mailer, cfg config.DigestConfig) *DigestService {
+	sendAt, _ := config.ParseTimeOfDay(cfg.SendAt)
+	location, err := time.LoadLocation(cfg.Timezone)
+	if err != nil {
+		location = time.UTC
+	}
+
+	return &DigestService{
+		digests:    digests,
+		changes:    changes,
+		watchlists: watchlists,
+		stocks:     stocks,
+		mailer:     mailer,
+		cfg:        cfg,
+		sendAt:     sendAt,
+		location:   location,
+		// Subscriptions are stored in TIMESTAMP columns, which hold UTC
+		now: func() time.Time { return time.Now().UTC() },
+		log: logger.Component("digest_service"),
+	}
+}
+
+// Subscription returns the user's subscription or ErrDigestNotSubscribed
+func (s *DigestService) Subscription(ctx context.Context, username string) (*models.DigestSubscription, error) {
+	sub, err := s.digests.GetDigestSubscription(ctx, username)
+	if err != nil {
+		return nil, err
+	}
+	if sub == nil {
+		return nil, ErrDigestNotSubscribed
+	}
+	return sub, nil
+}
+
+// Subscribe opts the user in to the digest or updates the subscription. The
+// first digest covers the changes written after the user subscribed.
+func (s *DigestService) Subscribe(ctx context.Context, username string, request models.DigestSubscriptionRequest) (*models.DigestSubscription, error) {
+	address, err := mail.ParseAddress(strings.TrimSpace(request.Email))
+	if err != nil || len(address.Address) > maxDigestEmail {
+		return nil, fmt.Errorf("%w: email must be a valid address", ErrInvalidDigestSubscription)
+	}
+
+	last, err := s.changes.LastChangeID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	sub := &models.DigestSubscription{
+		Username:     username,
+		Email:        address.Address,
+		Enabled:      request.Enabled == nil || *request.Enabled,
+		LastChangeID: last,
+	}
+	if err := s.digests.SaveDigestSubscription(ctx, sub); err != nil {
+		return nil, err
+	}
+	return sub, nil
+}
+
+// Unsubscribe deletes the user's subscription
+func (s *DigestService) Unsubscribe(ctx context.Context, username string) error {
+	found, err := s.digests.DeleteDigestSubscription(ctx, username)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return ErrDigestNotSubscribed
+	}
+	return nil
+}
+
+// Preview builds the digest the user would receive now, without sending it or
+// moving the subscription forward. Without a subscription it covers the newest changes.
+func (s *DigestService) Preview(ctx context.Context, username string) (*models.Digest, Email, error) {
+	sub, err := s.digests.GetDigestSubscription(ctx, username)
+	if err != nil {
+		return nil, Email{}, err
+	}
+	if sub == nil {
+		sub = &models.DigestSubscription{Username: username}
+	}
+
+	digest, err := s.Build(ctx, sub)
+	if err != nil {
+		return nil, Email{}, err
+	}
+	email, err := s.Render(digest)
+	if err != nil {
+		return nil, Email{}, err
+	}
+	email.To = sub.Email
+	return digest, email, nil
+}
+
+// Build collects the digest of a subscription: the current recommendations and
+// the changes written after its LastChangeID, up to maxDigestChanges of the newest
+func (s *DigestService) Build(ctx context.Context, sub *models.DigestSubscription) (*models.Digest, error) {
+	last, err := s.changes.LastChangeID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	recommendations, err := s.stocks.GetRecommendations(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	lists, err := s.watchlists.ListWatchlists(ctx, sub.Username)
+	if err != nil {
+		return nil, err
+	}
+	watched := make(map[string]bool)
+	for _, list := range lists {
+		for _, ticker := range list.Tickers {
+			watched[ticker] = true
+		}
+	}
+
+	digest := &models.Digest{
+		Username:         sub.Username,
+		GeneratedAt:      s.now(),
+		FromChangeID:     max(sub.LastChangeID, last-maxDigestChanges),
+		ToChangeID:       last,
+		Recommendations:  recommendations,
+		Upgrades:         []models.StockChange{},
+		Downgrades:       []models.StockChange{},
+		WatchlistChanges: []models.StockChange{},
+	}
+
+	// Changes written while the digest is built are left for the next one
+	for since := digest.FromChangeID; since < last; {
+		changes, err := s.changes.ListChanges(ctx, since, digestBatchSize)
+		if err != nil {
+			return nil, err
+		}
+		if len(changes) == 0 {
+			break
+		}
+		for _, change := range changes {
+			if change.ID > last {
+				break
+			}
+			switch change.Type {
+			case models.ChangeUpgrade:
+				digest.Upgrades = append(digest.Upgrades, change)
+			case models.ChangeDowngrade:
+				digest.Downgrades = append(digest.Downgrades, change)
+			}
+			if watched[change.Ticker] {
+				digest.WatchlistChanges = append(digest.WatchlistChanges, change)
+			}
+		}
+		since = changes[len(changes)-1].ID
+	}
+
+	digest.Upgrades = topChanges(digest.Upgrades, s.cfg.TopChanges, func(c models.StockChange) float64 { return c.ScoreTo - c.ScoreFrom })
+	digest.Downgrades = topChanges(digest.Downgrades, s.cfg.TopChanges, func(c models.StockChange) float64 { return c.ScoreFrom - c.ScoreTo })
+	slices.Reverse(digest.WatchlistChanges)
+	if len(digest.WatchlistChanges) > maxDigestWatchlistChanges {
+		digest.WatchlistChanges = digest.WatchlistChanges[:maxDigestWatchlistChanges]
+	}
+
+	return digest, nil
+}
+
+// topChanges returns the n changes with the largest size, newest first among equals
+func topChanges(changes []models.StockChange, n int, size func(models.StockChange) float64) []models.StockChange {
+	slices.SortStableFunc(changes, func(a, b models.StockChange) int {
+		return cmp.Or(cmp.Compare(size(b), size(a)), cmp.Compare(b.ID, a.ID))
+	})
+	if len(changes) > n {
+		changes = changes[:n]
+	}
+	return changes
+}
+
+// Render formats a digest as an email with HTML and plaintext versions
+func (s *DigestService) Render(digest *models.Digest) (Email, error) {
+	data := struct {
+		*models.Digest
+		Date string
+	}{digest, digest.GeneratedAt.In(s.location).Format("Monday, January 2, 2006")}
+
+	var text, html bytes.Buffer
+	if err := digestText.Execute(&text, data); err != nil {
+		return Email{}, fmt.Errorf("error rendering digest text: %w", err)
+	}
+	if err := digestHTML.Execute(&html, data); err != nil {
+		return Email{}, fmt.Errorf("error rendering digest HTML: %w", err)
+	}
+
+	return Email{
+		Subject: "Stock digest for " + data.Date,
+		Text:    text.String(),
+		HTML:    html.String(),
+	}, nil
+}
+
+// Run sends the digests that are due every CheckInterval until ctx is cancelled
+func (s *DigestService) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.cfg.CheckInterval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := s.SendDue(ctx); err != nil {
+			s.log.Warn("error sending digests", "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// SendDue sends a digest to every enabled subscription that has not received
+// one since the latest scheduled time and returns the number sent. A digest with
+// nothing to tell is skipped but counts as sent. Failed sends are retried on the
+// next call.
+func (s *DigestService) SendDue(ctx context.Context) (sent int, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ctx, span := tracing.Start(ctx, "DigestService.SendDue")
+	defer func() {
+		span.SetAttributes(attribute.Int("digests.sent", sent))
+		tracing.End(span, err)
+	}()
+
+	subs, err := s.digests.DueDigestSubscriptions(ctx, s.scheduledBefore(s.now()))
+	if err != nil {
+		return 0, err
+	}
+
+	var errs []error
+	for _, sub := range subs {
+		delivered, err := s.send(ctx, &sub)
+		if err != nil {
+			metrics.DigestsSent.WithLabelValues("error").Inc()
+			errs = append(errs, fmt.Errorf("digest for %s: %w", sub.Username, err))
+			continue
+		}
+		if delivered {
+			sent++
+			metrics.DigestsSent.WithLabelValues("sent").Inc()
+		} else {
+			metrics.DigestsSent.WithLabelValues("empty").Inc()
+		}
+	}
+
+	if len(subs) > 0 {
+		s.log.Info("digests sent", "due", len(subs), "sent", sent, "failed", len(errs))
+	}
+	return sent, errors.Join(errs...)
+}
+
+// send builds and mails the digest of a subscription, then records it as sent.
+// It reports whether an email went out.
+func (s *DigestService) send(ctx context.Context, sub *models.DigestSubscription) (bool, error) {
+	digest, err := s.Build(ctx, sub)
+	if err != nil {
+		return false, err
+	}
+
+	empty := len(digest.Recommendations) == 0 && len(digest.Upgrades) == 0 &&
+		len(digest.Downgrades) == 0 && len(digest.WatchlistChanges) == 0
+	if !empty {
+		email, err := s.Render(digest)
+		if err != nil {
+			return false, err
+		}
+		email.To = sub.Email
+		if err := s.mailer.Send(ctx, email); err != nil {
+			return false, err
+		}
+	}
+
+	// Saved even when the context is gone, so a sent digest is not sent again
+	return !empty, s.digests.MarkDigestSent(context.WithoutCancel(ctx), sub.Username, digest.ToChangeID, digest.GeneratedAt)
+}
+
+// scheduledBefore returns the latest scheduled send time at or before now
+func (s *DigestService) scheduledBefore(now time.Time) time.Time {
+	local := now.In(s.location)
+	scheduled := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, s.location).Add(s.sendAt)
+	if scheduled.After(local) {
+		day := local.AddDate(0, 0, -1)
+		scheduled = time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, s.location).Add(s.sendAt)
+	}
+	return scheduled.UTC()
+}
+
+var digestFuncs = map[string]any{
+	"score": func(v float64) string { return fmt.Sprintf("%.0f", v) },
+	"delta": func(from, to float64) string { return fmt.Sprintf("%+.0f", to-from) },
+}
+
+var digestText = texttemplate.Must(texttemplate.New("digest").Funcs(digestFuncs).Parse(`Stock digest for {{.Date}}
+
+RECOMMENDATIONS
+{{range .Recommendations}}- {{.Ticker}} ({{.Company}}): {{.RatingTo}} by {{.Brokerage}}, target {{.TargetTo}}, score {{score .Score}}
+{{else}}No recommendations today.
+{{end}}
+TOP UPGRADES
+{{range .Upgrades}}- {{.Ticker}}: {{.Brokerage}} upgraded from {{.RatingFrom}} to {{.RatingTo}} (score {{delta .ScoreFrom .ScoreTo}})
+{{else}}No upgrades since the previous digest.
+{{end}}
+TOP DOWNGRADES
+{{range .Downgrades}}- {{.Ticker}}: {{.Brokerage}} downgraded from {{.RatingFrom}} to {{.RatingTo}} (score {{delta .ScoreFrom .ScoreTo}})
+{{else}}No downgrades since the previous digest.
+{{end}}
+YOUR WATCHLISTS
+{{range .WatchlistChanges}}- {{.Ticker}}: {{.Type}} by {{.Brokerage}}, {{.RatingFrom}} -> {{.RatingTo}}, target {{.TargetFrom}} -> {{.TargetTo}}
+{{else}}No changes on your watchlists since the previous digest.
+{{end}}`))
+
+var digestHTML = htmltemplate.Must(htmltemplate.New("digest").Funcs(digestFuncs).Parse(`<!DOCTYPE html>
+<html>
+<body style="font-family: Arial, sans-serif; color: #222;">
+<h1 style="font-size: 20px;">Stock digest for {{.Date}}</h1>
+
+<h2 style="font-size: 16px;">Recommendations</h2>
+{{if .Recommendations}}<table cellpadding="4" style="border-collapse: collapse;">
+<tr><th align="left">Ticker</th><th align="left">Company</th><th align="left">Rating</th><th align="left">Brokerage</th><th align="left">Target</th><th align="right">Score</th></tr>
+{{range .Recommendations}}<tr><td><b>{{.Ticker}}</b></td><td>{{.Company}}</td><td>{{.RatingTo}}</td><td>{{.Brokerage}}</td><td>{{.TargetTo}}</td><td align="right">{{score .Score}}</td></tr>
+{{end}}</table>{{else}}<p>No recommendations today.</p>{{end}}
+
+<h2 style="font-size: 16px;">Top upgrades</h2>
+{{if .Upgrades}}<ul>
+{{range .Upgrades}}<li><b>{{.Ticker}}</b>: {{.Brokerage}} upgraded from {{.RatingFrom}} to {{.RatingTo}} (score {{delta .ScoreFrom .ScoreTo}})</li>
+{{end}}</ul>{{else}}<p>No upgrades since the previous digest.</p>{{end}}
+
+<h2 style="font-size: 16px;">Top downgrades</h2>
+{{if .Downgrades}}<ul>
+{{range .Downgrades}}<li><b>{{.Ticker}}</b>: {{.Brokerage}} downgraded from {{.RatingFrom}} to {{.RatingTo}} (score {{delta .ScoreFrom .ScoreTo}})</li>
+{{end}}</ul>{{else}}<p>No downgrades since the previous digest.</p>{{end}}
+
+<h2 style="font-size: 16px;">Your watchlists</h2>
+{{if .WatchlistChanges}}<ul>
+{{range .WatchlistChanges}}<li><b>{{.Ticker}}</b>: {{.Type}} by {{.Brokerage}}, {{.RatingFrom}} &rarr; {{.RatingTo}}, target {{.TargetFrom}} &rarr; {{.TargetTo}}</li>
+{{end}}</ul>{{else}}<p>No changes on your watchlists since the previous digest.</p>{{end}}
+</body>
+</html>
+`))
@@ -0,0 +1,196 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/mail"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"Backend/internal/config"
+	"Backend/internal/models"
+	"Backend/internal/repository"
+)
+
+// fakeMailer records the emails sent, failing while err is set
+type fakeMailer struct {
+	mu     sync.Mutex
+	err    error
+	emails []Email
+}
+
+func (m *fakeMailer) Send(ctx context.Context, email Email) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.err != nil {
+		return m.err
+	}
+	m.emails = append(m.emails, email)
+	return nil
+}
+
+func (m *fakeMailer) sent() []Email {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	emails := m.emails
+	m.emails = nil
+	return emails
+}
+
+func TestScheduledBefore(t *testing.T) {
+	service := NewDigestService(nil, nil, nil, nil, nil, config.DigestConfig{SendAt: "07:30", Timezone: "UTC"})
+	service.location = time.FixedZone("UTC-5", -5*60*60)
+
+	tests := []struct {
+		now  time.Time
+		want time.Time
+	}{
+		{time.Date(2026, 3, 2, 13, 0, 0, 0, time.UTC), time.Date(2026, 3, 2, 12, 30, 0, 0, time.UTC)},
+		{time.Date(2026, 3, 2, 12, 30, 0, 0, time.UTC), time.Date(2026, 3, 2, 12, 30, 0, 0, time.UTC)},
+		{time.Date(2026, 3, 2, 12, 29, 0, 0, time.UTC), time.Date(2026, 3, 1, 12, 30, 0, 0, time.UTC)},
+		// Still the previous day in UTC-5
+		{time.Date(2026, 3, 3, 2, 0, 0, 0, time.UTC), time.Date(2026, 3, 2, 12, 30, 0, 0, time.UTC)},
+	}
+
+	for _, tt := range tests {
+		if got := service.scheduledBefore(tt.now); !got.Equal(tt.want) {
+			t.Errorf("scheduledBefore(%v) = %v, want %v", tt.now, got, tt.want)
+		}
+	}
+}
+
+func TestDigest(t *testing.T) {
+	day := time.Date(2026, 3, 2, 6, 0, 0, 0, time.UTC)
+	repo := repository.NewMemoryRepository()
+	repo.Now = func() time.Time { return day }
+	stocks := NewStockService(repo, repo, repo, config.ScoringConfig{RecommendationLimit: 1})
+	mailer := &fakeMailer{}
+	service := NewDigestService(repo, repo, repo, stocks, mailer, config.DigestConfig{SendAt: "07:00", Timezone: "UTC", TopChanges: 1})
+	now := day.Add(2 * time.Hour)
+	service.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	stock := func(ticker, company, rating string, score float64) models.Stock {
+		return models.Stock{Ticker: ticker, Company: company, Brokerage: "UBS", RatingTo: rating, TargetTo: "$100.00", Score: score, Confidence: score / 100, Time: day}
+	}
+	upsert(t, repo, stock("T", "AT&T", "Buy", 90), stock("NVDA", "Nvidia", "Hold", 40), stock("MSFT", "Microsoft", "Hold", 50), stock("TSLA", "Tesla", "Buy", 60))
+
+	if _, err := service.Subscribe(ctx, "dashboard", models.DigestSubscriptionRequest{Email: "not an address"}); !errors.Is(err, ErrInvalidDigestSubscription) {
+		t.Errorf("Subscribe() with an invalid email error = %v, want ErrInvalidDigestSubscription", err)
+	}
+	sub, err := service.Subscribe(ctx, "dashboard", models.DigestSubscriptionRequest{Email: "Manager <manager@example.com>"})
+	if err != nil || sub.Email != "manager@example.com" || !sub.Enabled {
+		t.Fatalf("Subscribe() = %+v, %v; want an enabled subscription for manager@example.com", sub, err)
+	}
+	if err := repo.CreateWatchlist(ctx, &models.Watchlist{Username: "dashboard", Name: "AI", Tickers: []string{"NVDA"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Only the changes after the subscription are summarized
+	later := func(s models.Stock) models.Stock { s.Time = day.Add(time.Minute); return s }
+	upsert(t, repo, later(stock("NVDA", "Nvidia", "Buy", 70)), later(stock("MSFT", "Microsoft", "Buy", 55)), later(stock("TSLA", "Tesla", "Sell", 20)))
+
+	digest, email, err := service.Preview(ctx, "dashboard")
+	if err != nil {
+		t.Fatalf("Preview() error = %v", err)
+	}
+	if len(digest.Recommendations) != 1 || digest.Recommendations[0].Ticker != "T" {
+		t.Errorf("recommendations = %+v, want T", digest.Recommendations)
+	}
+	if len(digest.Upgrades) != 1 || digest.Upgrades[0].Ticker != "NVDA" {
+		t.Errorf("upgrades = %+v, want only the largest, NVDA", digest.Upgrades)
+	}
+	if len(digest.Downgrades) != 1 || digest.Downgrades[0].Ticker != "TSLA" {
+		t.Errorf("downgrades = %+v, want TSLA", digest.Downgrades)
+	}
+	if len(digest.WatchlistChanges) != 1 || digest.WatchlistChanges[0].Ticker != "NVDA" {
+		t.Errorf("watchlist changes = %+v, want NVDA", digest.WatchlistChanges)
+	}
+	if !strings.Contains(email.Subject, "Monday, March 2, 2026") || !strings.Contains(email.HTML, "AT&amp;T") ||
+		!strings.Contains(email.Text, "NVDA: UBS upgraded from Hold to Buy (score +30)") {
+		t.Errorf("Preview() email = %+v", email)
+	}
+
+	// The preview moved nothing, so the scheduled digest has the same content
+	if sent, err := service.SendDue(ctx); sent != 1 || err != nil {
+		t.Fatalf("SendDue() = %d, %v; want one digest", sent, err)
+	}
+	emails := mailer.sent()
+	if len(emails) != 1 || emails[0].To != "manager@example.com" || emails[0].Text != email.Text {
+		t.Fatalf("sent %+v, want the previewed digest", emails)
+	}
+	if sent, err := service.SendDue(ctx); sent != 0 || err != nil {
+		t.Errorf("SendDue() again = %d, %v; want nothing", sent, err)
+	}
+	if digest, _, err := service.Preview(ctx, "dashboard"); err != nil || len(digest.Upgrades) != 0 || len(digest.WatchlistChanges) != 0 {
+		t.Errorf("Preview() after sending = %+v, %v; want no changes", digest, err)
+	}
+
+	// A failed send is retried on the next check
+	now = now.Add(24 * time.Hour)
+	mailer.err = errors.New("mail server down")
+	if sent, err := service.SendDue(ctx); sent != 0 || err == nil {
+		t.Errorf("SendDue() with the mail server down = %d, %v; want an error", sent, err)
+	}
+	mailer.err = nil
+	if sent, err := service.SendDue(ctx); sent != 1 || err != nil {
+		t.Errorf("SendDue() retry = %d, %v; want one digest", sent, err)
+	}
+
+	disabled := false
+	if _, err := service.Subscribe(ctx, "dashboard", models.DigestSubscriptionRequest{Email: "manager@example.com", Enabled: &disabled}); err != nil {
+		t.Fatal(err)
+	}
+	now = now.Add(24 * time.Hour)
+	if sent, err := service.SendDue(ctx); sent != 0 || err != nil {
+		t.Errorf("SendDue() with the digest disabled = %d, %v; want nothing", sent, err)
+	}
+
+	if err := service.Unsubscribe(ctx, "dashboard"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := service.Subscription(ctx, "dashboard"); !errors.Is(err, ErrDigestNotSubscribed) {
+		t.Errorf("Subscription() after unsubscribing error = %v, want ErrDigestNotSubscribed", err)
+	}
+}
+
+func TestEmailMessage(t *testing.T) {
+	email := Email{Subject: "Stock digest für heute", Text: "NVDA upgraded", HTML: "<p>NVDA upgraded</p>"}
+	from := &mail.Address{Name: "Stock Analyzer", Address: "digest@example.com"}
+	raw, err := email.message(from, &mail.Address{Address: "manager@example.com"}, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msg, err := mail.ReadMessage(strings.NewReader(string(raw)))
+	if err != nil {
+		t.Fatalf("ReadMessage() error = %v", err)
+	}
+	if subject, err := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject")); err != nil || subject != email.Subject {
+		t.Errorf("Subject = %q, %v; want %q", subject, err, email.Subject)
+	}
+	if !strings.HasSuffix(msg.Header.Get("Message-ID"), "@example.com>") {
+		t.Errorf("Message-ID = %q", msg.Header.Get("Message-ID"))
+	}
+
+	mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/alternative" {
+		t.Fatalf("Content-Type = %q, %v", mediaType, err)
+	}
+	parts := multipart.NewReader(msg.Body, params["boundary"])
+	for _, want := range []string{email.Text, email.HTML} {
+		part, err := parts.NextPart()
+		if err != nil {
+			t.Fatalf("NextPart() error = %v", err)
+		}
+		// The reader decodes quoted-printable parts
+		if body, _ := io.ReadAll(part); string(body) != want {
+			t.Errorf("part = %q, want %q", body, want)
+		}
+	}
+}
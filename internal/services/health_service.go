@@ -0,0 +1,227 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"Backend/internal/database"
+	"Backend/internal/logger"
+	"Backend/internal/models"
+)
+
+// healthCheckTimeout bounds every component check
+const healthCheckTimeout = 3 * time.Second
+
+// HealthService checks the components the API needs to serve traffic. The report
+// is public, so failed checks carry a generic message and the error is only logged.
+type HealthService struct {
+	db                 *sql.DB
+	replica            *sql.DB
+	stockService       *StockService
+	stalenessThreshold time.Duration
+	log                *slog.Logger
+}
+
+// NewHealthService creates a new instance of HealthService. replica may be nil
+// when no read replica is configured.
+func NewHealthService(db, replica *sql.DB, stockService *StockService, stalenessThreshold time.Duration) *HealthService {
+	return &HealthService{
+		db:                 db,
+		replica:            replica,
+		stockService:       stockService,
+		stalenessThreshold: stalenessThreshold,
+		log:                logger.Component("health_service"),
+	}
+}
+
+// Readiness checks the database, the schema version, data freshness and the last sync run.
+// The overall status is the worst component status.
+func (h *HealthService) Readiness(ctx context.Context) models.HealthReport {
+	report := models.HealthReport{
+		Status:     models.HealthStatusUp,
+		Timestamp:  time.Now().UTC(),
+		Components: make(map[string]models.ComponentHealth),
+	}
+
+	dbHealth := h.check(ctx, h.checkDatabase)
+	report.Components["database"] = dbHealth
+	if h.replica != nil {
+		// Stock reads are served by the replica
+		report.Components["replica"] = h.check(ctx, h.checkReplica)
+	}
+
+	if dbHealth.Status == models.HealthStatusDown {
+		// Every other check needs the database
+		unavailable := models.ComponentHealth{Status: models.HealthStatusDown, Message: "database unavailable"}
+		report.Components["migrations"] = unavailable
+		report.Components["data_freshness"] = unavailable
+		report.Components["sync"] = unavailable
+	} else {
+		report.Components["migrations"] = h.check(ctx, h.checkMigrations)
+		report.Components["data_freshness"] = h.check(ctx, h.checkFreshness)
+		report.Components["sync"] = h.check(ctx, h.checkLastSync)
+	}
+
+	for _, component := range report.Components {
+		report.Status = worstStatus(report.Status, component.Status)
+	}
+
+	return report
+}
+
+// check runs a component check with a timeout and records its latency
+func (h *HealthService) check(ctx context.Context, fn func(context.Context) models.ComponentHealth) models.ComponentHealth {
+	ctx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+	defer cancel()
+
+	start := time.Now()
+	result := fn(ctx)
+	result.LatencyMs = time.Since(start).Milliseconds()
+	return result
+}
+
+func (h *HealthService) checkDatabase(ctx context.Context) models.ComponentHealth {
+	return h.pingPool(ctx, h.db, "database")
+}
+
+func (h *HealthService) checkReplica(ctx context.Context) models.ComponentHealth {
+	result := h.pingPool(ctx, h.replica, "replica")
+	if result.Status != models.HealthStatusUp {
+		return result
+	}
+
+	// Replication lag; NULL when the server is not in recovery
+	var lag sql.NullFloat64
+	err := h.replica.QueryRowContext(ctx, `SELECT EXTRACT(EPOCH FROM NOW() - pg_last_xact_replay_timestamp())`).Scan(&lag)
+	if err == nil && lag.Valid {
+		result.Details["lag_seconds"] = lag.Float64
+	}
+	return result
+}
+
+// down logs a failed check and reports the component down with a generic message
+func (h *HealthService) down(component, message string, err error) models.ComponentHealth {
+	h.log.Warn("health check failed", "component", component, "error", err)
+	return models.ComponentHealth{Status: models.HealthStatusDown, Message: message}
+}
+
+// pingPool pings a connection pool and reports its statistics
+func (h *HealthService) pingPool(ctx context.Context, db *sql.DB, component string) models.ComponentHealth {
+	if err := db.PingContext(ctx); err != nil {
+		return h.down(component, component+" unreachable", err)
+	}
+
+	stats := db.Stats()
+	return models.ComponentHealth{
+		Status: models.HealthStatusUp,
+		Details: map[string]any{
+			"open_connections": stats.OpenConnections,
+			"in_use":           stats.InUse,
+			"idle":             stats.Idle,
+		},
+	}
+}
+
+func (h *HealthService) checkMigrations(ctx context.Context) models.ComponentHealth {
+	version, err := database.CurrentSchemaVersion(ctx, h.db)
+	if err != nil {
+		return h.down("migrations", "error reading the schema version", err)
+	}
+
+	details := map[string]any{
+		"current":  version,
+		"expected": database.SchemaVersion,
+	}
+
+	if version < database.SchemaVersion {
+		return models.ComponentHealth{
+			Status:  models.HealthStatusDown,
+			Message: "pending migrations",
+			Details: details,
+		}
+	}
+
+	return models.ComponentHealth{Status: models.HealthStatusUp, Details: details}
+}
+
+func (h *HealthService) checkFreshness(ctx context.Context) models.ComponentHealth {
+	var lastUpdate sql.NullTime
+	if err := h.db.QueryRowContext(ctx, `SELECT MAX(updated_at) FROM stocks`).Scan(&lastUpdate); err != nil {
+		return h.down("data_freshness", "error reading the last stock update", err)
+	}
+
+	if !lastUpdate.Valid {
+		return models.ComponentHealth{
+			Status:  models.HealthStatusDegraded,
+			Message: "no stock data yet",
+			Details: map[string]any{"threshold": h.stalenessThreshold.String()},
+		}
+	}
+
+	age := time.Since(lastUpdate.Time)
+	details := map[string]any{
+		"last_update": lastUpdate.Time,
+		"age_seconds": int64(age.Seconds()),
+		"threshold":   h.stalenessThreshold.String(),
+	}
+
+	if age > h.stalenessThreshold {
+		return models.ComponentHealth{
+			Status:  models.HealthStatusDegraded,
+			Message: fmt.Sprintf("data is older than %s", h.stalenessThreshold),
+			Details: details,
+		}
+	}
+
+	return models.ComponentHealth{Status: models.HealthStatusUp, Details: details}
+}
+
+func (h *HealthService) checkLastSync(ctx context.Context) models.ComponentHealth {
+	run, err := h.stockService.LastSyncRun(ctx)
+	if err != nil {
+		return h.down("sync", "error reading the last sync run", err)
+	}
+
+	if run == nil {
+		return models.ComponentHealth{Status: models.HealthStatusDegraded, Message: "no sync run recorded yet"}
+	}
+
+	details := map[string]any{
+		"id":          run.ID,
+		"status":      run.Status,
+		"started_at":  run.StartedAt,
+		"stocks":      run.Stocks,
+		"rejected":    run.Rejected,
+		"quarantined": run.Quarantined,
+		"duplicates":  run.Duplicates,
+	}
+	if run.FinishedAt != nil {
+		details["finished_at"] = *run.FinishedAt
+	}
+
+	switch {
+	case run.Status == models.SyncStatusFailed:
+		return models.ComponentHealth{Status: models.HealthStatusDegraded, Message: "last sync failed: " + run.Error, Details: details}
+	case run.Status == models.SyncStatusRunning && time.Since(run.StartedAt) > h.stalenessThreshold:
+		return models.ComponentHealth{Status: models.HealthStatusDegraded, Message: "sync has been running longer than the staleness threshold", Details: details}
+	}
+
+	return models.ComponentHealth{Status: models.HealthStatusUp, Details: details}
+}
+
+// worstStatus returns the more severe of two health statuses
+func worstStatus(a, b string) string {
+	rank := map[string]int{
+		models.HealthStatusUp:       0,
+		models.HealthStatusDegraded: 1,
+		models.HealthStatusDown:     2,
+	}
+
+	if rank[b] > rank[a] {
+		return b
+	}
+	return a
+}
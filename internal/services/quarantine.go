@@ -0,0 +1,126 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"Backend/internal/models"
+)
+
+var (
+	// ErrQuarantineNotFound is returned for an unknown quarantined event ID
+	ErrQuarantineNotFound = errors.New("quarantined event not found")
+
+	// ErrQuarantineResolved is returned when replaying an event that is no longer pending
+	ErrQuarantineResolved = errors.New("quarantined event already resolved")
+)
+
+// validateRecords converts the valid upstream records into stocks and turns the
+// rest into quarantined events
+func (s *StockService) validateRecords(records []APIStock, source string) ([]models.Stock, []models.QuarantinedEvent) {
+	now := time.Now()
+	stocks := make([]models.Stock, 0, len(records))
+	var quarantined []models.QuarantinedEvent
+
+	for _, record := range records {
+		problems := apiStockProblems(record, now)
+		if len(problems) == 0 {
+			stocks = append(stocks, record.toStock(now))
+			continue
+		}
+
+		payload, err := json.Marshal(record)
+		if err != nil {
+			s.log.Warn("dropping record that cannot be quarantined", "ticker", record.Ticker, "error", err)
+			continue
+		}
+		quarantined = append(quarantined, models.QuarantinedEvent{
+			Source:  source,
+			Ticker:  record.Ticker,
+			Company: record.Company,
+			Payload: payload,
+			Reason:  strings.Join(problems, "; "),
+		})
+	}
+
+	return stocks, quarantined
+}
+
+// ListQuarantined returns the upstream records that failed validation
+func (s *StockService) ListQuarantined(ctx context.Context, filters models.QuarantineFilters) ([]models.QuarantinedEvent, error) {
+	return s.quarantine.ListQuarantined(ctx, filters)
+}
+
+// GetQuarantined returns a quarantined event or ErrQuarantineNotFound
+func (s *StockService) GetQuarantined(ctx context.Context, id int64) (*models.QuarantinedEvent, error) {
+	event, err := s.quarantine.GetQuarantined(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if event == nil {
+		return nil, ErrQuarantineNotFound
+	}
+	return event, nil
+}
+
+// ReplayQuarantined validates a pending quarantined event again and stores it.
+// When correction is not nil it replaces the quarantined payload, so an operator
+// can fix a record the provider keeps sending malformed. Errors wrap
+// ErrQuarantineNotFound, ErrQuarantineResolved or ErrInvalidRecord.
+func (s *StockService) ReplayQuarantined(ctx context.Context, id int64, correction *APIStock) (*models.QuarantinedEvent, error) {
+	// The event is claimed before it is stored, so concurrent replays store it once
+	event, err := s.quarantine.ClaimQuarantined(ctx, id, models.QuarantineStatusReplayed)
+	if err != nil {
+		return nil, err
+	}
+	if event == nil {
+		current, err := s.GetQuarantined(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("%w: status is %s", ErrQuarantineResolved, current.Status)
+	}
+
+	record, err := s.replay(ctx, event, correction)
+	if err != nil {
+		// A record that is still invalid or could not be stored stays pending
+		if err := s.quarantine.ReopenQuarantined(context.WithoutCancel(ctx), id); err != nil {
+			s.log.Error("error reopening quarantined event", "id", id, "error", err)
+		}
+		return nil, err
+	}
+	s.log.Info("quarantined event replayed", "id", id, "ticker", record.Ticker, "corrected", correction != nil)
+
+	return event, nil
+}
+
+// replay validates and stores a claimed quarantined event, or its correction
+func (s *StockService) replay(ctx context.Context, event *models.QuarantinedEvent, correction *APIStock) (APIStock, error) {
+	var record APIStock
+	if correction != nil {
+		record = *correction
+	} else if err := json.Unmarshal(event.Payload, &record); err != nil {
+		return record, fmt.Errorf("%w: payload cannot be decoded: %v", ErrInvalidRecord, err)
+	}
+
+	now := time.Now()
+	if err := ValidateAPIStock(record, now); err != nil {
+		return record, err
+	}
+
+	stocks := []models.Stock{record.toStock(now)}
+	scoreStocks(stocks)
+	report, err := s.stocks.UpsertStocks(ctx, stocks)
+	if err != nil {
+		return record, fmt.Errorf("error storing replayed event: %w", err)
+	}
+	if len(report.Rejected) > 0 {
+		return record, fmt.Errorf("%w: %s", ErrInvalidRecord, report.Rejected[0].Reason)
+	}
+
+	return record, nil
+}
@@ -0,0 +1,364 @@
+package services
+
+import (
+	"archive/zip"
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"time"
+
+	"Backend/internal/models"
+	"Backend/internal/tracing"
+
+	"github.com/parquet-go/parquet-go"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// ErrInvalidExport is returned for an unknown export format or column
+var ErrInvalidExport = errors.New("invalid export")
+
+const (
+	// exportRowGroupSize bounds the rows a parquet export buffers before writing them out
+	exportRowGroupSize = 10000
+	// maxXLSXRows is the number of rows in an Excel worksheet, header included
+	maxXLSXRows = 1 << 20
+)
+
+// exportKind is the type of the values of an export column
+type exportKind int
+
+const (
+	exportString exportKind = iota
+	exportInt
+	exportFloat
+	exportTime
+)
+
+// exportColumn is a stock field that can be exported. value returns a string,
+// int64, float64 or time.Time according to kind.
+type exportColumn struct {
+	name  string
+	kind  exportKind
+	value func(models.Stock) any
+}
+
+// exportColumns lists every exportable column in its default order
+var exportColumns = []exportColumn{
+	{"id", exportInt, func(s models.Stock) any { return int64(s.ID) }},
+	{"ticker", exportString, func(s models.Stock) any { return s.Ticker }},
+	{"company", exportString, func(s models.Stock) any { return s.Company }},
+	{"brokerage", exportString, func(s models.Stock) any { return s.Brokerage }},
+	{"action", exportString, func(s models.Stock) any { return s.Action }},
+	{"rating_from", exportString, func(s models.Stock) any { return s.RatingFrom }},
+	{"rating_to", exportString, func(s models.Stock) any { return s.RatingTo }},
+	{"target_from", exportString, func(s models.Stock) any { return s.TargetFrom }},
+	{"target_to", exportString, func(s models.Stock) any { return s.TargetTo }},
+	{"score", exportFloat, func(s models.Stock) any { return s.Score }},
+	{"confidence", exportFloat, func(s models.Stock) any { return s.Confidence }},
+	{"source", exportString, func(s models.Stock) any { return s.Source }},
+	{"time", exportTime, func(s models.Stock) any { return s.Time }},
+	{"created_at", exportTime, func(s models.Stock) any { return s.CreatedAt }},
+	{"updated_at", exportTime, func(s models.Stock) any { return s.UpdatedAt }},
+}
+
+// exportFormat is a file format stocks can be exported to
+type exportFormat struct {
+	contentType string
+	newEncoder  func(w io.Writer, columns []exportColumn) (stockEncoder, error)
+}
+
+var exportFormats = map[string]exportFormat{
+	"csv":     {"text/csv; charset=utf-8", newCSVEncoder},
+	"xlsx":    {"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet", newXLSXEncoder},
+	"parquet": {"application/vnd.apache.parquet", newParquetEncoder},
+}
+
+// stockEncoder writes stocks to a file one at a time
+type stockEncoder interface {
+	Encode(stock models.Stock) error
+	// Close writes whatever the format needs after the last row
+	Close() error
+}
+
+// StockExport is a validated request to export the stocks matching Filters
+type StockExport struct {
+	Format  string
+	Filters models.StockFilters
+	columns []exportColumn
+}
+
+// NewStockExport validates an export. format defaults to csv and columns to
+// every column; the columns appear in the order given.
+func NewStockExport(format string, columns []string, filters models.StockFilters) (*StockExport, error) {
+	if format == "" {
+		format = "csv"
+	}
+	if _, ok := exportFormats[format]; !ok {
+		return nil, fmt.Errorf("%w: format must be csv, xlsx or parquet", ErrInvalidExport)
+	}
+
+	export := &StockExport{Format: format, Filters: filters}
+	if len(columns) == 0 {
+		export.columns = exportColumns
+		return export, nil
+	}
+	for _, name := range columns {
+		i := slices.IndexFunc(exportColumns, func(c exportColumn) bool { return c.name == name })
+		if i < 0 {
+			return nil, fmt.Errorf("%w: unknown column %q", ErrInvalidExport, name)
+		}
+		if slices.ContainsFunc(export.columns, func(c exportColumn) bool { return c.name == name }) {
+			return nil, fmt.Errorf("%w: duplicate column %q", ErrInvalidExport, name)
+		}
+		export.columns = append(export.columns, exportColumns[i])
+	}
+	return export, nil
+}
+
+// ContentType returns the media type of the exported file
+func (e *StockExport) ContentType() string {
+	return exportFormats[e.Format].contentType
+}
+
+// Filename returns the name of the exported file, stamped with now in UTC
+func (e *StockExport) Filename(now time.Time) string {
+	return "stocks-" + now.UTC().Format("20060102-150405") + "." + e.Format
+}
+
+// Export writes the stocks matching the export's filters to w, one row at a
+// time. Nothing is written before the first row arrives, so an error from the
+// query leaves w untouched; an error afterwards leaves a truncated file.
+func (s *StockService) Export(ctx context.Context, w io.Writer, export *StockExport) (err error) {
+	ctx, span := tracing.Start(ctx, "StockService.Export", attribute.String("format", export.Format))
+	defer func() { tracing.End(span, err) }()
+
+	format := exportFormats[export.Format]
+	var enc stockEncoder
+	rows := 0
+	err = s.stocks.EachStock(ctx, export.Filters, func(stock models.Stock) error {
+		if enc == nil {
+			var err error
+			if enc, err = format.newEncoder(w, export.columns); err != nil {
+				return err
+			}
+		}
+		rows++
+		return enc.Encode(stock)
+	})
+	if err != nil {
+		return err
+	}
+	span.SetAttributes(attribute.Int("rows", rows))
+
+	if enc == nil {
+		if enc, err = format.newEncoder(w, export.columns); err != nil {
+			return err
+		}
+	}
+	return enc.Close()
+}
+
+// csvEncoder writes a header row and then one record per stock
+type csvEncoder struct {
+	w       *csv.Writer
+	columns []exportColumn
+	record  []string
+}
+
+func newCSVEncoder(w io.Writer, columns []exportColumn) (stockEncoder, error) {
+	enc := &csvEncoder{w: csv.NewWriter(w), columns: columns, record: make([]string, len(columns))}
+	for i, column := range columns {
+		enc.record[i] = column.name
+	}
+	if err := enc.w.Write(enc.record); err != nil {
+		return nil, err
+	}
+	return enc, nil
+}
+
+func (e *csvEncoder) Encode(stock models.Stock) error {
+	for i, column := range e.columns {
+		switch v := column.value(stock).(type) {
+		case string:
+			e.record[i] = v
+		case int64:
+			e.record[i] = strconv.FormatInt(v, 10)
+		case float64:
+			e.record[i] = strconv.FormatFloat(v, 'f', -1, 64)
+		case time.Time:
+			e.record[i] = v.UTC().Format(time.RFC3339)
+		}
+	}
+	return e.w.Write(e.record)
+}
+
+func (e *csvEncoder) Close() error {
+	e.w.Flush()
+	return e.w.Error()
+}
+
+// xlsxEncoder streams a single-sheet workbook. The fixed parts of the package
+// are written up front and the worksheet last, so rows go straight into the
+// zip stream instead of being held until the end like spreadsheet libraries do.
+type xlsxEncoder struct {
+	zip     *zip.Writer
+	sheet   *bufio.Writer
+	columns []exportColumn
+	rows    int
+}
+
+// xlsxParts are the parts of the workbook other than the worksheet. Style 1
+// formats dates.
+var xlsxParts = []struct{ name, content string }{
+	{"[Content_Types].xml", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types"><Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/><Default Extension="xml" ContentType="application/xml"/><Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/><Override PartName="/xl/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"/><Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/></Types>`},
+	{"_rels/.rels", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/></Relationships>`},
+	{"xl/workbook.xml", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets><sheet name="Stocks" sheetId="1" r:id="rId1"/></sheets></workbook>`},
+	{"xl/_rels/workbook.xml.rels", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/><Relationship Id="rId2" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/></Relationships>`},
+	{"xl/styles.xml", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><fonts count="1"><font><sz val="11"/><name val="Calibri"/></font></fonts><fills count="2"><fill><patternFill patternType="none"/></fill><fill><patternFill patternType="gray125"/></fill></fills><borders count="1"><border><left/><right/><top/><bottom/><diagonal/></border></borders><cellStyleXfs count="1"><xf numFmtId="0" fontId="0" fillId="0" borderId="0"/></cellStyleXfs><cellXfs count="2"><xf numFmtId="0" fontId="0" fillId="0" borderId="0" xfId="0"/><xf numFmtId="22" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1"/></cellXfs></styleSheet>`},
+}
+
+// excelEpoch is day zero of Excel's date serial numbers
+var excelEpoch = time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC)
+
+func newXLSXEncoder(w io.Writer, columns []exportColumn) (stockEncoder, error) {
+	zw := zip.NewWriter(w)
+	for _, part := range xlsxParts {
+		f, err := zw.Create(part.name)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := io.WriteString(f, part.content); err != nil {
+			return nil, err
+		}
+	}
+	f, err := zw.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return nil, err
+	}
+
+	enc := &xlsxEncoder{zip: zw, sheet: bufio.NewWriter(f), columns: columns}
+	enc.sheet.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData><row>`)
+	for _, column := range columns {
+		enc.inlineString(column.name)
+	}
+	enc.sheet.WriteString("</row>")
+	enc.rows = 1
+	return enc, nil
+}
+
+func (e *xlsxEncoder) Encode(stock models.Stock) error {
+	if e.rows == maxXLSXRows {
+		return fmt.Errorf("%w: xlsx is limited to %d rows, narrow the filters or use csv", ErrInvalidExport, maxXLSXRows-1)
+	}
+	e.rows++
+
+	e.sheet.WriteString("<row>")
+	for _, column := range e.columns {
+		switch v := column.value(stock).(type) {
+		case string:
+			e.inlineString(v)
+		case int64:
+			e.sheet.WriteString("<c><v>" + strconv.FormatInt(v, 10) + "</v></c>")
+		case float64:
+			e.sheet.WriteString("<c><v>" + strconv.FormatFloat(v, 'f', -1, 64) + "</v></c>")
+		case time.Time:
+			// Excel has no dates before its epoch
+			if v.Before(excelEpoch) {
+				e.sheet.WriteString("<c/>")
+				continue
+			}
+			days := float64(v.Sub(excelEpoch)) / float64(24*time.Hour)
+			e.sheet.WriteString(`<c s="1"><v>` + strconv.FormatFloat(days, 'f', -1, 64) + "</v></c>")
+		}
+	}
+	_, err := e.sheet.WriteString("</row>")
+	return err
+}
+
+// inlineString writes a text cell. EscapeText replaces characters XML cannot hold.
+func (e *xlsxEncoder) inlineString(s string) {
+	e.sheet.WriteString(`<c t="inlineStr"><is><t xml:space="preserve">`)
+	xml.EscapeText(e.sheet, []byte(s))
+	e.sheet.WriteString("</t></is></c>")
+}
+
+func (e *xlsxEncoder) Close() error {
+	e.sheet.WriteString("</sheetData></worksheet>")
+	if err := e.sheet.Flush(); err != nil {
+		return err
+	}
+	return e.zip.Close()
+}
+
+// parquetEncoder writes a row group every exportRowGroupSize stocks. Parquet
+// orders the columns of a schema by name, so index maps each selected column
+// to its position in the file.
+type parquetEncoder struct {
+	w       *parquet.Writer
+	columns []exportColumn
+	index   []int
+}
+
+func newParquetEncoder(w io.Writer, columns []exportColumn) (stockEncoder, error) {
+	group := parquet.Group{}
+	for _, column := range columns {
+		switch column.kind {
+		case exportString:
+			group[column.name] = parquet.String()
+		case exportInt:
+			group[column.name] = parquet.Int(64)
+		case exportFloat:
+			group[column.name] = parquet.Leaf(parquet.DoubleType)
+		case exportTime:
+			group[column.name] = parquet.Timestamp(parquet.Millisecond)
+		}
+	}
+	schema := parquet.NewSchema("stock", group)
+
+	enc := &parquetEncoder{columns: columns, index: make([]int, len(columns))}
+	fields := schema.Fields()
+	for i, column := range columns {
+		enc.index[i] = slices.IndexFunc(fields, func(f parquet.Field) bool { return f.Name() == column.name })
+	}
+	enc.w = parquet.NewWriter(w, schema,
+		parquet.Compression(&parquet.Snappy),
+		parquet.MaxRowsPerRowGroup(exportRowGroupSize),
+	)
+	return enc, nil
+}
+
+func (e *parquetEncoder) Encode(stock models.Stock) error {
+	row := make(parquet.Row, len(e.columns))
+	for i, column := range e.columns {
+		var value parquet.Value
+		switch v := column.value(stock).(type) {
+		case string:
+			value = parquet.ByteArrayValue([]byte(v))
+		case int64:
+			value = parquet.Int64Value(v)
+		case float64:
+			value = parquet.DoubleValue(v)
+		case time.Time:
+			value = parquet.Int64Value(v.UnixMilli())
+		}
+		row[e.index[i]] = value.Level(0, 0, e.index[i])
+	}
+	_, err := e.w.WriteRows([]parquet.Row{row})
+	return err
+}
+
+func (e *parquetEncoder) Close() error {
+	return e.w.Close()
+}
@@ -0,0 +1,142 @@
+package services
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"Backend/internal/models"
+
+	"github.com/parquet-go/parquet-go"
+)
+
+func TestNewStockExport(t *testing.T) {
+	tests := []struct {
+		format  string
+		columns []string
+		wantErr bool
+	}{
+		{"", nil, false},
+		{"xlsx", []string{"score", "ticker"}, false},
+		{"parquet", []string{"time"}, false},
+		{"pdf", nil, true},
+		{"csv", []string{"ticker", "price"}, true},
+		{"csv", []string{"ticker", "ticker"}, true},
+	}
+
+	for _, tt := range tests {
+		_, err := NewStockExport(tt.format, tt.columns, models.StockFilters{})
+		if gotErr := errors.Is(err, ErrInvalidExport); gotErr != tt.wantErr {
+			t.Errorf("NewStockExport(%q, %v) error = %v, want invalid %v", tt.format, tt.columns, err, tt.wantErr)
+		}
+	}
+
+	export, _ := NewStockExport("", nil, models.StockFilters{})
+	if got := export.Filename(time.Date(2026, 3, 2, 9, 5, 7, 0, time.FixedZone("UTC-5", -5*60*60))); got != "stocks-20260302-140507.csv" {
+		t.Errorf("Filename() = %q, want stocks-20260302-140507.csv", got)
+	}
+}
+
+func TestExport(t *testing.T) {
+	service, repo := newTestStockService(t)
+	day := time.Date(2026, 3, 2, 14, 30, 0, 0, time.UTC)
+	upsert(t, repo,
+		models.Stock{Ticker: "T", Company: "AT&T <Inc>", Brokerage: "UBS", RatingTo: "Buy", Score: 80.5, Confidence: 0.9, Time: day},
+		models.Stock{Ticker: "NVDA", Company: "Nvidia, \"Corp\"", Brokerage: "UBS", RatingTo: "Hold", Score: 60, Confidence: 0.5, Time: day},
+		models.Stock{Ticker: "TSLA", Company: "Tesla", Brokerage: "Barclays", RatingTo: "Sell", Score: 20, Confidence: 0.1, Time: day},
+	)
+	filters := models.StockFilters{Brokerage: "ubs", SortBy: "ticker", Order: "ASC"}
+
+	export := func(t *testing.T, format string, columns ...string) []byte {
+		t.Helper()
+		e, err := NewStockExport(format, columns, filters)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var buf bytes.Buffer
+		if err := service.Export(context.Background(), &buf, e); err != nil {
+			t.Fatalf("Export() error = %v", err)
+		}
+		return buf.Bytes()
+	}
+
+	t.Run("csv", func(t *testing.T) {
+		records, err := csv.NewReader(bytes.NewReader(export(t, "csv", "ticker", "company", "score", "time"))).ReadAll()
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := [][]string{
+			{"ticker", "company", "score", "time"},
+			{"NVDA", "Nvidia, \"Corp\"", "60", "2026-03-02T14:30:00Z"},
+			{"T", "AT&T <Inc>", "80.5", "2026-03-02T14:30:00Z"},
+		}
+		if len(records) != len(want) {
+			t.Fatalf("records = %q, want %q", records, want)
+		}
+		for i := range want {
+			if strings.Join(records[i], "|") != strings.Join(want[i], "|") {
+				t.Errorf("record %d = %q, want %q", i, records[i], want[i])
+			}
+		}
+	})
+
+	t.Run("csv without matches has a header", func(t *testing.T) {
+		e, _ := NewStockExport("csv", []string{"ticker", "score"}, models.StockFilters{Ticker: "none"})
+		var buf bytes.Buffer
+		if err := service.Export(context.Background(), &buf, e); err != nil || buf.String() != "ticker,score\n" {
+			t.Errorf("Export() = %q, %v; want only the header", buf.String(), err)
+		}
+	})
+
+	t.Run("xlsx", func(t *testing.T) {
+		file := export(t, "xlsx", "ticker", "company", "score", "time")
+		zr, err := zip.NewReader(bytes.NewReader(file), int64(len(file)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var sheet []byte
+		for _, f := range zr.File {
+			if f.Name == "xl/worksheets/sheet1.xml" {
+				rc, _ := f.Open()
+				sheet, _ = io.ReadAll(rc)
+				rc.Close()
+			}
+		}
+		for _, want := range []string{
+			`<t xml:space="preserve">company</t>`,
+			`<t xml:space="preserve">AT&amp;T &lt;Inc&gt;</t>`,
+			"<c><v>80.5</v></c>",
+			// 2026-03-02 14:30 as days since 1899-12-30
+			`<c s="1"><v>46083.604166666664</v></c>`,
+		} {
+			if !bytes.Contains(sheet, []byte(want)) {
+				t.Errorf("sheet = %s, want it to contain %s", sheet, want)
+			}
+		}
+		if n := bytes.Count(sheet, []byte("<row>")); n != 3 {
+			t.Errorf("sheet has %d rows, want 3", n)
+		}
+	})
+
+	t.Run("parquet", func(t *testing.T) {
+		type row struct {
+			Ticker string    `parquet:"ticker"`
+			Score  float64   `parquet:"score"`
+			Time   time.Time `parquet:"time,timestamp(millisecond)"`
+		}
+		file := export(t, "parquet", "time", "ticker", "score")
+		rows, err := parquet.Read[row](bytes.NewReader(file), int64(len(file)))
+		if err != nil {
+			t.Fatalf("Read() error = %v", err)
+		}
+		if len(rows) != 2 || rows[0].Ticker != "NVDA" || rows[1].Score != 80.5 || !rows[1].Time.Equal(day) {
+			t.Errorf("rows = %+v, want NVDA and T", rows)
+		}
+	})
+}
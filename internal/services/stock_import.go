@@ -0,0 +1,268 @@
+package services
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"time"
+
+	"Backend/internal/models"
+	"Backend/internal/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// ErrInvalidImport is returned for an upload that cannot be read as a whole,
+// such as an unknown format or a CSV header naming an unknown column. Problems
+// with single rows are reported per row instead.
+var ErrInvalidImport = errors.New("invalid import")
+
+// Formats accepted by Import
+const (
+	ImportFormatCSV   = "csv"
+	ImportFormatJSONL = "jsonl"
+)
+
+const (
+	// maxImportRows caps the rows of one import, which are upserted in a single batch
+	maxImportRows = 50000
+	// maxImportLine is the longest JSONL line accepted
+	maxImportLine = 64 * 1024
+)
+
+// importRecord is a row read from an upload, with the problems found parsing it
+type importRecord struct {
+	line     int
+	record   APIStock
+	problems []string
+}
+
+// importFields sets the APIStock field named by a CSV column
+var importFields = map[string]func(s *APIStock, value string) error{
+	"ticker":      func(s *APIStock, v string) error { s.Ticker = v; return nil },
+	"company":     func(s *APIStock, v string) error { s.Company = v; return nil },
+	"brokerage":   func(s *APIStock, v string) error { s.Brokerage = v; return nil },
+	"action":      func(s *APIStock, v string) error { s.Action = v; return nil },
+	"rating_from": func(s *APIStock, v string) error { s.RatingFrom = v; return nil },
+	"rating_to":   func(s *APIStock, v string) error { s.RatingTo = v; return nil },
+	"target_from": func(s *APIStock, v string) error { s.TargetFrom = v; return nil },
+	"target_to":   func(s *APIStock, v string) error { s.TargetTo = v; return nil },
+	"time":        func(s *APIStock, v string) (err error) { s.Time, err = parseImportTime(v); return err },
+}
+
+// Import reads rating events from a CSV or JSONL upload in the APIStock shape,
+// validates and scores them like the sync and stores the valid ones tagged with
+// models.StockSourceImport. Invalid rows are skipped and reported; a dry run
+// only validates. Errors wrap ErrInvalidImport when the upload cannot be read.
+func (s *StockService) Import(ctx context.Context, r io.Reader, format string, dryRun bool) (_ *models.ImportReport, err error) {
+	ctx, span := tracing.Start(ctx, "StockService.Import", attribute.String("format", format), attribute.Bool("dry_run", dryRun))
+	defer func() { tracing.End(span, err) }()
+
+	var records []importRecord
+	switch format {
+	case ImportFormatCSV:
+		records, err = readImportCSV(r)
+	case ImportFormatJSONL:
+		records, err = readImportJSONL(r)
+	default:
+		return nil, fmt.Errorf("%w: format must be csv or jsonl", ErrInvalidImport)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	report := &models.ImportReport{
+		Source:  models.StockSourceImport,
+		DryRun:  dryRun,
+		Rows:    len(records),
+		Results: make([]models.ImportRow, len(records)),
+	}
+	now := time.Now()
+	var stocks []models.Stock
+	var positions []int // index in Results of each stock
+	for i, rec := range records {
+		problems := rec.problems
+		if len(problems) == 0 {
+			problems = apiStockProblems(rec.record, now)
+		}
+		report.Results[i] = models.ImportRow{
+			Line:    rec.line,
+			Ticker:  rec.record.Ticker,
+			Company: rec.record.Company,
+			Status:  models.ImportStatusInvalid,
+			Errors:  problems,
+		}
+		if len(problems) > 0 {
+			continue
+		}
+
+		stock := rec.record.toStock(now)
+		stock.Source = models.StockSourceImport
+		stocks = append(stocks, stock)
+		positions = append(positions, i)
+	}
+
+	scoreStocks(stocks)
+	status := models.ImportStatusImported
+	if dryRun {
+		status = models.ImportStatusValid
+	}
+	for i, stock := range stocks {
+		result := &report.Results[positions[i]]
+		result.Status, result.Score, result.Reason = status, stock.Score, stock.Reason
+	}
+
+	if !dryRun && len(stocks) > 0 {
+		upserted, err := s.stocks.UpsertStocks(ctx, stocks)
+		if err != nil {
+			return nil, fmt.Errorf("error storing imported stocks: %w", err)
+		}
+		for _, rejected := range upserted.Rejected {
+			result := &report.Results[positions[rejected.Index]]
+			result.Status, result.Errors, result.Score, result.Reason = models.ImportStatusInvalid, []string{rejected.Reason}, 0, ""
+		}
+		report.Upserted, report.Duplicates = upserted.Upserted, upserted.Duplicates
+	}
+
+	for _, result := range report.Results {
+		if result.Status == models.ImportStatusInvalid {
+			report.Invalid++
+		} else if !dryRun {
+			report.Imported++
+		}
+	}
+	span.SetAttributes(attribute.Int("rows", report.Rows), attribute.Int("invalid", report.Invalid))
+	s.log.Info("stock import finished",
+		"format", format,
+		"dry_run", dryRun,
+		"rows", report.Rows,
+		"imported", report.Imported,
+		"invalid", report.Invalid,
+		"upserted", report.Upserted,
+	)
+
+	return report, nil
+}
+
+// readImportCSV reads a CSV upload whose header names APIStock fields. Columns
+// may come in any order and missing ones are left empty.
+func readImportCSV(r io.Reader) ([]importRecord, error) {
+	cr := csv.NewReader(r)
+	cr.TrimLeadingSpace = true
+
+	header, err := cr.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("%w: the file is empty", ErrInvalidImport)
+	}
+	if err != nil {
+		return nil, importReadError(err)
+	}
+	names := make([]string, len(header))
+	setters := make([]func(*APIStock, string) error, len(header))
+	for i, name := range header {
+		if i == 0 {
+			// Spreadsheets often save CSV with a byte order mark
+			name = strings.TrimPrefix(name, "\ufeff")
+		}
+		name = strings.ToLower(strings.TrimSpace(name))
+		set, ok := importFields[name]
+		if !ok {
+			return nil, fmt.Errorf("%w: unknown column %q", ErrInvalidImport, name)
+		}
+		if slices.Contains(names, name) {
+			return nil, fmt.Errorf("%w: duplicate column %q", ErrInvalidImport, name)
+		}
+		names[i], setters[i] = name, set
+	}
+
+	var records []importRecord
+	for {
+		fields, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			return records, nil
+		}
+		if err != nil && !errors.Is(err, csv.ErrFieldCount) {
+			return nil, importReadError(err)
+		}
+
+		rec := importRecord{}
+		rec.line, _ = cr.FieldPos(0)
+		if err != nil {
+			rec.problems = []string{fmt.Sprintf("has %d fields, the header has %d", len(fields), len(header))}
+		} else {
+			for i, value := range fields {
+				if err := setters[i](&rec.record, strings.TrimSpace(value)); err != nil {
+					rec.problems = append(rec.problems, fmt.Sprintf("%s: %v", names[i], err))
+				}
+			}
+		}
+		if records = append(records, rec); len(records) > maxImportRows {
+			return nil, fmt.Errorf("%w: more than %d rows", ErrInvalidImport, maxImportRows)
+		}
+	}
+}
+
+// readImportJSONL reads one APIStock object per line, skipping blank lines
+func readImportJSONL(r io.Reader) ([]importRecord, error) {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 0, 4096), maxImportLine)
+
+	var records []importRecord
+	for line := 1; sc.Scan(); line++ {
+		text := bytes.TrimSpace(sc.Bytes())
+		if len(text) == 0 {
+			continue
+		}
+
+		rec := importRecord{line: line}
+		dec := json.NewDecoder(bytes.NewReader(text))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&rec.record); err != nil {
+			rec.problems = []string{"invalid JSON: " + err.Error()}
+		} else if dec.More() {
+			rec.problems = []string{"invalid JSON: more than one value on the line"}
+		}
+		if records = append(records, rec); len(records) > maxImportRows {
+			return nil, fmt.Errorf("%w: more than %d rows", ErrInvalidImport, maxImportRows)
+		}
+	}
+	if err := sc.Err(); err != nil {
+		if errors.Is(err, bufio.ErrTooLong) {
+			return nil, fmt.Errorf("%w: a line is longer than %d bytes", ErrInvalidImport, maxImportLine)
+		}
+		return nil, importReadError(err)
+	}
+	return records, nil
+}
+
+// importReadError wraps a malformed file in ErrInvalidImport and passes read
+// errors, such as a body over the size limit, through unchanged
+func importReadError(err error) error {
+	var parseErr *csv.ParseError
+	if errors.As(err, &parseErr) {
+		return fmt.Errorf("%w: %v", ErrInvalidImport, err)
+	}
+	return err
+}
+
+// parseImportTime accepts an RFC 3339 timestamp or a date, taken as midnight UTC
+func parseImportTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse(time.DateOnly, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%q is not an RFC 3339 time or a YYYY-MM-DD date", value)
+	}
+	return t, nil
+}
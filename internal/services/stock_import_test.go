@@ -0,0 +1,110 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"Backend/internal/models"
+)
+
+func TestImport(t *testing.T) {
+	day := time.Now().UTC().AddDate(0, 0, -1).Format(time.DateOnly)
+	csvFile := "\ufeffTicker,company,brokerage,action,rating_from,rating_to,target_to,time\n" +
+		"NVDA,Nvidia,UBS,upgraded by,Hold,Buy,$150.00," + day + "\n" +
+		"nvda,Nvidia,UBS,upgraded by,Hold,Buy,$150.00," + day + "\n" +
+		"MSFT,Microsoft,UBS,initiated by,,Hold,\"$1,200.00\",yesterday\n" +
+		"TSLA,Tesla,UBS\n"
+
+	t.Run("csv", func(t *testing.T) {
+		service, repo := newTestStockService(t)
+		report, err := service.Import(context.Background(), strings.NewReader(csvFile), ImportFormatCSV, false)
+		if err != nil {
+			t.Fatalf("Import() error = %v", err)
+		}
+		if report.Rows != 4 || report.Imported != 1 || report.Invalid != 3 || report.Upserted != 1 || report.Source != models.StockSourceImport {
+			t.Errorf("report = %+v, want 4 rows, 1 imported and 3 invalid", report)
+		}
+
+		want := []struct {
+			line   int
+			status string
+			err    string
+		}{
+			{2, models.ImportStatusImported, ""},
+			{3, models.ImportStatusInvalid, "not a valid symbol"},
+			{4, models.ImportStatusInvalid, `time: "yesterday" is not`},
+			{5, models.ImportStatusInvalid, "has 3 fields, the header has 8"},
+		}
+		for i, w := range want {
+			got := report.Results[i]
+			if got.Line != w.line || got.Status != w.status || !strings.Contains(strings.Join(got.Errors, "; "), w.err) {
+				t.Errorf("row %d = %+v, want line %d %s with %q", i, got, w.line, w.status, w.err)
+			}
+		}
+		if got := report.Results[0]; got.Score == 0 || !strings.Contains(got.Reason, "Recent upgrade") {
+			t.Errorf("imported row = %+v, want a score and reason", got)
+		}
+
+		stocks, err := repo.ListStocks(context.Background(), models.StockFilters{Source: models.StockSourceImport})
+		if err != nil || len(stocks) != 1 || stocks[0].Ticker != "NVDA" || stocks[0].Score != report.Results[0].Score {
+			t.Errorf("imported stocks = %+v, %v; want NVDA with the reported score", stocks, err)
+		}
+	})
+
+	t.Run("dry run stores nothing", func(t *testing.T) {
+		service, repo := newTestStockService(t)
+		report, err := service.Import(context.Background(), strings.NewReader(csvFile), ImportFormatCSV, true)
+		if err != nil || report.Imported != 0 || report.Results[0].Status != models.ImportStatusValid {
+			t.Fatalf("Import() dry run = %+v, %v; want the first row valid and nothing imported", report, err)
+		}
+		if stocks, _ := repo.ListStocks(context.Background(), models.StockFilters{}); len(stocks) != 0 {
+			t.Errorf("stocks after a dry run = %+v, want none", stocks)
+		}
+	})
+
+	t.Run("jsonl", func(t *testing.T) {
+		service, _ := newTestStockService(t)
+		at := time.Now().UTC().Add(-time.Hour).Format(time.RFC3339)
+		jsonl := `{"ticker":"AAPL","company":"Apple","brokerage":"UBS","action":"target raised by","rating_to":"Buy","target_from":"$180","target_to":"$200","time":"` + at + `"}` + "\n" +
+			"\n" +
+			`{"ticker":"AAPL","company":"Apple","brokerage":"UBS","action":"reiterated by","note":"call","time":"` + at + `"}` + "\n" +
+			`{"ticker":"AAPL",` + "\n"
+
+		report, err := service.Import(context.Background(), strings.NewReader(jsonl), ImportFormatJSONL, false)
+		if err != nil {
+			t.Fatalf("Import() error = %v", err)
+		}
+		if report.Rows != 3 || report.Imported != 1 {
+			t.Errorf("report = %+v, want 3 rows and 1 imported", report)
+		}
+		for i, want := range []struct {
+			line int
+			err  string
+		}{{1, ""}, {3, `unknown field "note"`}, {4, "invalid JSON"}} {
+			got := report.Results[i]
+			if got.Line != want.line || !strings.Contains(strings.Join(got.Errors, "; "), want.err) {
+				t.Errorf("row %d = %+v, want line %d with %q", i, got, want.line, want.err)
+			}
+		}
+	})
+
+	t.Run("unreadable uploads", func(t *testing.T) {
+		service, _ := newTestStockService(t)
+		for _, tt := range []struct {
+			name, format, body string
+		}{
+			{"unknown format", "xml", "<stocks/>"},
+			{"empty csv", ImportFormatCSV, ""},
+			{"unknown column", ImportFormatCSV, "ticker,price\nAAPL,1\n"},
+			{"duplicate column", ImportFormatCSV, "ticker,Ticker\nAAPL,AAPL\n"},
+			{"bad quoting", ImportFormatCSV, "ticker,company\n\"AAPL,Apple\n"},
+		} {
+			if _, err := service.Import(context.Background(), strings.NewReader(tt.body), tt.format, false); !errors.Is(err, ErrInvalidImport) {
+				t.Errorf("%s: Import() error = %v, want ErrInvalidImport", tt.name, err)
+			}
+		}
+	})
+}
@@ -2,199 +2,104 @@
 package services
 
 import (
+	"context"
 	"fmt"
+	"log/slog"
 	"math"
 	"sort"
-	"strconv"
 	"strings"
 	"time"
 
+	"Backend/internal/config"
+	"Backend/internal/logger"
+	"Backend/internal/metrics"
 	"Backend/internal/models"
-	"database/sql"
+	"Backend/internal/repository"
+	"Backend/internal/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
 )
 
-// StockService handles stock-related operations and database interactions
+// StockService handles stock-related operations on top of the storage repositories
 type StockService struct {
-	db *sql.DB
+	stocks     repository.StockRepository
+	runs       repository.SyncRunRepository
+	quarantine repository.QuarantineRepository
+	log        *slog.Logger
+	scoring    config.ScoringConfig
 }
 
 // NewStockService creates a new instance of StockService
-func NewStockService(db *sql.DB) *StockService {
-	return &StockService{db: db}
+func NewStockService(
+	stocks repository.StockRepository,
+	runs repository.SyncRunRepository,
+	quarantine repository.QuarantineRepository,
+	scoring config.ScoringConfig,
+) *StockService {
+	return &StockService{
+		stocks:     stocks,
+		runs:       runs,
+		quarantine: quarantine,
+		log:        logger.Component("stock_service"),
+		scoring:    scoring,
+	}
 }
 
 // GetStocks retrieves stocks based on provided filters
-func (s *StockService) GetStocks(filters models.StockFilters) (*models.StockResponse, error) {
-	query := `
-		SELECT id, ticker, company, brokerage, action, rating_from, rating_to,
-		       target_from, target_to, time, created_at, updated_at, score, confidence,
-		       count(*) OVER() AS total_register,
-		       count(CASE WHEN rating_to = 'Buy' THEN 1 END) OVER() AS buy_count,
-		       (SELECT COUNT(DISTINCT brokerage) FROM stocks) AS total_brokerages,
-		       max(updated_at) OVER() AS last_update
-		FROM stocks
-	`
-
-	args := []any{}
-	argIndex := 1
-
-	query += fmt.Sprintf(" WHERE 1=1 ")
-
-	if filters.Ticker != "" {
-		query += fmt.Sprintf(" AND ticker ILIKE $%d", argIndex)
-		args = append(args, "%"+filters.Ticker+"%")
-		argIndex++
-	}
-
-	if filters.Company != "" {
-		query += fmt.Sprintf(" AND company ILIKE $%d", argIndex)
-		args = append(args, "%"+filters.Company+"%")
-		argIndex++
-	}
-
-	if filters.Brokerage != "" {
-		query += fmt.Sprintf(" AND brokerage ILIKE $%d", argIndex)
-		args = append(args, "%"+filters.Brokerage+"%")
-		argIndex++
-	}
-
-	if filters.ProductID != 0 {
-		query += fmt.Sprintf(" AND id = $%d", argIndex)
-		args = append(args, filters.ProductID)
-		argIndex++
-	}
-
-	if filters.Score > 0 {
-		query += fmt.Sprintf(" AND score >= $%d", argIndex)
-		args = append(args, filters.Score)
-		argIndex++
-	}
-
-	if filters.Today == "true" {
-		query += fmt.Sprintf(` AND (
-				DATE(time) = CURRENT_DATE 
-				OR (
-						DATE(time) = CURRENT_DATE - INTERVAL '1 day' 
-						AND NOT EXISTS (
-								SELECT 1 FROM stocks 
-								WHERE DATE(time) = CURRENT_DATE
-						)
-				)
-		)`)
-		argIndex++
-	}
-
-	if filters.Confidence != "" {
-		if strings.ToUpper(filters.Confidence) == "ASC" {
-			query += " ORDER BY confidence ASC"
-		} else if strings.ToUpper(filters.Confidence) == "DESC" {
-			query += " ORDER BY confidence DESC"
-		}
-	}
-
-	sortBy := "confidence"
-	if filters.SortBy != "" {
-		sortBy = filters.SortBy
-	}
-
-	order := "DESC"
-	if filters.Order == "ASC" || filters.Order == "DESC" {
-		order = filters.Order
-	}
-
-	query += fmt.Sprintf(" GROUP BY id, ticker, brokerage ORDER BY %s %s  ", sortBy, order)
+func (s *StockService) GetStocks(ctx context.Context, filters models.StockFilters) (_ *models.StockResponse, err error) {
+	ctx, span := tracing.Start(ctx, "StockService.GetStocks")
+	defer func() { tracing.End(span, err) }()
 
-	limit := 0
-	if filters.Limit > 0 {
-		limit = filters.Limit
-		query += fmt.Sprintf(" LIMIT %d ", limit)
-	}
-
-	rows, err := s.db.Query(query, args...)
+	stocks, err := s.stocks.ListStocks(ctx, filters)
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
-
-	var stocks []models.Stock
-	for rows.Next() {
-		var stock models.Stock
-		err := rows.Scan(
-			&stock.ID, &stock.Ticker, &stock.Company, &stock.Brokerage,
-			&stock.Action, &stock.RatingFrom, &stock.RatingTo,
-			&stock.TargetFrom, &stock.TargetTo, &stock.Time,
-			&stock.CreatedAt, &stock.UpdatedAt, &stock.Score, &stock.Confidence,
-			&stock.TotalRegister, &stock.BuyCount, &stock.TotalBrokerages, &stock.LastUpdateFilter,
-		)
-		if err != nil {
-			return nil, err
-		}
-		stocks = append(stocks, stock)
-	}
 
-	sort.Slice(stocks, func(i, j int) bool {
+	_, sortSpan := tracing.Start(ctx, "StockService.GetStocks.sort", attribute.Int("rows", len(stocks)))
+	sort.SliceStable(stocks, func(i, j int) bool {
 		return stocks[i].Confidence > stocks[j].Confidence
 	})
+	sortSpan.End()
 
 	return &models.StockResponse{Items: stocks}, nil
 }
 
 // GetRecommendations retrieves top stock recommendations based on score and confidence
-func (s *StockService) GetRecommendations() ([]models.Stock, error) {
-	query := `
-		WITH today_records AS (
-			SELECT *
-			FROM stocks 
-			WHERE DATE(time) = CURRENT_DATE
-			AND score > 0
-		),
-		yesterday_records AS (
-			SELECT *
-			FROM stocks
-			WHERE DATE(time) = CURRENT_DATE - INTERVAL '1 day'
-			AND score > 0
-		)
-		SELECT ticker, company, rating_to, brokerage, target_to, rating_from, action, time, score, confidence
-		FROM (
-			SELECT *
-			FROM today_records
-			UNION ALL
-			SELECT *
-			FROM yesterday_records
-			WHERE NOT EXISTS (SELECT 1 FROM today_records)
-		) combined_records
-		ORDER BY confidence DESC, score DESC, time DESC
-		LIMIT 1
-	`
-
-	rows, err := s.db.Query(query)
+func (s *StockService) GetRecommendations(ctx context.Context) ([]models.Stock, error) {
+	return s.stocks.Recommendations(ctx, s.scoring.MinScore, s.scoring.RecommendationLimit)
+}
+
+// StocksByTicker returns the stocks listed under each of tickers, matched
+// exactly, most recent event first. It reads them in one query so that callers
+// resolving many tickers can batch them; tickers without stocks are left out.
+func (s *StockService) StocksByTicker(ctx context.Context, tickers []string) (map[string][]models.Stock, error) {
+	return s.groupStocks(ctx, models.StockFilters{Tickers: tickers}, func(stock models.Stock) string { return stock.Ticker })
+}
+
+// StocksByBrokerage returns the stocks last rated by each of brokerages, matched
+// exactly, most recent event first, in one query like StocksByTicker
+func (s *StockService) StocksByBrokerage(ctx context.Context, brokerages []string) (map[string][]models.Stock, error) {
+	return s.groupStocks(ctx, models.StockFilters{Brokerages: brokerages}, func(stock models.Stock) string { return stock.Brokerage })
+}
+
+// groupStocks lists the stocks matching filters by time and groups them by key
+func (s *StockService) groupStocks(ctx context.Context, filters models.StockFilters, key func(models.Stock) string) (map[string][]models.Stock, error) {
+	filters.SortBy, filters.Order = "time", "DESC"
+	stocks, err := s.stocks.ListStocks(ctx, filters)
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
-
-	var recommendations []models.Stock
-	for rows.Next() {
-		var recommendation models.Stock
-		err := rows.Scan(
-			&recommendation.Ticker,
-			&recommendation.Company,
-			&recommendation.RatingTo,
-			&recommendation.Brokerage,
-			&recommendation.TargetTo,
-			&recommendation.RatingFrom,
-			&recommendation.Action,
-			&recommendation.Time,
-			&recommendation.Score,
-			&recommendation.Confidence,
-		)
-		if err != nil {
-			return nil, err
-		}
-		recommendations = append(recommendations, recommendation)
+
+	groups := make(map[string][]models.Stock)
+	for _, stock := range stocks {
+		groups[key(stock)] = append(groups[key(stock)], stock)
 	}
+	return groups, nil
+}
 
-	return recommendations, nil
+// LastSyncRun returns the most recent sync run, or nil if none has been recorded
+func (s *StockService) LastSyncRun(ctx context.Context) (*models.SyncRun, error) {
+	return s.runs.LastSyncRun(ctx)
 }
 
 // calculateScore computes a stock's score based on various factors
@@ -204,36 +109,8 @@ func calculateScore(
 ) float64 {
 	score := 50.0
 
-	ratingRank := map[string]int{
-		"sell":                1,
-		"strong sell":         1,
-		"underperform":        2,
-		"sector underperform": 3,
-		"underweight":         4,
-		"hold":                5,
-		"neutral":             5,
-		"equal weight":        5,
-		"market perform":      5,
-		"sector perform":      5,
-		"in-line":             5,
-		"peer perform":        5,
-		"sector weight":       5,
-		"positive":            6,
-		"outperformer":        6,
-		"outperform":          7,
-		"market outperform":   7,
-		"sector outperform":   7,
-		"overweight":          8,
-		"buy":                 8,
-		"strong-buy":          9,
-		"speculative buy":     9,
-	}
-
-	rf := strings.ToLower(strings.TrimSpace(ratingFrom))
-	rt := strings.ToLower(strings.TrimSpace(ratingTo))
-
-	if fromRank, ok1 := ratingRank[rf]; ok1 {
-		if toRank, ok2 := ratingRank[rt]; ok2 {
+	if fromRank, ok1 := models.RatingRank(ratingFrom); ok1 {
+		if toRank, ok2 := models.RatingRank(ratingTo); ok2 {
 			delta := toRank - fromRank
 			switch {
 			case delta > 2:
@@ -248,10 +125,8 @@ func calculateScore(
 		}
 	}
 
-	targetFromStr = strings.ReplaceAll(strings.ReplaceAll(targetFromStr, "$", ""), ",", "")
-	targetToStr = strings.ReplaceAll(strings.ReplaceAll(targetToStr, "$", ""), ",", "")
-	targetFromFloat, err1 := strconv.ParseFloat(targetFromStr, 64)
-	targetToFloat, err2 := strconv.ParseFloat(targetToStr, 64)
+	targetFromFloat, err1 := models.ParseTarget(targetFromStr)
+	targetToFloat, err2 := models.ParseTarget(targetToStr)
 	targetFrom := math.Round(targetFromFloat*100) / 100
 	targetTo := math.Round(targetToFloat*100) / 100
 
@@ -344,13 +219,8 @@ func generateReason(rating, action, target string) string {
 	return strings.Join(reasons, " • ")
 }
 
-// SyncAllData synchronizes stock data from the API to the database
-func (s *StockService) SyncAllData(apiClient *APIClient) error {
-	stocks, err := apiClient.FetchAllStocks()
-	if err != nil {
-		return fmt.Errorf("error fetching stocks from API: %w", err)
-	}
-
+// scoreStocks fills in the score, reason, current rating and confidence of every stock
+func scoreStocks(stocks []models.Stock) {
 	for i := range stocks {
 		score := calculateScore(stocks[i].RatingFrom, stocks[i].RatingTo, stocks[i].Action, stocks[i].TargetFrom, stocks[i].TargetTo, stocks[i].Time)
 		reason := generateReason(stocks[i].RatingTo, stocks[i].Action, stocks[i].TargetTo)
@@ -360,10 +230,115 @@ func (s *StockService) SyncAllData(apiClient *APIClient) error {
 		stocks[i].CurrentRating = stocks[i].RatingTo
 		stocks[i].Confidence = float64(int64((score/100)*1000)) / 1000
 	}
+}
+
+// SyncAllData synchronizes stock data from the API to the database
+func (s *StockService) SyncAllData(ctx context.Context, apiClient *APIClient) (err error) {
+	start := time.Now()
+	s.log.Info("stock sync started")
+
+	ctx, span := tracing.Start(ctx, "StockService.SyncAllData")
+	defer func() { tracing.End(span, err) }()
+
+	var result models.SyncResult
+	runID, runErr := s.runs.StartSyncRun(ctx)
+	if runErr != nil {
+		s.log.Warn("sync run will not be recorded", "error", runErr)
+	}
+	defer func() {
+		metrics.ObserveSync(start, err)
+		if runErr == nil {
+			// The run is recorded even when ctx was cancelled
+			if finishErr := s.runs.FinishSyncRun(context.WithoutCancel(ctx), runID, result, err); finishErr != nil {
+				s.log.Warn("error recording sync run", "error", finishErr)
+			}
+		}
+	}()
+
+	records, err := apiClient.FetchAllStocks(ctx)
+	if err != nil {
+		return fmt.Errorf("error fetching stocks from API: %w", err)
+	}
+	result.Stocks = len(records)
+	span.SetAttributes(attribute.Int("sync.stocks", len(records)))
 
-	if err := s.InsertStocks(stocks); err != nil {
-		return fmt.Errorf("error inserting stocks into database: %w", err)
+	ingested, err := s.ingest(ctx, records, models.QuarantineSourceSync)
+	if err != nil {
+		return err
 	}
+	result.Quarantined = ingested.Quarantined
+	result.Rejected = len(ingested.Report.Rejected)
+	result.Duplicates = ingested.Report.Duplicates
+	span.SetAttributes(
+		attribute.Int("sync.quarantined", result.Quarantined),
+		attribute.Int("sync.rejected", result.Rejected),
+		attribute.Int("sync.duplicates", result.Duplicates),
+	)
+
+	s.log.Info("stock sync finished",
+		"stocks", len(records),
+		"quarantined", result.Quarantined,
+		"upserted", ingested.Report.Upserted,
+		"rejected", result.Rejected,
+		"duplicates", result.Duplicates,
+		"duration_ms", time.Since(start).Milliseconds(),
+	)
 
 	return nil
 }
+
+// ingestResult counts what ingest did with a batch of upstream records
+type ingestResult struct {
+	Quarantined int
+	Report      models.UpsertReport
+}
+
+// ingest validates upstream records, quarantines the malformed ones and scores
+// and stores the rest. It is shared by the sync and backfills; backfilled events
+// are historical and stay out of the change feed.
+func (s *StockService) ingest(ctx context.Context, records []APIStock, source string) (ingestResult, error) {
+	var result ingestResult
+
+	stocks, quarantined := s.validateRecords(records, source)
+	if err := s.quarantine.QuarantineEvents(ctx, quarantined); err != nil {
+		return result, fmt.Errorf("error quarantining invalid records: %w", err)
+	}
+	result.Quarantined = len(quarantined)
+	metrics.SyncRowsQuarantined.Add(float64(len(quarantined)))
+
+	_, scoreSpan := tracing.Start(ctx, "StockService.score", attribute.Int("stocks", len(stocks)))
+	scoreStocks(stocks)
+	scoreSpan.End()
+
+	upsert := s.stocks.UpsertStocks
+	if source == models.QuarantineSourceBackfill {
+		upsert = s.stocks.UpsertHistoricalStocks
+	}
+	report, err := upsert(ctx, stocks)
+	if err != nil {
+		return result, fmt.Errorf("error inserting stocks into database: %w", err)
+	}
+	result.Report = report
+	s.logRejected(report.Rejected)
+
+	return result, nil
+}
+
+// maxLoggedRejections caps the rejected rows logged per batch; the rest are only counted
+const maxLoggedRejections = 10
+
+// logRejected logs the rows UpsertStocks skipped
+func (s *StockService) logRejected(rejected []models.RejectedRow) {
+	for i, row := range rejected {
+		if i == maxLoggedRejections {
+			s.log.Warn("more stock rows rejected", "count", len(rejected)-i)
+			return
+		}
+		s.log.Warn("stock row rejected",
+			"index", row.Index,
+			"ticker", row.Ticker,
+			"company", row.Company,
+			"reason", row.Reason,
+		)
+	}
+}
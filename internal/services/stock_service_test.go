@@ -0,0 +1,447 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"Backend/internal/config"
+	"Backend/internal/models"
+	"Backend/internal/repository"
+)
+
+func TestCalculateScore(t *testing.T) {
+	now := time.Now()
+	days := func(d float64) time.Time { return now.Add(-time.Duration(d * 24 * float64(time.Hour))) }
+
+	tests := []struct {
+		name                         string
+		ratingFrom, ratingTo, action string
+		targetFrom, targetTo         string
+		timestamp                    time.Time
+		want                         float64
+	}{
+		{
+			name:       "fresh upgrade with doubled target is capped and compressed",
+			ratingFrom: "Hold", ratingTo: "Buy", action: "upgraded by",
+			targetFrom: "$10", targetTo: "$20", timestamp: days(0.5),
+			want: 85, // 50+12+30+20+12 = 124 -> 100 -> 70+(30*0.5)
+		},
+		{
+			name:       "downgrade to sell with halved target bottoms out",
+			ratingFrom: "Buy", ratingTo: "Sell", action: "downgraded by",
+			targetFrom: "$20", targetTo: "$10", timestamp: days(2.5),
+			want: 15, // 50-28-20-20-3 = -21 -> 0 -> 30-(30*0.5)
+		},
+		{
+			name:       "unknown ratings and unparseable targets only count recency",
+			ratingFrom: "Mystery", ratingTo: "Unknown", action: "commented",
+			targetFrom: "n/a", targetTo: "", timestamp: days(1.5),
+			want: 55,
+		},
+		{
+			name:       "target raise within the linear range",
+			ratingFrom: "Neutral", ratingTo: "Neutral", action: "target raised by",
+			targetFrom: "$100", targetTo: "$110", timestamp: days(4),
+			want: 51, // 50+0+4+7-10
+		},
+		{
+			name:       "old reiteration is compressed below 30",
+			ratingFrom: "Buy", ratingTo: "Buy", action: "reiterated by",
+			timestamp: days(30),
+			want:      24, // 50+3-35 = 18 -> 30-(12*0.5)
+		},
+		{
+			name:       "ratings are case and whitespace insensitive and targets accept thousands separators",
+			ratingFrom: " BUY ", ratingTo: "Strong-Buy", action: "Initiated",
+			targetFrom: "$1,000.00", targetTo: "$1,200", timestamp: days(0.1),
+			want: 76.5, // 50+3+8+10+12 = 83 -> 70+(13*0.5)
+		},
+		{
+			name:       "small downgrade with zero target ignores the target",
+			ratingFrom: "Outperform", ratingTo: "Hold", action: "downgraded",
+			targetFrom: "$0", targetTo: "$50", timestamp: days(8),
+			want: 15.5, // 50-4-20-25 = 1 -> 30-(29*0.5)
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := calculateScore(tt.ratingFrom, tt.ratingTo, tt.action, tt.targetFrom, tt.targetTo, tt.timestamp)
+			if math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("calculateScore() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCalculateScoreStaysInRange(t *testing.T) {
+	ratings := []string{"Sell", "Hold", "Buy", "Strong-Buy", ""}
+	actions := []string{"upgraded by", "downgraded by", "removed", "target set", ""}
+	targets := []string{"$1", "$100", "$10000", ""}
+
+	for _, from := range ratings {
+		for _, to := range ratings {
+			for _, action := range actions {
+				for _, target := range targets {
+					score := calculateScore(from, to, action, "$100", target, time.Now())
+					if score < 15 || score > 85 {
+						t.Fatalf("calculateScore(%q, %q, %q, $100, %q) = %v, want within [15, 85]", from, to, action, target, score)
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestGenerateReason(t *testing.T) {
+	tests := []struct {
+		name                   string
+		rating, action, target string
+		want                   string
+	}{
+		{"upgrade with buy rating and target", "Buy", "upgraded by", "$20", "Recent upgrade • Buy rating • Target price: $20"},
+		{"strong buy without target", "Strong Buy", "reiterated by", "", "Buy rating"},
+		{"upgrade match is case insensitive", "Hold", "Upgraded", "", "Recent upgrade"},
+		{"target only", "Hold", "target raised by", "$15", "Target price: $15"},
+		{"nothing notable", "Sell", "downgraded by", "", "Favorable technical analysis"},
+		{"rating match is case sensitive", "buy", "", "", "Favorable technical analysis"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := generateReason(tt.rating, tt.action, tt.target); got != tt.want {
+				t.Errorf("generateReason() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestScoreStocks(t *testing.T) {
+	stocks := []models.Stock{{
+		Ticker: "AAA", RatingFrom: "Hold", RatingTo: "Buy", Action: "upgraded by",
+		TargetFrom: "$10", TargetTo: "$20", Time: time.Now(),
+	}}
+
+	scoreStocks(stocks)
+
+	got := stocks[0]
+	if got.Score != 85 || got.Confidence != 0.85 {
+		t.Errorf("score/confidence = %v/%v, want 85/0.85", got.Score, got.Confidence)
+	}
+	if got.CurrentRating != "Buy" {
+		t.Errorf("CurrentRating = %q, want Buy", got.CurrentRating)
+	}
+	if !strings.HasPrefix(got.Reason, "Recent upgrade") {
+		t.Errorf("Reason = %q, want it to start with Recent upgrade", got.Reason)
+	}
+}
+
+func newTestStockService(t *testing.T) (*StockService, *repository.MemoryRepository) {
+	t.Helper()
+	repo := repository.NewMemoryRepository()
+	return NewStockService(repo, repo, repo, config.ScoringConfig{MinScore: 30, RecommendationLimit: 2}), repo
+}
+
+func TestGetStocksSortsByConfidence(t *testing.T) {
+	service, repo := newTestStockService(t)
+	now := time.Now()
+
+	_, err := repo.UpsertStocks(context.Background(), []models.Stock{
+		{Ticker: "LOW", Company: "Low Inc", Brokerage: "A", Confidence: 0.2, Time: now},
+		{Ticker: "HIGH", Company: "High Inc", Brokerage: "B", Confidence: 0.9, Time: now},
+		{Ticker: "MID", Company: "Mid Inc", Brokerage: "A", Confidence: 0.5, Time: now},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Sorting by ticker in the repository is overridden by the confidence sort
+	resp, err := service.GetStocks(context.Background(), models.StockFilters{SortBy: "ticker", Order: "ASC"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var tickers []string
+	for _, s := range resp.Items {
+		tickers = append(tickers, s.Ticker)
+	}
+	if got := strings.Join(tickers, ","); got != "HIGH,MID,LOW" {
+		t.Errorf("tickers = %s, want HIGH,MID,LOW", got)
+	}
+	if resp.Items[0].TotalRegister != 3 || resp.Items[0].TotalBrokerages != 2 {
+		t.Errorf("aggregates = %d registers / %d brokerages, want 3 / 2", resp.Items[0].TotalRegister, resp.Items[0].TotalBrokerages)
+	}
+}
+
+func TestStocksByTickerAndBrokerage(t *testing.T) {
+	service, repo := newTestStockService(t)
+	now := time.Now()
+
+	_, err := repo.UpsertStocks(context.Background(), []models.Stock{
+		{Ticker: "BRK", Company: "Berkshire A", Brokerage: "UBS", Time: now.Add(-time.Hour)},
+		{Ticker: "BRK", Company: "Berkshire B", Brokerage: "Jefferies", Time: now},
+		{Ticker: "BRKX", Company: "Other", Brokerage: "UBS", Time: now},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	byTicker, err := service.StocksByTicker(context.Background(), []string{"BRK", "NONE"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := byTicker["BRK"]; len(byTicker) != 1 || len(got) != 2 || got[0].Company != "Berkshire B" {
+		t.Errorf("StocksByTicker() = %+v, want both BRK rows, newest first", byTicker)
+	}
+
+	byBrokerage, err := service.StocksByBrokerage(context.Background(), []string{"UBS", "Jefferies"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(byBrokerage["UBS"]) != 2 || len(byBrokerage["Jefferies"]) != 1 {
+		t.Errorf("StocksByBrokerage() = %+v, want two UBS rows and one Jefferies", byBrokerage)
+	}
+}
+
+func TestGetRecommendationsUsesScoringConfig(t *testing.T) {
+	service, repo := newTestStockService(t)
+	now := time.Now()
+
+	_, err := repo.UpsertStocks(context.Background(), []models.Stock{
+		{Ticker: "A", Company: "A", Score: 80, Confidence: 0.8, Time: now},
+		{Ticker: "B", Company: "B", Score: 60, Confidence: 0.6, Time: now},
+		{Ticker: "C", Company: "C", Score: 70, Confidence: 0.7, Time: now},
+		{Ticker: "D", Company: "D", Score: 20, Confidence: 0.9, Time: now},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	recommendations, err := service.GetRecommendations(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(recommendations) != 2 || recommendations[0].Ticker != "A" || recommendations[1].Ticker != "C" {
+		t.Errorf("recommendations = %+v, want A then C", recommendations)
+	}
+}
+
+func TestSyncAllData(t *testing.T) {
+	upstream := newFakeUpstream(t, map[string]APIResponse{
+		"": {Items: []APIStock{
+			{Ticker: "AAA", Company: "A Corp", Brokerage: "X", Action: "upgraded by", RatingFrom: "Hold", RatingTo: "Buy", TargetFrom: "$10", TargetTo: "$20", Time: time.Now()},
+		}, NextPage: "p2"},
+		"p2": {Items: []APIStock{
+			{Ticker: "BBB", Company: "B Corp", Brokerage: "Y", Action: "reiterated by", RatingFrom: "Hold", RatingTo: "Hold", Time: time.Now()},
+		}},
+	})
+	service, repo := newTestStockService(t)
+	client := NewAPIClient("key", upstream.URL, 0, 0)
+
+	if err := service.SyncAllData(context.Background(), client); err != nil {
+		t.Fatalf("SyncAllData() error = %v", err)
+	}
+
+	stocks, err := repo.ListStocks(context.Background(), models.StockFilters{SortBy: "ticker", Order: "ASC"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stocks) != 2 {
+		t.Fatalf("stored %d stocks, want 2", len(stocks))
+	}
+	if stocks[0].Score != 85 || stocks[0].Reason == "" {
+		t.Errorf("stock was not scored before storing: %+v", stocks[0])
+	}
+
+	run, err := service.LastSyncRun(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if run == nil || run.Status != models.SyncStatusSuccess || run.Stocks != 2 || run.FinishedAt == nil {
+		t.Errorf("last sync run = %+v, want a finished success with 2 stocks", run)
+	}
+}
+
+func TestSyncAllDataCountsDuplicateEvents(t *testing.T) {
+	at := time.Now().Add(-time.Hour)
+	event := APIStock{Ticker: "AAA", Company: "A Corp", Brokerage: "X", Action: "upgraded by", RatingFrom: "Hold", RatingTo: "Buy", Time: at}
+	pages := map[string]APIResponse{
+		// The upstream repeats the event on the next page
+		"":   {Items: []APIStock{event}, NextPage: "p2"},
+		"p2": {Items: []APIStock{event}},
+	}
+	upstream := newFakeUpstream(t, pages)
+	service, _ := newTestStockService(t)
+	client := NewAPIClient("key", upstream.URL, 0, 0)
+	ctx := context.Background()
+
+	if err := service.SyncAllData(ctx, client); err != nil {
+		t.Fatalf("SyncAllData() error = %v", err)
+	}
+	if run, _ := service.LastSyncRun(ctx); run == nil || run.Duplicates != 1 {
+		t.Errorf("first sync run = %+v, want 1 duplicate", run)
+	}
+
+	// A new action for the same stock is not a duplicate
+	upgrade := event
+	upgrade.RatingFrom, upgrade.RatingTo, upgrade.Time = "Buy", "Strong-Buy", at.Add(time.Minute)
+	pages["p2"] = APIResponse{Items: []APIStock{upgrade}}
+
+	if err := service.SyncAllData(ctx, client); err != nil {
+		t.Fatalf("SyncAllData() error = %v", err)
+	}
+	if run, _ := service.LastSyncRun(ctx); run == nil || run.Duplicates != 1 || run.Stocks != 2 {
+		t.Errorf("second sync run = %+v, want 1 duplicate out of 2 stocks", run)
+	}
+}
+
+func TestSyncAllDataRecordsFailure(t *testing.T) {
+	upstream := newFakeUpstream(t, nil)
+	upstream.status = http.StatusBadGateway
+	service, _ := newTestStockService(t)
+
+	err := service.SyncAllData(context.Background(), NewAPIClient("key", upstream.URL, 0, 0))
+	if err == nil {
+		t.Fatal("SyncAllData() error = nil, want the upstream error")
+	}
+
+	run, err := service.LastSyncRun(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if run == nil || run.Status != models.SyncStatusFailed || !strings.Contains(run.Error, "502") {
+		t.Errorf("last sync run = %+v, want a failure mentioning 502", run)
+	}
+}
+
+func TestSyncAllDataQuarantinesInvalidRecords(t *testing.T) {
+	upstream := newFakeUpstream(t, map[string]APIResponse{
+		"": stockPage("", "AAA", "TICKERTOOLONG", "BBB"),
+	})
+	service, repo := newTestStockService(t)
+	client := NewAPIClient("key", upstream.URL, 0, 0)
+
+	if err := service.SyncAllData(context.Background(), client); err != nil {
+		t.Fatalf("SyncAllData() error = %v, want invalid records quarantined", err)
+	}
+
+	stocks, err := repo.ListStocks(context.Background(), models.StockFilters{})
+	if err != nil || len(stocks) != 2 {
+		t.Fatalf("stored %d stocks, %v; want 2", len(stocks), err)
+	}
+
+	run, err := service.LastSyncRun(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if run == nil || run.Status != models.SyncStatusSuccess || run.Stocks != 3 || run.Quarantined != 1 || run.Rejected != 0 {
+		t.Errorf("last sync run = %+v, want a success with 3 stocks and 1 quarantined", run)
+	}
+
+	quarantined, err := service.ListQuarantined(context.Background(), models.QuarantineFilters{})
+	if err != nil || len(quarantined) != 1 {
+		t.Fatalf("ListQuarantined() = %+v, %v; want one event", quarantined, err)
+	}
+	event := quarantined[0]
+	if event.Ticker != "TICKERTOOLONG" || event.Source != models.QuarantineSourceSync || !strings.Contains(event.Reason, "ticker is 13 characters long") {
+		t.Errorf("quarantined event = %+v", event)
+	}
+
+	// The provider sends the same record again on the next sync
+	if err := service.SyncAllData(context.Background(), client); err != nil {
+		t.Fatal(err)
+	}
+	quarantined, _ = service.ListQuarantined(context.Background(), models.QuarantineFilters{})
+	if len(quarantined) != 1 || quarantined[0].SeenCount != 2 {
+		t.Errorf("after a second sync: %+v, want the same event seen twice", quarantined)
+	}
+}
+
+func TestReplayQuarantined(t *testing.T) {
+	service, repo := newTestStockService(t)
+	ctx := context.Background()
+
+	payload, _ := json.Marshal(APIStock{Ticker: "aapl", Company: "Apple Inc", Brokerage: "Goldman Sachs", Action: "upgraded by", RatingTo: "Buy", TargetTo: "$12", Time: time.Now()})
+	err := repo.QuarantineEvents(ctx, []models.QuarantinedEvent{
+		{Source: models.QuarantineSourceSync, Ticker: "aapl", Company: "Apple Inc", Payload: payload, Reason: "ticker is not a valid symbol"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := service.ReplayQuarantined(ctx, 1, nil); !errors.Is(err, ErrInvalidRecord) {
+		t.Fatalf("ReplayQuarantined() unchanged error = %v, want ErrInvalidRecord", err)
+	}
+	// A failed replay leaves the event pending for a corrected one
+	if event, err := service.GetQuarantined(ctx, 1); err != nil || event.Status != models.QuarantineStatusPending || event.ResolvedAt != nil {
+		t.Fatalf("GetQuarantined() after a failed replay = %+v, %v; want pending", event, err)
+	}
+	if _, err := service.ReplayQuarantined(ctx, 99, nil); !errors.Is(err, ErrQuarantineNotFound) {
+		t.Errorf("ReplayQuarantined(99) error = %v, want ErrQuarantineNotFound", err)
+	}
+
+	correction := APIStock{Ticker: "AAPL", Company: "Apple Inc", Brokerage: "Goldman Sachs", Action: "upgraded by", RatingTo: "Buy", TargetTo: "$12", Time: time.Now()}
+	event, err := service.ReplayQuarantined(ctx, 1, &correction)
+	if err != nil {
+		t.Fatalf("ReplayQuarantined() with correction error = %v", err)
+	}
+	if event.Status != models.QuarantineStatusReplayed || event.ResolvedAt == nil {
+		t.Errorf("event after replay = %+v, want replayed", event)
+	}
+
+	stocks, err := repo.ListStocks(ctx, models.StockFilters{Ticker: "AAPL"})
+	if err != nil || len(stocks) != 1 || stocks[0].Score == 0 {
+		t.Errorf("ListStocks() = %+v, %v; want the replayed, scored AAPL", stocks, err)
+	}
+
+	if _, err := service.ReplayQuarantined(ctx, 1, &correction); !errors.Is(err, ErrQuarantineResolved) {
+		t.Errorf("second replay error = %v, want ErrQuarantineResolved", err)
+	}
+}
+
+func TestReplayQuarantinedConcurrently(t *testing.T) {
+	service, repo := newTestStockService(t)
+	ctx := context.Background()
+
+	payload, _ := json.Marshal(APIStock{Ticker: "aapl", Company: "Apple Inc", Brokerage: "Goldman Sachs", Action: "upgraded by", RatingTo: "Buy", TargetTo: "$12", Time: time.Now()})
+	err := repo.QuarantineEvents(ctx, []models.QuarantinedEvent{
+		{Source: models.QuarantineSourceSync, Ticker: "aapl", Company: "Apple Inc", Payload: payload, Reason: "ticker is not a valid symbol"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	correction := APIStock{Ticker: "AAPL", Company: "Apple Inc", Brokerage: "Goldman Sachs", Action: "upgraded by", RatingTo: "Buy", TargetTo: "$12", Time: time.Now()}
+	const replays = 8
+	errs := make(chan error, replays)
+	for range replays {
+		go func() {
+			_, err := service.ReplayQuarantined(ctx, 1, &correction)
+			errs <- err
+		}()
+	}
+
+	replayed := 0
+	for range replays {
+		switch err := <-errs; {
+		case err == nil:
+			replayed++
+		case !errors.Is(err, ErrQuarantineResolved):
+			t.Errorf("ReplayQuarantined() error = %v, want nil or ErrQuarantineResolved", err)
+		}
+	}
+	if replayed != 1 {
+		t.Errorf("%d replays succeeded, want 1", replayed)
+	}
+
+	if changes, err := repo.ListChanges(ctx, 0, 100); err != nil || len(changes) != 1 {
+		t.Errorf("ListChanges() = %+v, %v; want the replayed event stored once", changes, err)
+	}
+}
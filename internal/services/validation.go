@@ -0,0 +1,102 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strings"
+	"time"
+
+	"Backend/internal/models"
+	"Backend/internal/repository"
+)
+
+// ErrInvalidRecord is wrapped by errors for upstream records that fail validation
+var ErrInvalidRecord = errors.New("invalid record")
+
+// tickerPattern matches exchange symbols such as AAPL, BRK.B or BF-B
+var tickerPattern = regexp.MustCompile(`^[A-Z][A-Z0-9]*([.-][A-Z0-9]+)?$`)
+
+// Event times outside [minEventTime, now+maxClockSkew] are treated as corrupt
+var minEventTime = time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+
+const maxClockSkew = 24 * time.Hour
+
+// ValidateAPIStock checks an upstream record before it is scored and stored:
+// ticker format, required fields, a plausible timestamp and parseable price
+// targets, then the column constraints checked by repository.ValidateStock.
+func ValidateAPIStock(s APIStock, now time.Time) error {
+	if problems := apiStockProblems(s, now); len(problems) > 0 {
+		return fmt.Errorf("%w: %s", ErrInvalidRecord, strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+func apiStockProblems(s APIStock, now time.Time) []string {
+	var problems []string
+
+	switch {
+	case s.Ticker == "":
+		problems = append(problems, "ticker is required")
+	case !tickerPattern.MatchString(s.Ticker):
+		problems = append(problems, fmt.Sprintf("ticker %q is not a valid symbol", s.Ticker))
+	}
+
+	for _, field := range []struct{ name, value string }{
+		{"company", s.Company},
+		{"brokerage", s.Brokerage},
+		{"action", s.Action},
+	} {
+		if strings.TrimSpace(field.value) == "" {
+			problems = append(problems, field.name+" is required")
+		}
+	}
+
+	switch {
+	case s.Time.IsZero():
+		problems = append(problems, "time is required")
+	case s.Time.Before(minEventTime):
+		problems = append(problems, fmt.Sprintf("time %s is before %s", s.Time.Format(time.RFC3339), minEventTime.Format("2006-01-02")))
+	case s.Time.After(now.Add(maxClockSkew)):
+		problems = append(problems, fmt.Sprintf("time %s is in the future", s.Time.Format(time.RFC3339)))
+	}
+
+	for _, target := range []struct{ name, value string }{
+		{"target_from", s.TargetFrom},
+		{"target_to", s.TargetTo},
+	} {
+		if target.value == "" {
+			continue
+		}
+		if price, err := models.ParseTarget(target.value); err != nil || price < 0 || math.IsInf(price, 0) || math.IsNaN(price) {
+			problems = append(problems, fmt.Sprintf("%s %q is not a price", target.name, target.value))
+		}
+	}
+
+	if len(problems) == 0 {
+		stock := s.toStock(now)
+		if err := repository.ValidateStock(&stock); err != nil {
+			problems = append(problems, err.Error())
+		}
+	}
+
+	return problems
+}
+
+// toStock converts an upstream record into an unscored stock
+func (s APIStock) toStock(now time.Time) models.Stock {
+	return models.Stock{
+		Ticker:     s.Ticker,
+		Company:    s.Company,
+		Brokerage:  s.Brokerage,
+		Action:     s.Action,
+		RatingFrom: s.RatingFrom,
+		RatingTo:   s.RatingTo,
+		TargetFrom: s.TargetFrom,
+		TargetTo:   s.TargetTo,
+		Time:       s.Time,
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	}
+}
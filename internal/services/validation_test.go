@@ -0,0 +1,69 @@
+package services
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestValidateAPIStock(t *testing.T) {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	valid := func() APIStock {
+		return APIStock{
+			Ticker: "AAPL", Company: "Apple Inc", Brokerage: "Goldman Sachs", Action: "upgraded by",
+			RatingFrom: "Hold", RatingTo: "Buy", TargetFrom: "$10.00", TargetTo: "$1,250.50", Time: now.Add(-time.Hour),
+		}
+	}
+
+	tests := []struct {
+		name    string
+		modify  func(s *APIStock)
+		wantErr string
+	}{
+		{"valid", func(s *APIStock) {}, ""},
+		{"class share ticker", func(s *APIStock) { s.Ticker = "BRK.B" }, ""},
+		{"hyphenated ticker", func(s *APIStock) { s.Ticker = "BF-B" }, ""},
+		{"targets are optional", func(s *APIStock) { s.TargetFrom, s.TargetTo = "", "" }, ""},
+		{"missing ticker", func(s *APIStock) { s.Ticker = "" }, "ticker is required"},
+		{"lowercase ticker", func(s *APIStock) { s.Ticker = "aapl" }, "not a valid symbol"},
+		{"ticker with spaces", func(s *APIStock) { s.Ticker = "AA PL" }, "not a valid symbol"},
+		{"ticker too long", func(s *APIStock) { s.Ticker = "ABCDEFGHIJK" }, "ticker is 11 characters long"},
+		{"missing company", func(s *APIStock) { s.Company = " " }, "company is required"},
+		{"missing brokerage", func(s *APIStock) { s.Brokerage = "" }, "brokerage is required"},
+		{"missing action", func(s *APIStock) { s.Action = "" }, "action is required"},
+		{"zero time", func(s *APIStock) { s.Time = time.Time{} }, "time is required"},
+		{"ancient time", func(s *APIStock) { s.Time = time.Date(1970, 1, 1, 0, 0, 0, 0, time.UTC) }, "is before 2000-01-01"},
+		{"future time", func(s *APIStock) { s.Time = now.Add(48 * time.Hour) }, "in the future"},
+		{"unparseable target", func(s *APIStock) { s.TargetTo = "ten dollars" }, `target_to "ten dollars" is not a price`},
+		{"negative target", func(s *APIStock) { s.TargetFrom = "$-5" }, "target_from"},
+		{"column too long", func(s *APIStock) { s.RatingTo = strings.Repeat("x", 51) }, "rating_to is 51 characters long"},
+		{"every problem is reported", func(s *APIStock) { s.Company, s.Action = "", "" }, "company is required; action is required"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := valid()
+			tt.modify(&s)
+
+			err := ValidateAPIStock(s, now)
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Errorf("ValidateAPIStock() error = %v, want nil", err)
+			case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+				t.Errorf("ValidateAPIStock() error = %v, want it to contain %q", err, tt.wantErr)
+			case tt.wantErr != "" && !errors.Is(err, ErrInvalidRecord):
+				t.Errorf("ValidateAPIStock() error = %v, want it to wrap ErrInvalidRecord", err)
+			}
+		})
+	}
+}
+
+func TestToStockSetsTimestamps(t *testing.T) {
+	now := time.Now()
+	stock := APIStock{Ticker: "AAPL", Company: "Apple Inc", Time: now.Add(-time.Hour)}.toStock(now)
+
+	if !stock.CreatedAt.Equal(now) || !stock.UpdatedAt.Equal(now) || !stock.Time.Equal(now.Add(-time.Hour)) {
+		t.Errorf("toStock() = %+v, want created_at and updated_at set to now", stock)
+	}
+}
@@ -0,0 +1,203 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"Backend/internal/models"
+	"Backend/internal/repository"
+)
+
+var (
+	// ErrWatchlistNotFound is returned for a watchlist that does not exist or belongs to another user
+	ErrWatchlistNotFound = errors.New("watchlist not found")
+
+	// ErrInvalidWatchlist is wrapped by errors for invalid names and tickers
+	ErrInvalidWatchlist = errors.New("invalid watchlist")
+
+	// ErrWatchlistExists is returned when the user already has a watchlist with the same name
+	ErrWatchlistExists = errors.New("a watchlist with that name already exists")
+
+	// ErrWatchlistFull is returned when adding tickers would exceed maxWatchlistTickers
+	ErrWatchlistFull = fmt.Errorf("a watchlist can hold at most %d tickers", maxWatchlistTickers)
+)
+
+// Limits for watchlists; names and tickers match the watchlists and watchlist_items columns
+const (
+	maxWatchlistName    = 100
+	maxWatchlistTickers = 100
+	maxTickerLength     = 10
+)
+
+// WatchlistService manages the watchlists of each user
+type WatchlistService struct {
+	lists  repository.WatchlistRepository
+	stocks *StockService
+}
+
+// NewWatchlistService creates a new instance of WatchlistService
+func NewWatchlistService(lists repository.WatchlistRepository, stocks *StockService) *WatchlistService {
+	return &WatchlistService{lists: lists, stocks: stocks}
+}
+
+// List returns the user's watchlists by name
+func (s *WatchlistService) List(ctx context.Context, username string) ([]models.Watchlist, error) {
+	return s.lists.ListWatchlists(ctx, username)
+}
+
+// Get returns one of the user's watchlists or ErrWatchlistNotFound
+func (s *WatchlistService) Get(ctx context.Context, username string, id int64) (*models.Watchlist, error) {
+	list, err := s.lists.GetWatchlist(ctx, username, id)
+	if err != nil {
+		return nil, err
+	}
+	if list == nil {
+		return nil, ErrWatchlistNotFound
+	}
+	return list, nil
+}
+
+// Create stores a new watchlist for the user. Tickers are upper-cased and must be
+// valid symbols.
+func (s *WatchlistService) Create(ctx context.Context, username, name string, tickers []string) (*models.Watchlist, error) {
+	name, err := watchlistName(name)
+	if err != nil {
+		return nil, err
+	}
+	tickers, err = watchlistTickers(tickers)
+	if err != nil {
+		return nil, err
+	}
+	if len(tickers) > maxWatchlistTickers {
+		return nil, ErrWatchlistFull
+	}
+
+	list := &models.Watchlist{Username: username, Name: name, Tickers: tickers}
+	if err := s.lists.CreateWatchlist(ctx, list); err != nil {
+		return nil, watchlistError(err)
+	}
+	return list, nil
+}
+
+// Rename changes the name of one of the user's watchlists
+func (s *WatchlistService) Rename(ctx context.Context, username string, id int64, name string) (*models.Watchlist, error) {
+	name, err := watchlistName(name)
+	if err != nil {
+		return nil, err
+	}
+
+	found, err := s.lists.RenameWatchlist(ctx, username, id, name)
+	if err := watchlistResult(found, err); err != nil {
+		return nil, err
+	}
+	return s.Get(ctx, username, id)
+}
+
+// Delete deletes one of the user's watchlists
+func (s *WatchlistService) Delete(ctx context.Context, username string, id int64) error {
+	return watchlistResult(s.lists.DeleteWatchlist(ctx, username, id))
+}
+
+// AddTickers adds tickers to one of the user's watchlists; those already in it are skipped
+func (s *WatchlistService) AddTickers(ctx context.Context, username string, id int64, tickers []string) (*models.Watchlist, error) {
+	tickers, err := watchlistTickers(tickers)
+	if err != nil {
+		return nil, err
+	}
+	if len(tickers) == 0 {
+		return nil, fmt.Errorf("%w: no tickers given", ErrInvalidWatchlist)
+	}
+
+	list, err := s.Get(ctx, username, id)
+	if err != nil {
+		return nil, err
+	}
+	added := 0
+	for _, ticker := range tickers {
+		if !slices.Contains(list.Tickers, ticker) {
+			added++
+		}
+	}
+	if len(list.Tickers)+added > maxWatchlistTickers {
+		return nil, ErrWatchlistFull
+	}
+
+	found, err := s.lists.AddWatchlistTickers(ctx, username, id, tickers)
+	if err := watchlistResult(found, err); err != nil {
+		return nil, err
+	}
+	return s.Get(ctx, username, id)
+}
+
+// RemoveTicker removes a ticker from one of the user's watchlists
+func (s *WatchlistService) RemoveTicker(ctx context.Context, username string, id int64, ticker string) (*models.Watchlist, error) {
+	found, err := s.lists.RemoveWatchlistTicker(ctx, username, id, strings.ToUpper(strings.TrimSpace(ticker)))
+	if err := watchlistResult(found, err); err != nil {
+		return nil, err
+	}
+	return s.Get(ctx, username, id)
+}
+
+// Stocks returns the stocks of a watchlist's tickers with the filtering, sorting
+// and scoring of StockService.GetStocks
+func (s *WatchlistService) Stocks(ctx context.Context, username string, id int64, filters models.StockFilters) (*models.StockResponse, error) {
+	list, err := s.Get(ctx, username, id)
+	if err != nil {
+		return nil, err
+	}
+	if len(list.Tickers) == 0 {
+		// An empty ticker filter would match every stock
+		return &models.StockResponse{Items: []models.Stock{}}, nil
+	}
+
+	filters.Tickers = list.Tickers
+	return s.stocks.GetStocks(ctx, filters)
+}
+
+func watchlistName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	switch {
+	case name == "":
+		return "", fmt.Errorf("%w: name is required", ErrInvalidWatchlist)
+	case utf8.RuneCountInString(name) > maxWatchlistName:
+		return "", fmt.Errorf("%w: name is longer than %d characters", ErrInvalidWatchlist, maxWatchlistName)
+	}
+	return name, nil
+}
+
+// watchlistTickers upper-cases tickers, drops repeats and checks they are valid symbols
+func watchlistTickers(tickers []string) ([]string, error) {
+	normalized := make([]string, 0, len(tickers))
+	for _, ticker := range tickers {
+		ticker = strings.ToUpper(strings.TrimSpace(ticker))
+		if !tickerPattern.MatchString(ticker) || len(ticker) > maxTickerLength {
+			return nil, fmt.Errorf("%w: ticker %q is not a valid symbol", ErrInvalidWatchlist, ticker)
+		}
+		if !slices.Contains(normalized, ticker) {
+			normalized = append(normalized, ticker)
+		}
+	}
+	return normalized, nil
+}
+
+// watchlistResult turns a repository's found flag into ErrWatchlistNotFound
+func watchlistResult(found bool, err error) error {
+	if err != nil {
+		return watchlistError(err)
+	}
+	if !found {
+		return ErrWatchlistNotFound
+	}
+	return nil
+}
+
+func watchlistError(err error) error {
+	if errors.Is(err, repository.ErrWatchlistNameTaken) {
+		return ErrWatchlistExists
+	}
+	return err
+}
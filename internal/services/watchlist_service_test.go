@@ -0,0 +1,120 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"Backend/internal/models"
+)
+
+func TestWatchlistService(t *testing.T) {
+	stocks, repo := newTestStockService(t)
+	service := NewWatchlistService(repo, stocks)
+	ctx := context.Background()
+	now := time.Now()
+
+	upsert(t, repo,
+		models.Stock{Ticker: "AAPL", Company: "Apple Inc", Brokerage: "A", Confidence: 0.5, Time: now},
+		models.Stock{Ticker: "MSFT", Company: "Microsoft Corp", Brokerage: "B", Confidence: 0.9, Time: now},
+		models.Stock{Ticker: "AAPLX", Company: "Not Apple", Brokerage: "C", Confidence: 0.7, Time: now},
+	)
+
+	list, err := service.Create(ctx, "dashboard", "  Tech ", []string{"aapl", " msft", "AAPL"})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if list.Name != "Tech" || strings.Join(list.Tickers, ",") != "AAPL,MSFT" {
+		t.Errorf("Create() = %+v, want Tech with AAPL and MSFT", list)
+	}
+
+	t.Run("invalid input", func(t *testing.T) {
+		tests := []struct {
+			name    string
+			create  func() error
+			wantErr error
+		}{
+			{"empty name", func() error { _, err := service.Create(ctx, "dashboard", " ", nil); return err }, ErrInvalidWatchlist},
+			{"long name", func() error { _, err := service.Create(ctx, "dashboard", strings.Repeat("x", 101), nil); return err }, ErrInvalidWatchlist},
+			{"invalid ticker", func() error { _, err := service.Create(ctx, "dashboard", "Bad", []string{"$$$"}); return err }, ErrInvalidWatchlist},
+			{"duplicate name", func() error { _, err := service.Create(ctx, "dashboard", "Tech", nil); return err }, ErrWatchlistExists},
+			{"no tickers to add", func() error { _, err := service.AddTickers(ctx, "dashboard", list.ID, nil); return err }, ErrInvalidWatchlist},
+			{"another user's list", func() error { _, err := service.AddTickers(ctx, "admin", list.ID, []string{"TSLA"}); return err }, ErrWatchlistNotFound},
+			{"unknown list", func() error { return service.Delete(ctx, "dashboard", 999) }, ErrWatchlistNotFound},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				if err := tt.create(); !errors.Is(err, tt.wantErr) {
+					t.Errorf("error = %v, want %v", err, tt.wantErr)
+				}
+			})
+		}
+	})
+
+	t.Run("full list", func(t *testing.T) {
+		tickers := make([]string, maxWatchlistTickers)
+		for i := range tickers {
+			tickers[i] = fmt.Sprintf("T%d", i)
+		}
+		full, err := service.Create(ctx, "dashboard", "Full", tickers)
+		if err != nil {
+			t.Fatalf("Create(%d tickers) error = %v", len(tickers), err)
+		}
+		if _, err := service.AddTickers(ctx, "dashboard", full.ID, []string{"T0", "EXTRA"}); !errors.Is(err, ErrWatchlistFull) {
+			t.Errorf("AddTickers() past the limit error = %v, want ErrWatchlistFull", err)
+		}
+		if _, err := service.AddTickers(ctx, "dashboard", full.ID, []string{"T0"}); err != nil {
+			t.Errorf("AddTickers() of a ticker already in the list error = %v", err)
+		}
+	})
+
+	t.Run("stocks", func(t *testing.T) {
+		resp, err := service.Stocks(ctx, "dashboard", list.ID, models.StockFilters{})
+		if err != nil {
+			t.Fatalf("Stocks() error = %v", err)
+		}
+		var tickers []string
+		for _, s := range resp.Items {
+			tickers = append(tickers, s.Ticker)
+		}
+		// Exact tickers only, sorted by confidence like GetStocks
+		if got := strings.Join(tickers, ","); got != "MSFT,AAPL" {
+			t.Errorf("Stocks() = %s, want MSFT,AAPL", got)
+		}
+
+		if _, err := service.RemoveTicker(ctx, "dashboard", list.ID, "msft"); err != nil {
+			t.Fatalf("RemoveTicker() error = %v", err)
+		}
+		if _, err := service.RemoveTicker(ctx, "dashboard", list.ID, "aapl"); err != nil {
+			t.Fatalf("RemoveTicker() error = %v", err)
+		}
+		resp, err = service.Stocks(ctx, "dashboard", list.ID, models.StockFilters{})
+		if err != nil || len(resp.Items) != 0 {
+			t.Errorf("Stocks() of an empty list = %+v, %v; want nothing", resp, err)
+		}
+
+		if _, err := service.Stocks(ctx, "admin", list.ID, models.StockFilters{}); !errors.Is(err, ErrWatchlistNotFound) {
+			t.Errorf("Stocks() of another user's list error = %v, want ErrWatchlistNotFound", err)
+		}
+	})
+
+	t.Run("rename and delete", func(t *testing.T) {
+		renamed, err := service.Rename(ctx, "dashboard", list.ID, "Megacaps")
+		if err != nil || renamed.Name != "Megacaps" {
+			t.Fatalf("Rename() = %+v, %v", renamed, err)
+		}
+		if _, err := service.Rename(ctx, "dashboard", list.ID, "Full"); !errors.Is(err, ErrWatchlistExists) {
+			t.Errorf("Rename() to a taken name error = %v, want ErrWatchlistExists", err)
+		}
+
+		if err := service.Delete(ctx, "dashboard", list.ID); err != nil {
+			t.Fatalf("Delete() error = %v", err)
+		}
+		if _, err := service.Get(ctx, "dashboard", list.ID); !errors.Is(err, ErrWatchlistNotFound) {
+			t.Errorf("Get() after Delete() error = %v, want ErrWatchlistNotFound", err)
+		}
+	})
+}
@@ -0,0 +1,607 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"Backend/internal/config"
+	"Backend/internal/logger"
+	"Backend/internal/metrics"
+	"Backend/internal/models"
+	"Backend/internal/repository"
+)
+
+var (
+	// ErrWebhookNotFound is returned for a webhook that does not exist or belongs to another user
+	ErrWebhookNotFound = errors.New("webhook not found")
+
+	// ErrInvalidWebhook is wrapped by errors for an invalid webhook or delivery filter
+	ErrInvalidWebhook = errors.New("invalid webhook")
+
+	// ErrWebhookLimit is returned when a user already has the configured maximum of webhooks
+	ErrWebhookLimit = errors.New("too many webhooks")
+
+	// ErrDeliveryNotFound is returned for a delivery that does not exist or belongs to another webhook
+	ErrDeliveryNotFound = errors.New("webhook delivery not found")
+)
+
+// Headers sent with every delivery. The signature is "sha256=" followed by the
+// hex HMAC-SHA256 of the timestamp, a dot and the body, keyed by the secret.
+const (
+	WebhookEventHeader     = "X-Webhook-Event"
+	WebhookDeliveryHeader  = "X-Webhook-Delivery"
+	WebhookTimestampHeader = "X-Webhook-Timestamp"
+	WebhookSignatureHeader = "X-Webhook-Signature"
+)
+
+// Limits for webhooks and their deliveries
+const (
+	maxWebhookURL       = 2048
+	minWebhookSecret    = 16
+	maxWebhookSecret    = 255
+	defaultDeliveryList = 50
+	maxDeliveryList     = 500
+
+	// webhookBatchSize is the number of changes or alerts queued at a time
+	webhookBatchSize = 500
+	// maxDeliveriesPerPoll bounds the deliveries sent by one poll
+	maxDeliveriesPerPoll = 100
+)
+
+// errPrivateAddress is returned when dialing a webhook that resolves to an address
+// the service must not reach, such as loopback, private networks or cloud metadata
+var errPrivateAddress = errors.New("webhook address is not public")
+
+// nonPublicNetworks are the ranges not covered by the net.IP classification
+// methods that webhooks must not reach either
+var nonPublicNetworks = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),     // "this" network
+	netip.MustParsePrefix("100.64.0.0/10"), // carrier-grade NAT
+	netip.MustParsePrefix("192.0.0.0/24"),  // IETF protocol assignments
+	netip.MustParsePrefix("198.18.0.0/15"), // benchmarking
+	netip.MustParsePrefix("240.0.0.0/4"),   // reserved, including broadcast
+}
+
+// webhookEvents are the events a webhook can subscribe to
+var webhookEvents = []string{models.WebhookEventAlert, models.WebhookEventChange}
+
+// deliveryStatuses are the statuses deliveries can be filtered by
+var deliveryStatuses = []string{models.DeliveryStatusPending, models.DeliveryStatusDelivered, models.DeliveryStatusDead}
+
+// WebhookService manages user webhooks and delivers alerts and change events to them.
+// Events are queued in the repository and sent by Poll, so a delivery that fails
+// is retried with exponential backoff until it runs out of attempts and is
+// dead-lettered. Every delivery can be replayed.
+type WebhookService struct {
+	hooks   repository.WebhookRepository
+	changes repository.ChangeRepository
+	alerts  repository.AlertRepository
+	cfg     config.WebhooksConfig
+	client  *http.Client
+	now     func() time.Time
+	log     *slog.Logger
+
+	mu sync.Mutex // serializes Poll
+}
+
+// NewWebhookService creates a new instance of WebhookService. Call Run to start delivering.
+func NewWebhookService(hooks repository.WebhookRepository, changes repository.ChangeRepository, alerts repository.AlertRepository, cfg config.WebhooksConfig) *WebhookService {
+	return &WebhookService{
+		hooks:   hooks,
+		changes: changes,
+		alerts:  alerts,
+		cfg:     cfg,
+		client: &http.Client{
+			Timeout:   cfg.Timeout,
+			Transport: webhookTransport(cfg.AllowPrivateNetworks),
+			// A redirect is reported as a failure instead of resending the payload elsewhere
+			CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+		},
+		// Deliveries are stored in TIMESTAMP columns, which hold UTC
+		now: func() time.Time { return time.Now().UTC() },
+		log: logger.Component("webhook_service"),
+	}
+}
+
+// List returns the user's webhooks without their secrets
+func (s *WebhookService) List(ctx context.Context, username string) ([]models.Webhook, error) {
+	hooks, err := s.hooks.ListWebhooks(ctx, username)
+	if err != nil {
+		return nil, err
+	}
+	for i := range hooks {
+		hooks[i].Secret = ""
+	}
+	return hooks, nil
+}
+
+// Get returns one of the user's webhooks without its secret, or ErrWebhookNotFound
+func (s *WebhookService) Get(ctx context.Context, username string, id int64) (*models.Webhook, error) {
+	hook, err := s.webhook(ctx, username, id)
+	if err != nil {
+		return nil, err
+	}
+	hook.Secret = ""
+	return hook, nil
+}
+
+// Create validates and stores a new webhook for the user. The webhook is returned
+// with its secret, which is not shown again.
+func (s *WebhookService) Create(ctx context.Context, username string, request models.WebhookRequest) (*models.Webhook, error) {
+	hook, err := webhook(request, s.cfg.AllowPrivateNetworks)
+	if err != nil {
+		return nil, err
+	}
+
+	hooks, err := s.hooks.ListWebhooks(ctx, username)
+	if err != nil {
+		return nil, err
+	}
+	if len(hooks) >= s.cfg.MaxPerUser {
+		return nil, fmt.Errorf("%w: a user can have at most %d", ErrWebhookLimit, s.cfg.MaxPerUser)
+	}
+
+	if hook.Secret == "" {
+		if hook.Secret, err = newWebhookSecret(); err != nil {
+			return nil, err
+		}
+	}
+
+	hook.Username = username
+	if err := s.hooks.CreateWebhook(ctx, hook); err != nil {
+		return nil, err
+	}
+	return hook, nil
+}
+
+// Delete deletes one of the user's webhooks together with its deliveries
+func (s *WebhookService) Delete(ctx context.Context, username string, id int64) error {
+	found, err := s.hooks.DeleteWebhook(ctx, username, id)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return ErrWebhookNotFound
+	}
+	return nil
+}
+
+// Deliveries returns the delivery log of one of the user's webhooks, newest first
+func (s *WebhookService) Deliveries(ctx context.Context, filters models.DeliveryFilters) ([]models.WebhookDelivery, error) {
+	if filters.Status != "" && !slices.Contains(deliveryStatuses, filters.Status) {
+		return nil, fmt.Errorf("%w: status must be one of %s", ErrInvalidWebhook, strings.Join(deliveryStatuses, ", "))
+	}
+	if _, err := s.webhook(ctx, filters.Username, filters.WebhookID); err != nil {
+		return nil, err
+	}
+
+	if filters.Limit <= 0 || filters.Limit > maxDeliveryList {
+		filters.Limit = defaultDeliveryList
+	}
+	return s.hooks.ListDeliveries(ctx, filters)
+}
+
+// Test sends a test event to one of the user's webhooks right away and returns
+// the delivery with its outcome. A failed test is retried like any delivery.
+func (s *WebhookService) Test(ctx context.Context, username string, id int64) (*models.WebhookDelivery, error) {
+	hook, err := s.webhook(ctx, username, id)
+	if err != nil {
+		return nil, err
+	}
+
+	payload, err := json.Marshal(models.WebhookEvent{Event: models.WebhookEventTest, Time: s.now(), WebhookID: hook.ID})
+	if err != nil {
+		return nil, fmt.Errorf("error encoding test event: %w", err)
+	}
+
+	return s.sendNow(ctx, hook, &models.WebhookDelivery{
+		WebhookID: hook.ID,
+		Event:     models.WebhookEventTest,
+		Payload:   payload,
+	})
+}
+
+// Replay sends the payload of one of the webhook's deliveries again, whatever its
+// status, as a new delivery that records the one it replays
+func (s *WebhookService) Replay(ctx context.Context, username string, webhookID, deliveryID int64) (*models.WebhookDelivery, error) {
+	hook, err := s.webhook(ctx, username, webhookID)
+	if err != nil {
+		return nil, err
+	}
+
+	original, err := s.hooks.GetDelivery(ctx, username, webhookID, deliveryID)
+	if err != nil {
+		return nil, err
+	}
+	if original == nil {
+		return nil, ErrDeliveryNotFound
+	}
+
+	return s.sendNow(ctx, hook, &models.WebhookDelivery{
+		WebhookID: hook.ID,
+		Event:     original.Event,
+		Payload:   original.Payload,
+		ReplayOf:  &original.ID,
+	})
+}
+
+// Run queues new events and sends due deliveries every PollInterval until ctx is cancelled
+func (s *WebhookService) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.cfg.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		s.Poll(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Poll queues a delivery of every change and alert recorded since the previous
+// poll for each webhook subscribed to it, then sends the deliveries that are due.
+// Alerts only go to the webhooks of the user whose rule triggered them.
+func (s *WebhookService) Poll(ctx context.Context) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.queue(ctx, models.WebhookEventChange, s.changeEvents); err != nil {
+		s.log.Warn("error queueing change events", "error", err)
+	}
+	if err := s.queue(ctx, models.WebhookEventAlert, s.alertEvents); err != nil {
+		s.log.Warn("error queueing alert events", "error", err)
+	}
+
+	for range maxDeliveriesPerPoll {
+		if ctx.Err() != nil {
+			return
+		}
+		delivery, err := s.hooks.ClaimDelivery(ctx, s.now(), s.lease())
+		if err != nil {
+			s.log.Warn("error claiming webhook delivery", "error", err)
+			return
+		}
+		if delivery == nil {
+			return
+		}
+		if err := s.send(ctx, delivery); err != nil {
+			s.log.Warn("error saving webhook delivery", "delivery_id", delivery.ID, "error", err)
+		}
+	}
+}
+
+// queuedEvent is an event for the webhooks subscribed to it, restricted to the
+// owner's webhooks when owner is set
+type queuedEvent struct {
+	id    int64
+	owner string
+	event models.WebhookEvent
+}
+
+// queue reads the entries of source after its cursor in batches with next and
+// queues a delivery of each for the enabled webhooks subscribed to source
+func (s *WebhookService) queue(ctx context.Context, source string, next func(ctx context.Context, since int64) ([]queuedEvent, error)) error {
+	for {
+		from, err := s.hooks.WebhookCursor(ctx, source)
+		if err != nil {
+			return err
+		}
+		events, err := next(ctx, from)
+		if err != nil || len(events) == 0 {
+			return err
+		}
+
+		hooks, err := s.hooks.EnabledWebhooks(ctx, source)
+		if err != nil {
+			return err
+		}
+
+		now := s.now()
+		var deliveries []models.WebhookDelivery
+		for _, e := range events {
+			payload, err := json.Marshal(e.event)
+			if err != nil {
+				return fmt.Errorf("error encoding %s event: %w", source, err)
+			}
+			for _, hook := range hooks {
+				if e.owner != "" && hook.Username != e.owner {
+					continue
+				}
+				deliveries = append(deliveries, models.WebhookDelivery{
+					WebhookID:     hook.ID,
+					Event:         source,
+					Payload:       payload,
+					Status:        models.DeliveryStatusPending,
+					NextAttemptAt: &now,
+				})
+			}
+		}
+
+		// Another instance queued this batch first when the cursor moved
+		if _, err := s.hooks.EnqueueDeliveries(ctx, source, from, events[len(events)-1].id, deliveries); err != nil {
+			return err
+		}
+		if len(events) < webhookBatchSize {
+			return nil
+		}
+	}
+}
+
+func (s *WebhookService) changeEvents(ctx context.Context, since int64) ([]queuedEvent, error) {
+	changes, err := s.changes.ListChanges(ctx, since, webhookBatchSize)
+	if err != nil {
+		return nil, err
+	}
+	events := make([]queuedEvent, len(changes))
+	for i := range changes {
+		events[i] = queuedEvent{
+			id:    changes[i].ID,
+			event: models.WebhookEvent{Event: models.WebhookEventChange, Time: changes[i].CreatedAt, Change: &changes[i]},
+		}
+	}
+	return events, nil
+}
+
+func (s *WebhookService) alertEvents(ctx context.Context, since int64) ([]queuedEvent, error) {
+	alerts, err := s.alerts.AlertsSince(ctx, since, webhookBatchSize)
+	if err != nil {
+		return nil, err
+	}
+	events := make([]queuedEvent, len(alerts))
+	for i := range alerts {
+		events[i] = queuedEvent{
+			id:    alerts[i].ID,
+			owner: alerts[i].Username,
+			event: models.WebhookEvent{Event: models.WebhookEventAlert, Time: alerts[i].TriggeredAt, Alert: &alerts[i]},
+		}
+	}
+	return events, nil
+}
+
+// sendNow stores a delivery for hook, leased so Poll leaves it alone, and sends it
+func (s *WebhookService) sendNow(ctx context.Context, hook *models.Webhook, delivery *models.WebhookDelivery) (*models.WebhookDelivery, error) {
+	leased := s.now().Add(s.lease())
+	delivery.Status = models.DeliveryStatusPending
+	delivery.NextAttemptAt = &leased
+	if err := s.hooks.CreateDelivery(ctx, delivery); err != nil {
+		return nil, err
+	}
+
+	delivery.URL, delivery.Secret = hook.URL, hook.Secret
+	if err := s.send(ctx, delivery); err != nil {
+		return nil, err
+	}
+	return delivery, nil
+}
+
+// send posts a claimed delivery to its webhook and saves the outcome: delivered on
+// a 2xx response, dead once MaxAttempts is reached, or pending until a retry
+func (s *WebhookService) send(ctx context.Context, delivery *models.WebhookDelivery) error {
+	now := s.now()
+	delivery.Attempts++
+	delivery.LastStatusCode, delivery.LastError = s.post(ctx, delivery, now)
+
+	result := "retry"
+	switch {
+	case delivery.LastError == "":
+		result = "delivered"
+		delivery.Status = models.DeliveryStatusDelivered
+		delivery.DeliveredAt = &now
+		delivery.NextAttemptAt = nil
+	case delivery.Attempts >= s.cfg.MaxAttempts:
+		result = "dead"
+		delivery.Status = models.DeliveryStatusDead
+		delivery.NextAttemptAt = nil
+		s.log.Warn("webhook delivery dead-lettered", "delivery_id", delivery.ID, "webhook_id", delivery.WebhookID,
+			"attempts", delivery.Attempts, "error", delivery.LastError)
+	default:
+		next := now.Add(s.backoff(delivery.Attempts))
+		delivery.NextAttemptAt = &next
+	}
+	metrics.WebhookDeliveries.WithLabelValues(result).Inc()
+
+	// The outcome is saved even when the request context is gone, so a sent
+	// delivery is not sent again
+	return s.hooks.UpdateDelivery(context.WithoutCancel(ctx), delivery)
+}
+
+// post sends the delivery and returns the response status code, and an error
+// message unless the webhook answered with a 2xx status. The response body is
+// never kept, so a webhook cannot be used to read another service's answers.
+func (s *WebhookService) post(ctx context.Context, delivery *models.WebhookDelivery, now time.Time) (int, string) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, fmt.Sprintf("error creating request: %v", err)
+	}
+
+	timestamp := strconv.FormatInt(now.Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "stock-analyzer-webhooks")
+	req.Header.Set(WebhookEventHeader, delivery.Event)
+	req.Header.Set(WebhookDeliveryHeader, strconv.FormatInt(delivery.ID, 10))
+	req.Header.Set(WebhookTimestampHeader, timestamp)
+	req.Header.Set(WebhookSignatureHeader, SignWebhook(delivery.Secret, timestamp, delivery.Payload))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, err.Error()
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return resp.StatusCode, ""
+	}
+	return resp.StatusCode, fmt.Sprintf("unexpected status code %d", resp.StatusCode)
+}
+
+// webhookTransport returns the transport of deliveries. Unless allowPrivate is
+// set, it refuses to connect to non-public addresses. The check runs on the
+// address actually dialed, after DNS resolution, so a name that resolves to a
+// public address at registration and to a private one later is still refused.
+// Proxies are not used, as they would dial on the service's behalf.
+func webhookTransport(allowPrivate bool) *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+
+	dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}
+	if !allowPrivate {
+		dialer.Control = func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip, err := netip.ParseAddr(host); err != nil || !publicAddress(ip) {
+				return fmt.Errorf("%w: %s", errPrivateAddress, host)
+			}
+			return nil
+		}
+	}
+	transport.DialContext = dialer.DialContext
+	return transport
+}
+
+// publicAddress reports whether ip may receive webhooks: it is not loopback,
+// private, link-local, multicast, unspecified or otherwise reserved
+func publicAddress(ip netip.Addr) bool {
+	ip = ip.Unmap()
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() || ip.IsUnspecified() {
+		return false
+	}
+	for _, network := range nonPublicNetworks {
+		if network.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+// publicHost reports whether a webhook URL host may be registered: an address
+// must be public and a name must be fully qualified and not local. Names are
+// resolved again when dialing, where webhookTransport checks the address.
+func publicHost(host string) bool {
+	if ip, err := netip.ParseAddr(host); err == nil {
+		return publicAddress(ip)
+	}
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	if !strings.Contains(host, ".") {
+		// Single-label names are internal service names
+		return false
+	}
+	for _, suffix := range []string{".localhost", ".local", ".internal"} {
+		if strings.HasSuffix(host, suffix) {
+			return false
+		}
+	}
+	return true
+}
+
+// backoff returns the wait after the given number of failed attempts: RetryBackoff
+// doubled for every attempt after the first, up to RetryMaxBackoff
+func (s *WebhookService) backoff(attempts int) time.Duration {
+	wait := s.cfg.RetryBackoff
+	for i := 1; i < attempts && wait < s.cfg.RetryMaxBackoff; i++ {
+		wait *= 2
+	}
+	return min(wait, s.cfg.RetryMaxBackoff)
+}
+
+// lease is how long a claimed delivery is held back from other pollers while it is sent
+func (s *WebhookService) lease() time.Duration {
+	return 2 * s.cfg.Timeout
+}
+
+// webhook returns one of the user's webhooks with its secret, or ErrWebhookNotFound
+func (s *WebhookService) webhook(ctx context.Context, username string, id int64) (*models.Webhook, error) {
+	hook, err := s.hooks.GetWebhook(ctx, username, id)
+	if err != nil {
+		return nil, err
+	}
+	if hook == nil {
+		return nil, ErrWebhookNotFound
+	}
+	return hook, nil
+}
+
+// SignWebhook returns the signature header of a delivery body sent at timestamp
+// (unix seconds). Receivers compute it with their copy of the secret and compare.
+func SignWebhook(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// webhook validates a request and returns the webhook it describes. Unless
+// allowPrivate is set, the URL must point to a public host.
+func webhook(request models.WebhookRequest, allowPrivate bool) (*models.Webhook, error) {
+	invalid := func(format string, args ...any) error {
+		return fmt.Errorf("%w: "+format, append([]any{ErrInvalidWebhook}, args...)...)
+	}
+
+	hook := &models.Webhook{
+		URL:     strings.TrimSpace(request.URL),
+		Secret:  request.Secret,
+		Enabled: request.Enabled == nil || *request.Enabled,
+	}
+
+	u, err := url.Parse(hook.URL)
+	switch {
+	case len(hook.URL) > maxWebhookURL:
+		return nil, invalid("url is longer than %d characters", maxWebhookURL)
+	case err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "":
+		return nil, invalid("url must be an absolute http or https URL")
+	case u.User != nil:
+		return nil, invalid("url must not contain credentials")
+	case !allowPrivate && !publicHost(u.Hostname()):
+		return nil, invalid("url must point to a public host, not a loopback, private or internal address")
+	case hook.Secret != "" && (len(hook.Secret) < minWebhookSecret || len(hook.Secret) > maxWebhookSecret):
+		return nil, invalid("secret must be between %d and %d characters", minWebhookSecret, maxWebhookSecret)
+	}
+
+	for _, event := range request.Events {
+		event = strings.ToLower(strings.TrimSpace(event))
+		if !slices.Contains(webhookEvents, event) {
+			return nil, invalid("events must be among %s", strings.Join(webhookEvents, ", "))
+		}
+		if !slices.Contains(hook.Events, event) {
+			hook.Events = append(hook.Events, event)
+		}
+	}
+	if len(hook.Events) == 0 {
+		hook.Events = slices.Clone(webhookEvents)
+	}
+
+	return hook, nil
+}
+
+// newWebhookSecret returns a random secret for a webhook registered without one
+func newWebhookSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("error generating webhook secret: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
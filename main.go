@@ -3,16 +3,32 @@ package main
 
 import (
 	"Backend/internal/api"
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"net"
 	"Backend/internal/config"
 	"Backend/internal/database"
+	"Backend/internal/grpcapi"
+	"Backend/internal/logger"
+	"Backend/internal/metrics"
+	"Backend/internal/middleware"
+	"Backend/internal/repository"
 	"Backend/internal/services"
-	"log"
+	"Backend/internal/tracing"
+	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
 	ginSwagger "github.com/swaggo/gin-swagger"
+	"google.golang.org/grpc"
 	"github.com/swaggo/files"
 	_ "Backend/docs" // This will be generated by swag init
 )
@@ -37,65 +53,252 @@ import (
 // @description Type "Bearer" followed by a space and JWT token.
 
 func main() {
-	// Config env
+	// Subcommands
+	if len(os.Args) > 2 && os.Args[1] == "config" && os.Args[2] == "print" {
+		os.Exit(printConfig(os.Args[3:]))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "backfill" {
+		os.Exit(runBackfill(os.Args[2:]))
+	}
+
+	// Config defaults, config file, env and flags
 	cfg := config.Load()
 
-	// Connect to database
-	db, err := database.Connect(cfg.DatabaseURL)
+	// Structured logger; secrets never reach the output
+	log := logger.Setup(cfg.Log.Level, cfg.Log.Format).With("component", "main")
+	logger.AddSecrets(cfg.APIKey, string(cfg.JwtSecretKey), cfg.DatabaseURL, cfg.DB.ReplicaURL)
+
+	// Tracing
+	shutdownTracing, err := tracing.Setup(context.Background(), tracing.Options{
+		Exporter:    cfg.Tracing.Exporter,
+		Endpoint:    cfg.Tracing.OTLPEndpoint,
+		Insecure:    cfg.Tracing.OTLPInsecure,
+		FilePath:    cfg.Tracing.File,
+		SampleRatio: cfg.Tracing.SampleRatio,
+		Environment: cfg.Environment,
+	})
 	if err != nil {
-		log.Fatal("Error connecting to database:", err)
+		log.Error("error configuring tracing", "error", err)
+		os.Exit(1)
+	}
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := shutdownTracing(ctx); err != nil {
+			log.Warn("error shutting down tracing", "error", err)
+		}
+	}()
+
+	// Stop on SIGINT/SIGTERM
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// Connect to database, retrying while it starts up
+	db, err := database.ConnectWithRetry(ctx, cfg.DatabaseURL, cfg.DB)
+	if err != nil {
+		log.Error("error connecting to database", "error", err)
+		os.Exit(1)
 	}
 	defer db.Close()
 
+	// Optional read replica for read-only queries
+	var replica *sql.DB
+	if cfg.DB.ReplicaURL != "" {
+		replica, err = database.ConnectWithRetry(ctx, cfg.DB.ReplicaURL, cfg.DB)
+		if err != nil {
+			log.Error("error connecting to read replica", "error", err)
+			os.Exit(1)
+		}
+		defer replica.Close()
+		log.Info("read replica configured")
+	}
+
 	// Run migrations
 	if err := database.Migrate(db); err != nil {
-		log.Fatal("Error executing migrations:", err)
-		log.Println("Error executing migrations:", err)
-	} else {
-		log.Println("Database migrations executed successfully")
+		log.Error("error executing migrations", "error", err)
+		os.Exit(1)
+	}
+	log.Info("database migrations executed successfully")
+
+	if err := metrics.RegisterDBStats(db, cfg.DatabaseName); err != nil {
+		log.Warn("error registering database metrics", "error", err)
+	}
+	if replica != nil {
+		if err := metrics.RegisterDBStats(replica, cfg.DatabaseName+"_replica"); err != nil {
+			log.Warn("error registering replica metrics", "error", err)
+		}
 	}
 
 	// Initialize services
-	stockService := services.NewStockService(db)
-	apiClient := services.NewAPIClient(cfg.APIKey, cfg.APIBaseURL)
+	stockRepo := repository.NewPostgresRepository(db, replica)
+	stockService := services.NewStockService(stockRepo, stockRepo, stockRepo, cfg.Scoring)
+	authService := services.NewAuthService(db, cfg.Auth)
+	healthService := services.NewHealthService(db, replica, stockService, cfg.Sync.StalenessThreshold)
+	apiClient := services.NewAPIClient(cfg.APIKey, cfg.APIBaseURL, cfg.Sync.PageDelay, cfg.Sync.MaxStocks)
+	backfillClient := services.NewAPIClient(cfg.APIKey, cfg.APIBaseURL, cfg.Backfill.PageDelay, 0)
+	backfillService := services.NewBackfillService(stockRepo, stockService, backfillClient, cfg.Backfill)
+	changeService := services.NewChangeService(stockRepo)
+	changeStream := services.NewChangeStream(stockRepo, stockService, cfg.Stream)
+	go changeStream.Run(ctx)
+	watchlistService := services.NewWatchlistService(stockRepo, stockService)
+	alertService := services.NewAlertService(stockRepo, stockRepo, cfg.Alerts)
+	webhookService := services.NewWebhookService(stockRepo, stockRepo, stockRepo, cfg.Webhooks)
+	go webhookService.Run(ctx)
+	digestService := services.NewDigestService(stockRepo, stockRepo, stockRepo, stockService, services.NewSMTPMailer(cfg.SMTP), cfg.Digest)
+	if cfg.Digest.Enabled {
+		go digestService.Run(ctx)
+	}
 
 	// Initialize stock data sync
-	go func() {
-		for {
+	if cfg.Sync.Enabled {
+		go func() {
 			for {
-				log.Printf("NewAPIClient", apiClient)
-				if err := stockService.SyncAllData(apiClient); err != nil {
+				if err := stockService.SyncAllData(ctx, apiClient); err != nil {
+					log.Error("stock sync failed", "error", err)
+				}
+				if _, err := alertService.Evaluate(ctx); err != nil {
+					log.Error("alert evaluation failed", "error", err)
+				}
+				select {
+				case <-ctx.Done():
+					return
+				case <-time.After(cfg.Sync.Interval):
 				}
-				break
 			}
-			time.Sleep(40 * time.Minute)
-		}
-	}()
+		}()
+	} else {
+		log.Info("periodic stock sync disabled")
+	}
 
 	// Config gin
-	r := gin.Default()
+	gin.SetMode(cfg.GinMode)
+	r := gin.New()
+	if err := r.SetTrustedProxies(cfg.HTTP.TrustedProxies); err != nil {
+		log.Error("invalid trusted proxies", "error", err)
+		os.Exit(1)
+	}
+	r.Use(gin.Recovery(), otelgin.Middleware(tracing.ServiceName), middleware.RequestLogger(), middleware.Metrics())
+	if cfg.HTTP.SecurityHeaders {
+		r.Use(middleware.SecurityHeaders(cfg.HTTP))
+	}
+	r.Use(middleware.MaxBodySize(cfg.HTTP.MaxBodyBytes))
 
 	// Configure middlewares - CORS
 	r.Use(cors.New(cors.Config{
-		AllowOrigins: []string{"http://localhost:3000", "http://localhost:5173", "http://localhost:8070" },
-		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowHeaders:     []string{"*"},
-		AllowCredentials: true,
+		AllowOrigins:     cfg.CORS.AllowOrigins,
+		AllowMethods:     cfg.CORS.AllowMethods,
+		AllowHeaders:     cfg.CORS.AllowHeaders,
+		AllowCredentials: cfg.CORS.AllowCredentials,
+		ExposeHeaders:    cfg.CORS.ExposeHeaders,
+		MaxAge:           cfg.CORS.MaxAge,
 	}))
 
 	// Swagger endpoint
 	r.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
 
 	// Config routes
-	api.SetupRoutes(r, stockService, cfg)
+	api.SetupRoutes(r, stockService, authService, healthService, backfillService, changeService, changeStream, watchlistService, alertService, webhookService, digestService, cfg)
 
 	// Start server
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "8080"
+	srv := &http.Server{
+		Addr:              ":" + cfg.Port,
+		Handler:           r,
+		ReadTimeout:       cfg.HTTP.ReadTimeout,
+		ReadHeaderTimeout: cfg.HTTP.ReadHeaderTimeout,
+		WriteTimeout:      cfg.HTTP.WriteTimeout,
+		IdleTimeout:       cfg.HTTP.IdleTimeout,
+		MaxHeaderBytes:    cfg.HTTP.MaxHeaderBytes,
 	}
 
-	log.Printf("Server started on port %s", port)
-	log.Printf("Swagger documentation available at: http://localhost:%s/swagger/index.html", port)
-	log.Fatal(r.Run(":" + port))
+	scheme := "http"
+	if cfg.HTTP.TLSEnabled() {
+		scheme = "https"
+	}
+
+	serverErr := make(chan error, 2)
+	go func() {
+		if cfg.HTTP.TLSEnabled() {
+			serverErr <- srv.ListenAndServeTLS(cfg.HTTP.TLSCertFile, cfg.HTTP.TLSKeyFile)
+		} else {
+			serverErr <- srv.ListenAndServe()
+		}
+	}()
+
+	log.Info("server started", "port", cfg.Port, "tls", cfg.HTTP.TLSEnabled())
+	log.Info("swagger documentation available", "url", scheme+"://localhost:"+cfg.Port+"/swagger/index.html")
+
+	// Start the gRPC server next to the HTTP one, sharing its services and tokens
+	var grpcServer *grpc.Server
+	if cfg.GRPC.Port != "" {
+		listener, err := net.Listen("tcp", ":"+cfg.GRPC.Port)
+		if err != nil {
+			log.Error("error listening for gRPC", "port", cfg.GRPC.Port, "error", err)
+			os.Exit(1)
+		}
+		grpcServer, err = grpcapi.NewServer(cfg, authService, grpcapi.NewService(stockService, changeService, changeStream, cfg.Stream.Heartbeat))
+		if err != nil {
+			log.Error("error creating gRPC server", "error", err)
+			os.Exit(1)
+		}
+		go func() {
+			serverErr <- grpcServer.Serve(listener)
+		}()
+		log.Info("gRPC server started", "port", cfg.GRPC.Port, "tls", cfg.HTTP.TLSEnabled())
+	}
+
+	select {
+	case err := <-serverErr:
+		if !errors.Is(err, http.ErrServerClosed) {
+			log.Error("server stopped", "error", err)
+			os.Exit(1)
+		}
+	case <-ctx.Done():
+		log.Info("shutting down server", "timeout", cfg.HTTP.ShutdownTimeout)
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.HTTP.ShutdownTimeout)
+		defer cancel()
+		if err := srv.Shutdown(shutdownCtx); err != nil {
+			log.Error("error shutting down server", "error", err)
+		}
+		if grpcServer != nil {
+			stopGRPC(shutdownCtx, grpcServer)
+		}
+	}
+
+	// A running backfill is paused and can be resumed later
+	backfillService.Stop()
+	log.Info("server stopped")
+}
+
+// stopGRPC waits for the running gRPC calls to finish until ctx is done, then
+// closes the connections left
+func stopGRPC(ctx context.Context, srv *grpc.Server) {
+	stopped := make(chan struct{})
+	go func() {
+		srv.GracefulStop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		srv.Stop()
+	}
+}
+
+// printConfig implements "config print": it shows the effective configuration with secrets masked
+func printConfig(args []string) int {
+	cfg, err := config.Parse(args)
+	if errors.Is(err, flag.ErrHelp) {
+		return 0
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Configuration error:\n%v\n", err)
+		return 1
+	}
+
+	if err := cfg.Print(os.Stdout); err != nil {
+		fmt.Fprintf(os.Stderr, "Error printing configuration: %v\n", err)
+		return 1
+	}
+	return 0
 }